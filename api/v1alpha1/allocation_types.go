@@ -32,6 +32,17 @@ type AllocationSpec struct {
 	Prefix string `json:"prefix"`
 
 	Tenant string `json:"tenant"`
+
+	// ControllerRef is the name of the NetrisController which manages the resource.
+	// The controller configured for the operator is used if not set.
+	// +optional
+	ControllerRef string `json:"controllerRef,omitempty"`
+
+	// NetrisName is the name of the resource in Netris. The naming policy of
+	// the operator is used if not set, and only when the resource is created
+	// or imported, so an existing resource is renamed only by setting it.
+	// +optional
+	NetrisName string `json:"netrisName,omitempty"`
 }
 
 // AllocationStatus defines the observed state of Allocation
@@ -40,6 +51,24 @@ type AllocationStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 	Status  string `json:"status,omitempty"`
 	Message string `json:"message,omitempty"`
+
+	// ObservedGeneration is the .metadata.generation the status was set for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// AppliedGeneration is the .metadata.generation last applied to Netris.
+	AppliedGeneration int64 `json:"appliedGeneration,omitempty"`
+
+	// Conditions are Ready, Synced, DependenciesReady and NetrisReachable.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// DriftedFields are the fields changed in Netris and kept there by the
+	// report or adopt drift policy.
+	// +nullable
+	// +optional
+	DriftedFields []DriftedField `json:"driftedFields"`
 }
 
 // +kubebuilder:object:root=true
@@ -32,9 +32,11 @@ type AllocationMetaSpec struct {
 	AllocationCRGeneration int64  `json:"allocationGeneration"`
 	ID                     int    `json:"id"`
 	AllocationName         string `json:"allocationName"`
+	NetrisName             string `json:"netrisName,omitempty"`
 
-	Prefix string `json:"prefix"`
-	Tenant string `json:"tenant"`
+	Prefix        string `json:"prefix"`
+	Tenant        string `json:"tenant"`
+	ControllerRef string `json:"controllerRef,omitempty"`
 }
 
 // AllocationMetaStatus defines the observed state of AllocationMeta
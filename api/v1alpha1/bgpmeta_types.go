@@ -32,6 +32,7 @@ type BGPMetaSpec struct {
 	BGPCRGeneration int64  `json:"bgpGeneration"`
 	ID              int    `json:"id"`
 	BGPName         string `json:"bgpName"`
+	NetrisName      string `json:"netrisName,omitempty"`
 
 	AllowasIn          int    `json:"allowas_in"`
 	HWID               int    `json:"hwid"`
@@ -63,6 +64,7 @@ type BGPMetaSpec struct {
 	UpdateSource       string `json:"update_source"`
 	Vlan               int    `json:"vlan"`
 	Weight             int    `json:"weight"`
+	ControllerRef      string `json:"controllerRef,omitempty"`
 }
 
 // BGPMetaStatus defines the observed state of BGPMeta
@@ -0,0 +1,148 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types of the resources.
+const (
+	// ConditionReady is True when the resource exists in Netris, is in sync
+	// with the spec and is provisioned.
+	ConditionReady = "Ready"
+	// ConditionSynced is True when the latest spec was applied to Netris.
+	ConditionSynced = "Synced"
+	// ConditionDependenciesReady is False while a resource the spec refers
+	// to, e.g. a site or a tenant, doesn't exist.
+	ConditionDependenciesReady = "DependenciesReady"
+	// ConditionNetrisReachable is False while the Netris controller can't be reached.
+	ConditionNetrisReachable = "NetrisReachable"
+	// ConditionPaused is True while the reconciliation is paused by the
+	// resource.k8s.netris.ai/paused annotation. It's removed on resume.
+	ConditionPaused = "Paused"
+)
+
+// Condition reasons of the resources.
+const (
+	ReasonActive             = "Active"
+	ReasonDisabled           = "Disabled"
+	ReasonProvisioning       = "Provisioning"
+	ReasonSynced             = "Synced"
+	ReasonSyncFailed         = "SyncFailed"
+	ReasonCacheStale         = "CacheStale"
+	ReasonResolved           = "Resolved"
+	ReasonDependencyNotFound = "DependencyNotFound"
+	ReasonReachable          = "Reachable"
+	ReasonNetrisUnavailable  = "NetrisUnavailable"
+	// ReasonDrifted means the object was changed in Netris and the change is
+	// kept by the drift policy.
+	ReasonDrifted = "Drifted"
+	// ReasonDryRun means the change was planned in the dry-run mode and not
+	// sent to Netris.
+	ReasonDryRun = "DryRun"
+	// ReasonPaused means the reconciliation is paused by the annotation.
+	ReasonPaused = "Paused"
+	// ReasonMaintenanceWindow means an update is deferred until a maintenance
+	// window opens.
+	ReasonMaintenanceWindow = "MaintenanceWindow"
+	// ReasonDeletionBlocked means a deleted resource is kept by the deletion
+	// protection or waits for the delete confirmation.
+	ReasonDeletionBlocked = "DeletionBlocked"
+	// ReasonNameCollision means the Netris name of the resource is taken by
+	// another resource or by an object which already exists in Netris.
+	ReasonNameCollision = "NameCollision"
+)
+
+// Condition is an observation of the state of a resource. It has the shape of
+// the metav1.Condition of the newer Kubernetes versions.
+type Condition struct {
+	// Type of the condition in CamelCase.
+	// +kubebuilder:validation:Pattern=`^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])$`
+	// +kubebuilder:validation:MaxLength=316
+	Type string `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown.
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status metav1.ConditionStatus `json:"status"`
+
+	// ObservedGeneration is the .metadata.generation the condition was set for.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastTransitionTime is the last time the condition changed its status.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Format=date-time
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// Reason is a CamelCase reason of the last transition.
+	// +kubebuilder:validation:MaxLength=1024
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=`^[A-Za-z]([A-Za-z0-9_,:]*[A-Za-z0-9_])?$`
+	Reason string `json:"reason"`
+
+	// Message is a human readable message of the last transition.
+	// +kubebuilder:validation:MaxLength=32768
+	Message string `json:"message"`
+}
+
+// SetCondition adds or updates the condition of the same type in conditions.
+// LastTransitionTime changes only when the status does.
+func SetCondition(conditions *[]Condition, condition Condition) {
+	if condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.Now()
+	}
+	for i, c := range *conditions {
+		if c.Type != condition.Type {
+			continue
+		}
+		if c.Status == condition.Status {
+			condition.LastTransitionTime = c.LastTransitionTime
+		}
+		(*conditions)[i] = condition
+		return
+	}
+	*conditions = append(*conditions, condition)
+}
+
+// RemoveCondition removes the condition of the given type from conditions.
+func RemoveCondition(conditions *[]Condition, conditionType string) {
+	kept := (*conditions)[:0]
+	for _, c := range *conditions {
+		if c.Type != conditionType {
+			kept = append(kept, c)
+		}
+	}
+	*conditions = kept
+}
+
+// FindCondition returns the condition of the given type.
+func FindCondition(conditions []Condition, conditionType string) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsConditionTrue reports whether the condition of the given type is True.
+func IsConditionTrue(conditions []Condition, conditionType string) bool {
+	c := FindCondition(conditions, conditionType)
+	return c != nil && c.Status == metav1.ConditionTrue
+}
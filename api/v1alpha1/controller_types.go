@@ -34,6 +34,17 @@ type ControllerSpec struct {
 
 	// +kubebuilder:validation:Pattern=`^(([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])$`
 	MainIP string `json:"mainIp,omitempty"`
+
+	// ControllerRef is the name of the NetrisController which manages the resource.
+	// The controller configured for the operator is used if not set.
+	// +optional
+	ControllerRef string `json:"controllerRef,omitempty"`
+
+	// NetrisName is the name of the resource in Netris. The naming policy of
+	// the operator is used if not set, and only when the resource is created
+	// or imported, so an existing resource is renamed only by setting it.
+	// +optional
+	NetrisName string `json:"netrisName,omitempty"`
 }
 
 // ControllerStatus defines the observed state of Controller
@@ -43,6 +54,24 @@ type ControllerStatus struct {
 
 	Status  string `json:"status,omitempty"`
 	Message string `json:"message,omitempty"`
+
+	// ObservedGeneration is the .metadata.generation the status was set for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// AppliedGeneration is the .metadata.generation last applied to Netris.
+	AppliedGeneration int64 `json:"appliedGeneration,omitempty"`
+
+	// Conditions are Ready, Synced, DependenciesReady and NetrisReachable.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// DriftedFields are the fields changed in Netris and kept there by the
+	// report or adopt drift policy.
+	// +nullable
+	// +optional
+	DriftedFields []DriftedField `json:"driftedFields"`
 }
 
 // +kubebuilder:object:root=true
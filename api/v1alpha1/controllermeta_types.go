@@ -33,11 +33,13 @@ type ControllerMetaSpec struct {
 	ControllerCRGeneration int64  `json:"controllerGeneration"`
 	ID                     int    `json:"id"`
 	ControllerName         string `json:"controllerName"`
+	NetrisName             string `json:"netrisName,omitempty"`
 
-	TenantID    int    `json:"tenant,omitempty"`
-	Description string `json:"description,omitempty"`
-	SiteID      int    `json:"site,omitempty"`
-	MainIP      string `json:"mainIp,omitempty"`
+	TenantID      int    `json:"tenant,omitempty"`
+	Description   string `json:"description,omitempty"`
+	SiteID        int    `json:"site,omitempty"`
+	MainIP        string `json:"mainIp,omitempty"`
+	ControllerRef string `json:"controllerRef,omitempty"`
 }
 
 // ControllerMetaStatus defines the observed state of ControllerMeta
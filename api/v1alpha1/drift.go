@@ -14,26 +14,24 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-package lbwatcher
-
-import (
-	"context"
-	"fmt"
-
-	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-)
-
-func getPodsByLabelSeector(clientset *kubernetes.Clientset, namespace, selectors string) (*v1.PodList, error) {
-	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
-	defer cancel()
-	listOptions := metav1.ListOptions{
-		LabelSelector: selectors,
-	}
-	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, listOptions)
-	if err != nil {
-		return pods, fmt.Errorf("{getPods} %s", err)
-	}
-	return pods, nil
+package v1alpha1
+
+import "fmt"
+
+// DriftedField is a field of the spec which has another value in Netris.
+type DriftedField struct {
+	// Field is the path of the field in the resource, e.g. spec.state.
+	Field string `json:"field"`
+
+	// Spec is the value of the field in the spec.
+	// +optional
+	Spec string `json:"spec,omitempty"`
+
+	// Netris is the value of the field in Netris.
+	// +optional
+	Netris string `json:"netris,omitempty"`
+}
+
+func (f DriftedField) String() string {
+	return fmt.Sprintf("%s: %s in the spec, %s in Netris", f.Field, f.Spec, f.Netris)
 }
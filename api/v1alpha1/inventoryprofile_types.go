@@ -34,6 +34,17 @@ type InventoryProfileSpec struct {
 	NTPServers       []NTPServer                  `json:"ntpServers,omitempty"`
 	DNSServers       []DNSServer                  `json:"dnsServers,omitempty"`
 	CustomRules      []InventoryProfileCustomRule `json:"customRules,omitempty"`
+
+	// ControllerRef is the name of the NetrisController which manages the resource.
+	// The controller configured for the operator is used if not set.
+	// +optional
+	ControllerRef string `json:"controllerRef,omitempty"`
+
+	// NetrisName is the name of the resource in Netris. The naming policy of
+	// the operator is used if not set, and only when the resource is created
+	// or imported, so an existing resource is renamed only by setting it.
+	// +optional
+	NetrisName string `json:"netrisName,omitempty"`
 }
 
 type InventoryProfileCustomRule struct {
@@ -65,6 +76,24 @@ type InventoryProfileStatus struct {
 	NTPServers  string `json:"ntpServers,omitempty"`
 	DNSServers  string `json:"dnsServers,omitempty"`
 	CustomRules string `json:"customRules,omitempty"`
+
+	// ObservedGeneration is the .metadata.generation the status was set for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// AppliedGeneration is the .metadata.generation last applied to Netris.
+	AppliedGeneration int64 `json:"appliedGeneration,omitempty"`
+
+	// Conditions are Ready, Synced, DependenciesReady and NetrisReachable.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// DriftedFields are the fields changed in Netris and kept there by the
+	// report or adopt drift policy.
+	// +nullable
+	// +optional
+	DriftedFields []DriftedField `json:"driftedFields"`
 }
 
 //+kubebuilder:object:root=true
@@ -30,6 +30,7 @@ type InventoryProfileMetaSpec struct {
 	InventoryProfileCRGeneration int64  `json:"inventoryProfileGeneration"`
 	ID                           int    `json:"id"`
 	InventoryProfileName         string `json:"inventoryProfileName"`
+	NetrisName                   string `json:"netrisName,omitempty"`
 
 	Description string `json:"description,omitempty"`
 
@@ -40,6 +41,7 @@ type InventoryProfileMetaSpec struct {
 	NTPServers       []string                     `json:"ntpServers,omitempty"`
 	DNSServers       []string                     `json:"dnsServers,omitempty"`
 	CustomRules      []InventoryProfileCustomRule `json:"customRules,omitempty"`
+	ControllerRef    string                       `json:"controllerRef,omitempty"`
 }
 
 // InventoryProfileMetaStatus defines the observed state of InventoryProfileMeta
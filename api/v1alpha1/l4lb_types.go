@@ -39,6 +39,17 @@ type L4LBSpec struct {
 
 	Frontend L4LBFrontend  `json:"frontend"`
 	Backend  []L4LBBackend `json:"backend"`
+
+	// ControllerRef is the name of the NetrisController which manages the resource.
+	// The controller configured for the operator is used if not set.
+	// +optional
+	ControllerRef string `json:"controllerRef,omitempty"`
+
+	// NetrisName is the name of the resource in Netris. The naming policy of
+	// the operator is used if not set, and only when the resource is created
+	// or imported, so an existing resource is renamed only by setting it.
+	// +optional
+	NetrisName string `json:"netrisName,omitempty"`
 }
 
 // L4LBCheck .
@@ -72,6 +83,29 @@ type L4LBStatus struct { // INSERT ADDITIONAL STATUS FIELD - define observed sta
 	Message      string      `json:"message,omitempty"`
 	ModifiedDate metav1.Time `json:"modified,omitempty"`
 	Port         string      `json:"port,omitempty"`
+
+	// ObservedGeneration is the .metadata.generation the status was set for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// AppliedGeneration is the .metadata.generation last applied to Netris.
+	AppliedGeneration int64 `json:"appliedGeneration,omitempty"`
+
+	// Conditions are Ready, Synced, DependenciesReady and NetrisReachable.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// DriftedFields are the fields changed in Netris and kept there by the
+	// report or adopt drift policy.
+	// +nullable
+	// +optional
+	DriftedFields []DriftedField `json:"driftedFields"`
+
+	// Plan is the Netris request of the dry-run mode, it isn't sent to Netris.
+	// +nullable
+	// +optional
+	Plan *NetrisPlan `json:"plan"`
 }
 
 // +kubebuilder:object:root=true
@@ -33,6 +33,7 @@ type L4LBMetaSpec struct {
 	L4LBCRGeneration int64  `json:"l4lbGeneration"`
 	ID               int    `json:"id"`
 	L4LBName         string `json:"l4lbName"`
+	NetrisName       string `json:"netrisName,omitempty"`
 
 	Tenant    int    `json:"tenantId"`
 	SiteID    int    `json:"siteId"`
@@ -51,7 +52,8 @@ type L4LBMetaSpec struct {
 
 	HealthCheck *L4LBMetaHealthCheck `json:"healthCheck"`
 
-	Backend []L4LBMetaBackend `json:"backendIps"`
+	Backend       []L4LBMetaBackend `json:"backendIps"`
+	ControllerRef string            `json:"controllerRef,omitempty"`
 }
 
 // L4LBMetaHealthCheckTCP .
@@ -33,8 +33,9 @@ type LinkMetaSpec struct {
 	ID               string `json:"id"`
 	LinkName         string `json:"linkName"`
 
-	Local  int `json:"local"`
-	Remote int `json:"remote"`
+	Local         int    `json:"local"`
+	Remote        int    `json:"remote"`
+	ControllerRef string `json:"controllerRef,omitempty"`
 }
 
 // LinkMetaStatus defines the observed state of LinkMeta
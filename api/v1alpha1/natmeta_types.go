@@ -32,20 +32,22 @@ type NatMetaSpec struct {
 	NatCRGeneration int64  `json:"natGeneration"`
 	ID              int    `json:"id"`
 	NatName         string `json:"natName"`
+	NetrisName      string `json:"netrisName,omitempty"`
 
-	Comment    string `json:"comment,omitempty"`
-	State      string `json:"state,omitempty"`
-	SiteID     int    `json:"siteID"`
-	Action     string `json:"action"`
-	Protocol   string `json:"protocol"`
-	SrcAddress string `json:"srcAddress"`
-	SrcPort    string `json:"srcPort,omitempty"`
-	DstAddress string `json:"dstAddress"`
-	DstPort    string `json:"dstPort,omitempty"`
-	SnatToIP   string `json:"snatToIp,omitempty"`
-	SnatToPool string `json:"snatToPool,omitempty"`
-	DnatToIP   string `json:"dnatToIp,omitempty"`
-	DnatToPort string `json:"dnatToPort,omitempty"`
+	Comment       string `json:"comment,omitempty"`
+	State         string `json:"state,omitempty"`
+	SiteID        int    `json:"siteID"`
+	Action        string `json:"action"`
+	Protocol      string `json:"protocol"`
+	SrcAddress    string `json:"srcAddress"`
+	SrcPort       string `json:"srcPort,omitempty"`
+	DstAddress    string `json:"dstAddress"`
+	DstPort       string `json:"dstPort,omitempty"`
+	SnatToIP      string `json:"snatToIp,omitempty"`
+	SnatToPool    string `json:"snatToPool,omitempty"`
+	DnatToIP      string `json:"dnatToIp,omitempty"`
+	DnatToPort    string `json:"dnatToPort,omitempty"`
+	ControllerRef string `json:"controllerRef,omitempty"`
 }
 
 // NatMetaStatus defines the observed state of NatMeta
@@ -0,0 +1,143 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// ControllerReferrer is implemented by the resources which can be managed
+// by a NetrisController.
+type ControllerReferrer interface {
+	GetControllerRef() string
+}
+
+// GetControllerRef .
+func (in *Allocation) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *AllocationMeta) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *BGP) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *BGPMeta) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *Controller) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *ControllerMeta) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *InventoryProfile) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *InventoryProfileMeta) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *L4LB) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *L4LBMeta) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *Link) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *LinkMeta) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *Nat) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *NatMeta) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *Site) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *SiteMeta) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *Softgate) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *SoftgateMeta) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *Subnet) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *SubnetMeta) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *Switch) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *SwitchMeta) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *VNet) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
+
+// GetControllerRef .
+func (in *VNetMeta) GetControllerRef() string {
+	return in.Spec.ControllerRef
+}
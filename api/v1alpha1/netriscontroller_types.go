@@ -0,0 +1,93 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NetrisControllerStatus defines the observed state of NetrisController
+type NetrisControllerStatus struct {
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	// ObservedGeneration is the .metadata.generation the status was set for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions are Ready, Synced, DependenciesReady and NetrisReachable.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// NetrisControllerSecretRef references the Secret with the credentials of the Netris controller.
+type NetrisControllerSecretRef struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// NetrisControllerSpec defines the desired state of NetrisController
+type NetrisControllerSpec struct {
+	// Host is the address of the Netris controller, e.g. https://netris.example.com
+	Host string `json:"host"`
+
+	// CredentialsSecretRef references the Secret with the "login" and "password" keys.
+	CredentialsSecretRef NetrisControllerSecretRef `json:"credentialsSecretRef"`
+
+	// +optional
+	Insecure bool `json:"insecure,omitempty"`
+
+	// VPCID is the VPC used for subnets and L4LBs, the default VPC if not set.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	VPCID int `json:"vpcid,omitempty"`
+
+	// L4LBTenant is the tenant of the L4LBs without an explicit owner.
+	// +optional
+	L4LBTenant string `json:"l4lbTenant,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Host",type=string,JSONPath=`.spec.host`
+// +kubebuilder:printcolumn:name="VPC ID",type=integer,JSONPath=`.spec.vpcid`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// NetrisController is the Schema for the netriscontrollers API
+type NetrisController struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NetrisControllerSpec   `json:"spec,omitempty"`
+	Status NetrisControllerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NetrisControllerList contains a list of NetrisController
+type NetrisControllerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NetrisController `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NetrisController{}, &NetrisControllerList{})
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// NetrisPlan is the request to Netris computed in the dry-run mode and not sent.
+type NetrisPlan struct {
+	// Action is create, update or delete.
+	Action string `json:"action"`
+
+	// ID is the ID of the object in Netris to update or delete.
+	// +optional
+	ID int `json:"id,omitempty"`
+
+	// Payload is the JSON body of the create or update request.
+	// +optional
+	Payload string `json:"payload,omitempty"`
+
+	// Changes are the fields of an update which differ from the object in the
+	// Netris storage, Spec is the value to send.
+	// +optional
+	Changes []DriftedField `json:"changes,omitempty"`
+}
@@ -37,6 +37,7 @@ type SiteMetaSpec struct {
 	SiteCRGeneration int64  `json:"siteGeneration"`
 	ID               int    `json:"id"`
 	SiteName         string `json:"siteName"`
+	NetrisName       string `json:"netrisName,omitempty"`
 
 	PublicASN           int    `json:"publicAsn"`
 	RohASN              int    `json:"rohAsn"`
@@ -45,6 +46,7 @@ type SiteMetaSpec struct {
 	SiteMesh            string `json:"siteMesh"`
 
 	ACLDefaultPolicy string `json:"aclDefaultPolicy"`
+	ControllerRef    string `json:"controllerRef,omitempty"`
 }
 
 // +kubebuilder:object:root=true
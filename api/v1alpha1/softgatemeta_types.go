@@ -33,13 +33,15 @@ type SoftgateMetaSpec struct {
 	SoftgateCRGeneration int64  `json:"softgateGeneration"`
 	ID                   int    `json:"id"`
 	SoftgateName         string `json:"softgateName"`
-
-	TenantID    int    `json:"tenantid,omitempty"`
-	Description string `json:"description,omitempty"`
-	SiteID      int    `json:"siteid,omitempty"`
-	ProfileID   int    `json:"profileid,omitempty"`
-	MainIP      string `json:"mainIp,omitempty"`
-	MgmtIP      string `json:"mgmtIp,omitempty"`
+	NetrisName           string `json:"netrisName,omitempty"`
+
+	TenantID      int    `json:"tenantid,omitempty"`
+	Description   string `json:"description,omitempty"`
+	SiteID        int    `json:"siteid,omitempty"`
+	ProfileID     int    `json:"profileid,omitempty"`
+	MainIP        string `json:"mainIp,omitempty"`
+	MgmtIP        string `json:"mgmtIp,omitempty"`
+	ControllerRef string `json:"controllerRef,omitempty"`
 }
 
 // SoftgateMetaStatus defines the observed state of SoftgateMeta
@@ -33,12 +33,14 @@ type SubnetMetaSpec struct {
 	SubnetCRGeneration int64  `json:"subnetGeneration"`
 	ID                 int    `json:"id"`
 	SubnetName         string `json:"subnetName"`
+	NetrisName         string `json:"netrisName,omitempty"`
 
 	Prefix         string `json:"prefix,omitempty"`
 	TenantID       int    `json:"tenantid,omitempty"`
 	Purpose        string `json:"purpose,omitempty"`
 	DefaultGateway string `json:"defaultGateway,omitempty"`
 	Sites          []int  `json:"sites,omitempty"`
+	ControllerRef  string `json:"controllerRef,omitempty"`
 }
 
 // SubnetMetaStatus defines the observed state of SubnetMeta
@@ -33,17 +33,19 @@ type SwitchMetaSpec struct {
 	SwitchCRGeneration int64  `json:"switchGeneration"`
 	ID                 int    `json:"id"`
 	SwitchName         string `json:"switchName"`
+	NetrisName         string `json:"netrisName,omitempty"`
 
-	TenantID    int           `json:"tenant,omitempty"`
-	Description string        `json:"description,omitempty"`
-	NOS         inventory.NOS `json:"nos,omitempty"`
-	SiteID      int           `json:"site,omitempty"`
-	ASN         int           `json:"asn,omitempty"`
-	ProfileID   int           `json:"profile,omitempty"`
-	MainIP      string        `json:"mainIp,omitempty"`
-	MgmtIP      string        `json:"mgmtIp,omitempty"`
-	PortsCount  int           `json:"portsCount,omitempty"`
-	MacAddress  string        `json:"macAddress,omitempty"`
+	TenantID      int           `json:"tenant,omitempty"`
+	Description   string        `json:"description,omitempty"`
+	NOS           inventory.NOS `json:"nos,omitempty"`
+	SiteID        int           `json:"site,omitempty"`
+	ASN           int           `json:"asn,omitempty"`
+	ProfileID     int           `json:"profile,omitempty"`
+	MainIP        string        `json:"mainIp,omitempty"`
+	MgmtIP        string        `json:"mgmtIp,omitempty"`
+	PortsCount    int           `json:"portsCount,omitempty"`
+	MacAddress    string        `json:"macAddress,omitempty"`
+	ControllerRef string        `json:"controllerRef,omitempty"`
 }
 
 // SwitchMetaStatus defines the observed state of SwitchMeta
@@ -40,6 +40,29 @@ type VNetStatus struct {
 	Gateways     string      `json:"gateways,omitempty"`
 	Sites        string      `json:"sites,omitempty"`
 	ModifiedDate metav1.Time `json:"modified,omitempty"`
+
+	// ObservedGeneration is the .metadata.generation the status was set for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// AppliedGeneration is the .metadata.generation last applied to Netris.
+	AppliedGeneration int64 `json:"appliedGeneration,omitempty"`
+
+	// Conditions are Ready, Synced, DependenciesReady and NetrisReachable.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// DriftedFields are the fields changed in Netris and kept there by the
+	// report or adopt drift policy.
+	// +nullable
+	// +optional
+	DriftedFields []DriftedField `json:"driftedFields"`
+
+	// Plan is the Netris request of the dry-run mode, it isn't sent to Netris.
+	// +nullable
+	// +optional
+	Plan *NetrisPlan `json:"plan"`
 }
 
 // +kubebuilder:object:root=true
@@ -82,6 +105,17 @@ type VNetSpec struct {
 	GuestTenants []string   `json:"guestTenants"`
 	Sites        []VNetSite `json:"sites"`
 	VlanID       string     `json:"vlanId,omitempty"`
+
+	// ControllerRef is the name of the NetrisController which manages the resource.
+	// The controller configured for the operator is used if not set.
+	// +optional
+	ControllerRef string `json:"controllerRef,omitempty"`
+
+	// NetrisName is the name of the resource in Netris. The naming policy of
+	// the operator is used if not set, and only when the resource is created
+	// or imported, so an existing resource is renamed only by setting it.
+	// +optional
+	NetrisName string `json:"netrisName,omitempty"`
 }
 
 // VNetSite .
@@ -33,6 +33,7 @@ type VNetMetaSpec struct {
 	Members          []VNetMetaMember  `json:"members"`
 	Name             string            `json:"name"`
 	VnetName         string            `json:"vnetName"`
+	NetrisName       string            `json:"netrisName,omitempty"`
 	Owner            string            `json:"owner"`
 	Provisioning     int               `json:"provisioning"`
 	Sites            []VNetMetaSite    `json:"sites"`
@@ -42,6 +43,7 @@ type VNetMetaSpec struct {
 	VaNativeVLAN     int               `json:"vaNativeVlan"`
 	VaVLANs          string            `json:"vaVlans"`
 	VlanID           string            `json:"vlanid"`
+	ControllerRef    string            `json:"controllerRef,omitempty"`
 }
 
 // VNetMetaSite .
@@ -21,9 +21,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -54,6 +56,10 @@ var (
 	contextTimeout  = requeueInterval
 )
 
+// stuckAfterIntervals is how many requeue intervals without a finished cycle
+// make the watcher unready.
+const stuckAfterIntervals = 5
+
 // Watcher is the main structure in order to manage calicowatcher
 type Watcher struct {
 	Options    Options
@@ -64,7 +70,11 @@ type Watcher struct {
 	client     client.Client
 	clientset  *kubernetes.Clientset
 	data       data
-	stop       chan struct{}
+
+	mu        sync.Mutex
+	running   bool
+	lastCycle time.Time
+	disabled  bool
 }
 
 type data struct {
@@ -105,7 +115,6 @@ func NewWatcher(nStorage *netrisstorage.Storage, mgr manager.Manager, options Op
 		MGR:      mgr,
 		Options:  options,
 		Calico:   calico.New(calico.Options{ContextTimeout: options.RequeueInterval}),
-		stop:     make(chan struct{}),
 	}
 	return watcher, nil
 }
@@ -138,14 +147,44 @@ func (w *Watcher) start() {
 		w.data.asnStart = 4230000000
 		w.data.asnEnd = 4239999999
 	}
+	started := time.Now()
 	err = w.mainProcessing()
 	if err != nil {
 		logger.Error(err, "")
 	}
+	observeCycle(started, err)
+	w.mu.Lock()
+	w.lastCycle = time.Now()
+	w.mu.Unlock()
 }
 
-// Start .
-func (w *Watcher) Start() {
+// NeedLeaderElection is true, so only the leader replica manages the BGPs of
+// the nodes and the node-to-node mesh of Calico.
+func (w *Watcher) NeedLeaderElection() bool {
+	return true
+}
+
+// Ready fails until the first processing of the nodes once the watcher is
+// started, and when its loop is stuck for several intervals. The replicas
+// which aren't the leader don't run it and are ready.
+func (w *Watcher) Ready(_ *http.Request) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.running {
+		return nil
+	}
+	if w.lastCycle.IsZero() {
+		return fmt.Errorf("calicowatcher hasn't processed the nodes yet")
+	}
+	if since := time.Since(w.lastCycle); since > stuckAfterIntervals*requeueInterval+contextTimeout {
+		return fmt.Errorf("calicowatcher loop is stuck, the last cycle finished %s ago", since.Round(time.Second))
+	}
+	return nil
+}
+
+// Start processes the nodes every requeue interval until stop is closed or
+// Calico isn't detected.
+func (w *Watcher) Start(stop <-chan struct{}) error {
 	if w.Options.LogLevel == "debug" {
 		logger = zap.New(zap.Level(zapcore.DebugLevel), zap.UseDevMode(false))
 	} else {
@@ -160,18 +199,36 @@ func (w *Watcher) Start() {
 		contextTimeout = requeueInterval
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cntxt = ctx
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	w.mu.Lock()
+	w.running = true
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		w.running = false
+		w.mu.Unlock()
+	}()
+
 	ticker := time.NewTicker(requeueInterval)
+	defer ticker.Stop()
 	w.start()
-	for {
+	for !w.disabled {
 		select {
 		case <-ticker.C:
 			w.start()
-		case <-w.stop:
-			ticker.Stop()
-			close(w.stop)
-			return
+		case <-stop:
+			logger.Info("Calico Watcher Stopped")
+			return nil
 		}
 	}
+	return nil
 }
 
 func (w *Watcher) process() error {
@@ -395,7 +452,7 @@ func (w *Watcher) mainProcessing() error {
 			logger.Info(err.Error())
 			logger.Info("Calico CNI not detected")
 			logger.Info("Calico Watcher Stopped")
-			w.stop <- struct{}{}
+			w.disabled = true
 			return nil
 		}
 		return err
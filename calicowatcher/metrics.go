@@ -0,0 +1,55 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package calicowatcher
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	cycleDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "netris_calicowatcher_cycle_duration_seconds",
+			Help:    "Duration of the calico BGP processing cycles by result (success, failure).",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"result"},
+	)
+	lastSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "netris_calicowatcher_last_success_timestamp_seconds",
+			Help: "Time of the last successful calico BGP processing cycle.",
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(cycleDuration, lastSuccess)
+}
+
+func observeCycle(started time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	} else {
+		lastSuccess.SetToCurrentTime()
+	}
+	cycleDuration.WithLabelValues(result).Observe(time.Since(started).Seconds())
+}
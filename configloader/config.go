@@ -10,9 +10,28 @@ type config struct {
 	Controller      controller `yaml:"controller"`
 	LogDevMode      bool       `yaml:"logdevmode" envconfig:"NOPERATOR_DEV_MODE"`
 	RequeueInterval int        `yaml:"requeueinterval" envconfig:"NOPERATOR_REQUEUE_INTERVAL"`
-	CalicoASNRange  string     `yaml:"calicoasnrange" envconfig:"NOPERATOR_CALICO_ASN_RANGE"`
-	L4lbTenant      string     `yaml:"l4lbtenant" envconfig:"NOPERATOR_L4LB_TENANT"`
-	VPCID           int        `yaml:"vpcid" envconfig:"NOPERATOR_VPC_ID"`
+	// DriftCheckInterval is how often in seconds a resource in sync is compared with Netris.
+	DriftCheckInterval int `yaml:"driftcheckinterval" envconfig:"NOPERATOR_DRIFT_CHECK_INTERVAL"`
+	// DryRun records the planned Netris changes of every resource in its status instead of sending them.
+	DryRun bool `yaml:"dryrun" envconfig:"NOPERATOR_DRY_RUN"`
+	// MaintenanceWindows are the windows of the disruptive updates, "min hour dom month dow duration" separated by ";", in UTC.
+	MaintenanceWindows string `yaml:"maintenancewindows" envconfig:"NOPERATOR_MAINTENANCE_WINDOWS"`
+	// EnableWebhooks serves the deletion protection webhook, it needs the serving certificate.
+	EnableWebhooks bool `yaml:"enablewebhooks" envconfig:"NOPERATOR_ENABLE_WEBHOOKS"`
+	// NamingPolicy is the text/template of the Netris names, it gets .ClusterID, .Namespace and .Name.
+	NamingPolicy string `yaml:"namingpolicy" envconfig:"NOPERATOR_NAMING_POLICY"`
+	// ClusterID tells apart the clusters which share a Netris controller in the NamingPolicy.
+	ClusterID string `yaml:"clusterid" envconfig:"NOPERATOR_CLUSTER_ID"`
+	// LBResyncInterval is how often in seconds lbwatcher recomputes the L4LBs of all the Services.
+	LBResyncInterval int `yaml:"lbresyncinterval" envconfig:"NOPERATOR_LB_RESYNC_INTERVAL"`
+
+	CalicoASNRange string `yaml:"calicoasnrange" envconfig:"NOPERATOR_CALICO_ASN_RANGE"`
+	L4lbTenant     string `yaml:"l4lbtenant" envconfig:"NOPERATOR_L4LB_TENANT"`
+	VPCID          int    `yaml:"vpcid" envconfig:"NOPERATOR_VPC_ID"`
+
+	StorageRefreshIntervals map[string]int `yaml:"storagerefreshintervals" envconfig:"NOPERATOR_STORAGE_REFRESH_INTERVALS"`
+	StorageSnapshot         string         `yaml:"storagesnapshot" envconfig:"NOPERATOR_STORAGE_SNAPSHOT"`
+	StorageSnapshotInterval int            `yaml:"storagesnapshotinterval" envconfig:"NOPERATOR_STORAGE_SNAPSHOT_INTERVAL"`
 }
 
 type controller struct {
@@ -20,6 +39,8 @@ type controller struct {
 	Login    string `yaml:"login" envconfig:"CONTROLLER_LOGIN"`
 	Password string `yaml:"password" envconfig:"CONTROLLER_PASSWORD"`
 	Insecure bool   `yaml:"insecure" envconfig:"CONTROLLER_INSECURE"`
+
+	CredentialsSecret string `yaml:"credentialssecret" envconfig:"CONTROLLER_CREDENTIALS_SECRET"`
 }
 
 // Root .
@@ -37,7 +58,9 @@ func init() {
 	if err != nil {
 		log.Fatalf("configloader error: %v", err)
 	} else {
-		if len(ptr.Controller.Host) == 0 {
+		if len(ptr.Controller.CredentialsSecret) > 0 {
+			log.Printf("loading credentials from secret - %v", ptr.Controller.CredentialsSecret)
+		} else if len(ptr.Controller.Host) == 0 {
 			log.Fatalln("Please set netris controller credentials")
 		} else {
 			log.Printf("connecting to host - %v", ptr.Controller.Host)
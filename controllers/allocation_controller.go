@@ -23,11 +23,13 @@ import (
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -36,10 +38,12 @@ import (
 // AllocationReconciler reconciles a Allocation object
 type AllocationReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=k8s.netris.ai,resources=allocations,verbs=get;list;watch;create;update;patch;delete
@@ -53,6 +57,19 @@ type AllocationReconciler struct {
 // perform operations to make the cluster state reflect the state specified by
 // the user.
 func (r *AllocationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.Allocation{}, &k8sv1alpha1.AllocationMeta{})
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *AllocationReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	logger := r.Log.WithValues("name", req.NamespacedName)
 	debugLogger := logger.V(int(zapcore.WarnLevel))
 	allocation := &k8sv1alpha1.Allocation{}
@@ -63,6 +80,7 @@ func (r *AllocationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	allocationCtx, allocationCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -92,13 +110,29 @@ func (r *AllocationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		}
 	}
 
+	if paused(allocation) {
+		if metaFound {
+			if err := u.annotateMeta(allocation, allocationMeta); err != nil {
+				logger.Error(err, "")
+				return ctrl.Result{Requeue: true}, nil
+			}
+		}
+		return u.patchAllocationStatus(allocation, "Paused", pausedMessage)
+	}
+
 	if allocation.DeletionTimestamp != nil {
+		if msg := deletionBlocked(allocation, false); msg != "" {
+			u.warning(allocation, EventDeleteBlocked, "%s", msg)
+			return u.patchAllocationStatus(allocation, "DeletionBlocked", msg)
+		}
 		logger.Info("Go to delete")
 		_, err := r.deleteAllocation(allocation, allocationMeta)
 		if err != nil {
 			logger.Error(fmt.Errorf("{deleteAllocation} %s", err), "")
+			u.warning(allocation, EventDeleteBlocked, "Couldn't delete Allocation from Netris: %s", err)
 			return u.patchAllocationStatus(allocation, "Failure", err.Error())
 		}
+		u.event(allocation, EventDeleted, "Allocation deleted from Netris")
 		logger.Info("Allocation deleted")
 		return ctrl.Result{}, nil
 	}
@@ -111,14 +145,14 @@ func (r *AllocationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		err := r.Patch(allocationPatchCtx, allocation.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 		if err != nil {
 			logger.Error(fmt.Errorf("{Patch Allocation default annotations} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 		return ctrl.Result{}, nil
 	}
 
 	if metaFound {
 		debugLogger.Info("Meta found")
-		if allocationCompareFieldsForNewMeta(allocation, allocationMeta) {
+		if ownerChanged, annotationsChanged := setOwner(allocation, allocationMeta, r.Scheme), syncAnnotations(allocation, allocationMeta); allocationCompareFieldsForNewMeta(allocation, allocationMeta) || ownerChanged || annotationsChanged {
 			debugLogger.Info("Generating New Meta")
 			allocationID := allocationMeta.Spec.ID
 			newVnetMeta, err := r.AllocationToAllocationMeta(allocation)
@@ -126,8 +160,16 @@ func (r *AllocationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 				logger.Error(fmt.Errorf("{AllocationToAllocationMeta} %s", err), "")
 				return u.patchAllocationStatus(allocation, "Failure", err.Error())
 			}
+			// the Netris controller of an existing resource never changes.
+			controllerRef := allocationMeta.Spec.ControllerRef
+			currentName := allocationMeta.Spec.NetrisName
+			if currentName == "" {
+				currentName = allocationMeta.Spec.AllocationName
+			}
 			allocationMeta.Spec = newVnetMeta.DeepCopy().Spec
 			allocationMeta.Spec.ID = allocationID
+			allocationMeta.Spec.ControllerRef = controllerRef
+			allocationMeta.Spec.NetrisName = keptNetrisName(allocation.Spec.NetrisName, currentName, allocationMeta.Spec.NetrisName, allocationID)
 			allocationMeta.Spec.AllocationCRGeneration = allocation.GetGeneration()
 
 			allocationMetaUpdateCtx, allocationMetaUpdateCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -135,7 +177,7 @@ func (r *AllocationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 			err = r.Update(allocationMetaUpdateCtx, allocationMeta.DeepCopyObject(), &client.UpdateOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{allocationMeta Update} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 		}
 	} else {
@@ -148,7 +190,7 @@ func (r *AllocationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 			err := r.Patch(allocationPatchCtx, allocation.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{Patch Allocation Finalizer} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 			return ctrl.Result{}, nil
 		}
@@ -161,20 +203,24 @@ func (r *AllocationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 
 		allocationMeta.Spec.AllocationCRGeneration = allocation.GetGeneration()
 
+		setOwner(allocation, allocationMeta, r.Scheme)
+		syncAnnotations(allocation, allocationMeta)
 		allocationMetaCreateCtx, allocationMetaCreateCancel := context.WithTimeout(cntxt, contextTimeout)
 		defer allocationMetaCreateCancel()
 		if err := r.Create(allocationMetaCreateCtx, allocationMeta.DeepCopyObject(), &client.CreateOptions{}); err != nil {
 			logger.Error(fmt.Errorf("{allocationMeta Create} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (r *AllocationReconciler) deleteAllocation(allocation *k8sv1alpha1.Allocation, allocationMeta *k8sv1alpha1.AllocationMeta) (ctrl.Result, error) {
 	if allocationMeta != nil && allocationMeta.Spec.ID > 0 && !allocationMeta.Spec.Reclaim {
-		reply, err := r.Cred.IPAM().Delete("allocation", allocationMeta.Spec.ID)
+		reply, err := netrisapi.Do(r.Cred, "ipam", "delete", func() (http.HTTPReply, error) {
+			return r.Cred.IPAM().Delete("allocation", allocationMeta.Spec.ID)
+		})
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("{deleteAllocation} %s", err)
 		}
@@ -226,5 +272,7 @@ func (r *AllocationReconciler) deleteAllocationMetaCR(allocationMeta *k8sv1alpha
 func (r *AllocationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.Allocation{}).
+		Owns(&k8sv1alpha1.AllocationMeta{}).
+		WithOptions(controllerOptions()).
 		Complete(r)
 }
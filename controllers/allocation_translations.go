@@ -44,8 +44,10 @@ func (r *AllocationReconciler) AllocationToAllocationMeta(allocation *k8sv1alpha
 		TypeMeta: metav1.TypeMeta{},
 		Spec: k8sv1alpha1.AllocationMetaSpec{
 			Imported:       imported,
+			ControllerRef:  allocation.Spec.ControllerRef,
 			Reclaim:        reclaim,
 			AllocationName: allocation.Name,
+			NetrisName:     netrisName(allocation, allocation.Spec.NetrisName),
 			Prefix:         allocation.Spec.Prefix,
 			Tenant:         allocation.Spec.Tenant,
 		},
@@ -95,7 +97,7 @@ func allocationUpdateDefaultAnnotations(allocation *k8sv1alpha1.Allocation) {
 // AllocationMetaToNetris converts the k8s Allocation resource to Netris type and used for add the Allocation for Netris API.
 func AllocationMetaToNetris(allocationMeta *k8sv1alpha1.AllocationMeta) (*ipam.Allocation, error) {
 	allocationAdd := &ipam.Allocation{
-		Name:   allocationMeta.Spec.AllocationName,
+		Name:   allocationMeta.Spec.NetrisName,
 		Prefix: allocationMeta.Spec.Prefix,
 		Tenant: ipam.IDName{Name: allocationMeta.Spec.Tenant},
 	}
@@ -106,7 +108,7 @@ func AllocationMetaToNetris(allocationMeta *k8sv1alpha1.AllocationMeta) (*ipam.A
 // AllocationMetaToNetrisUpdate converts the k8s Allocation resource to Netris type and used for update the Allocation for Netris API.
 func AllocationMetaToNetrisUpdate(allocationMeta *k8sv1alpha1.AllocationMeta) (*ipam.Allocation, error) {
 	allocationAdd := &ipam.Allocation{
-		Name:   allocationMeta.Spec.AllocationName,
+		Name:   allocationMeta.Spec.NetrisName,
 		Prefix: allocationMeta.Spec.Prefix,
 		Tenant: ipam.IDName{Name: allocationMeta.Spec.Tenant},
 	}
@@ -115,14 +117,27 @@ func AllocationMetaToNetrisUpdate(allocationMeta *k8sv1alpha1.AllocationMeta) (*
 }
 
 func compareAllocationMetaAPIEAllocation(allocationMeta *k8sv1alpha1.AllocationMeta, apiAllocation *ipam.IPAM, u uniReconciler) bool {
-	if apiAllocation.Name != allocationMeta.Spec.AllocationName {
-		u.DebugLogger.Info("Name changed", "netrisValue", apiAllocation.Name, "k8sValue", allocationMeta.Spec.AllocationName)
+	if apiAllocation.Name != allocationMeta.Spec.NetrisName {
+		u.DebugLogger.Info("Name changed", "netrisValue", apiAllocation.Name, "k8sValue", allocationMeta.Spec.NetrisName)
 		return false
 	}
 	if apiAllocation.Prefix != allocationMeta.Spec.Prefix {
-		u.DebugLogger.Info("Prefix changed", "netrisValue", apiAllocation.Name, "k8sValue", allocationMeta.Spec.AllocationName)
+		u.DebugLogger.Info("Prefix changed", "netrisValue", apiAllocation.Name, "k8sValue", allocationMeta.Spec.NetrisName)
 		return false
 	}
 
 	return true
 }
+
+// allocationDrift returns the difference of the Allocation spec with the
+// Netris allocation and sets the spec to the Netris values.
+func allocationDrift(spec *k8sv1alpha1.AllocationSpec, allocationMeta *k8sv1alpha1.AllocationMeta, apiAllocation *ipam.IPAM) *drift {
+	d := &drift{}
+	if apiAllocation.Name != allocationMeta.Spec.NetrisName {
+		driftFixed(d, "metadata.name", allocationMeta.Spec.NetrisName, apiAllocation.Name)
+	}
+	if apiAllocation.Prefix != allocationMeta.Spec.Prefix {
+		driftSet(d, "spec.prefix", &spec.Prefix, apiAllocation.Prefix)
+	}
+	return d
+}
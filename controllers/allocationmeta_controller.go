@@ -20,15 +20,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -38,10 +41,12 @@ import (
 // AllocationMetaReconciler reconciles a AllocationMeta object
 type AllocationMetaReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=k8s.netris.ai,resources=allocationmeta,verbs=get;list;watch;create;update;patch;delete
@@ -55,6 +60,19 @@ type AllocationMetaReconciler struct {
 // perform operations to make the cluster state reflect the state specified by
 // the user.
 func (r *AllocationMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.AllocationMeta{}, nil)
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *AllocationMetaReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	debugLogger := r.Log.WithValues("name", req.NamespacedName).V(int(zapcore.WarnLevel))
 
 	allocationMeta := &k8sv1alpha1.AllocationMeta{}
@@ -78,6 +96,7 @@ func (r *AllocationMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	provisionState := "OK"
@@ -94,16 +113,24 @@ func (r *AllocationMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 		return ctrl.Result{}, err
 	}
 
+	if paused(allocationCR) {
+		return u.patchAllocationStatus(allocationCR, "Paused", pausedMessage)
+	}
+
 	if allocationMeta.DeletionTimestamp != nil {
 		return ctrl.Result{}, nil
 	}
 
+	if allocationMeta.Spec.NetrisName == "" {
+		allocationMeta.Spec.NetrisName = allocationMeta.Spec.AllocationName
+	}
+
 	if allocationMeta.Spec.ID == 0 {
 		debugLogger.Info("ID Not found in meta")
 		if allocationMeta.Spec.Imported {
 			logger.Info("Importing allocation")
 			debugLogger.Info("Imported yaml mode. Finding Allocation by name")
-			if allocation, ok := r.NStorage.SubnetsStorage.FindByName(allocationMeta.Spec.AllocationName); ok {
+			if allocation, ok := r.NStorage.SubnetsStorage.FindByName(allocationMeta.Spec.NetrisName); ok {
 				debugLogger.Info("Imported yaml mode. Allocation found")
 				allocationMeta.Spec.ID = allocation.ID
 
@@ -115,26 +142,52 @@ func (r *AllocationMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 					return u.patchAllocationStatus(allocationCR, "Failure", err.Error())
 				}
 				debugLogger.Info("Imported yaml mode. ID patched")
+				u.event(allocationCR, EventImported, "Allocation imported from Netris, ID %d", allocationMeta.Spec.ID)
 				logger.Info("Allocation imported")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{}, nil
 			}
 			logger.Info("Allocation not found for import")
 			debugLogger.Info("Imported yaml mode. Allocation not found")
 		}
 
+		if msg := u.cacheStale(nil, netrisstorage.KindSubnets); msg != "" {
+			return u.patchAllocationStatus(allocationCR, "CacheStale", msg)
+		}
+		_, exists := r.NStorage.SubnetsStorage.FindByName(allocationMeta.Spec.NetrisName)
+		if msg := u.nameCollision(allocationMeta, "Allocation", allocationMeta.Spec.NetrisName, exists); msg != "" {
+			u.warning(allocationCR, EventNameCollision, "%s", msg)
+			return u.patchAllocationStatus(allocationCR, "NameCollision", msg)
+		}
 		logger.Info("Creating Allocation")
 		if _, err, errMsg := r.createAllocation(allocationMeta); err != nil {
 			logger.Error(fmt.Errorf("{createAllocation} %s", err), "")
+			u.warning(allocationCR, EventAPIError, "Couldn't create Allocation in Netris: %s", errMsg)
 			return u.patchAllocationStatus(allocationCR, "Failure", errMsg.Error())
 		}
+		allocationCR.Status.AppliedGeneration = allocationMeta.Spec.AllocationCRGeneration
+		u.event(allocationCR, EventCreated, "Allocation created in Netris")
 		logger.Info("Allocation Created")
 	} else {
 		if apiAllocation, ok := r.NStorage.SubnetsStorage.FindByID(allocationMeta.Spec.ID, "allocation"); ok {
 
 			debugLogger.Info("Comparing AllocationMeta with Netris Allocation")
+			driftedFields := allocationCR.Status.DriftedFields
+			allocationCR.Status.DriftedFields = nil
 			if ok := compareAllocationMetaAPIEAllocation(allocationMeta, apiAllocation, u); ok {
 				debugLogger.Info("Nothing Changed")
+				allocationCR.Status.AppliedGeneration = allocationMeta.Spec.AllocationCRGeneration
+			} else if keepsDrift(allocationCR, allocationCR.Status.AppliedGeneration) {
+				spec := allocationCR.Spec.DeepCopy()
+				d := allocationDrift(spec, allocationMeta, apiAllocation)
+				if driftPolicyOf(allocationCR) == driftAdopt && d.adoptable() {
+					allocationCR.Spec = *spec
+					return u.adoptDrift(allocationCR, "Allocation", d)
+				}
+				u.reportDrift(allocationCR, "Allocation", d, driftedFields)
+				allocationCR.Status.DriftedFields = d.fields
+				return u.patchAllocationStatus(allocationCR, "Drifted", d.String())
 			} else {
+				recordDrift("Allocation", allocationCR.Generation, allocationCR.Status.AppliedGeneration)
 				debugLogger.Info("Go to update Allocation in Netris")
 				logger.Info("Updating Allocation")
 				allocationUpdate, err := AllocationMetaToNetrisUpdate(allocationMeta)
@@ -149,18 +202,29 @@ func (r *AllocationMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 				_, err, errMsg := updateAllocation(allocationMeta.Spec.ID, allocationUpdate, r.Cred)
 				if err != nil {
 					logger.Error(fmt.Errorf("{updateAllocation} %s", err), "")
+					u.warning(allocationCR, EventAPIError, "Couldn't update Allocation in Netris: %s", errMsg)
 					return u.patchAllocationStatus(allocationCR, "Failure", errMsg.Error())
 				}
+				u.updated(allocationCR, allocationCR.Generation, allocationCR.Status.AppliedGeneration, "Allocation")
+				allocationCR.Status.AppliedGeneration = allocationMeta.Spec.AllocationCRGeneration
 				logger.Info("Allocation Updated")
 			}
 		} else {
 			debugLogger.Info("Allocation not found in Netris")
 			debugLogger.Info("Going to create Allocation")
+			if msg := u.cacheStale(func() (bool, error) {
+				return existsInNetris(r.Cred, "ipam", r.Cred.IPAM().Get, func(item *ipam.IPAM) bool { return findIPAM([]*ipam.IPAM{item}, allocationMeta.Spec.ID, "allocation") })
+			}, netrisstorage.KindSubnets); msg != "" {
+				return u.patchAllocationStatus(allocationCR, "CacheStale", msg)
+			}
 			logger.Info("Creating Allocation")
 			if _, err, errMsg := r.createAllocation(allocationMeta); err != nil {
 				logger.Error(fmt.Errorf("{createAllocation} %s", err), "")
+				u.warning(allocationCR, EventAPIError, "Couldn't create Allocation in Netris: %s", errMsg)
 				return u.patchAllocationStatus(allocationCR, "Failure", errMsg.Error())
 			}
+			allocationCR.Status.AppliedGeneration = allocationMeta.Spec.AllocationCRGeneration
+			u.event(allocationCR, EventCreated, "Allocation created in Netris")
 			logger.Info("Allocation Created")
 		}
 	}
@@ -181,7 +245,9 @@ func (r *AllocationMetaReconciler) createAllocation(allocationMeta *k8sv1alpha1.
 	js, _ := json.Marshal(allocationAdd)
 	debugLogger.Info("allocationToAdd", "payload", string(js))
 
-	reply, err := r.Cred.IPAM().AddAllocation(allocationAdd)
+	reply, err := netrisapi.Create(r.Cred, "ipam", func() (http.HTTPReply, error) {
+		return r.Cred.IPAM().AddAllocation(allocationAdd)
+	})
 	if err != nil {
 		return ctrl.Result{}, err, err
 	}
@@ -217,7 +283,9 @@ func (r *AllocationMetaReconciler) createAllocation(allocationMeta *k8sv1alpha1.
 }
 
 func updateAllocation(id int, allocation *ipam.Allocation, cred *api.Clientset) (ctrl.Result, error, error) {
-	reply, err := cred.IPAM().UpdateAllocation(id, allocation)
+	reply, err := netrisapi.Do(cred, "ipam", "update", func() (http.HTTPReply, error) {
+		return cred.IPAM().UpdateAllocation(id, allocation)
+	})
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("{updateAllocation} %s", err), err
 	}
@@ -234,7 +302,15 @@ func updateAllocation(id int, allocation *ipam.Allocation, cred *api.Clientset)
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *AllocationMetaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	src, hdl, err := watchStorage(mgr, r.Controllers.storageListeners(r.NStorage), &k8sv1alpha1.AllocationMeta{}, &k8sv1alpha1.AllocationMetaList{}, func(o runtime.Object) string {
+		return strconv.Itoa(o.(*k8sv1alpha1.AllocationMeta).Spec.ID)
+	}, netrisstorage.KindSubnets)
+	if err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.AllocationMeta{}).
+		WithOptions(controllerOptions()).
+		Watches(src, hdl).
 		Complete(r)
 }
@@ -23,7 +23,11 @@ import (
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
 	"github.com/netrisai/netris-operator/configloader"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
+	api "github.com/netrisai/netriswebapi/v2"
+	"github.com/netrisai/netriswebapi/v2/types/ipam"
+	"github.com/netrisai/netriswebapi/v2/types/link"
 )
 
 func init() {
@@ -31,6 +35,9 @@ func init() {
 		requeueInterval = time.Duration(time.Duration(configloader.Root.RequeueInterval) * time.Second)
 		contextTimeout = requeueInterval
 	}
+	if configloader.Root.DriftCheckInterval > 0 {
+		driftCheckInterval = time.Duration(configloader.Root.DriftCheckInterval) * time.Second
+	}
 }
 
 func (r *VNetReconciler) getPortsMeta(portNames []k8sv1alpha1.VNetSwitchPort) ([]k8sv1alpha1.VNetMetaMember, error) {
@@ -99,3 +106,37 @@ func getSites(names []string, nStorage *netrisstorage.Storage) map[string]int {
 	}
 	return siteList
 }
+
+// existsInNetris looks the resource up directly in Netris, bypassing
+// netrisstorage. The list endpoint is used, because the by-ID ones don't
+// distinguish a missing object from an unreachable controller.
+func existsInNetris[T any](cred *api.Clientset, resource string, list func() ([]T, error), match func(T) bool) (bool, error) {
+	items, err := netrisapi.Get(cred, resource, "list", list)
+	if err != nil {
+		return false, fmt.Errorf("{existsInNetris %s} %s", resource, err)
+	}
+	for _, item := range items {
+		if match(item) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// linkConnects reports whether the link connects the two ports in either
+// direction.
+func linkConnects(item *link.Link, local, remote int) bool {
+	return (item.Local.ID == local && item.Remote.ID == remote) || (item.Local.ID == remote && item.Remote.ID == local)
+}
+
+func findIPAM(items []*ipam.IPAM, id int, typo string) bool {
+	for _, item := range items {
+		if item.ID == id && item.Type == typo {
+			return true
+		}
+		if findIPAM(item.Children, id, typo) {
+			return true
+		}
+	}
+	return false
+}
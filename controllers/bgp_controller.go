@@ -24,10 +24,12 @@ import (
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -36,10 +38,12 @@ import (
 // BGPReconciler reconciles a BGP object
 type BGPReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=k8s.netris.ai,resources=bgps,verbs=get;list;watch;create;update;patch;delete
@@ -52,6 +56,19 @@ type BGPReconciler struct {
 
 // Reconcile is the main reconciler for the appropriate resource type
 func (r *BGPReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.BGP{}, &k8sv1alpha1.BGPMeta{})
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *BGPReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	logger := r.Log.WithValues("name", req.NamespacedName)
 	debugLogger := logger.V(int(zapcore.WarnLevel))
 	bgp := &k8sv1alpha1.BGP{}
@@ -62,6 +79,7 @@ func (r *BGPReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	bgpCtx, bgpCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -91,13 +109,33 @@ func (r *BGPReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 	}
 
+	if paused(bgp) {
+		if metaFound {
+			if err := u.annotateMeta(bgp, bgpMeta); err != nil {
+				logger.Error(err, "")
+				return ctrl.Result{Requeue: true}, nil
+			}
+		}
+		return u.patchBGPStatus(bgp, "Paused", pausedMessage)
+	}
+
 	if bgp.DeletionTimestamp != nil {
+		if msg := deletionBlocked(bgp, false); msg != "" {
+			u.warning(bgp, EventDeleteBlocked, "%s", msg)
+			return u.patchBGPStatus(bgp, "DeletionBlocked", msg)
+		}
+		if bgpMeta != nil && bgpMeta.Spec.ID > 0 && !bgpMeta.Spec.Reclaim && dryRun(bgp) {
+			bgp.Status.Plan = planOf(planDelete, bgpMeta.Spec.ID, nil, nil)
+			return u.patchBGPStatus(bgp, "DryRun", planMessage("BGP", bgp.Status.Plan))
+		}
 		logger.Info("Go to delete")
 		_, err := r.deleteBGP(bgp, bgpMeta)
 		if err != nil {
 			logger.Error(fmt.Errorf("{deleteBGP} %s", err), "")
+			u.warning(bgp, EventDeleteBlocked, "Couldn't delete BGP from Netris: %s", err)
 			return u.patchBGPStatus(bgp, "Failure", err.Error())
 		}
+		u.event(bgp, EventDeleted, "BGP deleted from Netris")
 		logger.Info("BGP deleted")
 		return ctrl.Result{}, nil
 	}
@@ -110,14 +148,14 @@ func (r *BGPReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		err := r.Patch(bgpPatchCtx, bgp.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 		if err != nil {
 			logger.Error(fmt.Errorf("{Patch BGP default annotations} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 		return ctrl.Result{}, nil
 	}
 
 	if metaFound {
 		debugLogger.Info("Meta found")
-		if bgpCompareFieldsForNewMeta(bgp, bgpMeta) {
+		if ownerChanged, annotationsChanged := setOwner(bgp, bgpMeta, r.Scheme), syncAnnotations(bgp, bgpMeta); bgpCompareFieldsForNewMeta(bgp, bgpMeta) || ownerChanged || annotationsChanged {
 			debugLogger.Info("Generating New Meta")
 			bgpID := bgpMeta.Spec.ID
 			newVnetMeta, err := r.BGPToBGPMeta(bgp)
@@ -125,8 +163,16 @@ func (r *BGPReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				logger.Error(fmt.Errorf("{BGPToBGPMeta} %s", err), "")
 				return u.patchBGPStatus(bgp, "Failure", err.Error())
 			}
+			// the Netris controller of an existing resource never changes.
+			controllerRef := bgpMeta.Spec.ControllerRef
+			currentName := bgpMeta.Spec.NetrisName
+			if currentName == "" {
+				currentName = bgpMeta.Spec.BGPName
+			}
 			bgpMeta.Spec = newVnetMeta.DeepCopy().Spec
 			bgpMeta.Spec.ID = bgpID
+			bgpMeta.Spec.ControllerRef = controllerRef
+			bgpMeta.Spec.NetrisName = keptNetrisName(bgp.Spec.NetrisName, currentName, bgpMeta.Spec.NetrisName, bgpID)
 			bgpMeta.Spec.BGPCRGeneration = bgp.GetGeneration()
 
 			bgpMetaUpdateCtx, bgpMetaUpdateCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -134,7 +180,7 @@ func (r *BGPReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err = r.Update(bgpMetaUpdateCtx, bgpMeta.DeepCopyObject(), &client.UpdateOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{bgpMeta Update} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 		}
 	} else {
@@ -147,7 +193,7 @@ func (r *BGPReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err := r.Patch(bgpPatchCtx, bgp.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{Patch BGP Finalizer} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 			return ctrl.Result{}, nil
 		}
@@ -160,20 +206,24 @@ func (r *BGPReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 		bgpMeta.Spec.BGPCRGeneration = bgp.GetGeneration()
 
+		setOwner(bgp, bgpMeta, r.Scheme)
+		syncAnnotations(bgp, bgpMeta)
 		bgpMetaCreateCtx, bgpMetaCreateCancel := context.WithTimeout(cntxt, contextTimeout)
 		defer bgpMetaCreateCancel()
 		if err := r.Create(bgpMetaCreateCtx, bgpMeta.DeepCopyObject(), &client.CreateOptions{}); err != nil {
 			logger.Error(fmt.Errorf("{bgpMeta Create} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (r *BGPReconciler) deleteBGP(bgp *k8sv1alpha1.BGP, bgpMeta *k8sv1alpha1.BGPMeta) (ctrl.Result, error) {
 	if bgpMeta != nil && bgpMeta.Spec.ID > 0 && !bgpMeta.Spec.Reclaim {
-		reply, err := r.Cred.BGP().Delete(bgpMeta.Spec.ID)
+		reply, err := netrisapi.Do(r.Cred, "bgp", "delete", func() (http.HTTPReply, error) {
+			return r.Cred.BGP().Delete(bgpMeta.Spec.ID)
+		})
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("{deleteBGP} %s", err)
 		}
@@ -225,5 +275,7 @@ func (r *BGPReconciler) deleteBGPMetaCR(bgpMeta *k8sv1alpha1.BGPMeta) (ctrl.Resu
 func (r *BGPReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.BGP{}).
+		Owns(&k8sv1alpha1.BGPMeta{}).
+		WithOptions(controllerOptions()).
 		Complete(r)
 }
@@ -23,6 +23,7 @@ import (
 	"strings"
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netriswebapi/v2/types/bgp"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -68,7 +69,7 @@ func (r *BGPReconciler) BGPToBGPMeta(bgp *k8sv1alpha1.BGP) (*k8sv1alpha1.BGPMeta
 		}
 		vlanID = -1
 	} else {
-		vnets, err := r.Cred.VNet().Get()
+		vnets, err := netrisapi.Get(r.Cred, "vnet", "list", r.Cred.VNet().Get)
 		if err != nil {
 			return nil, err
 		}
@@ -83,7 +84,7 @@ func (r *BGPReconciler) BGPToBGPMeta(bgp *k8sv1alpha1.BGP) (*k8sv1alpha1.BGPMeta
 		vlanID = bgp.Spec.Transport.VlanID
 	}
 
-	inventory, err := r.Cred.Inventory().Get()
+	inventory, err := netrisapi.Get(r.Cred, "inventory", "list", r.Cred.Inventory().Get)
 	if err != nil {
 		return nil, err
 	}
@@ -121,20 +122,22 @@ func (r *BGPReconciler) BGPToBGPMeta(bgp *k8sv1alpha1.BGP) (*k8sv1alpha1.BGPMeta
 		},
 		TypeMeta: metav1.TypeMeta{},
 		Spec: k8sv1alpha1.BGPMetaSpec{
-			Imported:    imported,
-			Reclaim:     reclaim,
-			Name:        string(bgp.GetUID()),
-			HWID:        hwID,
-			VnetID:      vnetID,
-			PortID:      portID,
-			Site:        bgp.Spec.Site,
-			BGPName:     bgp.Name,
-			Vlan:        vlanID,
-			NeighborAs:  bgp.Spec.NeighborAS,
-			LocalIP:     localIP.String(),
-			RemoteIP:    remoteIP.String(),
-			Description: bgp.Spec.Description,
-			Status:      state,
+			Imported:      imported,
+			ControllerRef: bgp.Spec.ControllerRef,
+			Reclaim:       reclaim,
+			Name:          string(bgp.GetUID()),
+			HWID:          hwID,
+			VnetID:        vnetID,
+			PortID:        portID,
+			Site:          bgp.Spec.Site,
+			BGPName:       bgp.Name,
+			NetrisName:    netrisName(bgp, bgp.Spec.NetrisName),
+			Vlan:          vlanID,
+			NeighborAs:    bgp.Spec.NeighborAS,
+			LocalIP:       localIP.String(),
+			RemoteIP:      remoteIP.String(),
+			Description:   bgp.Spec.Description,
+			Status:        state,
 
 			NeighborAddress: neighborAddress,
 			UpdateSource:    bgp.Spec.Multihop.UpdateSource,
@@ -232,7 +235,7 @@ func BGPMetaToNetris(bgpMeta *k8sv1alpha1.BGPMeta) (*bgp.EBGPAdd, error) {
 		LocalIP:            bgpMeta.Spec.LocalIP,
 		LocalPreference:    bgpMeta.Spec.LocalPreference,
 		Multihop:           bgpMeta.Spec.Multihop,
-		Name:               bgpMeta.Spec.BGPName,
+		Name:               bgpMeta.Spec.NetrisName,
 		NeighborAddress:    bgpMeta.Spec.NeighborAddress,
 		NeighborAS:         bgpMeta.Spec.NeighborAs,
 		DefaultOriginate:   bgpMeta.Spec.Originate,
@@ -281,7 +284,7 @@ func BGPMetaToNetrisUpdate(bgpMeta *k8sv1alpha1.BGPMeta) (*bgp.EBGPUpdate, error
 		LocalIP:            bgpMeta.Spec.LocalIP,
 		LocalPreference:    bgpMeta.Spec.LocalPreference,
 		Multihop:           bgpMeta.Spec.Multihop,
-		Name:               bgpMeta.Spec.BGPName,
+		Name:               bgpMeta.Spec.NetrisName,
 		NeighborAddress:    bgpMeta.Spec.NeighborAddress,
 		NeighborAS:         bgpMeta.Spec.NeighborAs,
 		DefaultOriginate:   bgpMeta.Spec.Originate,
@@ -344,8 +347,8 @@ func compareBGPMetaAPIEBGP(bgpMeta *k8sv1alpha1.BGPMeta, apiBGP *bgp.EBGP, u uni
 		u.DebugLogger.Info("Multihop changed", "netrisValue", apiBGP.Multihop, "k8sValue", bgpMeta.Spec.Multihop)
 		return false
 	}
-	if apiBGP.Name != bgpMeta.Spec.BGPName {
-		u.DebugLogger.Info("Name changed", "netrisValue", apiBGP.Name, "k8sValue", bgpMeta.Spec.BGPName)
+	if apiBGP.Name != bgpMeta.Spec.NetrisName {
+		u.DebugLogger.Info("Name changed", "netrisValue", apiBGP.Name, "k8sValue", bgpMeta.Spec.NetrisName)
 		return false
 	}
 	neighborAddress := ""
@@ -437,6 +440,89 @@ func compareBGPMetaAPIEBGP(bgpMeta *k8sv1alpha1.BGPMeta, apiBGP *bgp.EBGP, u uni
 	return true
 }
 
+// bgpDrift returns the difference of the BGP spec with the Netris BGP and
+// sets the spec to the Netris values.
+func bgpDrift(spec *k8sv1alpha1.BGPSpec, bgpMeta *k8sv1alpha1.BGPMeta, apiBGP *bgp.EBGP, u uniReconciler) *drift {
+	d := &drift{}
+	if apiBGP.Name != bgpMeta.Spec.NetrisName {
+		driftFixed(d, "metadata.name", bgpMeta.Spec.NetrisName, apiBGP.Name)
+	}
+	if apiBGP.SiteName != bgpMeta.Spec.Site {
+		driftSet(d, "spec.site", &spec.Site, apiBGP.SiteName)
+	}
+	if apiBGP.NeighborAs != bgpMeta.Spec.NeighborAs {
+		driftSet(d, "spec.neighborAs", &spec.NeighborAS, apiBGP.NeighborAs)
+	}
+	if port, ok := u.NStorage.PortsStorage.FindByID(apiBGP.Port.ID); ok && port.ID != bgpMeta.Spec.PortID {
+		driftSet(d, "spec.transport.name", &spec.Transport.Name, fmt.Sprintf("%s@%s", port.Port_, port.SwitchName))
+	}
+	if apiBGP.Vlan != bgpMeta.Spec.Vlan && bgpMeta.Spec.Vlan != -1 {
+		driftSet(d, "spec.transport.vlanId", &spec.Transport.VlanID, apiBGP.Vlan)
+	}
+	if apiBGP.LocalIP != bgpMeta.Spec.LocalIP || apiBGP.PrefixLength != bgpMeta.Spec.PrefixLength {
+		driftSet(d, "spec.localIP", &spec.LocalIP, fmt.Sprintf("%s/%d", apiBGP.LocalIP, apiBGP.PrefixLength))
+	}
+	if apiBGP.RemoteIP != bgpMeta.Spec.RemoteIP || apiBGP.PrefixLength != bgpMeta.Spec.PrefixLength {
+		driftSet(d, "spec.remoteIP", &spec.RemoteIP, fmt.Sprintf("%s/%d", apiBGP.RemoteIP, apiBGP.PrefixLength))
+	}
+	if apiBGP.Description != bgpMeta.Spec.Description {
+		driftSet(d, "spec.description", &spec.Description, apiBGP.Description)
+	}
+	if apiBGP.Status != bgpMeta.Spec.Status {
+		driftSet(d, "spec.state", &spec.State, apiBGP.Status)
+	}
+	if apiBGP.Multihop != bgpMeta.Spec.Multihop {
+		driftSet(d, "spec.multihop.hops", &spec.Multihop.Hops, apiBGP.Multihop)
+	}
+	if apiBGP.NeighborAddress != bgpMeta.Spec.NeighborAddress {
+		driftSet(d, "spec.multihop.neighborAddress", &spec.Multihop.NeighborAddress, apiBGP.NeighborAddress)
+	}
+	if apiBGP.UpdateSource != bgpMeta.Spec.UpdateSource {
+		driftSet(d, "spec.multihop.updateSource", &spec.Multihop.UpdateSource, apiBGP.UpdateSource)
+	}
+	if apiBGP.BgpPassword != bgpMeta.Spec.BgpPassword {
+		driftSecret(d, "spec.bgpPassword", &spec.BGPPassword, apiBGP.BgpPassword)
+	}
+	if apiBGP.AllowasIn != bgpMeta.Spec.AllowasIn {
+		driftSet(d, "spec.allowAsIn", &spec.AllowAsIn, apiBGP.AllowasIn)
+	}
+	if apiBGP.Originate != bgpMeta.Spec.Originate {
+		driftSet(d, "spec.defaultOriginate", &spec.DefaultOriginate, apiBGP.Originate == "enabled")
+	}
+	prefixLimit, _ := strconv.Atoi(bgpMeta.Spec.PrefixLimit)
+	if apiBGP.PrefixLimit != prefixLimit && !(apiBGP.PrefixLimit == 1000 && prefixLimit == 0 && apiBGP.TerminateOnSwitch == "yes") {
+		driftSet(d, "spec.prefixInboundMax", &spec.PrefixInboundMax, apiBGP.PrefixLimit)
+	}
+	if apiBGP.InboundRouteMap != bgpMeta.Spec.InboundRouteMap {
+		driftFixed(d, "spec.inboundRouteMap", bgpMeta.Spec.InboundRouteMap, apiBGP.InboundRouteMap)
+	}
+	if apiBGP.OutboundRouteMap != bgpMeta.Spec.OutboundRouteMap {
+		driftFixed(d, "spec.outboundRouteMap", bgpMeta.Spec.OutboundRouteMap, apiBGP.OutboundRouteMap)
+	}
+	if apiBGP.LocalPreference != bgpMeta.Spec.LocalPreference {
+		driftSet(d, "spec.localPreference", &spec.LocalPreference, apiBGP.LocalPreference)
+	}
+	if apiBGP.Weight != bgpMeta.Spec.Weight {
+		driftSet(d, "spec.weight", &spec.Weight, apiBGP.Weight)
+	}
+	if apiBGP.PrependInbound != bgpMeta.Spec.PrependInbound {
+		driftSet(d, "spec.prependInbound", &spec.PrependInbound, apiBGP.PrependInbound)
+	}
+	if apiBGP.PrependOutbound != bgpMeta.Spec.PrependInbound {
+		driftSet(d, "spec.prependOutbound", &spec.PrependOutbound, apiBGP.PrependOutbound)
+	}
+	if apiBGP.PrefixListInbound != bgpMeta.Spec.PrefixListInbound {
+		driftSet(d, "spec.prefixListInbound", &spec.PrefixListInbound, driftList(apiBGP.PrefixListInbound, "\n"))
+	}
+	if apiBGP.PrefixListOutbound != bgpMeta.Spec.PrefixListOutbound {
+		driftSet(d, "spec.prefixListOutbound", &spec.PrefixListOutbound, driftList(apiBGP.PrefixListOutbound, "\n"))
+	}
+	if apiBGP.Community != bgpMeta.Spec.Community {
+		driftSet(d, "spec.sendBGPCommunity", &spec.SendBGPCommunity, driftList(apiBGP.Community, "\n"))
+	}
+	return d
+}
+
 func optionalRouteMapID(value int) *int {
 	if value <= 0 {
 		return nil
@@ -31,20 +31,24 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 )
 
 // BGPMetaReconciler reconciles a BGPMeta object
 type BGPMetaReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=k8s.netris.ai,resources=bgpmeta,verbs=get;list;watch;create;update;patch;delete
@@ -53,6 +57,19 @@ type BGPMetaReconciler struct {
 
 // Reconcile is the main reconciler for the appropriate resource type
 func (r *BGPMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.BGPMeta{}, nil)
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *BGPMetaReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	debugLogger := r.Log.WithValues("name", req.NamespacedName).V(int(zapcore.WarnLevel))
 
 	bgpMeta := &k8sv1alpha1.BGPMeta{}
@@ -76,6 +93,7 @@ func (r *BGPMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	provisionState := "Provisioning"
@@ -91,17 +109,26 @@ func (r *BGPMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 		return ctrl.Result{}, err
 	}
+	bgpCR.Status.Plan = nil
+
+	if paused(bgpCR) {
+		return u.patchBGPStatus(bgpCR, "Paused", pausedMessage)
+	}
 
 	if bgpMeta.DeletionTimestamp != nil {
 		return ctrl.Result{}, nil
 	}
 
+	if bgpMeta.Spec.NetrisName == "" {
+		bgpMeta.Spec.NetrisName = bgpMeta.Spec.BGPName
+	}
+
 	if bgpMeta.Spec.ID == 0 {
 		debugLogger.Info("ID Not found in meta")
 		if bgpMeta.Spec.Imported {
 			logger.Info("Importing bgp")
 			debugLogger.Info("Imported yaml mode. Finding BGP by name")
-			if bgp, ok := r.NStorage.BGPStorage.FindByName(bgpMeta.Spec.BGPName); ok {
+			if bgp, ok := r.NStorage.BGPStorage.FindByName(bgpMeta.Spec.NetrisName); ok {
 				debugLogger.Info("Imported yaml mode. BGP found")
 				bgpMeta.Spec.ID = bgp.ID
 				bgpCR.Status.ModifiedDate = metav1.NewTime(time.Unix(int64(bgp.ModifiedDate/1000), 0))
@@ -129,18 +156,33 @@ func (r *BGPMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 					return u.patchBGPStatus(bgpCR, "Failure", err.Error())
 				}
 				debugLogger.Info("Imported yaml mode. ID patched")
+				u.event(bgpCR, EventImported, "BGP imported from Netris, ID %d", bgpMeta.Spec.ID)
 				logger.Info("BGP imported")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{}, nil
 			}
 			logger.Info("BGP not found for import")
 			debugLogger.Info("Imported yaml mode. BGP not found")
 		}
 
+		if msg := u.cacheStale(nil, netrisstorage.KindBGPs); msg != "" {
+			return u.patchBGPStatus(bgpCR, "CacheStale", msg)
+		}
+		_, exists := r.NStorage.BGPStorage.FindByName(bgpMeta.Spec.NetrisName)
+		if msg := u.nameCollision(bgpMeta, "BGP", bgpMeta.Spec.NetrisName, exists); msg != "" {
+			u.warning(bgpCR, EventNameCollision, "%s", msg)
+			return u.patchBGPStatus(bgpCR, "NameCollision", msg)
+		}
+		if dryRun(bgpCR) {
+			return planBGPCreate(&u, bgpCR, bgpMeta)
+		}
 		logger.Info("Creating BGP")
 		if _, err, errMsg := r.createBGP(bgpMeta); err != nil {
 			logger.Error(fmt.Errorf("{createBGP} %s", err), "")
+			u.warning(bgpCR, EventAPIError, "Couldn't create BGP in Netris: %s", errMsg)
 			return u.patchBGPStatus(bgpCR, "Failure", errMsg.Error())
 		}
+		bgpCR.Status.AppliedGeneration = bgpMeta.Spec.BGPCRGeneration
+		u.event(bgpCR, EventCreated, "BGP created in Netris")
 		logger.Info("BGP Created")
 	} else {
 		if apiBGP, ok := r.NStorage.BGPStorage.FindByID(bgpMeta.Spec.ID); ok {
@@ -161,9 +203,23 @@ func (r *BGPMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				bgpCR.Status.VLANID = "untagged"
 			}
 			debugLogger.Info("Comparing BGPMeta with Netris BGP")
+			driftedFields := bgpCR.Status.DriftedFields
+			bgpCR.Status.DriftedFields = nil
 			if ok := compareBGPMetaAPIEBGP(bgpMeta, apiBGP, u); ok {
 				debugLogger.Info("Nothing Changed")
+				bgpCR.Status.AppliedGeneration = bgpMeta.Spec.BGPCRGeneration
+			} else if keepsDrift(bgpCR, bgpCR.Status.AppliedGeneration) {
+				spec := bgpCR.Spec.DeepCopy()
+				d := bgpDrift(spec, bgpMeta, apiBGP, u)
+				if driftPolicyOf(bgpCR) == driftAdopt && d.adoptable() {
+					bgpCR.Spec = *spec
+					return u.adoptDrift(bgpCR, "BGP", d)
+				}
+				u.reportDrift(bgpCR, "BGP", d, driftedFields)
+				bgpCR.Status.DriftedFields = d.fields
+				return u.patchBGPStatus(bgpCR, "Drifted", d.String())
 			} else {
+				recordDrift("BGP", bgpCR.Generation, bgpCR.Status.AppliedGeneration)
 				debugLogger.Info("Go to update BGP in Netris")
 				logger.Info("Updating BGP")
 				bgpUpdate, err := BGPMetaToNetrisUpdate(bgpMeta)
@@ -175,27 +231,62 @@ func (r *BGPMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				js, _ := json.Marshal(bgpUpdate)
 				debugLogger.Info("bgpUpdate", "payload", string(js))
 
+				if dryRun(bgpCR) {
+					d := bgpDrift(bgpCR.Spec.DeepCopy(), bgpMeta, apiBGP, u)
+					bgpCR.Status.Plan = planOf(planUpdate, bgpMeta.Spec.ID, bgpUpdate, d.fields)
+					return u.patchBGPStatus(bgpCR, "DryRun", planMessage("BGP", bgpCR.Status.Plan))
+				}
+
+				if ok, msg, result := deferred(time.Now()); ok {
+					logger.Info(msg)
+					u.patchBGPStatus(bgpCR, "MaintenanceWindow", msg)
+					return result, nil
+				}
 				_, err, errMsg := updateBGP(bgpMeta.Spec.ID, bgpUpdate, r.Cred)
 				if err != nil {
 					logger.Error(fmt.Errorf("{updateBGP} %s", err), "")
+					u.warning(bgpCR, EventAPIError, "Couldn't update BGP in Netris: %s", errMsg)
 					return u.patchBGPStatus(bgpCR, "Failure", errMsg.Error())
 				}
+				u.updated(bgpCR, bgpCR.Generation, bgpCR.Status.AppliedGeneration, "BGP")
+				bgpCR.Status.AppliedGeneration = bgpMeta.Spec.BGPCRGeneration
 				logger.Info("BGP Updated")
 			}
 		} else {
 			debugLogger.Info("BGP not found in Netris")
 			debugLogger.Info("Going to create BGP")
+			if msg := u.cacheStale(func() (bool, error) {
+				return existsInNetris(r.Cred, "bgp", r.Cred.BGP().Get, func(item *bgp.EBGP) bool { return item.ID == bgpMeta.Spec.ID })
+			}, netrisstorage.KindBGPs); msg != "" {
+				return u.patchBGPStatus(bgpCR, "CacheStale", msg)
+			}
+			if dryRun(bgpCR) {
+				return planBGPCreate(&u, bgpCR, bgpMeta)
+			}
 			logger.Info("Creating BGP")
 			if _, err, errMsg := r.createBGP(bgpMeta); err != nil {
 				logger.Error(fmt.Errorf("{createBGP} %s", err), "")
+				u.warning(bgpCR, EventAPIError, "Couldn't create BGP in Netris: %s", errMsg)
 				return u.patchBGPStatus(bgpCR, "Failure", errMsg.Error())
 			}
+			bgpCR.Status.AppliedGeneration = bgpMeta.Spec.BGPCRGeneration
+			u.event(bgpCR, EventCreated, "BGP created in Netris")
 			logger.Info("BGP Created")
 		}
 	}
 	return u.patchBGPStatus(bgpCR, provisionState, "Success")
 }
 
+// planBGPCreate records the create request of the BGP in its status instead of sending it.
+func planBGPCreate(u *uniReconciler, bgpCR *k8sv1alpha1.BGP, bgpMeta *k8sv1alpha1.BGPMeta) (ctrl.Result, error) {
+	bgpAdd, err := BGPMetaToNetris(bgpMeta)
+	if err != nil {
+		return u.patchBGPStatus(bgpCR, "Failure", err.Error())
+	}
+	bgpCR.Status.Plan = planOf(planCreate, 0, bgpAdd, nil)
+	return u.patchBGPStatus(bgpCR, "DryRun", planMessage("BGP", bgpCR.Status.Plan))
+}
+
 func (r *BGPMetaReconciler) createBGP(bgpMeta *k8sv1alpha1.BGPMeta) (ctrl.Result, error, error) {
 	debugLogger := r.Log.WithValues(
 		"name", fmt.Sprintf("%s/%s", bgpMeta.Namespace, bgpMeta.Spec.BGPName),
@@ -210,7 +301,9 @@ func (r *BGPMetaReconciler) createBGP(bgpMeta *k8sv1alpha1.BGPMeta) (ctrl.Result
 	js, _ := json.Marshal(bgpAdd)
 	debugLogger.Info("bgpToAdd", "payload", string(js))
 
-	reply, err := r.Cred.BGP().Add(bgpAdd)
+	reply, err := netrisapi.Create(r.Cred, "bgp", func() (http.HTTPReply, error) {
+		return r.Cred.BGP().Add(bgpAdd)
+	})
 	if err != nil {
 		return ctrl.Result{}, err, err
 	}
@@ -248,13 +341,23 @@ func (r *BGPMetaReconciler) createBGP(bgpMeta *k8sv1alpha1.BGPMeta) (ctrl.Result
 
 // SetupWithManager .
 func (r *BGPMetaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	src, hdl, err := watchStorage(mgr, r.Controllers.storageListeners(r.NStorage), &k8sv1alpha1.BGPMeta{}, &k8sv1alpha1.BGPMetaList{}, func(o runtime.Object) string {
+		return strconv.Itoa(o.(*k8sv1alpha1.BGPMeta).Spec.ID)
+	}, netrisstorage.KindBGPs)
+	if err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.BGPMeta{}).
+		WithOptions(controllerOptions()).
+		Watches(src, hdl).
 		Complete(r)
 }
 
 func updateBGP(id int, bgp *bgp.EBGPUpdate, cred *api.Clientset) (ctrl.Result, error, error) {
-	reply, err := cred.BGP().Update(id, bgp)
+	reply, err := netrisapi.Do(cred, "bgp", "update", func() (http.HTTPReply, error) {
+		return cred.BGP().Update(id, bgp)
+	})
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("{updateBGP} %s", err), err
 	}
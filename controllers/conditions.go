@@ -0,0 +1,110 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+)
+
+var (
+	// dependencyErrorRe matches the errors of the translations which couldn't
+	// find a site, a tenant, a profile, a port or a VPC the spec refers to.
+	dependencyErrorRe = regexp.MustCompile(`(?i)(invalid (site|tenant|profile)|not found|cou(l)?ndn't find|no sites for)`)
+	reasonRe          = regexp.MustCompile(`^[A-Za-z]([A-Za-z0-9_,:]*[A-Za-z0-9_])?$`)
+)
+
+// setConditions translates the status string of the patch*Status helpers
+// into the conditions of a resource, and marks the status as observed for
+// the generation.
+func setConditions(conditions *[]k8sv1alpha1.Condition, observedGeneration *int64, generation int64, status, message string) {
+	set := func(conditionType string, conditionStatus metav1.ConditionStatus, reason string) {
+		k8sv1alpha1.SetCondition(conditions, k8sv1alpha1.Condition{
+			Type:               conditionType,
+			Status:             conditionStatus,
+			ObservedGeneration: generation,
+			Reason:             reason,
+			Message:            message,
+		})
+	}
+
+	if status == "Paused" {
+		// the other conditions keep the state of the last reconcile.
+		set(k8sv1alpha1.ConditionPaused, metav1.ConditionTrue, k8sv1alpha1.ReasonPaused)
+		return
+	}
+	k8sv1alpha1.RemoveCondition(conditions, k8sv1alpha1.ConditionPaused)
+
+	switch status {
+	case "Failure":
+		set(k8sv1alpha1.ConditionNetrisReachable, metav1.ConditionTrue, k8sv1alpha1.ReasonReachable)
+		reason := k8sv1alpha1.ReasonSyncFailed
+		if dependencyErrorRe.MatchString(message) {
+			reason = k8sv1alpha1.ReasonDependencyNotFound
+			set(k8sv1alpha1.ConditionDependenciesReady, metav1.ConditionFalse, reason)
+		}
+		set(k8sv1alpha1.ConditionSynced, metav1.ConditionFalse, reason)
+		set(k8sv1alpha1.ConditionReady, metav1.ConditionFalse, reason)
+	case "NetrisUnavailable":
+		set(k8sv1alpha1.ConditionNetrisReachable, metav1.ConditionFalse, k8sv1alpha1.ReasonNetrisUnavailable)
+		set(k8sv1alpha1.ConditionSynced, metav1.ConditionFalse, k8sv1alpha1.ReasonNetrisUnavailable)
+		set(k8sv1alpha1.ConditionReady, metav1.ConditionFalse, k8sv1alpha1.ReasonNetrisUnavailable)
+	case "Drifted":
+		set(k8sv1alpha1.ConditionNetrisReachable, metav1.ConditionTrue, k8sv1alpha1.ReasonReachable)
+		set(k8sv1alpha1.ConditionDependenciesReady, metav1.ConditionTrue, k8sv1alpha1.ReasonResolved)
+		set(k8sv1alpha1.ConditionSynced, metav1.ConditionFalse, k8sv1alpha1.ReasonDrifted)
+		set(k8sv1alpha1.ConditionReady, metav1.ConditionFalse, k8sv1alpha1.ReasonDrifted)
+	case "DryRun":
+		set(k8sv1alpha1.ConditionNetrisReachable, metav1.ConditionTrue, k8sv1alpha1.ReasonReachable)
+		set(k8sv1alpha1.ConditionDependenciesReady, metav1.ConditionTrue, k8sv1alpha1.ReasonResolved)
+		set(k8sv1alpha1.ConditionSynced, metav1.ConditionFalse, k8sv1alpha1.ReasonDryRun)
+		set(k8sv1alpha1.ConditionReady, metav1.ConditionFalse, k8sv1alpha1.ReasonDryRun)
+	case "MaintenanceWindow":
+		set(k8sv1alpha1.ConditionNetrisReachable, metav1.ConditionTrue, k8sv1alpha1.ReasonReachable)
+		set(k8sv1alpha1.ConditionDependenciesReady, metav1.ConditionTrue, k8sv1alpha1.ReasonResolved)
+		set(k8sv1alpha1.ConditionSynced, metav1.ConditionFalse, k8sv1alpha1.ReasonMaintenanceWindow)
+		set(k8sv1alpha1.ConditionReady, metav1.ConditionFalse, k8sv1alpha1.ReasonMaintenanceWindow)
+	case "DeletionBlocked":
+		set(k8sv1alpha1.ConditionSynced, metav1.ConditionFalse, k8sv1alpha1.ReasonDeletionBlocked)
+		set(k8sv1alpha1.ConditionReady, metav1.ConditionFalse, k8sv1alpha1.ReasonDeletionBlocked)
+	case "NameCollision":
+		set(k8sv1alpha1.ConditionSynced, metav1.ConditionFalse, k8sv1alpha1.ReasonNameCollision)
+		set(k8sv1alpha1.ConditionReady, metav1.ConditionFalse, k8sv1alpha1.ReasonNameCollision)
+	case "CacheStale":
+		set(k8sv1alpha1.ConditionSynced, metav1.ConditionFalse, k8sv1alpha1.ReasonCacheStale)
+		set(k8sv1alpha1.ConditionReady, metav1.ConditionFalse, k8sv1alpha1.ReasonCacheStale)
+	default:
+		set(k8sv1alpha1.ConditionNetrisReachable, metav1.ConditionTrue, k8sv1alpha1.ReasonReachable)
+		set(k8sv1alpha1.ConditionDependenciesReady, metav1.ConditionTrue, k8sv1alpha1.ReasonResolved)
+		set(k8sv1alpha1.ConditionSynced, metav1.ConditionTrue, k8sv1alpha1.ReasonSynced)
+		switch {
+		case status == "Provisioning":
+			set(k8sv1alpha1.ConditionReady, metav1.ConditionFalse, k8sv1alpha1.ReasonProvisioning)
+		case status == "Disabled":
+			// a disabled resource is in its desired state.
+			set(k8sv1alpha1.ConditionReady, metav1.ConditionTrue, k8sv1alpha1.ReasonDisabled)
+		case reasonRe.MatchString(status):
+			set(k8sv1alpha1.ConditionReady, metav1.ConditionTrue, status)
+		default:
+			set(k8sv1alpha1.ConditionReady, metav1.ConditionTrue, k8sv1alpha1.ReasonActive)
+		}
+	}
+	*observedGeneration = generation
+}
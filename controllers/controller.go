@@ -23,29 +23,148 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	api "github.com/netrisai/netriswebapi/v2"
 )
 
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+
 var (
 	requeueInterval = time.Duration(10 * time.Second)
 	cntxt           = context.Background()
 	contextTimeout  = requeueInterval
+
+	// driftCheckInterval is how often a resource in sync is compared with Netris.
+	driftCheckInterval = 5 * time.Minute
+	driftCheckJitter   = 0.2
+
+	// the bounds of the exponential backoff of the failed reconciles.
+	failureBaseDelay = 1 * time.Second
+	failureMaxDelay  = 5 * time.Minute
 )
 
+// controllerOptions are the options of all resource controllers: the failed
+// reconciles are retried with a per-object exponential backoff.
+func controllerOptions() controller.Options {
+	return controller.Options{
+		RateLimiter: workqueue.NewItemExponentialFailureRateLimiter(failureBaseDelay, failureMaxDelay),
+	}
+}
+
+// driftCheck is the result of a reconcile which found the resource in sync.
+// The jitter keeps the resources created together from being checked together.
+func driftCheck() ctrl.Result {
+	return ctrl.Result{RequeueAfter: wait.Jitter(driftCheckInterval, driftCheckJitter)}
+}
+
+// resultOf returns the result of a reconcile which ended with the status.
+// Failures are retried with the backoff, a resource being provisioned is
+// polled, and anything else waits for a change or the drift check.
+func resultOf(status string) ctrl.Result {
+	switch status {
+	case "Failure", "NetrisUnavailable", "CacheStale":
+		return ctrl.Result{Requeue: true}
+	case "Provisioning":
+		return ctrl.Result{RequeueAfter: requeueInterval}
+	}
+	return driftCheck()
+}
+
+// setOwner makes owner the controller of its meta, so the owner is reconciled
+// on the changes of the meta. It reports whether the meta was changed, the
+// metas created by older versions get the reference this way.
+func setOwner(owner, meta metav1.Object, scheme *runtime.Scheme) bool {
+	if metav1.GetControllerOf(meta) != nil {
+		return false
+	}
+	return controllerutil.SetControllerReference(owner, meta, scheme) == nil
+}
+
+// metaAnnotations are the annotations of a resource its meta reconciler
+// reads. They are copied to the meta, so a change of them reconciles the meta.
+var metaAnnotations = []string{dryRunAnnotation, pausedAnnotation}
+
+// syncAnnotations copies the metaAnnotations of owner to its meta. It reports
+// whether the meta was changed.
+func syncAnnotations(owner, meta metav1.Object) bool {
+	changed := false
+	annotations := meta.GetAnnotations()
+	for _, key := range metaAnnotations {
+		want, ok := owner.GetAnnotations()[key]
+		if have, found := annotations[key]; have == want && found == ok {
+			continue
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		if ok {
+			annotations[key] = want
+		} else {
+			delete(annotations, key)
+		}
+		changed = true
+	}
+	if changed {
+		meta.SetAnnotations(annotations)
+	}
+	return changed
+}
+
 type uniReconciler struct {
 	client.Client
 	Logger      logr.Logger
 	DebugLogger logr.InfoLogger
 	Cred        *api.Clientset
 	NStorage    *netrisstorage.Storage
+	Recorder    record.EventRecorder
+}
+
+// statusOf reports a failure as "NetrisUnavailable" while the Netris
+// controller can't be reached, so an outage isn't mistaken for a problem of
+// the resource.
+func (u *uniReconciler) statusOf(status string) string {
+	if status == "Failure" && netrisapi.For(u.Cred).Unavailable() {
+		return "NetrisUnavailable"
+	}
+	return status
+}
+
+// cacheStale returns why a create or delete decision can't be made yet: the
+// sub-storages it is based on are stale, or the object missing in the storage
+// isn't confirmed absent by the direct Netris lookup exists, which may be nil.
+// An empty string means the decision is safe.
+func (u *uniReconciler) cacheStale(exists func() (bool, error), kinds ...string) string {
+	msg := ""
+	if u.NStorage.IsStale(kinds...) {
+		msg = fmt.Sprintf("Netris storage is stale (%s), waiting for a successful refresh", strings.Join(kinds, ", "))
+	} else if exists != nil {
+		found, err := exists()
+		if err != nil {
+			msg = fmt.Sprintf("Couldn't confirm the absence in Netris: %s", err)
+		} else if found {
+			msg = "Found in Netris, but not in the storage yet, waiting for a refresh"
+		}
+	}
+	if msg != "" {
+		u.Logger.Info(msg)
+	}
+	return msg
 }
 
 func (u *uniReconciler) patchVNetStatus(vnet *k8sv1alpha1.VNet, status, message string) (ctrl.Result, error) {
+	status = u.statusOf(status)
 	u.DebugLogger.Info("Patching Status", "status", status, "message", message)
 	state := "active"
 	if len(vnet.Spec.State) > 0 {
@@ -56,6 +175,7 @@ func (u *uniReconciler) patchVNetStatus(vnet *k8sv1alpha1.VNet, status, message
 	vnet.Status.State = state
 	vnet.Status.Gateways = vnet.GatewaysString()
 	vnet.Status.Sites = vnet.SitesString()
+	setConditions(&vnet.Status.Conditions, &vnet.Status.ObservedGeneration, vnet.Generation, status, message)
 
 	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
 	defer cancel()
@@ -63,10 +183,11 @@ func (u *uniReconciler) patchVNetStatus(vnet *k8sv1alpha1.VNet, status, message
 	if err != nil {
 		u.DebugLogger.Info("{r.Status().Patch}", "error", err, "action", "status update")
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return resultOf(status), nil
 }
 
 func (u *uniReconciler) patchBGPStatus(bgp *k8sv1alpha1.BGP, status, message string) (ctrl.Result, error) {
+	status = u.statusOf(status)
 	u.DebugLogger.Info("Patching Status", "status", status, "message", message)
 
 	state := "enabled"
@@ -77,6 +198,7 @@ func (u *uniReconciler) patchBGPStatus(bgp *k8sv1alpha1.BGP, status, message str
 	bgp.Status.Status = status
 	bgp.Status.State = state
 	bgp.Status.Message = message
+	setConditions(&bgp.Status.Conditions, &bgp.Status.ObservedGeneration, bgp.Generation, status, message)
 
 	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
 	defer cancel()
@@ -84,10 +206,11 @@ func (u *uniReconciler) patchBGPStatus(bgp *k8sv1alpha1.BGP, status, message str
 	if err != nil {
 		u.DebugLogger.Info("{r.Status().Patch}", "error", err, "action", "status update")
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return resultOf(status), nil
 }
 
 func (u *uniReconciler) patchL4LBStatus(l4lb *k8sv1alpha1.L4LB, status, message string) (ctrl.Result, error) {
+	status = u.statusOf(status)
 	u.DebugLogger.Info("Patching Status", "status", status, "message", message)
 
 	state := "active"
@@ -98,6 +221,7 @@ func (u *uniReconciler) patchL4LBStatus(l4lb *k8sv1alpha1.L4LB, status, message
 	l4lb.Status.Status = status
 	l4lb.Status.State = state
 	l4lb.Status.Message = message
+	setConditions(&l4lb.Status.Conditions, &l4lb.Status.ObservedGeneration, l4lb.Generation, status, message)
 
 	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
 	defer cancel()
@@ -105,7 +229,7 @@ func (u *uniReconciler) patchL4LBStatus(l4lb *k8sv1alpha1.L4LB, status, message
 	if err != nil {
 		u.DebugLogger.Info("{r.Status().Patch}", "error", err, "action", "status update")
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return resultOf(status), nil
 }
 
 func (u *uniReconciler) patchL4LB(l4lb *k8sv1alpha1.L4LB) (ctrl.Result, error) {
@@ -116,14 +240,16 @@ func (u *uniReconciler) patchL4LB(l4lb *k8sv1alpha1.L4LB) (ctrl.Result, error) {
 	if err != nil {
 		u.DebugLogger.Info("{r.Patch()}", "error", err)
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (u *uniReconciler) patchSiteStatus(l4lb *k8sv1alpha1.Site, status, message string) (ctrl.Result, error) {
+	status = u.statusOf(status)
 	u.DebugLogger.Info("Patching Status", "status", status, "message", message)
 
 	l4lb.Status.Status = status
 	l4lb.Status.Message = message
+	setConditions(&l4lb.Status.Conditions, &l4lb.Status.ObservedGeneration, l4lb.Generation, status, message)
 
 	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
 	defer cancel()
@@ -131,14 +257,16 @@ func (u *uniReconciler) patchSiteStatus(l4lb *k8sv1alpha1.Site, status, message
 	if err != nil {
 		u.DebugLogger.Info("{r.Status().Patch}", "error", err, "action", "status update")
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return resultOf(status), nil
 }
 
 func (u *uniReconciler) patchAllocationStatus(allocation *k8sv1alpha1.Allocation, status, message string) (ctrl.Result, error) {
+	status = u.statusOf(status)
 	u.DebugLogger.Info("Patching Status", "status", status, "message", message)
 
 	allocation.Status.Status = status
 	allocation.Status.Message = message
+	setConditions(&allocation.Status.Conditions, &allocation.Status.ObservedGeneration, allocation.Generation, status, message)
 
 	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
 	defer cancel()
@@ -146,14 +274,16 @@ func (u *uniReconciler) patchAllocationStatus(allocation *k8sv1alpha1.Allocation
 	if err != nil {
 		u.DebugLogger.Info("{r.Status().Patch}", "error", err, "action", "status update")
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return resultOf(status), nil
 }
 
 func (u *uniReconciler) patchSubnetStatus(subnet *k8sv1alpha1.Subnet, status, message string) (ctrl.Result, error) {
+	status = u.statusOf(status)
 	u.DebugLogger.Info("Patching Status", "status", status, "message", message)
 
 	subnet.Status.Status = status
 	subnet.Status.Message = message
+	setConditions(&subnet.Status.Conditions, &subnet.Status.ObservedGeneration, subnet.Generation, status, message)
 
 	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
 	defer cancel()
@@ -161,14 +291,16 @@ func (u *uniReconciler) patchSubnetStatus(subnet *k8sv1alpha1.Subnet, status, me
 	if err != nil {
 		u.DebugLogger.Info("{r.Status().Patch}", "error", err, "action", "status update")
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return resultOf(status), nil
 }
 
 func (u *uniReconciler) patchSoftgateStatus(softgate *k8sv1alpha1.Softgate, status, message string) (ctrl.Result, error) {
+	status = u.statusOf(status)
 	u.DebugLogger.Info("Patching Status", "status", status, "message", message)
 
 	softgate.Status.Status = status
 	softgate.Status.Message = message
+	setConditions(&softgate.Status.Conditions, &softgate.Status.ObservedGeneration, softgate.Generation, status, message)
 
 	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
 	defer cancel()
@@ -176,14 +308,16 @@ func (u *uniReconciler) patchSoftgateStatus(softgate *k8sv1alpha1.Softgate, stat
 	if err != nil {
 		u.DebugLogger.Info("{r.Status().Patch}", "error", err, "action", "status update")
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return resultOf(status), nil
 }
 
 func (u *uniReconciler) patchSwitchStatus(switchH *k8sv1alpha1.Switch, status, message string) (ctrl.Result, error) {
+	status = u.statusOf(status)
 	u.DebugLogger.Info("Patching Status", "status", status, "message", message)
 
 	switchH.Status.Status = status
 	switchH.Status.Message = message
+	setConditions(&switchH.Status.Conditions, &switchH.Status.ObservedGeneration, switchH.Generation, status, message)
 
 	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
 	defer cancel()
@@ -191,14 +325,16 @@ func (u *uniReconciler) patchSwitchStatus(switchH *k8sv1alpha1.Switch, status, m
 	if err != nil {
 		u.DebugLogger.Info("{r.Status().Patch}", "error", err, "action", "status update")
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return resultOf(status), nil
 }
 
 func (u *uniReconciler) patchControllerStatus(controller *k8sv1alpha1.Controller, status, message string) (ctrl.Result, error) {
+	status = u.statusOf(status)
 	u.DebugLogger.Info("Patching Status", "status", status, "message", message)
 
 	controller.Status.Status = status
 	controller.Status.Message = message
+	setConditions(&controller.Status.Conditions, &controller.Status.ObservedGeneration, controller.Generation, status, message)
 
 	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
 	defer cancel()
@@ -206,14 +342,16 @@ func (u *uniReconciler) patchControllerStatus(controller *k8sv1alpha1.Controller
 	if err != nil {
 		u.DebugLogger.Info("{r.Status().Patch}", "error", err, "action", "status update")
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return resultOf(status), nil
 }
 
 func (u *uniReconciler) patchNatStatus(nat *k8sv1alpha1.Nat, status, message string) (ctrl.Result, error) {
+	status = u.statusOf(status)
 	u.DebugLogger.Info("Patching Status", "status", status, "message", message)
 
 	nat.Status.Status = status
 	nat.Status.Message = message
+	setConditions(&nat.Status.Conditions, &nat.Status.ObservedGeneration, nat.Generation, status, message)
 
 	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
 	defer cancel()
@@ -221,10 +359,11 @@ func (u *uniReconciler) patchNatStatus(nat *k8sv1alpha1.Nat, status, message str
 	if err != nil {
 		u.DebugLogger.Info("{r.Status().Patch}", "error", err, "action", "status update")
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return resultOf(status), nil
 }
 
 func (u *uniReconciler) patchInventoryProfileStatus(inventoryProfile *k8sv1alpha1.InventoryProfile, status, message string) (ctrl.Result, error) {
+	status = u.statusOf(status)
 	u.DebugLogger.Info("Patching Status", "status", status, "message", message)
 
 	ntpServers := []string{}
@@ -263,6 +402,7 @@ func (u *uniReconciler) patchInventoryProfileStatus(inventoryProfile *k8sv1alpha
 	inventoryProfile.Status.NTPServers = "[" + strings.Join(ntpServers, ",") + "]"
 	inventoryProfile.Status.DNSServers = "[" + strings.Join(dnsServers, ",") + "]"
 	inventoryProfile.Status.CustomRules = "[" + strings.Join(customRules, ",") + "]"
+	setConditions(&inventoryProfile.Status.Conditions, &inventoryProfile.Status.ObservedGeneration, inventoryProfile.Generation, status, message)
 
 	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
 	defer cancel()
@@ -270,15 +410,17 @@ func (u *uniReconciler) patchInventoryProfileStatus(inventoryProfile *k8sv1alpha
 	if err != nil {
 		u.DebugLogger.Info("{r.Status().Patch}", "error", err, "action", "status update")
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return resultOf(status), nil
 }
 
 func (u *uniReconciler) patchLinkStatus(link *k8sv1alpha1.Link, status, message string) (ctrl.Result, error) {
+	status = u.statusOf(status)
 	u.DebugLogger.Info("Patching Status", "status", status, "message", message)
 
 	link.Status.Status = status
 	link.Status.Message = message
 	link.Status.Ports = fmt.Sprintf("%s, %s", link.Spec.Ports[0], link.Spec.Ports[1])
+	setConditions(&link.Status.Conditions, &link.Status.ObservedGeneration, link.Generation, status, message)
 
 	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
 	defer cancel()
@@ -286,7 +428,7 @@ func (u *uniReconciler) patchLinkStatus(link *k8sv1alpha1.Link, status, message
 	if err != nil {
 		u.DebugLogger.Info("{r.Status().Patch}", "error", err, "action", "status update")
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return resultOf(status), nil
 }
 
 func (u *uniReconciler) patchSoftgate(softgate *k8sv1alpha1.Softgate) (ctrl.Result, error) {
@@ -297,7 +439,7 @@ func (u *uniReconciler) patchSoftgate(softgate *k8sv1alpha1.Softgate) (ctrl.Resu
 	if err != nil {
 		u.DebugLogger.Info("{r.Patch()}", "error", err)
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (u *uniReconciler) patchSwitch(switchH *k8sv1alpha1.Switch) (ctrl.Result, error) {
@@ -308,7 +450,7 @@ func (u *uniReconciler) patchSwitch(switchH *k8sv1alpha1.Switch) (ctrl.Result, e
 	if err != nil {
 		u.DebugLogger.Info("{r.Patch()}", "error", err)
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (u *uniReconciler) patchController(controller *k8sv1alpha1.Controller) (ctrl.Result, error) {
@@ -319,5 +461,5 @@ func (u *uniReconciler) patchController(controller *k8sv1alpha1.Controller) (ctr
 	if err != nil {
 		u.DebugLogger.Info("{r.Patch()}", "error", err)
 	}
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
@@ -23,11 +23,13 @@ import (
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -36,10 +38,12 @@ import (
 // ControllerReconciler reconciles a Controller object
 type ControllerReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.netris.ai,resources=controllers,verbs=get;list;watch;create;update;patch;delete
@@ -56,6 +60,19 @@ type ControllerReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (r *ControllerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.Controller{}, &k8sv1alpha1.ControllerMeta{})
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *ControllerReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	logger := r.Log.WithValues("name", req.NamespacedName)
 	debugLogger := logger.V(int(zapcore.WarnLevel))
 	controller := &k8sv1alpha1.Controller{}
@@ -66,6 +83,7 @@ func (r *ControllerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	controllerCtx, controllerCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -95,13 +113,29 @@ func (r *ControllerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		}
 	}
 
+	if paused(controller) {
+		if metaFound {
+			if err := u.annotateMeta(controller, controllerMeta); err != nil {
+				logger.Error(err, "")
+				return ctrl.Result{Requeue: true}, nil
+			}
+		}
+		return u.patchControllerStatus(controller, "Paused", pausedMessage)
+	}
+
 	if controller.DeletionTimestamp != nil {
+		if msg := deletionBlocked(controller, false); msg != "" {
+			u.warning(controller, EventDeleteBlocked, "%s", msg)
+			return u.patchControllerStatus(controller, "DeletionBlocked", msg)
+		}
 		logger.Info("Go to delete")
 		_, err := r.deleteController(controller, controllerMeta)
 		if err != nil {
 			logger.Error(fmt.Errorf("{deleteController} %s", err), "")
+			u.warning(controller, EventDeleteBlocked, "Couldn't delete Controller from Netris: %s", err)
 			return u.patchControllerStatus(controller, "Failure", err.Error())
 		}
+		u.event(controller, EventDeleted, "Controller deleted from Netris")
 		logger.Info("Controller deleted")
 		return ctrl.Result{}, nil
 	}
@@ -114,14 +148,14 @@ func (r *ControllerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		err := r.Patch(controllerPatchCtx, controller.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 		if err != nil {
 			logger.Error(fmt.Errorf("{Patch Controller default annotations} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 		return ctrl.Result{}, nil
 	}
 
 	if metaFound {
 		debugLogger.Info("Meta found")
-		if controllerCompareFieldsForNewMeta(controller, controllerMeta) {
+		if ownerChanged, annotationsChanged := setOwner(controller, controllerMeta, r.Scheme), syncAnnotations(controller, controllerMeta); controllerCompareFieldsForNewMeta(controller, controllerMeta) || ownerChanged || annotationsChanged {
 			debugLogger.Info("Generating New Meta")
 			controllerID := controllerMeta.Spec.ID
 			newControllerMeta, err := r.ControllerToControllerMeta(controller)
@@ -129,8 +163,16 @@ func (r *ControllerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 				logger.Error(fmt.Errorf("{ControllerToControllerMeta} %s", err), "")
 				return u.patchControllerStatus(controller, "Failure", err.Error())
 			}
+			// the Netris controller of an existing resource never changes.
+			controllerRef := controllerMeta.Spec.ControllerRef
+			currentName := controllerMeta.Spec.NetrisName
+			if currentName == "" {
+				currentName = controllerMeta.Spec.ControllerName
+			}
 			controllerMeta.Spec = newControllerMeta.DeepCopy().Spec
 			controllerMeta.Spec.ID = controllerID
+			controllerMeta.Spec.ControllerRef = controllerRef
+			controllerMeta.Spec.NetrisName = keptNetrisName(controller.Spec.NetrisName, currentName, controllerMeta.Spec.NetrisName, controllerID)
 			controllerMeta.Spec.ControllerCRGeneration = controller.GetGeneration()
 
 			controllerMetaUpdateCtx, controllerMetaUpdateCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -138,7 +180,7 @@ func (r *ControllerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 			err = r.Update(controllerMetaUpdateCtx, controllerMeta.DeepCopyObject(), &client.UpdateOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{controllerMeta Update} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 		}
 	} else {
@@ -151,7 +193,7 @@ func (r *ControllerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 			err := r.Patch(controllerPatchCtx, controller.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{Patch Controller Finalizer} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 			return ctrl.Result{}, nil
 		}
@@ -164,20 +206,24 @@ func (r *ControllerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 
 		controllerMeta.Spec.ControllerCRGeneration = controller.GetGeneration()
 
+		setOwner(controller, controllerMeta, r.Scheme)
+		syncAnnotations(controller, controllerMeta)
 		controllerMetaCreateCtx, controllerMetaCreateCancel := context.WithTimeout(cntxt, contextTimeout)
 		defer controllerMetaCreateCancel()
 		if err := r.Create(controllerMetaCreateCtx, controllerMeta.DeepCopyObject(), &client.CreateOptions{}); err != nil {
 			logger.Error(fmt.Errorf("{controllerMeta Create} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (r *ControllerReconciler) deleteController(controller *k8sv1alpha1.Controller, controllerMeta *k8sv1alpha1.ControllerMeta) (ctrl.Result, error) {
 	if controllerMeta != nil && controllerMeta.Spec.ID > 0 && !controllerMeta.Spec.Reclaim {
-		reply, err := r.Cred.Inventory().Delete("controller", controllerMeta.Spec.ID)
+		reply, err := netrisapi.Do(r.Cred, "inventory", "delete", func() (http.HTTPReply, error) {
+			return r.Cred.Inventory().Delete("controller", controllerMeta.Spec.ID)
+		})
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("{deleteController} %s", err)
 		}
@@ -229,5 +275,7 @@ func (r *ControllerReconciler) deleteControllerMetaCR(controllerMeta *k8sv1alpha
 func (r *ControllerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.Controller{}).
+		Owns(&k8sv1alpha1.ControllerMeta{}).
+		WithOptions(controllerOptions()).
 		Complete(r)
 }
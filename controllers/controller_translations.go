@@ -60,8 +60,10 @@ func (r *ControllerReconciler) ControllerToControllerMeta(controller *k8sv1alpha
 		TypeMeta: metav1.TypeMeta{},
 		Spec: k8sv1alpha1.ControllerMetaSpec{
 			Imported:       imported,
+			ControllerRef:  controller.Spec.ControllerRef,
 			Reclaim:        reclaim,
 			ControllerName: controller.Name,
+			NetrisName:     netrisName(controller, controller.Spec.NetrisName),
 			Description:    controller.Spec.Description,
 			TenantID:       tenantID,
 			SiteID:         siteID,
@@ -118,7 +120,7 @@ func ControllerMetaToNetris(controllerMeta *k8sv1alpha1.ControllerMeta) (*invent
 	}
 
 	controllerAdd := &inventory.HWController{
-		Name:        controllerMeta.Spec.ControllerName,
+		Name:        controllerMeta.Spec.NetrisName,
 		Description: controllerMeta.Spec.Description,
 		Tenant:      inventory.IDName{ID: controllerMeta.Spec.TenantID},
 		Site:        inventory.IDName{ID: controllerMeta.Spec.SiteID},
@@ -136,7 +138,7 @@ func ControllerMetaToNetrisUpdate(controllerMeta *k8sv1alpha1.ControllerMeta) (*
 	}
 
 	controllerUpdate := &inventory.HWControllerUpdate{
-		Name:        controllerMeta.Spec.ControllerName,
+		Name:        controllerMeta.Spec.NetrisName,
 		Description: controllerMeta.Spec.Description,
 		MainAddress: mainIP,
 	}
@@ -145,8 +147,8 @@ func ControllerMetaToNetrisUpdate(controllerMeta *k8sv1alpha1.ControllerMeta) (*
 }
 
 func compareControllerMetaAPIEController(controllerMeta *k8sv1alpha1.ControllerMeta, apiController *inventory.HW, u uniReconciler) bool {
-	if apiController.Name != controllerMeta.Spec.ControllerName {
-		u.DebugLogger.Info("Name changed", "netrisValue", apiController.Name, "k8sValue", controllerMeta.Spec.ControllerName)
+	if apiController.Name != controllerMeta.Spec.NetrisName {
+		u.DebugLogger.Info("Name changed", "netrisValue", apiController.Name, "k8sValue", controllerMeta.Spec.NetrisName)
 		return false
 	}
 
@@ -162,3 +164,19 @@ func compareControllerMetaAPIEController(controllerMeta *k8sv1alpha1.ControllerM
 
 	return true
 }
+
+// controllerDrift returns the difference of the Controller spec with the
+// Netris controller and sets the spec to the Netris values.
+func controllerDrift(spec *k8sv1alpha1.ControllerSpec, controllerMeta *k8sv1alpha1.ControllerMeta, apiController *inventory.HW) *drift {
+	d := &drift{}
+	if apiController.Name != controllerMeta.Spec.NetrisName {
+		driftFixed(d, "metadata.name", controllerMeta.Spec.NetrisName, apiController.Name)
+	}
+	if apiController.Description != controllerMeta.Spec.Description {
+		driftSet(d, "spec.description", &spec.Description, apiController.Description)
+	}
+	if apiController.MainIP.Address != controllerMeta.Spec.MainIP {
+		driftSet(d, "spec.mainIp", &spec.MainIP, apiController.MainIP.Address)
+	}
+	return d
+}
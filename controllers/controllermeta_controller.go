@@ -20,15 +20,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -38,10 +41,12 @@ import (
 // ControllerMetaReconciler reconciles a ControllerMeta object
 type ControllerMetaReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.netris.ai,resources=controllermeta,verbs=get;list;watch;create;update;patch;delete
@@ -58,6 +63,19 @@ type ControllerMetaReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (r *ControllerMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.ControllerMeta{}, nil)
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *ControllerMetaReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	debugLogger := r.Log.WithValues("name", req.NamespacedName).V(int(zapcore.WarnLevel))
 
 	controllerMeta := &k8sv1alpha1.ControllerMeta{}
@@ -81,6 +99,7 @@ func (r *ControllerMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	provisionState := "OK"
@@ -97,16 +116,24 @@ func (r *ControllerMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 		return ctrl.Result{}, err
 	}
 
+	if paused(controllerCR) {
+		return u.patchControllerStatus(controllerCR, "Paused", pausedMessage)
+	}
+
 	if controllerMeta.DeletionTimestamp != nil {
 		return ctrl.Result{}, nil
 	}
 
+	if controllerMeta.Spec.NetrisName == "" {
+		controllerMeta.Spec.NetrisName = controllerMeta.Spec.ControllerName
+	}
+
 	if controllerMeta.Spec.ID == 0 {
 		debugLogger.Info("ID Not found in meta")
 		if controllerMeta.Spec.Imported {
 			logger.Info("Importing controller")
 			debugLogger.Info("Imported yaml mode. Finding Controller by name")
-			if controller, ok := r.NStorage.HWsStorage.FindControllerByName(controllerMeta.Spec.ControllerName); ok {
+			if controller, ok := r.NStorage.HWsStorage.FindControllerByName(controllerMeta.Spec.NetrisName); ok {
 				debugLogger.Info("Imported yaml mode. Controller found")
 				controllerMeta.Spec.ID = controller.ID
 				controllerMeta.Spec.MainIP = controller.MainIP.Address
@@ -119,26 +146,52 @@ func (r *ControllerMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 					return u.patchControllerStatus(controllerCR, "Failure", err.Error())
 				}
 				debugLogger.Info("Imported yaml mode. ID patched")
+				u.event(controllerCR, EventImported, "Controller imported from Netris, ID %d", controllerMeta.Spec.ID)
 				logger.Info("Controller imported")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{}, nil
 			}
 			logger.Info("Controller not found for import")
 			debugLogger.Info("Imported yaml mode. Controller not found")
 		}
 
+		if msg := u.cacheStale(nil, netrisstorage.KindHWs); msg != "" {
+			return u.patchControllerStatus(controllerCR, "CacheStale", msg)
+		}
+		_, exists := r.NStorage.HWsStorage.FindControllerByName(controllerMeta.Spec.NetrisName)
+		if msg := u.nameCollision(controllerMeta, "Controller", controllerMeta.Spec.NetrisName, exists); msg != "" {
+			u.warning(controllerCR, EventNameCollision, "%s", msg)
+			return u.patchControllerStatus(controllerCR, "NameCollision", msg)
+		}
 		logger.Info("Creating Controller")
 		if _, err, errMsg := r.createController(controllerMeta); err != nil {
 			logger.Error(fmt.Errorf("{createController} %s", err), "")
+			u.warning(controllerCR, EventAPIError, "Couldn't create Controller in Netris: %s", errMsg)
 			return u.patchControllerStatus(controllerCR, "Failure", errMsg.Error())
 		}
+		controllerCR.Status.AppliedGeneration = controllerMeta.Spec.ControllerCRGeneration
+		u.event(controllerCR, EventCreated, "Controller created in Netris")
 		logger.Info("Controller Created")
 	} else {
 		if apiController, ok := r.NStorage.HWsStorage.FindControllerByID(controllerMeta.Spec.ID); ok {
 			debugLogger.Info("Comparing ControllerMeta with Netris Controller")
 
+			driftedFields := controllerCR.Status.DriftedFields
+			controllerCR.Status.DriftedFields = nil
 			if ok := compareControllerMetaAPIEController(controllerMeta, apiController, u); ok {
 				debugLogger.Info("Nothing Changed")
+				controllerCR.Status.AppliedGeneration = controllerMeta.Spec.ControllerCRGeneration
+			} else if keepsDrift(controllerCR, controllerCR.Status.AppliedGeneration) {
+				spec := controllerCR.Spec.DeepCopy()
+				d := controllerDrift(spec, controllerMeta, apiController)
+				if driftPolicyOf(controllerCR) == driftAdopt && d.adoptable() {
+					controllerCR.Spec = *spec
+					return u.adoptDrift(controllerCR, "Controller", d)
+				}
+				u.reportDrift(controllerCR, "Controller", d, driftedFields)
+				controllerCR.Status.DriftedFields = d.fields
+				return u.patchControllerStatus(controllerCR, "Drifted", d.String())
 			} else {
+				recordDrift("Controller", controllerCR.Generation, controllerCR.Status.AppliedGeneration)
 				debugLogger.Info("Go to update Controller in Netris")
 				logger.Info("Updating Controller")
 				controllerUpdate, err := ControllerMetaToNetrisUpdate(controllerMeta)
@@ -153,19 +206,30 @@ func (r *ControllerMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 				_, err, errMsg := updateController(controllerMeta.Spec.ID, controllerUpdate, r.Cred)
 				if err != nil {
 					logger.Error(fmt.Errorf("{updateController} %s", err), "")
+					u.warning(controllerCR, EventAPIError, "Couldn't update Controller in Netris: %s", errMsg)
 					return u.patchControllerStatus(controllerCR, "Failure", errMsg.Error())
 				}
+				u.updated(controllerCR, controllerCR.Generation, controllerCR.Status.AppliedGeneration, "Controller")
+				controllerCR.Status.AppliedGeneration = controllerMeta.Spec.ControllerCRGeneration
 				logger.Info("Controller Updated")
 			}
 			controllerMeta.Spec.MainIP = apiController.MainIP.Address
 		} else {
 			debugLogger.Info("Controller not found in Netris")
 			debugLogger.Info("Going to create Controller")
+			if msg := u.cacheStale(func() (bool, error) {
+				return existsInNetris(r.Cred, "inventory", r.Cred.Inventory().Get, func(item *inventory.HW) bool { return item.ID == controllerMeta.Spec.ID })
+			}, netrisstorage.KindHWs); msg != "" {
+				return u.patchControllerStatus(controllerCR, "CacheStale", msg)
+			}
 			logger.Info("Creating Controller")
 			if _, err, errMsg := r.createController(controllerMeta); err != nil {
 				logger.Error(fmt.Errorf("{createController} %s", err), "")
+				u.warning(controllerCR, EventAPIError, "Couldn't create Controller in Netris: %s", errMsg)
 				return u.patchControllerStatus(controllerCR, "Failure", errMsg.Error())
 			}
+			controllerCR.Status.AppliedGeneration = controllerMeta.Spec.ControllerCRGeneration
+			u.event(controllerCR, EventCreated, "Controller created in Netris")
 			logger.Info("Controller Created")
 		}
 	}
@@ -192,7 +256,9 @@ func (r *ControllerMetaReconciler) createController(controllerMeta *k8sv1alpha1.
 	js, _ := json.Marshal(controllerAdd)
 	debugLogger.Info("controllerToAdd", "payload", string(js))
 
-	reply, err := r.Cred.Inventory().AddController(controllerAdd)
+	reply, err := netrisapi.Create(r.Cred, "inventory", func() (http.HTTPReply, error) {
+		return r.Cred.Inventory().AddController(controllerAdd)
+	})
 	if err != nil {
 		return ctrl.Result{}, err, err
 	}
@@ -228,7 +294,9 @@ func (r *ControllerMetaReconciler) createController(controllerMeta *k8sv1alpha1.
 }
 
 func updateController(id int, controller *inventory.HWControllerUpdate, cred *api.Clientset) (ctrl.Result, error, error) {
-	reply, err := cred.Inventory().UpdateController(id, controller)
+	reply, err := netrisapi.Do(cred, "inventory", "update", func() (http.HTTPReply, error) {
+		return cred.Inventory().UpdateController(id, controller)
+	})
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("{updateController} %s", err), err
 	}
@@ -245,8 +313,16 @@ func updateController(id int, controller *inventory.HWControllerUpdate, cred *ap
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ControllerMetaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	src, hdl, err := watchStorage(mgr, r.Controllers.storageListeners(r.NStorage), &k8sv1alpha1.ControllerMeta{}, &k8sv1alpha1.ControllerMetaList{}, func(o runtime.Object) string {
+		return strconv.Itoa(o.(*k8sv1alpha1.ControllerMeta).Spec.ID)
+	}, netrisstorage.KindHWs)
+	if err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.ControllerMeta{}).
+		WithOptions(controllerOptions()).
+		Watches(src, hdl).
 		Complete(r)
 }
 
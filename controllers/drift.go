@@ -0,0 +1,166 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+)
+
+// Drift policies, chosen with the resource.k8s.netris.ai/driftPolicy
+// annotation. They apply when an object is changed in Netris: a spec change
+// is always applied to Netris.
+const (
+	driftPolicyAnnotation = "resource.k8s.netris.ai/driftPolicy"
+
+	// driftEnforce overwrites the change in Netris with the spec. It's the default.
+	driftEnforce = "enforce"
+	// driftReport keeps the change in Netris and records it in the status and the events.
+	driftReport = "report"
+	// driftAdopt writes the change in Netris back into the spec.
+	driftAdopt = "adopt"
+)
+
+// driftPolicyOf returns the drift policy of obj, enforce if not set or invalid.
+func driftPolicyOf(obj metav1.Object) string {
+	switch policy := obj.GetAnnotations()[driftPolicyAnnotation]; policy {
+	case driftReport, driftAdopt:
+		return policy
+	}
+	return driftEnforce
+}
+
+// keepsDrift reports whether the change of obj in Netris must be kept
+// instead of being overwritten with the spec. A spec edit not yet applied to
+// Netris is always written, so it is never taken for a drift.
+func keepsDrift(obj metav1.Object, appliedGeneration int64) bool {
+	return driftPolicyOf(obj) != driftEnforce && !specChanged(obj.GetGeneration(), appliedGeneration)
+}
+
+// drift is the field level difference of a spec with the Netris object.
+type drift struct {
+	fields []k8sv1alpha1.DriftedField
+
+	// fixed is set by a field which can't be written into the spec, e.g. the
+	// name of the resource.
+	fixed bool
+}
+
+// adoptable reports whether the spec can take all the Netris values.
+func (d *drift) adoptable() bool {
+	return len(d.fields) > 0 && !d.fixed
+}
+
+func (d *drift) String() string {
+	if len(d.fields) == 0 {
+		return "Changed in Netris in fields the spec doesn't have"
+	}
+	fields := []string{}
+	for _, f := range d.fields {
+		fields = append(fields, f.String())
+	}
+	return "Changed in Netris: " + strings.Join(fields, "; ")
+}
+
+// driftSet records field when the Netris value differs from the spec one,
+// and sets the spec to the Netris value. The spec is a copy, it's kept only
+// by the adopt policy.
+func driftSet[T any](d *drift, field string, spec *T, netris T) {
+	if reflect.DeepEqual(*spec, netris) {
+		return
+	}
+	d.fields = append(d.fields, k8sv1alpha1.DriftedField{
+		Field:  field,
+		Spec:   driftValue(*spec),
+		Netris: driftValue(netris),
+	})
+	*spec = netris
+}
+
+// driftSecret is driftSet for a field whose values mustn't be shown.
+func driftSecret(d *drift, field string, spec *string, netris string) {
+	if *spec == netris {
+		return
+	}
+	d.fields = append(d.fields, k8sv1alpha1.DriftedField{Field: field, Spec: "(hidden)", Netris: "(hidden)"})
+	*spec = netris
+}
+
+// driftFixed records a field which differs and can't be adopted.
+func driftFixed(d *drift, field string, spec, netris interface{}) {
+	d.fields = append(d.fields, k8sv1alpha1.DriftedField{
+		Field:  field,
+		Spec:   driftValue(spec),
+		Netris: driftValue(netris),
+	})
+	d.fixed = true
+}
+
+// driftList splits a Netris list field joined with sep into the spec list.
+func driftList(s, sep string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, sep)
+}
+
+func driftValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// reportDrift records the drift of obj of kind in the events. Unchanged
+// drifts, found again by the drift check, aren't recorded again.
+func (u *uniReconciler) reportDrift(obj runtime.Object, kind string, d *drift, recorded []k8sv1alpha1.DriftedField) {
+	if len(recorded) > 0 && reflect.DeepEqual(recorded, d.fields) {
+		return
+	}
+	u.warning(obj, EventDriftDetected, "%s %s", kind, lowerFirst(d.String()))
+}
+
+// adoptDrift writes the Netris values, already set in the spec of obj, to
+// the resource. The new generation is then applied as usual.
+func (u *uniReconciler) adoptDrift(obj runtime.Object, kind string, d *drift) (ctrl.Result, error) {
+	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
+	defer cancel()
+	if err := u.Update(ctx, obj); err != nil {
+		u.Logger.Error(fmt.Errorf("{adoptDrift} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	u.event(obj, EventDriftAdopted, "%s %s, adopted into the spec", kind, lowerFirst(d.String()))
+	u.Logger.Info("Netris changes adopted", "fields", len(d.fields))
+	return ctrl.Result{}, nil
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
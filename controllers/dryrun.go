@@ -0,0 +1,77 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/configloader"
+)
+
+// dryRunAnnotation set to "true" plans the Netris changes of a resource in
+// its status.plan instead of sending them. The --dry-run flag does it for all
+// resources.
+const dryRunAnnotation = "resource.k8s.netris.ai/dry-run"
+
+// Actions of a plan.
+const (
+	planCreate = "create"
+	planUpdate = "update"
+	planDelete = "delete"
+)
+
+// dryRun reports whether the Netris changes of obj must be planned only.
+func dryRun(obj metav1.Object) bool {
+	return configloader.Root.DryRun || obj.GetAnnotations()[dryRunAnnotation] == "true"
+}
+
+// planOf returns the plan of the request with the payload to the Netris
+// object id. changes are the fields of an update differing in Netris.
+func planOf(action string, id int, payload interface{}, changes []k8sv1alpha1.DriftedField) *k8sv1alpha1.NetrisPlan {
+	plan := &k8sv1alpha1.NetrisPlan{
+		Action:  action,
+		ID:      id,
+		Changes: changes,
+	}
+	if payload != nil {
+		if b, err := json.Marshal(payload); err == nil {
+			plan.Payload = string(b)
+		}
+	}
+	return plan
+}
+
+// planMessage returns the status message of the plan of the kind.
+func planMessage(kind string, plan *k8sv1alpha1.NetrisPlan) string {
+	msg := fmt.Sprintf("Dry run: would %s %s in Netris", plan.Action, kind)
+	if plan.ID > 0 {
+		msg = fmt.Sprintf("Dry run: would %s %s %d in Netris", plan.Action, kind, plan.ID)
+	}
+	if len(plan.Changes) > 0 {
+		fields := make([]string, 0, len(plan.Changes))
+		for _, c := range plan.Changes {
+			fields = append(fields, c.Field)
+		}
+		msg += ": " + strings.Join(fields, ", ")
+	}
+	return msg
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Reasons of the events emitted on the resources.
+const (
+	EventCreated        = "Created"
+	EventUpdated        = "Updated"
+	EventImported       = "Imported"
+	EventDriftCorrected = "DriftCorrected"
+	EventDriftDetected  = "DriftDetected"
+	EventDriftAdopted   = "DriftAdopted"
+	EventDeleted        = "Deleted"
+	EventDeleteBlocked  = "DeleteBlocked"
+	EventAPIError       = "APIError"
+	EventNameCollision  = "NameCollision"
+)
+
+// event records a Normal event on obj. It's a no-op without a recorder.
+func (u *uniReconciler) event(obj runtime.Object, reason, messageFmt string, args ...interface{}) {
+	if u.Recorder == nil {
+		return
+	}
+	u.Recorder.Event(obj, v1.EventTypeNormal, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// warning records a Warning event on obj. It's a no-op without a recorder.
+func (u *uniReconciler) warning(obj runtime.Object, reason, messageFmt string, args ...interface{}) {
+	if u.Recorder == nil {
+		return
+	}
+	u.Recorder.Event(obj, v1.EventTypeWarning, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// updated records the event of an update pushed to Netris: Updated when the
+// spec changed since it was last applied to Netris, DriftCorrected when the
+// spec didn't change and the object was modified in Netris.
+func (u *uniReconciler) updated(obj runtime.Object, generation, appliedGeneration int64, kind string) {
+	if specChanged(generation, appliedGeneration) {
+		u.event(obj, EventUpdated, "%s updated in Netris", kind)
+		return
+	}
+	u.event(obj, EventDriftCorrected, "%s was changed in Netris, restored from the spec", kind)
+}
@@ -23,11 +23,13 @@ import (
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -36,10 +38,12 @@ import (
 // InventoryProfileReconciler reconciles a InventoryProfile object
 type InventoryProfileReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.netris.ai,resources=inventoryprofiles,verbs=get;list;watch;create;update;patch;delete
@@ -56,6 +60,19 @@ type InventoryProfileReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (r *InventoryProfileReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.InventoryProfile{}, &k8sv1alpha1.InventoryProfileMeta{})
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *InventoryProfileReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	logger := r.Log.WithValues("name", req.NamespacedName)
 	debugLogger := logger.V(int(zapcore.WarnLevel))
 	inventoryProfile := &k8sv1alpha1.InventoryProfile{}
@@ -66,6 +83,7 @@ func (r *InventoryProfileReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	inventoryProfileCtx, inventoryProfileCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -95,13 +113,29 @@ func (r *InventoryProfileReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 		}
 	}
 
+	if paused(inventoryProfile) {
+		if metaFound {
+			if err := u.annotateMeta(inventoryProfile, inventoryProfileMeta); err != nil {
+				logger.Error(err, "")
+				return ctrl.Result{Requeue: true}, nil
+			}
+		}
+		return u.patchInventoryProfileStatus(inventoryProfile, "Paused", pausedMessage)
+	}
+
 	if inventoryProfile.DeletionTimestamp != nil {
+		if msg := deletionBlocked(inventoryProfile, false); msg != "" {
+			u.warning(inventoryProfile, EventDeleteBlocked, "%s", msg)
+			return u.patchInventoryProfileStatus(inventoryProfile, "DeletionBlocked", msg)
+		}
 		logger.Info("Go to delete")
 		_, err := r.deleteInventoryProfile(inventoryProfile, inventoryProfileMeta)
 		if err != nil {
 			logger.Error(fmt.Errorf("{deleteInventoryProfile} %s", err), "")
+			u.warning(inventoryProfile, EventDeleteBlocked, "Couldn't delete InventoryProfile from Netris: %s", err)
 			return u.patchInventoryProfileStatus(inventoryProfile, "Failure", err.Error())
 		}
+		u.event(inventoryProfile, EventDeleted, "InventoryProfile deleted from Netris")
 		logger.Info("InventoryProfile deleted")
 		return ctrl.Result{}, nil
 	}
@@ -114,14 +148,14 @@ func (r *InventoryProfileReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 		err := r.Patch(inventoryProfilePatchCtx, inventoryProfile.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 		if err != nil {
 			logger.Error(fmt.Errorf("{Patch InventoryProfile default annotations} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 		return ctrl.Result{}, nil
 	}
 
 	if metaFound {
 		debugLogger.Info("Meta found")
-		if inventoryProfileCompareFieldsForNewMeta(inventoryProfile, inventoryProfileMeta) {
+		if ownerChanged, annotationsChanged := setOwner(inventoryProfile, inventoryProfileMeta, r.Scheme), syncAnnotations(inventoryProfile, inventoryProfileMeta); inventoryProfileCompareFieldsForNewMeta(inventoryProfile, inventoryProfileMeta) || ownerChanged || annotationsChanged {
 			debugLogger.Info("Generating New Meta")
 			inventoryProfileID := inventoryProfileMeta.Spec.ID
 			newVnetMeta, err := r.InventoryProfileToInventoryProfileMeta(inventoryProfile)
@@ -129,8 +163,16 @@ func (r *InventoryProfileReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 				logger.Error(fmt.Errorf("{InventoryProfileToInventoryProfileMeta} %s", err), "")
 				return u.patchInventoryProfileStatus(inventoryProfile, "Failure", err.Error())
 			}
+			// the Netris controller of an existing resource never changes.
+			controllerRef := inventoryProfileMeta.Spec.ControllerRef
+			currentName := inventoryProfileMeta.Spec.NetrisName
+			if currentName == "" {
+				currentName = inventoryProfileMeta.Spec.InventoryProfileName
+			}
 			inventoryProfileMeta.Spec = newVnetMeta.DeepCopy().Spec
 			inventoryProfileMeta.Spec.ID = inventoryProfileID
+			inventoryProfileMeta.Spec.ControllerRef = controllerRef
+			inventoryProfileMeta.Spec.NetrisName = keptNetrisName(inventoryProfile.Spec.NetrisName, currentName, inventoryProfileMeta.Spec.NetrisName, inventoryProfileID)
 			inventoryProfileMeta.Spec.InventoryProfileCRGeneration = inventoryProfile.GetGeneration()
 
 			inventoryProfileMetaUpdateCtx, inventoryProfileMetaUpdateCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -138,7 +180,7 @@ func (r *InventoryProfileReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 			err = r.Update(inventoryProfileMetaUpdateCtx, inventoryProfileMeta.DeepCopyObject(), &client.UpdateOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{inventoryProfileMeta Update} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 		}
 	} else {
@@ -151,7 +193,7 @@ func (r *InventoryProfileReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 			err := r.Patch(inventoryProfilePatchCtx, inventoryProfile.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{Patch InventoryProfile Finalizer} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 			return ctrl.Result{}, nil
 		}
@@ -164,20 +206,24 @@ func (r *InventoryProfileReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 
 		inventoryProfileMeta.Spec.InventoryProfileCRGeneration = inventoryProfile.GetGeneration()
 
+		setOwner(inventoryProfile, inventoryProfileMeta, r.Scheme)
+		syncAnnotations(inventoryProfile, inventoryProfileMeta)
 		inventoryProfileMetaCreateCtx, inventoryProfileMetaCreateCancel := context.WithTimeout(cntxt, contextTimeout)
 		defer inventoryProfileMetaCreateCancel()
 		if err := r.Create(inventoryProfileMetaCreateCtx, inventoryProfileMeta.DeepCopyObject(), &client.CreateOptions{}); err != nil {
 			logger.Error(fmt.Errorf("{inventoryProfileMeta Create} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (r *InventoryProfileReconciler) deleteInventoryProfile(inventoryProfile *k8sv1alpha1.InventoryProfile, inventoryProfileMeta *k8sv1alpha1.InventoryProfileMeta) (ctrl.Result, error) {
 	if inventoryProfileMeta != nil && inventoryProfileMeta.Spec.ID > 0 && !inventoryProfileMeta.Spec.Reclaim {
-		reply, err := r.Cred.InventoryProfile().Delete(inventoryProfileMeta.Spec.ID)
+		reply, err := netrisapi.Do(r.Cred, "inventoryprofile", "delete", func() (http.HTTPReply, error) {
+			return r.Cred.InventoryProfile().Delete(inventoryProfileMeta.Spec.ID)
+		})
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("{deleteInventoryProfile} %s", err)
 		}
@@ -229,5 +275,7 @@ func (r *InventoryProfileReconciler) deleteInventoryProfileMetaCR(inventoryProfi
 func (r *InventoryProfileReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.InventoryProfile{}).
+		Owns(&k8sv1alpha1.InventoryProfileMeta{}).
+		WithOptions(controllerOptions()).
 		Complete(r)
 }
@@ -58,8 +58,10 @@ func (r *InventoryProfileReconciler) InventoryProfileToInventoryProfileMeta(inve
 		TypeMeta: metav1.TypeMeta{},
 		Spec: k8sv1alpha1.InventoryProfileMetaSpec{
 			Imported:             imported,
+			ControllerRef:        inventoryProfile.Spec.ControllerRef,
 			Reclaim:              reclaim,
 			InventoryProfileName: inventoryProfile.Name,
+			NetrisName:           netrisName(inventoryProfile, inventoryProfile.Spec.NetrisName),
 			Description:          inventoryProfile.Spec.Description,
 			Timezone:             inventoryProfile.Spec.Timezone,
 			AllowSSHFromIPv4:     inventoryProfile.Spec.AllowSSHFromIPv4,
@@ -125,7 +127,7 @@ func InventoryProfileMetaToNetris(inventoryProfileMeta *k8sv1alpha1.InventoryPro
 	}
 
 	inventoryProfileAdd := &inventoryprofile.ProfileW{
-		Name:        inventoryProfileMeta.Spec.InventoryProfileName,
+		Name:        inventoryProfileMeta.Spec.NetrisName,
 		Description: inventoryProfileMeta.Spec.Description,
 		Timezone:    inventoryprofile.Timezone{Label: inventoryProfileMeta.Spec.Timezone, TzCode: inventoryProfileMeta.Spec.Timezone},
 		Ipv4List:    strings.Join(inventoryProfileMeta.Spec.AllowSSHFromIPv4, ","),
@@ -153,7 +155,7 @@ func InventoryProfileMetaToNetrisUpdate(inventoryProfileMeta *k8sv1alpha1.Invent
 
 	inventoryProfileAdd := &inventoryprofile.ProfileW{
 		ID:          inventoryProfileMeta.Spec.ID,
-		Name:        inventoryProfileMeta.Spec.InventoryProfileName,
+		Name:        inventoryProfileMeta.Spec.NetrisName,
 		Description: inventoryProfileMeta.Spec.Description,
 		Timezone:    inventoryprofile.Timezone{Label: inventoryProfileMeta.Spec.Timezone, TzCode: inventoryProfileMeta.Spec.Timezone},
 		Ipv4List:    strings.Join(inventoryProfileMeta.Spec.AllowSSHFromIPv4, ","),
@@ -167,8 +169,8 @@ func InventoryProfileMetaToNetrisUpdate(inventoryProfileMeta *k8sv1alpha1.Invent
 }
 
 func compareInventoryProfileMetaAPIEInventoryProfile(inventoryProfileMeta *k8sv1alpha1.InventoryProfileMeta, apiInventoryProfile *inventoryprofile.Profile, u uniReconciler) bool {
-	if apiInventoryProfile.Name != inventoryProfileMeta.Spec.InventoryProfileName {
-		u.DebugLogger.Info("Name changed", "netrisValue", apiInventoryProfile.Name, "k8sValue", inventoryProfileMeta.Spec.InventoryProfileName)
+	if apiInventoryProfile.Name != inventoryProfileMeta.Spec.NetrisName {
+		u.DebugLogger.Info("Name changed", "netrisValue", apiInventoryProfile.Name, "k8sValue", inventoryProfileMeta.Spec.NetrisName)
 		return false
 	}
 	if apiInventoryProfile.Description != inventoryProfileMeta.Spec.Description {
@@ -239,6 +241,54 @@ func compareInventoryProfileAPIInventoryProfileCustomRules(InventoryProfileRules
 	return len(changelog) <= 0
 }
 
+// inventoryProfileDrift returns the difference of the InventoryProfile spec
+// with the Netris inventory profile and sets the spec to the Netris values.
+func inventoryProfileDrift(spec *k8sv1alpha1.InventoryProfileSpec, inventoryProfileMeta *k8sv1alpha1.InventoryProfileMeta, apiInventoryProfile *inventoryprofile.Profile) *drift {
+	d := &drift{}
+	if apiInventoryProfile.Name != inventoryProfileMeta.Spec.NetrisName {
+		driftFixed(d, "metadata.name", inventoryProfileMeta.Spec.NetrisName, apiInventoryProfile.Name)
+	}
+	if apiInventoryProfile.Description != inventoryProfileMeta.Spec.Description {
+		driftSet(d, "spec.description", &spec.Description, apiInventoryProfile.Description)
+	}
+	if timeZone := unmarshalTimezone(apiInventoryProfile.Timezone); timeZone.TzCode != inventoryProfileMeta.Spec.Timezone {
+		driftSet(d, "spec.timezone", &spec.Timezone, timeZone.TzCode)
+	}
+	if apiInventoryProfile.Ipv4SSH != strings.Join(inventoryProfileMeta.Spec.AllowSSHFromIPv4, ",") {
+		driftSet(d, "spec.allowSshFromIpv4", &spec.AllowSSHFromIPv4, driftList(apiInventoryProfile.Ipv4SSH, ","))
+	}
+	if apiInventoryProfile.Ipv6SSH != strings.Join(inventoryProfileMeta.Spec.AllowSSHFromIPv6, ",") {
+		driftSet(d, "spec.allowSshFromIpv6", &spec.AllowSSHFromIPv6, driftList(apiInventoryProfile.Ipv6SSH, ","))
+	}
+	if apiInventoryProfile.NTPServers != strings.Join(inventoryProfileMeta.Spec.NTPServers, ",") {
+		var ntpServers []k8sv1alpha1.NTPServer
+		for _, s := range driftList(apiInventoryProfile.NTPServers, ",") {
+			ntpServers = append(ntpServers, k8sv1alpha1.NTPServer(s))
+		}
+		driftSet(d, "spec.ntpServers", &spec.NTPServers, ntpServers)
+	}
+	if apiInventoryProfile.DNSServers != strings.Join(inventoryProfileMeta.Spec.DNSServers, ",") {
+		var dnsServers []k8sv1alpha1.DNSServer
+		for _, s := range driftList(apiInventoryProfile.DNSServers, ",") {
+			dnsServers = append(dnsServers, k8sv1alpha1.DNSServer(s))
+		}
+		driftSet(d, "spec.dnsServers", &spec.DNSServers, dnsServers)
+	}
+	if ok := compareInventoryProfileAPIInventoryProfileCustomRules(inventoryProfileMeta.Spec.CustomRules, apiInventoryProfile.CustomRules); !ok {
+		var rules []k8sv1alpha1.InventoryProfileCustomRule
+		for _, rule := range apiInventoryProfile.CustomRules {
+			rules = append(rules, k8sv1alpha1.InventoryProfileCustomRule{
+				SrcSubnet: rule.SrcSubnet,
+				SrcPort:   rule.SrcPort,
+				DstPort:   rule.DstPort,
+				Protocol:  rule.Protocol,
+			})
+		}
+		driftSet(d, "spec.customRules", &spec.CustomRules, rules)
+	}
+	return d
+}
+
 func unmarshalTimezone(s string) *inventoryprofile.Timezone {
 	timezone := &inventoryprofile.Timezone{}
 	_ = json.Unmarshal([]byte(s), timezone)
@@ -20,15 +20,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	"github.com/netrisai/netriswebapi/v1/types/inventoryprofile"
@@ -38,10 +41,12 @@ import (
 // InventoryProfileMetaReconciler reconciles a InventoryProfileMeta object
 type InventoryProfileMetaReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.netris.ai,resources=inventoryprofilemeta,verbs=get;list;watch;create;update;patch;delete
@@ -58,6 +63,19 @@ type InventoryProfileMetaReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (r *InventoryProfileMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.InventoryProfileMeta{}, nil)
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *InventoryProfileMetaReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	debugLogger := r.Log.WithValues("name", req.NamespacedName).V(int(zapcore.WarnLevel))
 
 	inventoryProfileMeta := &k8sv1alpha1.InventoryProfileMeta{}
@@ -81,6 +99,7 @@ func (r *InventoryProfileMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Resul
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	provisionState := "OK"
@@ -97,16 +116,24 @@ func (r *InventoryProfileMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Resul
 		return ctrl.Result{}, err
 	}
 
+	if paused(inventoryProfileCR) {
+		return u.patchInventoryProfileStatus(inventoryProfileCR, "Paused", pausedMessage)
+	}
+
 	if inventoryProfileMeta.DeletionTimestamp != nil {
 		return ctrl.Result{}, nil
 	}
 
+	if inventoryProfileMeta.Spec.NetrisName == "" {
+		inventoryProfileMeta.Spec.NetrisName = inventoryProfileMeta.Spec.InventoryProfileName
+	}
+
 	if inventoryProfileMeta.Spec.ID == 0 {
 		debugLogger.Info("ID Not found in meta")
 		if inventoryProfileMeta.Spec.Imported {
 			logger.Info("Importing inventoryProfile")
 			debugLogger.Info("Imported yaml mode. Finding InventoryProfile by name")
-			if inventoryProfile, ok := r.NStorage.InventoryProfileStorage.FindByName(inventoryProfileMeta.Spec.InventoryProfileName); ok {
+			if inventoryProfile, ok := r.NStorage.InventoryProfileStorage.FindByName(inventoryProfileMeta.Spec.NetrisName); ok {
 				debugLogger.Info("Imported yaml mode. InventoryProfile found")
 				inventoryProfileMeta.Spec.ID = inventoryProfile.ID
 
@@ -118,26 +145,52 @@ func (r *InventoryProfileMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Resul
 					return u.patchInventoryProfileStatus(inventoryProfileCR, "Failure", err.Error())
 				}
 				debugLogger.Info("Imported yaml mode. ID patched")
+				u.event(inventoryProfileCR, EventImported, "InventoryProfile imported from Netris, ID %d", inventoryProfileMeta.Spec.ID)
 				logger.Info("InventoryProfile imported")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{}, nil
 			}
 			logger.Info("InventoryProfile not found for import")
 			debugLogger.Info("Imported yaml mode. InventoryProfile not found")
 		}
 
+		if msg := u.cacheStale(nil, netrisstorage.KindInventoryProfiles); msg != "" {
+			return u.patchInventoryProfileStatus(inventoryProfileCR, "CacheStale", msg)
+		}
+		_, exists := r.NStorage.InventoryProfileStorage.FindByName(inventoryProfileMeta.Spec.NetrisName)
+		if msg := u.nameCollision(inventoryProfileMeta, "InventoryProfile", inventoryProfileMeta.Spec.NetrisName, exists); msg != "" {
+			u.warning(inventoryProfileCR, EventNameCollision, "%s", msg)
+			return u.patchInventoryProfileStatus(inventoryProfileCR, "NameCollision", msg)
+		}
 		logger.Info("Creating InventoryProfile")
 		if _, err, errMsg := r.createInventoryProfile(inventoryProfileMeta); err != nil {
 			logger.Error(fmt.Errorf("{createInventoryProfile} %s", err), "")
+			u.warning(inventoryProfileCR, EventAPIError, "Couldn't create InventoryProfile in Netris: %s", errMsg)
 			return u.patchInventoryProfileStatus(inventoryProfileCR, "Failure", errMsg.Error())
 		}
+		inventoryProfileCR.Status.AppliedGeneration = inventoryProfileMeta.Spec.InventoryProfileCRGeneration
+		u.event(inventoryProfileCR, EventCreated, "InventoryProfile created in Netris")
 		logger.Info("InventoryProfile Created")
 	} else {
 		if apiInventoryProfile, ok := r.NStorage.InventoryProfileStorage.FindByID(inventoryProfileMeta.Spec.ID); ok {
 
 			debugLogger.Info("Comparing InventoryProfileMeta with Netris InventoryProfile")
+			driftedFields := inventoryProfileCR.Status.DriftedFields
+			inventoryProfileCR.Status.DriftedFields = nil
 			if ok := compareInventoryProfileMetaAPIEInventoryProfile(inventoryProfileMeta, apiInventoryProfile, u); ok {
 				debugLogger.Info("Nothing Changed")
+				inventoryProfileCR.Status.AppliedGeneration = inventoryProfileMeta.Spec.InventoryProfileCRGeneration
+			} else if keepsDrift(inventoryProfileCR, inventoryProfileCR.Status.AppliedGeneration) {
+				spec := inventoryProfileCR.Spec.DeepCopy()
+				d := inventoryProfileDrift(spec, inventoryProfileMeta, apiInventoryProfile)
+				if driftPolicyOf(inventoryProfileCR) == driftAdopt && d.adoptable() {
+					inventoryProfileCR.Spec = *spec
+					return u.adoptDrift(inventoryProfileCR, "InventoryProfile", d)
+				}
+				u.reportDrift(inventoryProfileCR, "InventoryProfile", d, driftedFields)
+				inventoryProfileCR.Status.DriftedFields = d.fields
+				return u.patchInventoryProfileStatus(inventoryProfileCR, "Drifted", d.String())
 			} else {
+				recordDrift("InventoryProfile", inventoryProfileCR.Generation, inventoryProfileCR.Status.AppliedGeneration)
 				debugLogger.Info("Go to update InventoryProfile in Netris")
 				logger.Info("Updating InventoryProfile")
 				inventoryProfileUpdate, err := InventoryProfileMetaToNetrisUpdate(inventoryProfileMeta)
@@ -152,18 +205,29 @@ func (r *InventoryProfileMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Resul
 				_, err, errMsg := updateInventoryProfile(inventoryProfileMeta.Spec.ID, inventoryProfileUpdate, r.Cred)
 				if err != nil {
 					logger.Error(fmt.Errorf("{updateInventoryProfile} %s", err), "")
+					u.warning(inventoryProfileCR, EventAPIError, "Couldn't update InventoryProfile in Netris: %s", errMsg)
 					return u.patchInventoryProfileStatus(inventoryProfileCR, "Failure", errMsg.Error())
 				}
+				u.updated(inventoryProfileCR, inventoryProfileCR.Generation, inventoryProfileCR.Status.AppliedGeneration, "InventoryProfile")
+				inventoryProfileCR.Status.AppliedGeneration = inventoryProfileMeta.Spec.InventoryProfileCRGeneration
 				logger.Info("InventoryProfile Updated")
 			}
 		} else {
 			debugLogger.Info("InventoryProfile not found in Netris")
 			debugLogger.Info("Going to create InventoryProfile")
+			if msg := u.cacheStale(func() (bool, error) {
+				return existsInNetris(r.Cred, "inventoryprofile", r.Cred.InventoryProfile().Get, func(item *inventoryprofile.Profile) bool { return item.ID == inventoryProfileMeta.Spec.ID })
+			}, netrisstorage.KindInventoryProfiles); msg != "" {
+				return u.patchInventoryProfileStatus(inventoryProfileCR, "CacheStale", msg)
+			}
 			logger.Info("Creating InventoryProfile")
 			if _, err, errMsg := r.createInventoryProfile(inventoryProfileMeta); err != nil {
 				logger.Error(fmt.Errorf("{createInventoryProfile} %s", err), "")
+				u.warning(inventoryProfileCR, EventAPIError, "Couldn't create InventoryProfile in Netris: %s", errMsg)
 				return u.patchInventoryProfileStatus(inventoryProfileCR, "Failure", errMsg.Error())
 			}
+			inventoryProfileCR.Status.AppliedGeneration = inventoryProfileMeta.Spec.InventoryProfileCRGeneration
+			u.event(inventoryProfileCR, EventCreated, "InventoryProfile created in Netris")
 			logger.Info("InventoryProfile Created")
 		}
 	}
@@ -184,7 +248,9 @@ func (r *InventoryProfileMetaReconciler) createInventoryProfile(inventoryProfile
 	js, _ := json.Marshal(inventoryProfileAdd)
 	debugLogger.Info("inventoryProfileToAdd", "payload", string(js))
 
-	reply, err := r.Cred.InventoryProfile().Add(inventoryProfileAdd)
+	reply, err := netrisapi.Create(r.Cred, "inventoryprofile", func() (http.HTTPReply, error) {
+		return r.Cred.InventoryProfile().Add(inventoryProfileAdd)
+	})
 	if err != nil {
 		return ctrl.Result{}, err, err
 	}
@@ -221,7 +287,9 @@ func (r *InventoryProfileMetaReconciler) createInventoryProfile(inventoryProfile
 }
 
 func updateInventoryProfile(id int, inventoryProfile *inventoryprofile.ProfileW, cred *api.Clientset) (ctrl.Result, error, error) {
-	reply, err := cred.InventoryProfile().Update(inventoryProfile)
+	reply, err := netrisapi.Do(cred, "inventoryprofile", "update", func() (http.HTTPReply, error) {
+		return cred.InventoryProfile().Update(inventoryProfile)
+	})
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("{updateInventoryProfile} %s", err), err
 	}
@@ -238,7 +306,15 @@ func updateInventoryProfile(id int, inventoryProfile *inventoryprofile.ProfileW,
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *InventoryProfileMetaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	src, hdl, err := watchStorage(mgr, r.Controllers.storageListeners(r.NStorage), &k8sv1alpha1.InventoryProfileMeta{}, &k8sv1alpha1.InventoryProfileMetaList{}, func(o runtime.Object) string {
+		return strconv.Itoa(o.(*k8sv1alpha1.InventoryProfileMeta).Spec.ID)
+	}, netrisstorage.KindInventoryProfiles)
+	if err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.InventoryProfileMeta{}).
+		WithOptions(controllerOptions()).
+		Watches(src, hdl).
 		Complete(r)
 }
@@ -24,6 +24,7 @@ import (
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -35,12 +36,14 @@ import (
 // L4LBReconciler reconciles a L4LB object
 type L4LBReconciler struct {
 	client.Client
-	Log        logr.Logger
-	Scheme     *runtime.Scheme
-	Cred       *api.Clientset
-	NStorage   *netrisstorage.Storage
-	L4LBTenant string
-	VPCID      int
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
+	L4LBTenant  string
+	VPCID       int
 }
 
 // +kubebuilder:rbac:groups=k8s.netris.ai,resources=l4lbs,verbs=get;list;watch;create;update;patch;delete
@@ -50,10 +53,25 @@ type L4LBReconciler struct {
 // +kubebuilder:rbac:groups=core,resources=services/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is the main reconciler for the appropriate resource type
 func (r *L4LBReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.L4LB{}, &k8sv1alpha1.L4LBMeta{})
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+		rc.VPCID, rc.L4LBTenant = conn.VPCID, conn.L4LBTenant
+	}
+	return rc.reconcile(req)
+}
+
+func (r *L4LBReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	logger := r.Log.WithValues("name", req.NamespacedName)
 	debugLogger := logger.V(int(zapcore.WarnLevel))
 	l4lb := &k8sv1alpha1.L4LB{}
@@ -64,6 +82,7 @@ func (r *L4LBReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	l4lbCtx, l4lbCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -92,14 +111,34 @@ func (r *L4LBReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 	}
 
+	if paused(l4lb) {
+		if metaFound {
+			if err := u.annotateMeta(l4lb, l4lbMeta); err != nil {
+				logger.Error(err, "")
+				return ctrl.Result{Requeue: true}, nil
+			}
+		}
+		return u.patchL4LBStatus(l4lb, "Paused", pausedMessage)
+	}
+
 	if l4lb.DeletionTimestamp != nil {
+		if msg := deletionBlocked(l4lb, false); msg != "" {
+			u.warning(l4lb, EventDeleteBlocked, "%s", msg)
+			return u.patchL4LBStatus(l4lb, "DeletionBlocked", msg)
+		}
+		if l4lbMeta != nil && l4lbMeta.Spec.ID > 0 && !l4lbMeta.Spec.Reclaim && dryRun(l4lb) {
+			l4lb.Status.Plan = planOf(planDelete, l4lbMeta.Spec.ID, nil, nil)
+			return u.patchL4LBStatus(l4lb, "DryRun", planMessage("L4LB", l4lb.Status.Plan))
+		}
 		logger.Info("Go to delete")
 		result, err := r.deleteL4LB(l4lb, l4lbMeta)
 		if err != nil {
 			logger.Error(fmt.Errorf("{deleteL4LB} %s", err), "")
+			u.warning(l4lb, EventDeleteBlocked, "Couldn't delete L4LB from Netris: %s", err)
 			return u.patchL4LBStatus(l4lb, "Failure", err.Error())
 		}
 		if result.IsZero() {
+			u.event(l4lb, EventDeleted, "L4LB deleted from Netris")
 			logger.Info("L4LB deleted")
 		}
 		return result, nil
@@ -113,14 +152,14 @@ func (r *L4LBReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		err := r.Patch(l4lbPatchCtx, l4lb.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 		if err != nil {
 			logger.Error(fmt.Errorf("{Patch L4LB default annotations} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 		return ctrl.Result{}, nil
 	}
 
 	if metaFound {
 		debugLogger.Info("Meta found")
-		if l4lbCompareFieldsForNewMeta(l4lb, l4lbMeta) {
+		if ownerChanged, annotationsChanged := setOwner(l4lb, l4lbMeta, r.Scheme), syncAnnotations(l4lb, l4lbMeta); l4lbCompareFieldsForNewMeta(l4lb, l4lbMeta) || ownerChanged || annotationsChanged {
 			debugLogger.Info("Generating New Meta")
 			l4lbID := l4lbMeta.Spec.ID
 			newL4LBMeta, err := r.L4LBToL4LBMeta(l4lb)
@@ -128,8 +167,16 @@ func (r *L4LBReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				logger.Error(fmt.Errorf("{L4LBToL4LBMeta} %s", err), "")
 				return u.patchL4LBStatus(l4lb, "Failure", err.Error())
 			}
+			// the Netris controller of an existing resource never changes.
+			controllerRef := l4lbMeta.Spec.ControllerRef
+			currentName := l4lbMeta.Spec.NetrisName
+			if currentName == "" {
+				currentName = l4lbMeta.Spec.L4LBName
+			}
 			l4lbMeta.Spec = newL4LBMeta.DeepCopy().Spec
 			l4lbMeta.Spec.ID = l4lbID
+			l4lbMeta.Spec.ControllerRef = controllerRef
+			l4lbMeta.Spec.NetrisName = keptNetrisName(l4lb.Spec.NetrisName, currentName, l4lbMeta.Spec.NetrisName, l4lbID)
 			l4lbMeta.Spec.L4LBCRGeneration = l4lb.GetGeneration()
 
 			l4lbMetaUpdateCtx, l4lbMetaUpdateCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -137,7 +184,7 @@ func (r *L4LBReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err = r.Update(l4lbMetaUpdateCtx, l4lbMeta.DeepCopyObject(), &client.UpdateOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{l4lbMeta Update} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 		}
 	} else {
@@ -149,7 +196,7 @@ func (r *L4LBReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err := r.Patch(l4lbCtx, l4lb.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{Patch L4LB Finalizer} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 			return ctrl.Result{}, nil
 		}
@@ -163,15 +210,17 @@ func (r *L4LBReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		l4lbMeta.Spec.L4LBCRGeneration = l4lb.GetGeneration()
 		l4lbMeta.SetFinalizers([]string{"resource.k8s.netris.ai/delete"})
 
+		setOwner(l4lb, l4lbMeta, r.Scheme)
+		syncAnnotations(l4lb, l4lbMeta)
 		l4lbCreateCtx, l4lbCreateCancel := context.WithTimeout(cntxt, contextTimeout)
 		defer l4lbCreateCancel()
 		if err := r.Create(l4lbCreateCtx, l4lbMeta.DeepCopyObject(), &client.CreateOptions{}); err != nil {
 			logger.Error(fmt.Errorf("{l4lbMeta Create} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (r *L4LBReconciler) deleteL4LB(l4lb *k8sv1alpha1.L4LB, l4lbMeta *k8sv1alpha1.L4LBMeta) (ctrl.Result, error) {
@@ -216,5 +265,7 @@ func (r *L4LBReconciler) deleteL4LBMetaCR(l4lbMeta *k8sv1alpha1.L4LBMeta) (ctrl.
 func (r *L4LBReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.L4LB{}).
+		Owns(&k8sv1alpha1.L4LBMeta{}).
+		WithOptions(controllerOptions()).
 		Complete(r)
 }
@@ -25,7 +25,7 @@ import (
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
 	"github.com/netrisai/netris-operator/calicowatcher"
-	"github.com/netrisai/netris-operator/configloader"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netriswebapi/v2/types/ipam"
 	"github.com/netrisai/netriswebapi/v2/types/l4lb"
 	"github.com/r3labs/diff/v2"
@@ -163,21 +163,23 @@ func (r *L4LBReconciler) L4LBToL4LBMeta(l4lb *k8sv1alpha1.L4LB) (*k8sv1alpha1.L4
 		},
 		TypeMeta: metav1.TypeMeta{},
 		Spec: k8sv1alpha1.L4LBMetaSpec{
-			Imported:    imported,
-			Reclaim:     reclaim,
-			L4LBName:    l4lb.Name,
-			SiteID:      siteID,
-			SiteName:    l4lb.Spec.Site,
-			VPCID:       vpcID,
-			VPCName:     vpcName,
-			Tenant:      tenantID,
-			Status:      state,
-			Automatic:   automatic,
-			Protocol:    strings.ToUpper(proto),
-			Port:        l4lb.Spec.Frontend.Port,
-			IP:          l4lb.Spec.Frontend.IP,
-			Backend:     l4lbMetaBackends,
-			HealthCheck: healthCheck,
+			Imported:      imported,
+			ControllerRef: l4lb.Spec.ControllerRef,
+			Reclaim:       reclaim,
+			L4LBName:      l4lb.Name,
+			NetrisName:    netrisName(l4lb, l4lb.Spec.NetrisName),
+			SiteID:        siteID,
+			SiteName:      l4lb.Spec.Site,
+			VPCID:         vpcID,
+			VPCName:       vpcName,
+			Tenant:        tenantID,
+			Status:        state,
+			Automatic:     automatic,
+			Protocol:      strings.ToUpper(proto),
+			Port:          l4lb.Spec.Frontend.Port,
+			IP:            l4lb.Spec.Frontend.IP,
+			Backend:       l4lbMetaBackends,
+			HealthCheck:   healthCheck,
 		},
 	}
 
@@ -231,7 +233,7 @@ func compareL4LBMetaAPIL4LBBackend(l4lbMetaBackends []k8sv1alpha1.L4LBMetaBacken
 }
 
 func compareL4LBMetaAPIL4LB(l4lbMeta *k8sv1alpha1.L4LBMeta, apiL4LB *l4lb.LoadBalancer) bool {
-	if l4lbMeta.Spec.L4LBName != apiL4LB.Name {
+	if l4lbMeta.Spec.NetrisName != apiL4LB.Name {
 		return false
 	}
 	if l4lbMeta.Spec.IP != apiL4LB.IP {
@@ -270,6 +272,64 @@ func compareL4LBMetaAPIL4LB(l4lbMeta *k8sv1alpha1.L4LBMeta, apiL4LB *l4lb.LoadBa
 	return true
 }
 
+// l4lbDrift returns the difference of the L4LB spec with the Netris load
+// balancer and sets the spec to the Netris values.
+func l4lbDrift(spec *k8sv1alpha1.L4LBSpec, l4lbMeta *k8sv1alpha1.L4LBMeta, apiL4LB *l4lb.LoadBalancer) *drift {
+	d := &drift{}
+	if l4lbMeta.Spec.NetrisName != apiL4LB.Name {
+		driftFixed(d, "metadata.name", l4lbMeta.Spec.NetrisName, apiL4LB.Name)
+	}
+	if l4lbMeta.Spec.IP != apiL4LB.IP || l4lbMeta.Spec.Automatic != apiL4LB.Automatic {
+		ip := apiL4LB.IP
+		if apiL4LB.Automatic {
+			ip = ""
+		}
+		driftSet(d, "spec.frontend.ip", &spec.Frontend.IP, ip)
+	}
+	if l4lbMeta.Spec.Port != apiL4LB.Port {
+		driftSet(d, "spec.frontend.port", &spec.Frontend.Port, apiL4LB.Port)
+	}
+	if l4lbMeta.Spec.Protocol != apiL4LB.Protocol {
+		driftSet(d, "spec.protocol", &spec.Protocol, strings.ToLower(apiL4LB.Protocol))
+	}
+	if l4lbMeta.Spec.SiteID != apiL4LB.Site.ID {
+		driftSet(d, "spec.site", &spec.Site, apiL4LB.Site.Name)
+	}
+	if l4lbMeta.Spec.Tenant != apiL4LB.Tenant.ID {
+		driftSet(d, "spec.ownerTenant", &spec.OwnerTenant, apiL4LB.Tenant.Name)
+	}
+	if l4lbMeta.Spec.Status != apiL4LB.Status {
+		state := apiL4LB.Status
+		if state == "enable" {
+			state = "active"
+		}
+		driftSet(d, "spec.state", &spec.State, state)
+	}
+	if l4lbMeta.Spec.VPCID != apiL4LB.Vpc.ID {
+		driftFixed(d, "vpc", l4lbMeta.Spec.VPCName, apiL4LB.Vpc.Name)
+	}
+	if ok := compareL4LBMetaAPIL4LBHealthCheck(*l4lbMeta.Spec.HealthCheck, apiL4LB.HealthCheck); !ok {
+		check := k8sv1alpha1.L4LBCheck{Type: "none"}
+		if apiL4LB.HealthCheck.TCP.Timeout != "" {
+			check.Type = "tcp"
+			check.Timeout, _ = strconv.Atoi(apiL4LB.HealthCheck.TCP.Timeout)
+		} else if apiL4LB.HealthCheck.HTTP.Timeout != "" {
+			check.Type = "http"
+			check.Timeout, _ = strconv.Atoi(apiL4LB.HealthCheck.HTTP.Timeout)
+			check.RequestPath = apiL4LB.HealthCheck.HTTP.RequestPath
+		}
+		driftSet(d, "spec.check", &spec.Check, check)
+	}
+	if ok := compareL4LBMetaAPIL4LBBackend(l4lbMeta.Spec.Backend, apiL4LB.BackendIPs); !ok {
+		backends := []k8sv1alpha1.L4LBBackend{}
+		for _, backend := range apiL4LB.BackendIPs {
+			backends = append(backends, k8sv1alpha1.L4LBBackend(fmt.Sprintf("%s:%s", backend.IP, backend.Port)))
+		}
+		driftSet(d, "spec.backend", &spec.Backend, backends)
+	}
+	return d
+}
+
 // L4LBMetaToNetris converts the k8s L4LB resource to Netris type and used for add the L4LB for Netris API.
 func L4LBMetaToNetris(l4lbMeta *k8sv1alpha1.L4LBMeta) (*l4lb.LoadBalancerAdd, error) {
 	healthCheck := ""
@@ -311,7 +371,7 @@ func L4LBMetaToNetris(l4lbMeta *k8sv1alpha1.L4LBMeta) (*l4lb.LoadBalancerAdd, er
 	}
 
 	l4lbAdd := &l4lb.LoadBalancerAdd{
-		Name:        l4lbMeta.Spec.L4LBName,
+		Name:        l4lbMeta.Spec.NetrisName,
 		Tenant:      tenant,
 		Site:        site,
 		Automatic:   l4lbMeta.Spec.Automatic,
@@ -368,7 +428,7 @@ func L4LBMetaToNetrisUpdate(l4lbMeta *k8sv1alpha1.L4LBMeta) (*l4lb.LoadBalancerU
 	}
 
 	l4lbUpdate := &l4lb.LoadBalancerUpdate{
-		Name:        l4lbMeta.Spec.L4LBName,
+		Name:        l4lbMeta.Spec.NetrisName,
 		Tenant:      tenant,
 		Site:        site,
 		SiteName:    l4lbMeta.Spec.SiteName,
@@ -430,11 +490,9 @@ func l4lbUpdateDefaultAnnotations(l4lb *k8sv1alpha1.L4LB) {
 
 func (r *L4LBReconciler) findSiteByIP(ip string) (int, error) {
 	siteID := 0
-	vpcid := configloader.Root.VPCID
-	if vpcid == 0 {
-		vpcid = 1
-	}
-	subnets, err := r.Cred.IPAM().GetByVPC(vpcid)
+	subnets, err := netrisapi.Get(r.Cred, "ipam", "list", func() ([]*ipam.IPAM, error) {
+		return r.Cred.IPAM().GetByVPC(r.VPCID)
+	})
 	if err != nil {
 		return siteID, err
 	}
@@ -19,6 +19,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -26,10 +27,12 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -39,11 +42,13 @@ import (
 // L4LBMetaReconciler reconciles a L4LBMeta object
 type L4LBMetaReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
-	VPCID    int
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
+	VPCID       int
 }
 
 // +kubebuilder:rbac:groups=k8s.netris.ai,resources=l4lbmeta,verbs=get;list;watch;create;update;patch;delete
@@ -52,6 +57,20 @@ type L4LBMetaReconciler struct {
 
 // Reconcile is the main reconciler for the appropriate resource type
 func (r *L4LBMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.L4LBMeta{}, nil)
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+		rc.VPCID = conn.VPCID
+	}
+	return rc.reconcile(req)
+}
+
+func (r *L4LBMetaReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	debugLogger := r.Log.WithValues("name", req.NamespacedName).V(int(zapcore.WarnLevel))
 
 	l4lbMeta := &k8sv1alpha1.L4LBMeta{}
@@ -71,7 +90,9 @@ func (r *L4LBMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	if l4lbMeta.DeletionTimestamp != nil {
 		if l4lbMeta.Spec.ID > 0 && !l4lbMeta.Spec.Reclaim {
-			reply, err := r.Cred.L4LB().Delete(l4lbMeta.Spec.ID)
+			reply, err := netrisapi.Do(r.Cred, "l4lb", "delete", func() (http.HTTPReply, error) {
+				return r.Cred.L4LB().Delete(l4lbMeta.Spec.ID)
+			})
 			if err != nil {
 				return ctrl.Result{}, fmt.Errorf("{deleteL4LB} %s", err)
 			}
@@ -89,7 +110,7 @@ func (r *L4LBMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		defer l4lbCancel()
 		err := r.Update(l4lbCtx, l4lbMeta.DeepCopyObject(), &client.UpdateOptions{})
 		if client.IgnoreNotFound(err) != nil {
-			return ctrl.Result{RequeueAfter: requeueInterval}, fmt.Errorf("{DeleteL4LBMetaCR Finalizer} %s", err)
+			return ctrl.Result{}, fmt.Errorf("{DeleteL4LBMetaCR Finalizer} %s", err)
 		}
 
 		return ctrl.Result{}, nil
@@ -102,7 +123,7 @@ func (r *L4LBMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		err := r.Patch(l4lbCtx, l4lbMeta.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 		if err != nil {
 			logger.Error(fmt.Errorf("{Patch L4LBMeta Finalizer} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
@@ -112,6 +133,7 @@ func (r *L4LBMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	provisionState := ""
@@ -127,13 +149,22 @@ func (r *L4LBMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 		return ctrl.Result{}, err
 	}
+	l4lbCR.Status.Plan = nil
+
+	if paused(l4lbCR) {
+		return u.patchL4LBStatus(l4lbCR, "Paused", pausedMessage)
+	}
+
+	if l4lbMeta.Spec.NetrisName == "" {
+		l4lbMeta.Spec.NetrisName = l4lbMeta.Spec.L4LBName
+	}
 
 	if l4lbMeta.Spec.ID == 0 {
 		debugLogger.Info("ID Not found in meta")
 		if l4lbMeta.Spec.Imported {
 			logger.Info("Importing l4lb")
 			debugLogger.Info("Imported yaml mode. Finding L4LB by name")
-			if l4lb, ok := r.NStorage.L4LBStorage.FindByName(l4lbMeta.Spec.L4LBName); ok {
+			if l4lb, ok := r.NStorage.L4LBStorage.FindByName(l4lbMeta.Spec.NetrisName); ok {
 				debugLogger.Info("Imported yaml mode. L4LB found")
 				l4lbMeta.Spec.ID = l4lb.ID
 				l4lbMeta.Spec.VPCID = l4lb.Vpc.ID
@@ -147,8 +178,9 @@ func (r *L4LBMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 					return u.patchL4LBStatus(l4lbCR, "Failure", err.Error())
 				}
 				debugLogger.Info("Imported yaml mode. ID patched")
+				u.event(l4lbCR, EventImported, "L4LB imported from Netris, ID %d", l4lbMeta.Spec.ID)
 				logger.Info("L4LB imported")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{}, nil
 			}
 			logger.Info("L4LB not found for import")
 			debugLogger.Info("Imported yaml mode. L4LB not found")
@@ -158,11 +190,25 @@ func (r *L4LBMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			logger.Error(fmt.Errorf("{populateMetaVPC} %s", err), "")
 			return u.patchL4LBStatus(l4lbCR, "Failure", err.Error())
 		}
+		if msg := u.cacheStale(nil, netrisstorage.KindL4LBs); msg != "" {
+			return u.patchL4LBStatus(l4lbCR, "CacheStale", msg)
+		}
+		_, exists := r.NStorage.L4LBStorage.FindByName(l4lbMeta.Spec.NetrisName)
+		if msg := u.nameCollision(l4lbMeta, "L4LB", l4lbMeta.Spec.NetrisName, exists); msg != "" {
+			u.warning(l4lbCR, EventNameCollision, "%s", msg)
+			return u.patchL4LBStatus(l4lbCR, "NameCollision", msg)
+		}
+		if dryRun(l4lbCR) {
+			return planL4LBCreate(&u, l4lbCR, l4lbMeta)
+		}
 		logger.Info("Creating L4LB")
 		if _, err, errMsg := r.createL4LB(l4lbMeta); err != nil {
 			logger.Error(fmt.Errorf("{createL4LB} %s", err), "")
+			u.warning(l4lbCR, EventAPIError, "Couldn't create L4LB in Netris: %s", errMsg)
 			return u.patchL4LBStatus(l4lbCR, "Failure", errMsg.Error())
 		}
+		l4lbCR.Status.AppliedGeneration = l4lbMeta.Spec.L4LBCRGeneration
+		u.event(l4lbCR, EventCreated, "L4LB created in Netris")
 		logger.Info("L4LB Created")
 	} else {
 		apiL4LB, ok := r.NStorage.L4LBStorage.FindByID(l4lbMeta.Spec.ID)
@@ -173,11 +219,22 @@ func (r *L4LBMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				logger.Error(fmt.Errorf("{populateMetaVPC} %s", err), "")
 				return u.patchL4LBStatus(l4lbCR, "Failure", err.Error())
 			}
+			if msg := u.cacheStale(func() (bool, error) {
+				return existsInNetris(r.Cred, "l4lb", r.Cred.L4LB().Get, func(item *l4lb.LoadBalancer) bool { return item.ID == l4lbMeta.Spec.ID })
+			}, netrisstorage.KindL4LBs); msg != "" {
+				return u.patchL4LBStatus(l4lbCR, "CacheStale", msg)
+			}
+			if dryRun(l4lbCR) {
+				return planL4LBCreate(&u, l4lbCR, l4lbMeta)
+			}
 			logger.Info("Creating L4LB")
 			if _, err, errMsg := r.createL4LB(l4lbMeta); err != nil {
 				logger.Error(fmt.Errorf("{createL4LB} %s", err), "")
+				u.warning(l4lbCR, EventAPIError, "Couldn't create L4LB in Netris: %s", errMsg)
 				return u.patchL4LBStatus(l4lbCR, "Failure", errMsg.Error())
 			}
+			l4lbCR.Status.AppliedGeneration = l4lbMeta.Spec.L4LBCRGeneration
+			u.event(l4lbCR, EventCreated, "L4LB created in Netris")
 			logger.Info("L4LB Created")
 		} else {
 			l4lbCR.Status.ModifiedDate = metav1.NewTime(time.Unix(int64(apiL4LB.ModifiedDate/1000), 0))
@@ -187,9 +244,23 @@ func (r *L4LBMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				return u.patchL4LBStatus(l4lbCR, "Failure", err.Error())
 			}
 			debugLogger.Info("Comparing L4LBMeta with Netris L4LB")
+			driftedFields := l4lbCR.Status.DriftedFields
+			l4lbCR.Status.DriftedFields = nil
 			if ok := compareL4LBMetaAPIL4LB(l4lbMeta, apiL4LB); ok {
 				debugLogger.Info("Nothing Changed")
+				l4lbCR.Status.AppliedGeneration = l4lbMeta.Spec.L4LBCRGeneration
+			} else if keepsDrift(l4lbCR, l4lbCR.Status.AppliedGeneration) {
+				spec := l4lbCR.Spec.DeepCopy()
+				d := l4lbDrift(spec, l4lbMeta, apiL4LB)
+				if driftPolicyOf(l4lbCR) == driftAdopt && d.adoptable() {
+					l4lbCR.Spec = *spec
+					return u.adoptDrift(l4lbCR, "L4LB", d)
+				}
+				u.reportDrift(l4lbCR, "L4LB", d, driftedFields)
+				l4lbCR.Status.DriftedFields = d.fields
+				return u.patchL4LBStatus(l4lbCR, "Drifted", d.String())
 			} else {
+				recordDrift("L4LB", l4lbCR.Generation, l4lbCR.Status.AppliedGeneration)
 				debugLogger.Info("Something changed")
 				debugLogger.Info("Go to update L4LB in Netris")
 				logger.Info("Updating L4LB")
@@ -198,10 +269,18 @@ func (r *L4LBMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 					logger.Error(fmt.Errorf("{VnetMetaToNetrisUpdate} %s", err), "")
 					return u.patchL4LBStatus(l4lbCR, "Failure", err.Error())
 				}
+				if dryRun(l4lbCR) {
+					d := l4lbDrift(l4lbCR.Spec.DeepCopy(), l4lbMeta, apiL4LB)
+					l4lbCR.Status.Plan = planOf(planUpdate, l4lbMeta.Spec.ID, l4lbUpdate, d.fields)
+					return u.patchL4LBStatus(l4lbCR, "DryRun", planMessage("L4LB", l4lbCR.Status.Plan))
+				}
 				if _, err, errMsg := r.updateL4LB(l4lbMeta.Spec.ID, l4lbUpdate); err != nil {
 					logger.Error(fmt.Errorf("{updateL4LB} %s", err), "")
+					u.warning(l4lbCR, EventAPIError, "Couldn't update L4LB in Netris: %s", errMsg)
 					return u.patchL4LBStatus(l4lbCR, "Failure", errMsg.Error())
 				}
+				u.updated(l4lbCR, l4lbCR.Generation, l4lbCR.Status.AppliedGeneration, "L4LB")
+				l4lbCR.Status.AppliedGeneration = l4lbMeta.Spec.L4LBCRGeneration
 				logger.Info("L4LB Updated")
 			}
 			provisionState = apiL4LB.Label.Text
@@ -217,6 +296,16 @@ func (r *L4LBMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	return u.patchL4LBStatus(l4lbCR, provisionState, "Successfully reconciled")
 }
 
+// planL4LBCreate records the create request of the L4LB in its status instead of sending it.
+func planL4LBCreate(u *uniReconciler, l4lbCR *k8sv1alpha1.L4LB, l4lbMeta *k8sv1alpha1.L4LBMeta) (ctrl.Result, error) {
+	l4lbAdd, err := L4LBMetaToNetris(l4lbMeta)
+	if err != nil {
+		return u.patchL4LBStatus(l4lbCR, "Failure", err.Error())
+	}
+	l4lbCR.Status.Plan = planOf(planCreate, 0, l4lbAdd, nil)
+	return u.patchL4LBStatus(l4lbCR, "DryRun", planMessage("L4LB", l4lbCR.Status.Plan))
+}
+
 func (r *L4LBMetaReconciler) createL4LB(l4lbMeta *k8sv1alpha1.L4LBMeta) (ctrl.Result, error, error) {
 	debugLogger := r.Log.WithValues(
 		"name", fmt.Sprintf("%s/%s", l4lbMeta.Namespace, l4lbMeta.Spec.L4LBName),
@@ -227,7 +316,9 @@ func (r *L4LBMetaReconciler) createL4LB(l4lbMeta *k8sv1alpha1.L4LBMeta) (ctrl.Re
 	if err != nil {
 		return ctrl.Result{}, err, err
 	}
-	reply, err := r.Cred.L4LB().Add(l4lbAdd)
+	reply, err := netrisapi.Create(r.Cred, "l4lb", func() (http.HTTPReply, error) {
+		return r.Cred.L4LB().Add(l4lbAdd)
+	})
 	if err != nil {
 		return ctrl.Result{}, err, err
 	}
@@ -276,7 +367,9 @@ func (r *L4LBMetaReconciler) createL4LB(l4lbMeta *k8sv1alpha1.L4LBMeta) (ctrl.Re
 }
 
 func (r *L4LBMetaReconciler) updateL4LB(id int, l4lb *l4lb.LoadBalancerUpdate) (ctrl.Result, error, error) {
-	reply, err := r.Cred.L4LB().Update(id, l4lb)
+	reply, err := netrisapi.Do(r.Cred, "l4lb", "update", func() (http.HTTPReply, error) {
+		return r.Cred.L4LB().Update(id, l4lb)
+	})
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("{updateL4LB} %s", err), err
 	}
@@ -293,8 +386,16 @@ func (r *L4LBMetaReconciler) updateL4LB(id int, l4lb *l4lb.LoadBalancerUpdate) (
 
 // SetupWithManager .
 func (r *L4LBMetaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	src, hdl, err := watchStorage(mgr, r.Controllers.storageListeners(r.NStorage), &k8sv1alpha1.L4LBMeta{}, &k8sv1alpha1.L4LBMetaList{}, func(o runtime.Object) string {
+		return strconv.Itoa(o.(*k8sv1alpha1.L4LBMeta).Spec.ID)
+	}, netrisstorage.KindL4LBs)
+	if err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.L4LBMeta{}).
+		WithOptions(controllerOptions()).
+		Watches(src, hdl).
 		Complete(r)
 }
 
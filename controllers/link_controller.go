@@ -24,11 +24,13 @@ import (
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -37,10 +39,12 @@ import (
 // LinkReconciler reconciles a Link object
 type LinkReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.netris.ai,resources=links,verbs=get;list;watch;create;update;patch;delete
@@ -57,6 +61,19 @@ type LinkReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (r *LinkReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.Link{}, &k8sv1alpha1.LinkMeta{})
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *LinkReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	logger := r.Log.WithValues("name", req.NamespacedName)
 	debugLogger := logger.V(int(zapcore.WarnLevel))
 	link := &k8sv1alpha1.Link{}
@@ -67,6 +84,7 @@ func (r *LinkReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	linkCtx, linkCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -96,13 +114,29 @@ func (r *LinkReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 	}
 
+	if paused(link) {
+		if metaFound {
+			if err := u.annotateMeta(link, linkMeta); err != nil {
+				logger.Error(err, "")
+				return ctrl.Result{Requeue: true}, nil
+			}
+		}
+		return u.patchLinkStatus(link, "Paused", pausedMessage)
+	}
+
 	if link.DeletionTimestamp != nil {
+		if msg := deletionBlocked(link, false); msg != "" {
+			u.warning(link, EventDeleteBlocked, "%s", msg)
+			return u.patchLinkStatus(link, "DeletionBlocked", msg)
+		}
 		logger.Info("Go to delete")
 		_, err := r.deleteLink(link, linkMeta)
 		if err != nil {
 			logger.Error(fmt.Errorf("{deleteLink} %s", err), "")
+			u.warning(link, EventDeleteBlocked, "Couldn't delete Link from Netris: %s", err)
 			return u.patchLinkStatus(link, "Failure", err.Error())
 		}
+		u.event(link, EventDeleted, "Link deleted from Netris")
 		logger.Info("Link deleted")
 		return ctrl.Result{}, nil
 	}
@@ -115,14 +149,14 @@ func (r *LinkReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		err := r.Patch(linkPatchCtx, link.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 		if err != nil {
 			logger.Error(fmt.Errorf("{Patch Link default annotations} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 		return ctrl.Result{}, nil
 	}
 
 	if metaFound {
 		debugLogger.Info("Meta found")
-		if linkCompareFieldsForNewMeta(link, linkMeta) {
+		if ownerChanged, annotationsChanged := setOwner(link, linkMeta, r.Scheme), syncAnnotations(link, linkMeta); linkCompareFieldsForNewMeta(link, linkMeta) || ownerChanged || annotationsChanged {
 			debugLogger.Info("Generating New Meta")
 			linkID := linkMeta.Spec.ID
 			newVnetMeta, err := r.LinkToLinkMeta(link)
@@ -130,8 +164,11 @@ func (r *LinkReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				logger.Error(fmt.Errorf("{LinkToLinkMeta} %s", err), "")
 				return u.patchLinkStatus(link, "Failure", err.Error())
 			}
+			// the Netris controller of an existing resource never changes.
+			controllerRef := linkMeta.Spec.ControllerRef
 			linkMeta.Spec = newVnetMeta.DeepCopy().Spec
 			linkMeta.Spec.ID = linkID
+			linkMeta.Spec.ControllerRef = controllerRef
 			linkMeta.Spec.LinkCRGeneration = link.GetGeneration()
 
 			linkMetaUpdateCtx, linkMetaUpdateCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -139,7 +176,7 @@ func (r *LinkReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err = r.Update(linkMetaUpdateCtx, linkMeta.DeepCopyObject(), &client.UpdateOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{linkMeta Update} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 		}
 	} else {
@@ -152,7 +189,7 @@ func (r *LinkReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err := r.Patch(linkPatchCtx, link.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{Patch Link Finalizer} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 			return ctrl.Result{}, nil
 		}
@@ -165,15 +202,17 @@ func (r *LinkReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 		linkMeta.Spec.LinkCRGeneration = link.GetGeneration()
 
+		setOwner(link, linkMeta, r.Scheme)
+		syncAnnotations(link, linkMeta)
 		linkMetaCreateCtx, linkMetaCreateCancel := context.WithTimeout(cntxt, contextTimeout)
 		defer linkMetaCreateCancel()
 		if err := r.Create(linkMetaCreateCtx, linkMeta.DeepCopyObject(), &client.CreateOptions{}); err != nil {
 			logger.Error(fmt.Errorf("{linkMeta Create} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (r *LinkReconciler) deleteLink(linkCR *k8sv1alpha1.Link, linkMeta *k8sv1alpha1.LinkMeta) (ctrl.Result, error) {
@@ -182,7 +221,9 @@ func (r *LinkReconciler) deleteLink(linkCR *k8sv1alpha1.Link, linkMeta *k8sv1alp
 			Local:  link.LinkIDName{ID: linkMeta.Spec.Local},
 			Remote: link.LinkIDName{ID: linkMeta.Spec.Remote},
 		}
-		reply, err := r.Cred.Link().Delete(linkDelete)
+		reply, err := netrisapi.Do(r.Cred, "link", "delete", func() (http.HTTPReply, error) {
+			return r.Cred.Link().Delete(linkDelete)
+		})
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("{deleteLink} %s", err)
 		}
@@ -234,5 +275,7 @@ func (r *LinkReconciler) deleteLinkMetaCR(linkMeta *k8sv1alpha1.LinkMeta) (ctrl.
 func (r *LinkReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.Link{}).
+		Owns(&k8sv1alpha1.LinkMeta{}).
+		WithOptions(controllerOptions()).
 		Complete(r)
 }
@@ -59,11 +59,12 @@ func (r *LinkReconciler) LinkToLinkMeta(link *k8sv1alpha1.Link) (*k8sv1alpha1.Li
 		},
 		TypeMeta: metav1.TypeMeta{},
 		Spec: k8sv1alpha1.LinkMetaSpec{
-			Imported: imported,
-			Reclaim:  reclaim,
-			LinkName: link.Name,
-			Local:    local,
-			Remote:   remote,
+			Imported:      imported,
+			ControllerRef: link.Spec.ControllerRef,
+			Reclaim:       reclaim,
+			LinkName:      link.Name,
+			Local:         local,
+			Remote:        remote,
 		},
 	}
 
@@ -26,11 +26,13 @@ import (
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -40,10 +42,12 @@ import (
 // LinkMetaReconciler reconciles a LinkMeta object
 type LinkMetaReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.netris.ai,resources=linkmeta,verbs=get;list;watch;create;update;patch;delete
@@ -60,6 +64,19 @@ type LinkMetaReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (r *LinkMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.LinkMeta{}, nil)
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *LinkMetaReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	debugLogger := r.Log.WithValues("name", req.NamespacedName).V(int(zapcore.WarnLevel))
 
 	linkMeta := &k8sv1alpha1.LinkMeta{}
@@ -83,6 +100,7 @@ func (r *LinkMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	provisionState := "OK"
@@ -99,6 +117,10 @@ func (r *LinkMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
+	if paused(linkCR) {
+		return u.patchLinkStatus(linkCR, "Paused", pausedMessage)
+	}
+
 	if linkMeta.DeletionTimestamp != nil {
 		return ctrl.Result{}, nil
 	}
@@ -119,18 +141,25 @@ func (r *LinkMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 					return u.patchLinkStatus(linkCR, "Failure", err.Error())
 				}
 				debugLogger.Info("Imported yaml mode. ID patched")
+				u.event(linkCR, EventImported, "Link imported from Netris, ID %s", linkMeta.Spec.ID)
 				logger.Info("Link imported")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{}, nil
 			}
 			logger.Info("Link not found for import")
 			debugLogger.Info("Imported yaml mode. Link not found")
 		}
 
+		if msg := u.cacheStale(nil, netrisstorage.KindLinks); msg != "" {
+			return u.patchLinkStatus(linkCR, "CacheStale", msg)
+		}
 		logger.Info("Creating Link")
 		if _, err, errMsg := r.createLink(linkMeta); err != nil {
 			logger.Error(fmt.Errorf("{createLink} %s", err), "")
+			u.warning(linkCR, EventAPIError, "Couldn't create Link in Netris: %s", errMsg)
 			return u.patchLinkStatus(linkCR, "Failure", errMsg.Error())
 		}
+		linkCR.Status.AppliedGeneration = linkMeta.Spec.LinkCRGeneration
+		u.event(linkCR, EventCreated, "Link created in Netris")
 		logger.Info("Link Created")
 	} else {
 		oldID := strings.Split(linkMeta.Spec.ID, "-")
@@ -154,12 +183,19 @@ func (r *LinkMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 			if (local == oldLocal && remote == oldRemote) || (local == oldRemote && remote == oldLocal) {
 				debugLogger.Info("Nothing Changed")
+				linkCR.Status.AppliedGeneration = linkMeta.Spec.LinkCRGeneration
 			} else {
+				recordDrift("Link", linkCR.Generation, linkCR.Status.AppliedGeneration)
+				if msg := u.cacheStale(nil, netrisstorage.KindLinks, netrisstorage.KindPorts); msg != "" {
+					return u.patchLinkStatus(linkCR, "CacheStale", msg)
+				}
 				linkDelete := &link.Link{
 					Local:  link.LinkIDName{ID: oldLocal},
 					Remote: link.LinkIDName{ID: oldRemote},
 				}
-				reply, err := r.Cred.Link().Delete(linkDelete)
+				reply, err := netrisapi.Do(r.Cred, "link", "delete", func() (http.HTTPReply, error) {
+					return r.Cred.Link().Delete(linkDelete)
+				})
 				if err != nil {
 					return ctrl.Result{}, fmt.Errorf("{deleteLink} %s", err)
 				}
@@ -184,11 +220,19 @@ func (r *LinkMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		} else {
 			debugLogger.Info("Link not found in Netris")
 			debugLogger.Info("Going to create Link")
+			if msg := u.cacheStale(func() (bool, error) {
+				return existsInNetris(r.Cred, "link", r.Cred.Link().Get, func(item *link.Link) bool { return linkConnects(item, oldLocal, oldRemote) })
+			}, netrisstorage.KindLinks); msg != "" {
+				return u.patchLinkStatus(linkCR, "CacheStale", msg)
+			}
 			logger.Info("Creating Link")
 			if _, err, errMsg := r.createLink(linkMeta); err != nil {
 				logger.Error(fmt.Errorf("{createLink} %s", err), "")
+				u.warning(linkCR, EventAPIError, "Couldn't create Link in Netris: %s", errMsg)
 				return u.patchLinkStatus(linkCR, "Failure", errMsg.Error())
 			}
+			linkCR.Status.AppliedGeneration = linkMeta.Spec.LinkCRGeneration
+			u.event(linkCR, EventCreated, "Link created in Netris")
 			logger.Info("Link Created")
 		}
 	}
@@ -210,7 +254,9 @@ func (r *LinkMetaReconciler) createLink(linkMeta *k8sv1alpha1.LinkMeta) (ctrl.Re
 	js, _ := json.Marshal(linkAdd)
 	debugLogger.Info("linkToAdd", "payload", string(js))
 
-	reply, err := r.Cred.Link().Add(linkAdd)
+	reply, err := netrisapi.Create(r.Cred, "link", func() (http.HTTPReply, error) {
+		return r.Cred.Link().Add(linkAdd)
+	})
 	if err != nil {
 		return ctrl.Result{}, err, err
 	}
@@ -235,7 +281,14 @@ func (r *LinkMetaReconciler) createLink(linkMeta *k8sv1alpha1.LinkMeta) (ctrl.Re
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *LinkMetaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// link changes carry the Netris link ID, which LinkMeta doesn't store, so all LinkMetas are enqueued.
+	src, hdl, err := watchStorage(mgr, r.Controllers.storageListeners(r.NStorage), &k8sv1alpha1.LinkMeta{}, &k8sv1alpha1.LinkMetaList{}, nil, netrisstorage.KindLinks)
+	if err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.LinkMeta{}).
+		WithOptions(controllerOptions()).
+		Watches(src, hdl).
 		Complete(r)
 }
@@ -0,0 +1,51 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	driftCorrections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "netris_drift_corrections_total",
+			Help: "Number of objects found different in Netris and pushed again, by kind and cause (spec, netris).",
+		},
+		[]string{"kind", "cause"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(driftCorrections)
+}
+
+// specChanged reports whether the spec changed since it was last applied to
+// Netris, as opposed to the object being changed in Netris.
+func specChanged(generation, appliedGeneration int64) bool {
+	return generation != appliedGeneration
+}
+
+// recordDrift counts an object of kind which differs from the Netris one.
+func recordDrift(kind string, generation, appliedGeneration int64) {
+	cause := "netris"
+	if specChanged(generation, appliedGeneration) {
+		cause = "spec"
+	}
+	driftCorrections.WithLabelValues(kind, cause).Inc()
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/configloader"
+)
+
+// defaultNamingPolicy keeps the names of the resources as they are in Netris.
+const defaultNamingPolicy = "{{.Name}}"
+
+// namingPolicy renders the Netris names of the resources which don't set
+// spec.netrisName.
+var namingPolicy = template.Must(parseNamingPolicy(""))
+
+// SetNamingPolicy parses the naming policy of the operator config, the
+// default one is used if it's empty.
+func SetNamingPolicy(policy string) error {
+	parsed, err := parseNamingPolicy(policy)
+	if err != nil {
+		return fmt.Errorf("{SetNamingPolicy} %s", err)
+	}
+	namingPolicy = parsed
+	return nil
+}
+
+// nameFields are the fields a naming policy can use.
+type nameFields struct {
+	ClusterID string
+	Namespace string
+	Name      string
+}
+
+var namingFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"trunc": func(n int, s string) string {
+		if len(s) > n {
+			return s[:n]
+		}
+		return s
+	},
+}
+
+// parseNamingPolicy parses the policy and checks it renders a name.
+func parseNamingPolicy(policy string) (*template.Template, error) {
+	if policy == "" {
+		policy = defaultNamingPolicy
+	}
+	t, err := template.New("naming").Funcs(namingFuncs).Option("missingkey=error").Parse(policy)
+	if err != nil {
+		return nil, err
+	}
+	if name, err := renderName(t, nameFields{ClusterID: "cluster", Namespace: "default", Name: "name"}); err != nil {
+		return nil, err
+	} else if name == "" {
+		return nil, fmt.Errorf("%q renders an empty name", policy)
+	}
+	return t, nil
+}
+
+func renderName(t *template.Template, fields nameFields) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, fields); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// netrisName is the name of obj in Netris: override if it's set, the naming
+// policy otherwise.
+func netrisName(obj metav1.Object, override string) string {
+	if override != "" {
+		return override
+	}
+	name, err := renderName(namingPolicy, nameFields{
+		ClusterID: configloader.Root.ClusterID,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	})
+	if err != nil || name == "" {
+		return obj.GetName()
+	}
+	return name
+}
+
+// nameCollision reports why the Netris name of a meta which isn't created
+// yet can't be used: an object with the name exists in Netris, or another
+// meta of the kind, in any namespace, got the name first.
+func (u *uniReconciler) nameCollision(meta metav1.Object, kind, name string, exists bool) string {
+	if exists {
+		return fmt.Sprintf("%s %q already exists in Netris, import it or set spec.netrisName", kind, name)
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(k8sv1alpha1.GroupVersion.WithKind(kind + "MetaList"))
+	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
+	defer cancel()
+	if err := u.List(ctx, list); err != nil {
+		u.Logger.Error(fmt.Errorf("{nameCollision} %s", err), "")
+		return ""
+	}
+	created := meta.GetCreationTimestamp()
+	for _, item := range list.Items {
+		if item.GetUID() == meta.GetUID() {
+			continue
+		}
+		other, _, _ := unstructured.NestedString(item.Object, "spec", "netrisName")
+		if other == "" {
+			// the metas made before spec.netrisName are named after their resource.
+			other = ownerName(&item)
+		}
+		if other != name {
+			continue
+		}
+		otherCreated := item.GetCreationTimestamp()
+		if otherCreated.Before(&created) || (otherCreated.Equal(&created) && item.GetUID() < meta.GetUID()) {
+			return fmt.Sprintf("%s %q is already used by %s/%s, set spec.netrisName", kind, name, item.GetNamespace(), ownerName(&item))
+		}
+	}
+	return ""
+}
+
+// ownerName is the name of the resource which owns a meta.
+func ownerName(meta metav1.Object) string {
+	if owner := metav1.GetControllerOf(meta); owner != nil {
+		return owner.Name
+	}
+	return meta.GetName()
+}
+
+// keptNetrisName is the Netris name of a regenerated meta. The naming policy
+// names a resource once, when it's created or imported, so changing the
+// policy or the cluster ID doesn't rename what already exists in Netris.
+// Only spec.netrisName renames an existing resource.
+func keptNetrisName(override, current, generated string, id int) string {
+	if override != "" || id == 0 || current == "" {
+		return generated
+	}
+	return current
+}
@@ -23,11 +23,13 @@ import (
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -36,10 +38,12 @@ import (
 // NatReconciler reconciles a Nat object
 type NatReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.netris.ai,resources=nats,verbs=get;list;watch;create;update;patch;delete
@@ -56,6 +60,19 @@ type NatReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (r *NatReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.Nat{}, &k8sv1alpha1.NatMeta{})
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *NatReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	logger := r.Log.WithValues("name", req.NamespacedName)
 	debugLogger := logger.V(int(zapcore.WarnLevel))
 	nat := &k8sv1alpha1.Nat{}
@@ -66,6 +83,7 @@ func (r *NatReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	natCtx, natCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -95,13 +113,33 @@ func (r *NatReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 	}
 
+	if paused(nat) {
+		if metaFound {
+			if err := u.annotateMeta(nat, natMeta); err != nil {
+				logger.Error(err, "")
+				return ctrl.Result{Requeue: true}, nil
+			}
+		}
+		return u.patchNatStatus(nat, "Paused", pausedMessage)
+	}
+
 	if nat.DeletionTimestamp != nil {
+		if msg := deletionBlocked(nat, false); msg != "" {
+			u.warning(nat, EventDeleteBlocked, "%s", msg)
+			return u.patchNatStatus(nat, "DeletionBlocked", msg)
+		}
+		if natMeta != nil && natMeta.Spec.ID > 0 && !natMeta.Spec.Reclaim && dryRun(nat) {
+			nat.Status.Plan = planOf(planDelete, natMeta.Spec.ID, nil, nil)
+			return u.patchNatStatus(nat, "DryRun", planMessage("Nat", nat.Status.Plan))
+		}
 		logger.Info("Go to delete")
 		_, err := r.deleteNat(nat, natMeta)
 		if err != nil {
 			logger.Error(fmt.Errorf("{deleteNat} %s", err), "")
+			u.warning(nat, EventDeleteBlocked, "Couldn't delete Nat from Netris: %s", err)
 			return u.patchNatStatus(nat, "Failure", err.Error())
 		}
+		u.event(nat, EventDeleted, "Nat deleted from Netris")
 		logger.Info("Nat deleted")
 		return ctrl.Result{}, nil
 	}
@@ -114,14 +152,14 @@ func (r *NatReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		err := r.Patch(natPatchCtx, nat.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 		if err != nil {
 			logger.Error(fmt.Errorf("{Patch Nat default annotations} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 		return ctrl.Result{}, nil
 	}
 
 	if metaFound {
 		debugLogger.Info("Meta found")
-		if natCompareFieldsForNewMeta(nat, natMeta) {
+		if ownerChanged, annotationsChanged := setOwner(nat, natMeta, r.Scheme), syncAnnotations(nat, natMeta); natCompareFieldsForNewMeta(nat, natMeta) || ownerChanged || annotationsChanged {
 			debugLogger.Info("Generating New Meta")
 			natID := natMeta.Spec.ID
 			newVnetMeta, err := r.NatToNatMeta(nat)
@@ -129,8 +167,16 @@ func (r *NatReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				logger.Error(fmt.Errorf("{NatToNatMeta} %s", err), "")
 				return u.patchNatStatus(nat, "Failure", err.Error())
 			}
+			// the Netris controller of an existing resource never changes.
+			controllerRef := natMeta.Spec.ControllerRef
+			currentName := natMeta.Spec.NetrisName
+			if currentName == "" {
+				currentName = natMeta.Spec.NatName
+			}
 			natMeta.Spec = newVnetMeta.DeepCopy().Spec
 			natMeta.Spec.ID = natID
+			natMeta.Spec.ControllerRef = controllerRef
+			natMeta.Spec.NetrisName = keptNetrisName(nat.Spec.NetrisName, currentName, natMeta.Spec.NetrisName, natID)
 			natMeta.Spec.NatCRGeneration = nat.GetGeneration()
 
 			natMetaUpdateCtx, natMetaUpdateCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -138,7 +184,7 @@ func (r *NatReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err = r.Update(natMetaUpdateCtx, natMeta.DeepCopyObject(), &client.UpdateOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{natMeta Update} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 		}
 	} else {
@@ -151,7 +197,7 @@ func (r *NatReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err := r.Patch(natPatchCtx, nat.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{Patch Nat Finalizer} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 			return ctrl.Result{}, nil
 		}
@@ -164,20 +210,24 @@ func (r *NatReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 		natMeta.Spec.NatCRGeneration = nat.GetGeneration()
 
+		setOwner(nat, natMeta, r.Scheme)
+		syncAnnotations(nat, natMeta)
 		natMetaCreateCtx, natMetaCreateCancel := context.WithTimeout(cntxt, contextTimeout)
 		defer natMetaCreateCancel()
 		if err := r.Create(natMetaCreateCtx, natMeta.DeepCopyObject(), &client.CreateOptions{}); err != nil {
 			logger.Error(fmt.Errorf("{natMeta Create} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (r *NatReconciler) deleteNat(nat *k8sv1alpha1.Nat, natMeta *k8sv1alpha1.NatMeta) (ctrl.Result, error) {
 	if natMeta != nil && natMeta.Spec.ID > 0 && !natMeta.Spec.Reclaim {
-		reply, err := r.Cred.NAT().Delete(natMeta.Spec.ID)
+		reply, err := netrisapi.Do(r.Cred, "nat", "delete", func() (http.HTTPReply, error) {
+			return r.Cred.NAT().Delete(natMeta.Spec.ID)
+		})
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("{deleteNat} %s", err)
 		}
@@ -229,5 +279,7 @@ func (r *NatReconciler) deleteNatMetaCR(natMeta *k8sv1alpha1.NatMeta) (ctrl.Resu
 func (r *NatReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.Nat{}).
+		Owns(&k8sv1alpha1.NatMeta{}).
+		WithOptions(controllerOptions()).
 		Complete(r)
 }
@@ -58,22 +58,24 @@ func (r *NatReconciler) NatToNatMeta(nat *k8sv1alpha1.Nat) (*k8sv1alpha1.NatMeta
 		},
 		TypeMeta: metav1.TypeMeta{},
 		Spec: k8sv1alpha1.NatMetaSpec{
-			Imported:   imported,
-			Reclaim:    reclaim,
-			NatName:    nat.Name,
-			Comment:    nat.Spec.Comment,
-			State:      state,
-			SiteID:     siteID,
-			Action:     strings.ToUpper(nat.Spec.Action),
-			Protocol:   nat.Spec.Protocol,
-			SrcAddress: nat.Spec.SrcAddress,
-			SrcPort:    nat.Spec.SrcPort,
-			DstAddress: nat.Spec.DstAddress,
-			DstPort:    nat.Spec.DstPort,
-			SnatToIP:   nat.Spec.SnatToIP,
-			SnatToPool: nat.Spec.SnatToPool,
-			DnatToIP:   nat.Spec.DnatToIP,
-			DnatToPort: nat.Spec.DnatToPort,
+			Imported:      imported,
+			ControllerRef: nat.Spec.ControllerRef,
+			Reclaim:       reclaim,
+			NatName:       nat.Name,
+			NetrisName:    netrisName(nat, nat.Spec.NetrisName),
+			Comment:       nat.Spec.Comment,
+			State:         state,
+			SiteID:        siteID,
+			Action:        strings.ToUpper(nat.Spec.Action),
+			Protocol:      nat.Spec.Protocol,
+			SrcAddress:    nat.Spec.SrcAddress,
+			SrcPort:       nat.Spec.SrcPort,
+			DstAddress:    nat.Spec.DstAddress,
+			DstPort:       nat.Spec.DstPort,
+			SnatToIP:      nat.Spec.SnatToIP,
+			SnatToPool:    nat.Spec.SnatToPool,
+			DnatToIP:      nat.Spec.DnatToIP,
+			DnatToPort:    nat.Spec.DnatToPort,
 		},
 	}
 
@@ -121,7 +123,7 @@ func natUpdateDefaultAnnotations(nat *k8sv1alpha1.Nat) {
 // NatMetaToNetris converts the k8s Nat resource to Netris type and used for add the Nat for Netris API.
 func NatMetaToNetris(natMeta *k8sv1alpha1.NatMeta) (*nat.NATw, error) {
 	natAdd := &nat.NATw{
-		Name:               natMeta.Spec.NatName,
+		Name:               natMeta.Spec.NetrisName,
 		Comment:            natMeta.Spec.Comment,
 		State:              natMeta.Spec.State,
 		Site:               nat.IDName{ID: natMeta.Spec.SiteID},
@@ -143,7 +145,7 @@ func NatMetaToNetris(natMeta *k8sv1alpha1.NatMeta) (*nat.NATw, error) {
 // NatMetaToNetrisUpdate converts the k8s Nat resource to Netris type and used for update the Nat for Netris API.
 func NatMetaToNetrisUpdate(natMeta *k8sv1alpha1.NatMeta) (*nat.NATw, error) {
 	natAdd := &nat.NATw{
-		Name:               natMeta.Spec.NatName,
+		Name:               natMeta.Spec.NetrisName,
 		Comment:            natMeta.Spec.Comment,
 		State:              natMeta.Spec.State,
 		Site:               nat.IDName{ID: natMeta.Spec.SiteID},
@@ -163,8 +165,8 @@ func NatMetaToNetrisUpdate(natMeta *k8sv1alpha1.NatMeta) (*nat.NATw, error) {
 }
 
 func compareNatMetaAPIENat(natMeta *k8sv1alpha1.NatMeta, apiNat *nat.NAT, u uniReconciler) bool {
-	if apiNat.Name != natMeta.Spec.NatName {
-		u.DebugLogger.Info("Name changed", "netrisValue", apiNat.Name, "k8sValue", natMeta.Spec.NatName)
+	if apiNat.Name != natMeta.Spec.NetrisName {
+		u.DebugLogger.Info("Name changed", "netrisValue", apiNat.Name, "k8sValue", natMeta.Spec.NetrisName)
 		return false
 	}
 	if apiNat.Comment != natMeta.Spec.Comment {
@@ -229,3 +231,57 @@ func compareNatMetaAPIENat(natMeta *k8sv1alpha1.NatMeta, apiNat *nat.NAT, u uniR
 
 	return true
 }
+
+// natDrift returns the difference of the Nat spec with the Netris NAT rule
+// and sets the spec to the Netris values.
+func natDrift(spec *k8sv1alpha1.NatSpec, natMeta *k8sv1alpha1.NatMeta, apiNat *nat.NAT) *drift {
+	d := &drift{}
+	transport := apiNat.Protocol.Value == "tcp" || apiNat.Protocol.Value == "udp"
+	if apiNat.Name != natMeta.Spec.NetrisName {
+		driftFixed(d, "metadata.name", natMeta.Spec.NetrisName, apiNat.Name)
+	}
+	if apiNat.Comment != natMeta.Spec.Comment {
+		driftSet(d, "spec.comment", &spec.Comment, apiNat.Comment)
+	}
+	if apiNat.State.Value != natMeta.Spec.State {
+		driftSet(d, "spec.state", &spec.State, apiNat.State.Value)
+	}
+	if apiNat.Site.ID != natMeta.Spec.SiteID {
+		driftSet(d, "spec.site", &spec.Site, apiNat.Site.Name)
+	}
+	apiAction := apiNat.Action.Label
+	if apiAction == "ACCEPT" {
+		apiAction = "ACCEPT_SNAT"
+	}
+	if apiAction != natMeta.Spec.Action {
+		driftSet(d, "spec.action", &spec.Action, strings.ToLower(apiAction))
+	}
+	if apiNat.Protocol.Value != natMeta.Spec.Protocol {
+		driftSet(d, "spec.protocol", &spec.Protocol, apiNat.Protocol.Value)
+	}
+	if apiNat.SourceAddress != natMeta.Spec.SrcAddress {
+		driftSet(d, "spec.srcAddress", &spec.SrcAddress, apiNat.SourceAddress)
+	}
+	if transport && apiNat.SourcePort != natMeta.Spec.SrcPort {
+		driftSet(d, "spec.srcPort", &spec.SrcPort, apiNat.SourcePort)
+	}
+	if apiNat.DestinationAddress != natMeta.Spec.DstAddress && apiNat.DestinationAddress != strings.Split(natMeta.Spec.DstAddress, "/")[0] {
+		driftSet(d, "spec.dstAddress", &spec.DstAddress, apiNat.DestinationAddress)
+	}
+	if transport && apiNat.DestinationPort != natMeta.Spec.DstPort {
+		driftSet(d, "spec.dstPort", &spec.DstPort, apiNat.DestinationPort)
+	}
+	if apiNat.SnatToIP != natMeta.Spec.SnatToIP {
+		driftSet(d, "spec.snatToIp", &spec.SnatToIP, apiNat.SnatToIP)
+	}
+	if apiNat.SnatToPool != natMeta.Spec.SnatToPool {
+		driftSet(d, "spec.snatToPool", &spec.SnatToPool, apiNat.SnatToPool)
+	}
+	if apiNat.DnatToIP != natMeta.Spec.DnatToIP {
+		driftSet(d, "spec.dnatToIp", &spec.DnatToIP, apiNat.DnatToIP)
+	}
+	if apiNat.DnatToPort != natMeta.Spec.DnatToPort {
+		driftSet(d, "spec.dnatToPort", &spec.DnatToPort, apiNat.DnatToPort)
+	}
+	return d
+}
@@ -20,15 +20,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -38,10 +41,12 @@ import (
 // NatMetaReconciler reconciles a NatMeta object
 type NatMetaReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.netris.ai,resources=natmeta,verbs=get;list;watch;create;update;patch;delete
@@ -58,6 +63,19 @@ type NatMetaReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (r *NatMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.NatMeta{}, nil)
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *NatMetaReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	debugLogger := r.Log.WithValues("name", req.NamespacedName).V(int(zapcore.WarnLevel))
 
 	natMeta := &k8sv1alpha1.NatMeta{}
@@ -81,6 +99,7 @@ func (r *NatMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	provisionState := "OK"
@@ -96,17 +115,26 @@ func (r *NatMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 		return ctrl.Result{}, err
 	}
+	natCR.Status.Plan = nil
+
+	if paused(natCR) {
+		return u.patchNatStatus(natCR, "Paused", pausedMessage)
+	}
 
 	if natMeta.DeletionTimestamp != nil {
 		return ctrl.Result{}, nil
 	}
 
+	if natMeta.Spec.NetrisName == "" {
+		natMeta.Spec.NetrisName = natMeta.Spec.NatName
+	}
+
 	if natMeta.Spec.ID == 0 {
 		debugLogger.Info("ID Not found in meta")
 		if natMeta.Spec.Imported {
 			logger.Info("Importing nat")
 			debugLogger.Info("Imported yaml mode. Finding Nat by name")
-			if nat, ok := r.NStorage.NATStorage.FindByName(natMeta.Spec.NatName); ok {
+			if nat, ok := r.NStorage.NATStorage.FindByName(natMeta.Spec.NetrisName); ok {
 				debugLogger.Info("Imported yaml mode. Nat found")
 				natMeta.Spec.ID = nat.ID
 
@@ -118,26 +146,55 @@ func (r *NatMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 					return u.patchNatStatus(natCR, "Failure", err.Error())
 				}
 				debugLogger.Info("Imported yaml mode. ID patched")
+				u.event(natCR, EventImported, "Nat imported from Netris, ID %d", natMeta.Spec.ID)
 				logger.Info("Nat imported")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{}, nil
 			}
 			logger.Info("Nat not found for import")
 			debugLogger.Info("Imported yaml mode. Nat not found")
 		}
 
+		if msg := u.cacheStale(nil, netrisstorage.KindNATs); msg != "" {
+			return u.patchNatStatus(natCR, "CacheStale", msg)
+		}
+		_, exists := r.NStorage.NATStorage.FindByName(natMeta.Spec.NetrisName)
+		if msg := u.nameCollision(natMeta, "Nat", natMeta.Spec.NetrisName, exists); msg != "" {
+			u.warning(natCR, EventNameCollision, "%s", msg)
+			return u.patchNatStatus(natCR, "NameCollision", msg)
+		}
+		if dryRun(natCR) {
+			return planNatCreate(&u, natCR, natMeta)
+		}
 		logger.Info("Creating Nat")
 		if _, err, errMsg := r.createNat(natMeta); err != nil {
 			logger.Error(fmt.Errorf("{createNat} %s", err), "")
+			u.warning(natCR, EventAPIError, "Couldn't create Nat in Netris: %s", errMsg)
 			return u.patchNatStatus(natCR, "Failure", errMsg.Error())
 		}
+		natCR.Status.AppliedGeneration = natMeta.Spec.NatCRGeneration
+		u.event(natCR, EventCreated, "Nat created in Netris")
 		logger.Info("Nat Created")
 	} else {
 		if apiNat, ok := r.NStorage.NATStorage.FindByID(natMeta.Spec.ID); ok {
 
 			debugLogger.Info("Comparing NatMeta with Netris Nat")
+			driftedFields := natCR.Status.DriftedFields
+			natCR.Status.DriftedFields = nil
 			if ok := compareNatMetaAPIENat(natMeta, apiNat, u); ok {
 				debugLogger.Info("Nothing Changed")
+				natCR.Status.AppliedGeneration = natMeta.Spec.NatCRGeneration
+			} else if keepsDrift(natCR, natCR.Status.AppliedGeneration) {
+				spec := natCR.Spec.DeepCopy()
+				d := natDrift(spec, natMeta, apiNat)
+				if driftPolicyOf(natCR) == driftAdopt && d.adoptable() {
+					natCR.Spec = *spec
+					return u.adoptDrift(natCR, "Nat", d)
+				}
+				u.reportDrift(natCR, "Nat", d, driftedFields)
+				natCR.Status.DriftedFields = d.fields
+				return u.patchNatStatus(natCR, "Drifted", d.String())
 			} else {
+				recordDrift("Nat", natCR.Generation, natCR.Status.AppliedGeneration)
 				debugLogger.Info("Go to update Nat in Netris")
 				logger.Info("Updating Nat")
 				natUpdate, err := NatMetaToNetrisUpdate(natMeta)
@@ -149,27 +206,57 @@ func (r *NatMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				js, _ := json.Marshal(natUpdate)
 				debugLogger.Info("natUpdate", "payload", string(js))
 
+				if dryRun(natCR) {
+					d := natDrift(natCR.Spec.DeepCopy(), natMeta, apiNat)
+					natCR.Status.Plan = planOf(planUpdate, natMeta.Spec.ID, natUpdate, d.fields)
+					return u.patchNatStatus(natCR, "DryRun", planMessage("Nat", natCR.Status.Plan))
+				}
+
 				_, err, errMsg := updateNat(natMeta.Spec.ID, natUpdate, r.Cred)
 				if err != nil {
 					logger.Error(fmt.Errorf("{updateNat} %s", err), "")
+					u.warning(natCR, EventAPIError, "Couldn't update Nat in Netris: %s", errMsg)
 					return u.patchNatStatus(natCR, "Failure", errMsg.Error())
 				}
+				u.updated(natCR, natCR.Generation, natCR.Status.AppliedGeneration, "Nat")
+				natCR.Status.AppliedGeneration = natMeta.Spec.NatCRGeneration
 				logger.Info("Nat Updated")
 			}
 		} else {
 			debugLogger.Info("Nat not found in Netris")
 			debugLogger.Info("Going to create Nat")
+			if msg := u.cacheStale(func() (bool, error) {
+				return existsInNetris(r.Cred, "nat", r.Cred.NAT().Get, func(item *nat.NAT) bool { return item.ID == natMeta.Spec.ID })
+			}, netrisstorage.KindNATs); msg != "" {
+				return u.patchNatStatus(natCR, "CacheStale", msg)
+			}
+			if dryRun(natCR) {
+				return planNatCreate(&u, natCR, natMeta)
+			}
 			logger.Info("Creating Nat")
 			if _, err, errMsg := r.createNat(natMeta); err != nil {
 				logger.Error(fmt.Errorf("{createNat} %s", err), "")
+				u.warning(natCR, EventAPIError, "Couldn't create Nat in Netris: %s", errMsg)
 				return u.patchNatStatus(natCR, "Failure", errMsg.Error())
 			}
+			natCR.Status.AppliedGeneration = natMeta.Spec.NatCRGeneration
+			u.event(natCR, EventCreated, "Nat created in Netris")
 			logger.Info("Nat Created")
 		}
 	}
 	return u.patchNatStatus(natCR, provisionState, "Success")
 }
 
+// planNatCreate records the create request of the Nat in its status instead of sending it.
+func planNatCreate(u *uniReconciler, natCR *k8sv1alpha1.Nat, natMeta *k8sv1alpha1.NatMeta) (ctrl.Result, error) {
+	natAdd, err := NatMetaToNetris(natMeta)
+	if err != nil {
+		return u.patchNatStatus(natCR, "Failure", err.Error())
+	}
+	natCR.Status.Plan = planOf(planCreate, 0, natAdd, nil)
+	return u.patchNatStatus(natCR, "DryRun", planMessage("Nat", natCR.Status.Plan))
+}
+
 func (r *NatMetaReconciler) createNat(natMeta *k8sv1alpha1.NatMeta) (ctrl.Result, error, error) {
 	debugLogger := r.Log.WithValues(
 		"name", fmt.Sprintf("%s/%s", natMeta.Namespace, natMeta.Spec.NatName),
@@ -184,7 +271,9 @@ func (r *NatMetaReconciler) createNat(natMeta *k8sv1alpha1.NatMeta) (ctrl.Result
 	js, _ := json.Marshal(natAdd)
 	debugLogger.Info("natToAdd", "payload", string(js))
 
-	reply, err := r.Cred.NAT().Add(natAdd)
+	reply, err := netrisapi.Create(r.Cred, "nat", func() (http.HTTPReply, error) {
+		return r.Cred.NAT().Add(natAdd)
+	})
 	if err != nil {
 		return ctrl.Result{}, err, err
 	}
@@ -221,7 +310,9 @@ func (r *NatMetaReconciler) createNat(natMeta *k8sv1alpha1.NatMeta) (ctrl.Result
 }
 
 func updateNat(id int, nat *nat.NATw, cred *api.Clientset) (ctrl.Result, error, error) {
-	reply, err := cred.NAT().Update(id, nat)
+	reply, err := netrisapi.Do(cred, "nat", "update", func() (http.HTTPReply, error) {
+		return cred.NAT().Update(id, nat)
+	})
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("{updateNat} %s", err), err
 	}
@@ -238,7 +329,15 @@ func updateNat(id int, nat *nat.NATw, cred *api.Clientset) (ctrl.Result, error,
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *NatMetaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	src, hdl, err := watchStorage(mgr, r.Controllers.storageListeners(r.NStorage), &k8sv1alpha1.NatMeta{}, &k8sv1alpha1.NatMetaList{}, func(o runtime.Object) string {
+		return strconv.Itoa(o.(*k8sv1alpha1.NatMeta).Spec.ID)
+	}, netrisstorage.KindNATs)
+	if err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.NatMeta{}).
+		WithOptions(controllerOptions()).
+		Watches(src, hdl).
 		Complete(r)
 }
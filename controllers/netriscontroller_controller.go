@@ -0,0 +1,162 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/credwatcher"
+	"github.com/netrisai/netris-operator/netrisstorage"
+	api "github.com/netrisai/netriswebapi/v2"
+)
+
+// NetrisControllerReconciler reconciles a NetrisController object
+type NetrisControllerReconciler struct {
+	client.Client
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Controllers *ControllerSet
+	// Timeout is the Netris API client timeout in seconds.
+	Timeout          int
+	RefreshIntervals map[string]int
+}
+
+// +kubebuilder:rbac:groups=k8s.netris.ai,resources=netriscontrollers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=k8s.netris.ai,resources=netriscontrollers/status,verbs=get;update;patch
+
+// Reconcile logs in to the Netris controller and registers its connection.
+// The credentials Secret isn't watched, it's re-read on every requeue.
+func (r *NetrisControllerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("name", req.Name)
+	debugLogger := logger.V(int(zapcore.WarnLevel))
+	nc := &k8sv1alpha1.NetrisController{}
+
+	ncCtx, ncCancel := context.WithTimeout(cntxt, contextTimeout)
+	defer ncCancel()
+	if err := r.Get(ncCtx, req.NamespacedName, nc); err != nil {
+		if errors.IsNotFound(err) {
+			debugLogger.Info(err.Error())
+			r.Controllers.remove(req.Name)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if nc.DeletionTimestamp != nil {
+		r.Controllers.remove(req.Name)
+		return ctrl.Result{}, nil
+	}
+
+	secret := &corev1.Secret{}
+	secretNN := types.NamespacedName{Namespace: nc.Spec.CredentialsSecretRef.Namespace, Name: nc.Spec.CredentialsSecretRef.Name}
+	secretCtx, secretCancel := context.WithTimeout(cntxt, contextTimeout)
+	defer secretCancel()
+	if err := r.Get(secretCtx, secretNN, secret); err != nil {
+		logger.Error(fmt.Errorf("{Get credentials Secret} %s", err), "")
+		return r.patchStatus(nc, "Failure", err.Error())
+	}
+	login := string(secret.Data[credwatcher.KeyLogin])
+	password := string(secret.Data[credwatcher.KeyPassword])
+	if login == "" {
+		return r.patchStatus(nc, "Failure", fmt.Sprintf("Secret %s must contain the %q and %q keys", secretNN, credwatcher.KeyLogin, credwatcher.KeyPassword))
+	}
+
+	fingerprint := ncFingerprint(nc.Spec, login, password)
+	if conn, ok := r.Controllers.Get(req.Name); ok && conn.fingerprint == fingerprint {
+		debugLogger.Info("Nothing Changed")
+		return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	}
+
+	logger.Info("Connecting", "host", nc.Spec.Host)
+	cred, err := api.Client(nc.Spec.Host, login, password, r.Timeout)
+	if err != nil {
+		logger.Error(fmt.Errorf("{api.Client} %s", err), "")
+		return r.patchStatus(nc, "Failure", err.Error())
+	}
+	cred.Client.InsecureVerify(nc.Spec.Insecure)
+	if err := cred.Client.LoginUser(); err != nil {
+		logger.Error(fmt.Errorf("{LoginUser} %s", err), "")
+		return r.patchStatus(nc, "Failure", err.Error())
+	}
+
+	vpcid := nc.Spec.VPCID
+	if vpcid == 0 {
+		vpcid = 1
+	}
+	nStorage := netrisstorage.NewStorageWithOptions(cred, netrisstorage.Options{
+		VPCID:            vpcid,
+		RefreshIntervals: r.RefreshIntervals,
+		Name:             req.Name,
+	})
+	// a failed download leaves the storage stale, which blocks creates until the next refresh succeeds.
+	downloadErr := nStorage.Download()
+
+	r.Controllers.set(req.Name, &NetrisConn{
+		Cred:        cred,
+		NStorage:    nStorage,
+		VPCID:       vpcid,
+		L4LBTenant:  nc.Spec.L4LBTenant,
+		fingerprint: fingerprint,
+		stop:        make(chan struct{}),
+	})
+	logger.Info("Connected", "host", nc.Spec.Host)
+
+	if downloadErr != nil {
+		logger.Error(fmt.Errorf("{Storage.Download} %s", downloadErr), "")
+		return r.patchStatus(nc, "Failure", downloadErr.Error())
+	}
+	return r.patchStatus(nc, "OK", "Success")
+}
+
+func (r *NetrisControllerReconciler) patchStatus(nc *k8sv1alpha1.NetrisController, status, message string) (ctrl.Result, error) {
+	nc.Status.Status = status
+	nc.Status.Message = message
+	setConditions(&nc.Status.Conditions, &nc.Status.ObservedGeneration, nc.Generation, status, message)
+
+	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
+	defer cancel()
+	err := r.Status().Patch(ctx, nc.DeepCopyObject(), client.Merge, &client.PatchOptions{})
+	if err != nil {
+		r.Log.V(int(zapcore.WarnLevel)).Info("{r.Status().Patch}", "error", err, "action", "status update")
+	}
+	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+}
+
+func ncFingerprint(spec k8sv1alpha1.NetrisControllerSpec, login, password string) string {
+	js, _ := json.Marshal(spec)
+	return fmt.Sprintf("%x", sha256.Sum256(append(js, []byte(login+"\x00"+password)...)))
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NetrisControllerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&k8sv1alpha1.NetrisController{}).
+		Complete(r)
+}
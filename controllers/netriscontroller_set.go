@@ -0,0 +1,187 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
+	"github.com/netrisai/netris-operator/netrisstorage"
+	api "github.com/netrisai/netriswebapi/v2"
+)
+
+// NetrisConn is the API client and the storage of a NetrisController.
+type NetrisConn struct {
+	Cred       *api.Clientset
+	NStorage   *netrisstorage.Storage
+	VPCID      int
+	L4LBTenant string
+
+	// fingerprint identifies the spec and the credentials the connection was made with.
+	fingerprint string
+	stop        chan struct{}
+}
+
+// start keeps the session and the storage of the connection fresh until close.
+func (c *NetrisConn) start() {
+	go c.NStorage.DownloadUntil(c.stop)
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+			}
+			if err := c.Cred.Client.CheckAuth(); err != nil {
+				if err := c.Cred.Client.LoginUser(); err != nil {
+					ctrl.Log.WithName("NetrisController").Error(err, "LoginUser")
+				}
+			}
+		}
+	}()
+}
+
+func (c *NetrisConn) close() {
+	close(c.stop)
+	netrisapi.Forget(c.Cred)
+}
+
+type controllerReferrer interface {
+	runtime.Object
+	k8sv1alpha1.ControllerReferrer
+}
+
+// ControllerSet holds the connections of the NetrisController resources.
+// Resources without a controllerRef stay with the controller configured for
+// the operator, which the reconcilers are created with.
+type ControllerSet struct {
+	mu        sync.RWMutex
+	conns     map[string]*NetrisConn
+	nStorage  *netrisstorage.Storage
+	listeners []storageListener
+}
+
+// NewControllerSet . nStorage is the storage of the default controller.
+func NewControllerSet(nStorage *netrisstorage.Storage) *ControllerSet {
+	return &ControllerSet{
+		conns:    make(map[string]*NetrisConn),
+		nStorage: nStorage,
+	}
+}
+
+// Get returns the connection of the NetrisController with the given name.
+func (s *ControllerSet) Get(name string) (*NetrisConn, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	conn, ok := s.conns[name]
+	return conn, ok
+}
+
+// addListener registers the listener on the storages of all controllers,
+// including the ones added later.
+func (s *ControllerSet) addListener(listener storageListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+	s.nStorage.AddListener(bindListener("", listener))
+	for name, conn := range s.conns {
+		conn.NStorage.AddListener(bindListener(name, listener))
+	}
+}
+
+func (s *ControllerSet) set(name string, conn *NetrisConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, listener := range s.listeners {
+		conn.NStorage.AddListener(bindListener(name, listener))
+	}
+	if old, ok := s.conns[name]; ok {
+		old.close()
+	}
+	s.conns[name] = conn
+	conn.start()
+}
+
+func (s *ControllerSet) remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if conn, ok := s.conns[name]; ok {
+		conn.close()
+		delete(s.conns, name)
+	}
+}
+
+// storageListeners returns where the storage listeners of a reconciler are registered.
+func (s *ControllerSet) storageListeners(nStorage *netrisstorage.Storage) storageListeners {
+	if s == nil {
+		return defaultStorage{nStorage: nStorage}
+	}
+	return s
+}
+
+// connFor returns the connection of the NetrisController which manages the
+// object, nil means the default controller. The controller a resource was
+// created with is recorded in its Meta, which wins over the controllerRef of
+// the resource, so a resource never moves between Netris controllers.
+func (s *ControllerSet) connFor(c client.Client, nn types.NamespacedName, obj, meta controllerReferrer) (*NetrisConn, error) {
+	if s == nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
+	defer cancel()
+	if err := c.Get(ctx, nn, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ref := obj.GetControllerRef()
+	if meta != nil {
+		objMeta, err := apimeta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		metaNN := nn
+		metaNN.Name = string(objMeta.GetUID())
+		if err := c.Get(ctx, metaNN, meta); err == nil {
+			ref = meta.GetControllerRef()
+		} else if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	if ref == "" {
+		return nil, nil
+	}
+	conn, ok := s.Get(ref)
+	if !ok {
+		return nil, fmt.Errorf("NetrisController %q isn't ready", ref)
+	}
+	return conn, nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/netrisai/netris-operator/maintenance"
+)
+
+// pausedAnnotation set to "true" stops all Netris writes of a resource, e.g.
+// during an incident. The CR and the meta reconcilers only report the Paused
+// condition until it's removed.
+const pausedAnnotation = "resource.k8s.netris.ai/paused"
+
+const pausedMessage = "Reconciliation is paused by the " + pausedAnnotation + " annotation"
+
+// maintenanceWindows defer the disruptive updates of VNets, BGPs and switches.
+// No windows allow them at any time.
+var maintenanceWindows maintenance.Windows
+
+// SetMaintenanceWindows parses the maintenance windows of the operator
+// config, e.g. "0 2 * * SAT 4h".
+func SetMaintenanceWindows(windows string) error {
+	parsed, err := maintenance.Parse(windows)
+	if err != nil {
+		return fmt.Errorf("{SetMaintenanceWindows} %s", err)
+	}
+	maintenanceWindows = parsed
+	return nil
+}
+
+// paused reports whether the reconciliation of obj is paused.
+func paused(obj metav1.Object) bool {
+	return obj.GetAnnotations()[pausedAnnotation] == "true"
+}
+
+// annotateMeta copies the metaAnnotations of a paused owner to its meta, the
+// meta is reconciled again when the owner is resumed this way.
+func (u *uniReconciler) annotateMeta(owner metav1.Object, meta runtime.Object) error {
+	if !syncAnnotations(owner, meta.(metav1.Object)) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
+	defer cancel()
+	if err := u.Update(ctx, meta.DeepCopyObject(), &client.UpdateOptions{}); err != nil {
+		return fmt.Errorf("{annotateMeta} %s", err)
+	}
+	return nil
+}
+
+// deferred reports whether a disruptive update must wait for a maintenance
+// window at now. It returns the status message and the result of the
+// reconcile, which is requeued at the start of the next window.
+func deferred(now time.Time) (bool, string, ctrl.Result) {
+	if maintenanceWindows.Open(now) {
+		return false, "", ctrl.Result{}
+	}
+	next := maintenanceWindows.Next(now)
+	if next.IsZero() {
+		return true, "Update deferred, no maintenance window starts within a year", driftCheck()
+	}
+	msg := fmt.Sprintf("Update deferred to the maintenance window at %s", next.Format(time.RFC3339))
+	return true, msg, ctrl.Result{RequeueAfter: next.Sub(now)}
+}
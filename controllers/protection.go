@@ -0,0 +1,105 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+)
+
+// Annotations of the deletion protection. A protected resource can't be
+// deleted until the protection annotation is removed. Deleting a Site, a
+// Switch or a VNet with active switch ports from Netris needs the confirm
+// annotation set to the name of the resource.
+const (
+	deletionProtectionAnnotation = "resource.k8s.netris.ai/deletion-protection"
+	confirmDeleteAnnotation      = "resource.k8s.netris.ai/confirm-delete"
+	reclaimPolicyAnnotation      = "resource.k8s.netris.ai/reclaimPolicy"
+)
+
+// DeletionWebhookPath is the path of the DeletionValidator in the webhook server.
+const DeletionWebhookPath = "/validate-netris-deletion"
+
+// deletionBlocked returns why obj can't be deleted, an empty string if it can.
+// needsConfirm is set when the deletion removes a critical object from Netris.
+func deletionBlocked(obj metav1.Object, needsConfirm bool) string {
+	if obj.GetAnnotations()[deletionProtectionAnnotation] == "true" {
+		return fmt.Sprintf("Deletion is protected, remove the %s annotation to delete", deletionProtectionAnnotation)
+	}
+	if needsConfirm && obj.GetAnnotations()[confirmDeleteAnnotation] != obj.GetName() {
+		return fmt.Sprintf("Deleting from Netris needs a confirmation, set the %s annotation to %q", confirmDeleteAnnotation, obj.GetName())
+	}
+	return ""
+}
+
+// vnetHasActivePorts reports whether a switch port of the VNet is active.
+func vnetHasActivePorts(vnet *k8sv1alpha1.VNet) bool {
+	for _, site := range vnet.Spec.Sites {
+		for _, port := range site.SwitchPorts {
+			if port.State == "" || port.State == "active" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// +kubebuilder:webhook:path=/validate-netris-deletion,mutating=false,failurePolicy=ignore,groups=k8s.netris.ai,resources=allocations;bgps;controllers;inventoryprofiles;l4lbs;links;nats;sites;softgates;subnets;switches;vnets,verbs=delete,versions=v1alpha1,name=deletion.k8s.netris.ai
+
+// DeletionValidator denies the deletion of the protected resources and of
+// the unconfirmed critical ones. The finalizers enforce the same rules when
+// the webhook isn't deployed, the deleted resource is kept then.
+type DeletionValidator struct{}
+
+// Handle .
+func (v *DeletionValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != v1beta1.Delete {
+		return admission.Allowed("")
+	}
+	var obj metav1.Object
+	needsConfirm := false
+	switch req.Kind.Kind {
+	case "VNet":
+		vnet := &k8sv1alpha1.VNet{}
+		if err := json.Unmarshal(req.OldObject.Raw, vnet); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		obj, needsConfirm = vnet, vnetHasActivePorts(vnet)
+	default:
+		partial := &metav1.PartialObjectMetadata{}
+		if err := json.Unmarshal(req.OldObject.Raw, partial); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		obj, needsConfirm = partial, req.Kind.Kind == "Site" || req.Kind.Kind == "Switch"
+	}
+	// a retained object isn't deleted from Netris.
+	if obj.GetAnnotations()[reclaimPolicyAnnotation] == "retain" {
+		needsConfirm = false
+	}
+	if msg := deletionBlocked(obj, needsConfirm); msg != "" {
+		return admission.Denied(msg)
+	}
+	return admission.Allowed("")
+}
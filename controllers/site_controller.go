@@ -24,6 +24,7 @@ import (
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -35,10 +36,12 @@ import (
 // SiteReconciler reconciles a Site object
 type SiteReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=k8s.netris.ai,resources=sites,verbs=get;list;watch;create;update;patch;delete
@@ -47,6 +50,19 @@ type SiteReconciler struct {
 
 // Reconcile is the main reconciler for the appropriate resource type
 func (r *SiteReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.Site{}, &k8sv1alpha1.SiteMeta{})
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *SiteReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	logger := r.Log.WithValues("name", req.NamespacedName)
 	debugLogger := logger.V(int(zapcore.WarnLevel))
 	site := &k8sv1alpha1.Site{}
@@ -57,6 +73,7 @@ func (r *SiteReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	siteCtx, siteCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -86,14 +103,30 @@ func (r *SiteReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 	}
 
+	if paused(site) {
+		if metaFound {
+			if err := u.annotateMeta(site, siteMeta); err != nil {
+				logger.Error(err, "")
+				return ctrl.Result{Requeue: true}, nil
+			}
+		}
+		return u.patchSiteStatus(site, "Paused", pausedMessage)
+	}
+
 	if site.DeletionTimestamp != nil {
+		if msg := deletionBlocked(site, siteMeta != nil && siteMeta.Spec.ID > 0 && !siteMeta.Spec.Reclaim); msg != "" {
+			u.warning(site, EventDeleteBlocked, "%s", msg)
+			return u.patchSiteStatus(site, "DeletionBlocked", msg)
+		}
 		logger.Info("Go to delete")
 		result, err := r.deleteSite(site, siteMeta)
 		if err != nil {
 			logger.Error(fmt.Errorf("{deleteSite} %s", err), "")
+			u.warning(site, EventDeleteBlocked, "Couldn't delete Site from Netris: %s", err)
 			return u.patchSiteStatus(site, "Failure", err.Error())
 		}
 		if result.IsZero() {
+			u.event(site, EventDeleted, "Site deleted from Netris")
 			logger.Info("Site deleted")
 		}
 		return ctrl.Result{}, nil
@@ -107,14 +140,14 @@ func (r *SiteReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		err := r.Patch(sitePatchCtx, site.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 		if err != nil {
 			logger.Error(fmt.Errorf("{Patch Site default annotations} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 		return ctrl.Result{}, nil
 	}
 
 	if metaFound {
 		debugLogger.Info("Meta found")
-		if siteCompareFieldsForNewMeta(site, siteMeta) {
+		if ownerChanged, annotationsChanged := setOwner(site, siteMeta, r.Scheme), syncAnnotations(site, siteMeta); siteCompareFieldsForNewMeta(site, siteMeta) || ownerChanged || annotationsChanged {
 			debugLogger.Info("Generating New Meta")
 			siteID := siteMeta.Spec.ID
 			newVnetMeta, err := r.SiteToSiteMeta(site)
@@ -122,8 +155,16 @@ func (r *SiteReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				logger.Error(fmt.Errorf("{SiteToSiteMeta} %s", err), "")
 				return u.patchSiteStatus(site, "Failure", err.Error())
 			}
+			// the Netris controller of an existing resource never changes.
+			controllerRef := siteMeta.Spec.ControllerRef
+			currentName := siteMeta.Spec.NetrisName
+			if currentName == "" {
+				currentName = siteMeta.Spec.SiteName
+			}
 			siteMeta.Spec = newVnetMeta.DeepCopy().Spec
 			siteMeta.Spec.ID = siteID
+			siteMeta.Spec.ControllerRef = controllerRef
+			siteMeta.Spec.NetrisName = keptNetrisName(site.Spec.NetrisName, currentName, siteMeta.Spec.NetrisName, siteID)
 			siteMeta.Spec.SiteCRGeneration = site.GetGeneration()
 
 			siteMetaUpdateCtx, siteMetaUpdateCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -131,7 +172,7 @@ func (r *SiteReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err = r.Update(siteMetaUpdateCtx, siteMeta.DeepCopyObject(), &client.UpdateOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{siteMeta Update} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 		}
 	} else {
@@ -144,7 +185,7 @@ func (r *SiteReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err := r.Patch(sitePatchCtx, site.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{Patch Site Finalizer} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 			return ctrl.Result{}, nil
 		}
@@ -158,15 +199,17 @@ func (r *SiteReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		siteMeta.Spec.SiteCRGeneration = site.GetGeneration()
 		siteMeta.SetFinalizers([]string{"resource.k8s.netris.ai/delete"})
 
+		setOwner(site, siteMeta, r.Scheme)
+		syncAnnotations(site, siteMeta)
 		siteMetaCreateCtx, siteMetaCreateCancel := context.WithTimeout(cntxt, contextTimeout)
 		defer siteMetaCreateCancel()
 		if err := r.Create(siteMetaCreateCtx, siteMeta.DeepCopyObject(), &client.CreateOptions{}); err != nil {
 			logger.Error(fmt.Errorf("{siteMeta Create} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (r *SiteReconciler) deleteSite(site *k8sv1alpha1.Site, siteMeta *k8sv1alpha1.SiteMeta) (ctrl.Result, error) {
@@ -212,5 +255,7 @@ func (r *SiteReconciler) deleteSiteMetaCR(siteMeta *k8sv1alpha1.SiteMeta) (ctrl.
 func (r *SiteReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.Site{}).
+		Owns(&k8sv1alpha1.SiteMeta{}).
+		WithOptions(controllerOptions()).
 		Complete(r)
 }
@@ -50,8 +50,10 @@ func (r *SiteReconciler) SiteToSiteMeta(site *k8sv1alpha1.Site) (*k8sv1alpha1.Si
 		TypeMeta: metav1.TypeMeta{},
 		Spec: k8sv1alpha1.SiteMetaSpec{
 			Imported:            imported,
+			ControllerRef:       site.Spec.ControllerRef,
 			Reclaim:             reclaim,
 			SiteName:            site.Name,
+			NetrisName:          netrisName(site, site.Spec.NetrisName),
 			PublicASN:           site.Spec.PublicASN,
 			RohASN:              site.Spec.RohASN,
 			VMASN:               site.Spec.VMASN,
@@ -105,7 +107,7 @@ func siteUpdateDefaultAnnotations(site *k8sv1alpha1.Site) {
 // SiteMetaToNetris converts the k8s Site resource to Netris type and used for add the Site for Netris API.
 func SiteMetaToNetris(siteMeta *k8sv1alpha1.SiteMeta) (*site.Site, error) {
 	siteAdd := &site.Site{
-		Name:       siteMeta.Spec.SiteName,
+		Name:       siteMeta.Spec.NetrisName,
 		PublicAsn:  siteMeta.Spec.PublicASN,
 		RohAsn:     siteMeta.Spec.RohASN,
 		VMAsn:      siteMeta.Spec.VMASN,
@@ -121,7 +123,7 @@ func SiteMetaToNetris(siteMeta *k8sv1alpha1.SiteMeta) (*site.Site, error) {
 func SiteMetaToNetrisUpdate(siteMeta *k8sv1alpha1.SiteMeta) (*site.Site, error) {
 	siteAdd := &site.Site{
 		ID:         siteMeta.Spec.ID,
-		Name:       siteMeta.Spec.SiteName,
+		Name:       siteMeta.Spec.NetrisName,
 		PublicAsn:  siteMeta.Spec.PublicASN,
 		RohAsn:     siteMeta.Spec.RohASN,
 		VMAsn:      siteMeta.Spec.VMASN,
@@ -134,8 +136,8 @@ func SiteMetaToNetrisUpdate(siteMeta *k8sv1alpha1.SiteMeta) (*site.Site, error)
 }
 
 func compareSiteMetaAPIESite(siteMeta *k8sv1alpha1.SiteMeta, apiSite *site.Site, u uniReconciler) bool {
-	if apiSite.Name != siteMeta.Spec.SiteName {
-		u.DebugLogger.Info("Name changed", "netrisValue", apiSite.Name, "k8sValue", siteMeta.Spec.SiteName)
+	if apiSite.Name != siteMeta.Spec.NetrisName {
+		u.DebugLogger.Info("Name changed", "netrisValue", apiSite.Name, "k8sValue", siteMeta.Spec.NetrisName)
 		return false
 	}
 	if apiSite.PublicAsn != siteMeta.Spec.PublicASN {
@@ -165,3 +167,35 @@ func compareSiteMetaAPIESite(siteMeta *k8sv1alpha1.SiteMeta, apiSite *site.Site,
 
 	return true
 }
+
+// siteDrift returns the difference of the Site spec with the Netris site and
+// sets the spec to the Netris values.
+func siteDrift(spec *k8sv1alpha1.SiteSpec, siteMeta *k8sv1alpha1.SiteMeta, apiSite *site.Site) *drift {
+	d := &drift{}
+	if apiSite.Name != siteMeta.Spec.NetrisName {
+		driftFixed(d, "metadata.name", siteMeta.Spec.NetrisName, apiSite.Name)
+	}
+	if apiSite.PublicAsn != siteMeta.Spec.PublicASN {
+		driftSet(d, "spec.publicAsn", &spec.PublicASN, apiSite.PublicAsn)
+	}
+	if apiSite.RohAsn != siteMeta.Spec.RohASN {
+		driftSet(d, "spec.rohAsn", &spec.RohASN, apiSite.RohAsn)
+	}
+	if apiSite.VMAsn != siteMeta.Spec.VMASN {
+		driftSet(d, "spec.vmAsn", &spec.VMASN, apiSite.VMAsn)
+	}
+	if apiSite.RohProfile != nil && apiSite.RohProfile.ID != siteMeta.Spec.RohRoutingProfileID {
+		for name, id := range routingProfiles {
+			if id == apiSite.RohProfile.ID {
+				driftSet(d, "spec.rohRoutingProfile", &spec.RohRoutingProfile, name)
+			}
+		}
+	}
+	if apiSite.SiteMesh.Value != siteMeta.Spec.SiteMesh {
+		driftSet(d, "spec.siteMesh", &spec.SiteMesh, apiSite.SiteMesh.Value)
+	}
+	if apiSite.AclPolicy != siteMeta.Spec.ACLDefaultPolicy {
+		driftSet(d, "spec.aclDefaultPolicy", &spec.ACLDefaultPolicy, apiSite.AclPolicy)
+	}
+	return d
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"github.com/go-logr/logr"
 	"github.com/netrisai/netriswebapi/http"
@@ -28,20 +29,24 @@ import (
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 )
 
 // SiteMetaReconciler reconciles a SiteMeta object
 type SiteMetaReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=k8s.netris.ai,resources=sitemeta,verbs=get;list;watch;create;update;patch;delete
@@ -50,6 +55,19 @@ type SiteMetaReconciler struct {
 
 // Reconcile is the main reconciler for the appropriate resource type
 func (r *SiteMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.SiteMeta{}, nil)
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *SiteMetaReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	debugLogger := r.Log.WithValues("name", req.NamespacedName).V(int(zapcore.WarnLevel))
 
 	siteMeta := &k8sv1alpha1.SiteMeta{}
@@ -69,7 +87,9 @@ func (r *SiteMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	if siteMeta.DeletionTimestamp != nil {
 		if siteMeta.Spec.ID > 0 && !siteMeta.Spec.Reclaim {
-			reply, err := r.Cred.Site().Delete(siteMeta.Spec.ID)
+			reply, err := netrisapi.Do(r.Cred, "site", "delete", func() (http.HTTPReply, error) {
+				return r.Cred.Site().Delete(siteMeta.Spec.ID)
+			})
 			if err != nil {
 				return ctrl.Result{}, fmt.Errorf("{deleteSite} %s", err)
 			}
@@ -87,7 +107,7 @@ func (r *SiteMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		defer siteCancel()
 		err := r.Update(siteCtx, siteMeta.DeepCopyObject(), &client.UpdateOptions{})
 		if client.IgnoreNotFound(err) != nil {
-			return ctrl.Result{RequeueAfter: requeueInterval}, fmt.Errorf("{DeleteSiteMetaCR Finalizer} %s", err)
+			return ctrl.Result{}, fmt.Errorf("{DeleteSiteMetaCR Finalizer} %s", err)
 		}
 
 		return ctrl.Result{}, nil
@@ -100,7 +120,7 @@ func (r *SiteMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		err := r.Patch(siteCtx, siteMeta.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 		if err != nil {
 			logger.Error(fmt.Errorf("{Patch SiteMeta Finalizer} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
@@ -110,6 +130,7 @@ func (r *SiteMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	provisionState := "OK"
@@ -126,16 +147,24 @@ func (r *SiteMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
+	if paused(siteCR) {
+		return u.patchSiteStatus(siteCR, "Paused", pausedMessage)
+	}
+
 	if siteMeta.DeletionTimestamp != nil {
 		return ctrl.Result{}, nil
 	}
 
+	if siteMeta.Spec.NetrisName == "" {
+		siteMeta.Spec.NetrisName = siteMeta.Spec.SiteName
+	}
+
 	if siteMeta.Spec.ID == 0 {
 		debugLogger.Info("ID Not found in meta")
 		if siteMeta.Spec.Imported {
 			logger.Info("Importing site")
 			debugLogger.Info("Imported yaml mode. Finding Site by name")
-			if site, ok := r.NStorage.SitesStorage.FindByName(siteMeta.Spec.SiteName); ok {
+			if site, ok := r.NStorage.SitesStorage.FindByName(siteMeta.Spec.NetrisName); ok {
 				debugLogger.Info("Imported yaml mode. Site found")
 				siteMeta.Spec.ID = site.ID
 
@@ -147,26 +176,52 @@ func (r *SiteMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 					return u.patchSiteStatus(siteCR, "Failure", err.Error())
 				}
 				debugLogger.Info("Imported yaml mode. ID patched")
+				u.event(siteCR, EventImported, "Site imported from Netris, ID %d", siteMeta.Spec.ID)
 				logger.Info("Site imported")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{}, nil
 			}
 			logger.Info("Site not found for import")
 			debugLogger.Info("Imported yaml mode. Site not found")
 		}
 
+		if msg := u.cacheStale(nil, netrisstorage.KindSites); msg != "" {
+			return u.patchSiteStatus(siteCR, "CacheStale", msg)
+		}
+		_, exists := r.NStorage.SitesStorage.FindByName(siteMeta.Spec.NetrisName)
+		if msg := u.nameCollision(siteMeta, "Site", siteMeta.Spec.NetrisName, exists); msg != "" {
+			u.warning(siteCR, EventNameCollision, "%s", msg)
+			return u.patchSiteStatus(siteCR, "NameCollision", msg)
+		}
 		logger.Info("Creating Site")
 		if _, err, errMsg := r.createSite(siteMeta); err != nil {
 			logger.Error(fmt.Errorf("{createSite} %s", err), "")
+			u.warning(siteCR, EventAPIError, "Couldn't create Site in Netris: %s", errMsg)
 			return u.patchSiteStatus(siteCR, "Failure", errMsg.Error())
 		}
+		siteCR.Status.AppliedGeneration = siteMeta.Spec.SiteCRGeneration
+		u.event(siteCR, EventCreated, "Site created in Netris")
 		logger.Info("Site Created")
 	} else {
 		if apiSite, ok := r.NStorage.SitesStorage.FindByID(siteMeta.Spec.ID); ok {
 
 			debugLogger.Info("Comparing SiteMeta with Netris Site")
+			driftedFields := siteCR.Status.DriftedFields
+			siteCR.Status.DriftedFields = nil
 			if ok := compareSiteMetaAPIESite(siteMeta, apiSite, u); ok {
 				debugLogger.Info("Nothing Changed")
+				siteCR.Status.AppliedGeneration = siteMeta.Spec.SiteCRGeneration
+			} else if keepsDrift(siteCR, siteCR.Status.AppliedGeneration) {
+				spec := siteCR.Spec.DeepCopy()
+				d := siteDrift(spec, siteMeta, apiSite)
+				if driftPolicyOf(siteCR) == driftAdopt && d.adoptable() {
+					siteCR.Spec = *spec
+					return u.adoptDrift(siteCR, "Site", d)
+				}
+				u.reportDrift(siteCR, "Site", d, driftedFields)
+				siteCR.Status.DriftedFields = d.fields
+				return u.patchSiteStatus(siteCR, "Drifted", d.String())
 			} else {
+				recordDrift("Site", siteCR.Generation, siteCR.Status.AppliedGeneration)
 				debugLogger.Info("Go to update Site in Netris")
 				logger.Info("Updating Site")
 				siteUpdate, err := SiteMetaToNetrisUpdate(siteMeta)
@@ -181,18 +236,29 @@ func (r *SiteMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				_, err, errMsg := updateSite(siteMeta.Spec.ID, siteUpdate, r.Cred)
 				if err != nil {
 					logger.Error(fmt.Errorf("{updateSite} %s", err), "")
+					u.warning(siteCR, EventAPIError, "Couldn't update Site in Netris: %s", errMsg)
 					return u.patchSiteStatus(siteCR, "Failure", errMsg.Error())
 				}
+				u.updated(siteCR, siteCR.Generation, siteCR.Status.AppliedGeneration, "Site")
+				siteCR.Status.AppliedGeneration = siteMeta.Spec.SiteCRGeneration
 				logger.Info("Site Updated")
 			}
 		} else {
 			debugLogger.Info("Site not found in Netris")
 			debugLogger.Info("Going to create Site")
+			if msg := u.cacheStale(func() (bool, error) {
+				return existsInNetris(r.Cred, "site", r.Cred.Site().Get, func(item *site.Site) bool { return item.ID == siteMeta.Spec.ID })
+			}, netrisstorage.KindSites); msg != "" {
+				return u.patchSiteStatus(siteCR, "CacheStale", msg)
+			}
 			logger.Info("Creating Site")
 			if _, err, errMsg := r.createSite(siteMeta); err != nil {
 				logger.Error(fmt.Errorf("{createSite} %s", err), "")
+				u.warning(siteCR, EventAPIError, "Couldn't create Site in Netris: %s", errMsg)
 				return u.patchSiteStatus(siteCR, "Failure", errMsg.Error())
 			}
+			siteCR.Status.AppliedGeneration = siteMeta.Spec.SiteCRGeneration
+			u.event(siteCR, EventCreated, "Site created in Netris")
 			logger.Info("Site Created")
 		}
 	}
@@ -213,7 +279,9 @@ func (r *SiteMetaReconciler) createSite(siteMeta *k8sv1alpha1.SiteMeta) (ctrl.Re
 	js, _ := json.Marshal(siteAdd)
 	debugLogger.Info("siteToAdd", "payload", string(js))
 
-	reply, err := r.Cred.Site().Add(siteAdd)
+	reply, err := netrisapi.Create(r.Cred, "site", func() (http.HTTPReply, error) {
+		return r.Cred.Site().Add(siteAdd)
+	})
 	if err != nil {
 		return ctrl.Result{}, err, err
 	}
@@ -250,7 +318,9 @@ func (r *SiteMetaReconciler) createSite(siteMeta *k8sv1alpha1.SiteMeta) (ctrl.Re
 }
 
 func updateSite(id int, site *site.Site, cred *api.Clientset) (ctrl.Result, error, error) {
-	reply, err := cred.Site().Update(id, site)
+	reply, err := netrisapi.Do(cred, "site", "update", func() (http.HTTPReply, error) {
+		return cred.Site().Update(id, site)
+	})
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("{updateSite} %s", err), err
 	}
@@ -267,7 +337,15 @@ func updateSite(id int, site *site.Site, cred *api.Clientset) (ctrl.Result, erro
 
 // SetupWithManager .
 func (r *SiteMetaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	src, hdl, err := watchStorage(mgr, r.Controllers.storageListeners(r.NStorage), &k8sv1alpha1.SiteMeta{}, &k8sv1alpha1.SiteMetaList{}, func(o runtime.Object) string {
+		return strconv.Itoa(o.(*k8sv1alpha1.SiteMeta).Spec.ID)
+	}, netrisstorage.KindSites)
+	if err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.SiteMeta{}).
+		WithOptions(controllerOptions()).
+		Watches(src, hdl).
 		Complete(r)
 }
@@ -23,11 +23,13 @@ import (
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -36,10 +38,12 @@ import (
 // SoftgateReconciler reconciles a Softgate object
 type SoftgateReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.netris.ai,resources=softgates,verbs=get;list;watch;create;update;patch;delete
@@ -56,6 +60,19 @@ type SoftgateReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (r *SoftgateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.Softgate{}, &k8sv1alpha1.SoftgateMeta{})
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *SoftgateReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	logger := r.Log.WithValues("name", req.NamespacedName)
 	debugLogger := logger.V(int(zapcore.WarnLevel))
 	softgate := &k8sv1alpha1.Softgate{}
@@ -66,6 +83,7 @@ func (r *SoftgateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	softgateCtx, softgateCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -95,13 +113,29 @@ func (r *SoftgateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 	}
 
+	if paused(softgate) {
+		if metaFound {
+			if err := u.annotateMeta(softgate, softgateMeta); err != nil {
+				logger.Error(err, "")
+				return ctrl.Result{Requeue: true}, nil
+			}
+		}
+		return u.patchSoftgateStatus(softgate, "Paused", pausedMessage)
+	}
+
 	if softgate.DeletionTimestamp != nil {
+		if msg := deletionBlocked(softgate, false); msg != "" {
+			u.warning(softgate, EventDeleteBlocked, "%s", msg)
+			return u.patchSoftgateStatus(softgate, "DeletionBlocked", msg)
+		}
 		logger.Info("Go to delete")
 		_, err := r.deleteSoftgate(softgate, softgateMeta)
 		if err != nil {
 			logger.Error(fmt.Errorf("{deleteSoftgate} %s", err), "")
+			u.warning(softgate, EventDeleteBlocked, "Couldn't delete Softgate from Netris: %s", err)
 			return u.patchSoftgateStatus(softgate, "Failure", err.Error())
 		}
+		u.event(softgate, EventDeleted, "Softgate deleted from Netris")
 		logger.Info("Softgate deleted")
 		return ctrl.Result{}, nil
 	}
@@ -114,14 +148,14 @@ func (r *SoftgateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		err := r.Patch(softgatePatchCtx, softgate.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 		if err != nil {
 			logger.Error(fmt.Errorf("{Patch Softgate default annotations} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 		return ctrl.Result{}, nil
 	}
 
 	if metaFound {
 		debugLogger.Info("Meta found")
-		if softgateCompareFieldsForNewMeta(softgate, softgateMeta) {
+		if ownerChanged, annotationsChanged := setOwner(softgate, softgateMeta, r.Scheme), syncAnnotations(softgate, softgateMeta); softgateCompareFieldsForNewMeta(softgate, softgateMeta) || ownerChanged || annotationsChanged {
 			debugLogger.Info("Generating New Meta")
 			softgateID := softgateMeta.Spec.ID
 			newSoftgateMeta, err := r.SoftgateToSoftgateMeta(softgate)
@@ -129,8 +163,16 @@ func (r *SoftgateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				logger.Error(fmt.Errorf("{SoftgateToSoftgateMeta} %s", err), "")
 				return u.patchSoftgateStatus(softgate, "Failure", err.Error())
 			}
+			// the Netris controller of an existing resource never changes.
+			controllerRef := softgateMeta.Spec.ControllerRef
+			currentName := softgateMeta.Spec.NetrisName
+			if currentName == "" {
+				currentName = softgateMeta.Spec.SoftgateName
+			}
 			softgateMeta.Spec = newSoftgateMeta.DeepCopy().Spec
 			softgateMeta.Spec.ID = softgateID
+			softgateMeta.Spec.ControllerRef = controllerRef
+			softgateMeta.Spec.NetrisName = keptNetrisName(softgate.Spec.NetrisName, currentName, softgateMeta.Spec.NetrisName, softgateID)
 			softgateMeta.Spec.SoftgateCRGeneration = softgate.GetGeneration()
 
 			softgateMetaUpdateCtx, softgateMetaUpdateCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -138,7 +180,7 @@ func (r *SoftgateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err = r.Update(softgateMetaUpdateCtx, softgateMeta.DeepCopyObject(), &client.UpdateOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{softgateMeta Update} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 		}
 	} else {
@@ -151,7 +193,7 @@ func (r *SoftgateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err := r.Patch(softgatePatchCtx, softgate.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{Patch Softgate Finalizer} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 			return ctrl.Result{}, nil
 		}
@@ -164,20 +206,24 @@ func (r *SoftgateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 		softgateMeta.Spec.SoftgateCRGeneration = softgate.GetGeneration()
 
+		setOwner(softgate, softgateMeta, r.Scheme)
+		syncAnnotations(softgate, softgateMeta)
 		softgateMetaCreateCtx, softgateMetaCreateCancel := context.WithTimeout(cntxt, contextTimeout)
 		defer softgateMetaCreateCancel()
 		if err := r.Create(softgateMetaCreateCtx, softgateMeta.DeepCopyObject(), &client.CreateOptions{}); err != nil {
 			logger.Error(fmt.Errorf("{softgateMeta Create} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (r *SoftgateReconciler) deleteSoftgate(softgate *k8sv1alpha1.Softgate, softgateMeta *k8sv1alpha1.SoftgateMeta) (ctrl.Result, error) {
 	if softgateMeta != nil && softgateMeta.Spec.ID > 0 && !softgateMeta.Spec.Reclaim {
-		reply, err := r.Cred.Inventory().Delete("softgate", softgateMeta.Spec.ID)
+		reply, err := netrisapi.Do(r.Cred, "inventory", "delete", func() (http.HTTPReply, error) {
+			return r.Cred.Inventory().Delete("softgate", softgateMeta.Spec.ID)
+		})
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("{deleteSoftgate} %s", err)
 		}
@@ -229,5 +275,7 @@ func (r *SoftgateReconciler) deleteSoftgateMetaCR(softgateMeta *k8sv1alpha1.Soft
 func (r *SoftgateReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.Softgate{}).
+		Owns(&k8sv1alpha1.SoftgateMeta{}).
+		WithOptions(controllerOptions()).
 		Complete(r)
 }
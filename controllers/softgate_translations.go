@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netriswebapi/v2/types/inventory"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -53,7 +54,7 @@ func (r *SoftgateReconciler) SoftgateToSoftgateMeta(softgate *k8sv1alpha1.Softga
 	}
 
 	profileID := 0
-	profiles, err := r.Cred.InventoryProfile().Get()
+	profiles, err := netrisapi.Get(r.Cred, "inventoryprofile", "list", r.Cred.InventoryProfile().Get)
 	if err != nil {
 		return nil, err
 	}
@@ -75,15 +76,17 @@ func (r *SoftgateReconciler) SoftgateToSoftgateMeta(softgate *k8sv1alpha1.Softga
 		},
 		TypeMeta: metav1.TypeMeta{},
 		Spec: k8sv1alpha1.SoftgateMetaSpec{
-			Imported:     imported,
-			Reclaim:      reclaim,
-			SoftgateName: softgate.Name,
-			Description:  softgate.Spec.Description,
-			TenantID:     tenantID,
-			SiteID:       siteID,
-			ProfileID:    profileID,
-			MainIP:       softgate.Spec.MainIP,
-			MgmtIP:       softgate.Spec.MgmtIP,
+			Imported:      imported,
+			ControllerRef: softgate.Spec.ControllerRef,
+			Reclaim:       reclaim,
+			SoftgateName:  softgate.Name,
+			NetrisName:    netrisName(softgate, softgate.Spec.NetrisName),
+			Description:   softgate.Spec.Description,
+			TenantID:      tenantID,
+			SiteID:        siteID,
+			ProfileID:     profileID,
+			MainIP:        softgate.Spec.MainIP,
+			MgmtIP:        softgate.Spec.MgmtIP,
 		},
 	}
 
@@ -141,7 +144,7 @@ func SoftgateMetaToNetris(softgateMeta *k8sv1alpha1.SoftgateMeta) (*inventory.HW
 	}
 
 	softgateAdd := &inventory.HWSoftgate{
-		Name:        softgateMeta.Spec.SoftgateName,
+		Name:        softgateMeta.Spec.NetrisName,
 		Description: softgateMeta.Spec.Description,
 		Tenant:      inventory.IDName{ID: softgateMeta.Spec.TenantID},
 		Site:        inventory.IDName{ID: softgateMeta.Spec.SiteID},
@@ -167,7 +170,7 @@ func SoftgateMetaToNetrisUpdate(softgateMeta *k8sv1alpha1.SoftgateMeta) (*invent
 	}
 
 	softgateUpdate := &inventory.HWSoftgateUpdate{
-		Name:        softgateMeta.Spec.SoftgateName,
+		Name:        softgateMeta.Spec.NetrisName,
 		Description: softgateMeta.Spec.Description,
 		Tenant:      inventory.IDName{ID: softgateMeta.Spec.TenantID},
 		Site:        inventory.IDName{ID: softgateMeta.Spec.SiteID},
@@ -181,8 +184,8 @@ func SoftgateMetaToNetrisUpdate(softgateMeta *k8sv1alpha1.SoftgateMeta) (*invent
 }
 
 func compareSoftgateMetaAPIESoftgate(softgateMeta *k8sv1alpha1.SoftgateMeta, apiSoftgate *inventory.HW, u uniReconciler) bool {
-	if apiSoftgate.Name != softgateMeta.Spec.SoftgateName {
-		u.DebugLogger.Info("Name changed", "netrisValue", apiSoftgate.Name, "k8sValue", softgateMeta.Spec.SoftgateName)
+	if apiSoftgate.Name != softgateMeta.Spec.NetrisName {
+		u.DebugLogger.Info("Name changed", "netrisValue", apiSoftgate.Name, "k8sValue", softgateMeta.Spec.NetrisName)
 		return false
 	}
 
@@ -218,3 +221,31 @@ func compareSoftgateMetaAPIESoftgate(softgateMeta *k8sv1alpha1.SoftgateMeta, api
 
 	return true
 }
+
+// softgateDrift returns the difference of the Softgate spec with the Netris
+// softgate and sets the spec to the Netris values.
+func softgateDrift(spec *k8sv1alpha1.SoftgateSpec, softgateMeta *k8sv1alpha1.SoftgateMeta, apiSoftgate *inventory.HW) *drift {
+	d := &drift{}
+	if apiSoftgate.Name != softgateMeta.Spec.NetrisName {
+		driftFixed(d, "metadata.name", softgateMeta.Spec.NetrisName, apiSoftgate.Name)
+	}
+	if apiSoftgate.Description != softgateMeta.Spec.Description {
+		driftSet(d, "spec.description", &spec.Description, apiSoftgate.Description)
+	}
+	if apiSoftgate.Tenant.ID != softgateMeta.Spec.TenantID {
+		driftSet(d, "spec.tenant", &spec.Tenant, apiSoftgate.Tenant.Name)
+	}
+	if apiSoftgate.Site.ID != softgateMeta.Spec.SiteID {
+		driftSet(d, "spec.site", &spec.Site, apiSoftgate.Site.Name)
+	}
+	if apiSoftgate.Profile.ID != softgateMeta.Spec.ProfileID {
+		driftSet(d, "spec.profile", &spec.Profile, apiSoftgate.Profile.Name)
+	}
+	if apiSoftgate.MainIP.Address != softgateMeta.Spec.MainIP {
+		driftSet(d, "spec.mainIp", &spec.MainIP, apiSoftgate.MainIP.Address)
+	}
+	if apiSoftgate.MgmtIP.Address != softgateMeta.Spec.MgmtIP {
+		driftSet(d, "spec.mgmtIp", &spec.MgmtIP, apiSoftgate.MgmtIP.Address)
+	}
+	return d
+}
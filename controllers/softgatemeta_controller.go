@@ -20,16 +20,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -39,10 +42,12 @@ import (
 // SoftgateMetaReconciler reconciles a SoftgateMeta object
 type SoftgateMetaReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.netris.ai,resources=softgatemeta,verbs=get;list;watch;create;update;patch;delete
@@ -59,6 +64,19 @@ type SoftgateMetaReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (r *SoftgateMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.SoftgateMeta{}, nil)
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *SoftgateMetaReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	debugLogger := r.Log.WithValues("name", req.NamespacedName).V(int(zapcore.WarnLevel))
 
 	softgateMeta := &k8sv1alpha1.SoftgateMeta{}
@@ -82,6 +100,7 @@ func (r *SoftgateMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	provisionState := "OK"
@@ -98,16 +117,24 @@ func (r *SoftgateMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 		return ctrl.Result{}, err
 	}
 
+	if paused(softgateCR) {
+		return u.patchSoftgateStatus(softgateCR, "Paused", pausedMessage)
+	}
+
 	if softgateMeta.DeletionTimestamp != nil {
 		return ctrl.Result{}, nil
 	}
 
+	if softgateMeta.Spec.NetrisName == "" {
+		softgateMeta.Spec.NetrisName = softgateMeta.Spec.SoftgateName
+	}
+
 	if softgateMeta.Spec.ID == 0 {
 		debugLogger.Info("ID Not found in meta")
 		if softgateMeta.Spec.Imported {
 			logger.Info("Importing softgate")
 			debugLogger.Info("Imported yaml mode. Finding Softgate by name")
-			if softgate, ok := r.NStorage.HWsStorage.FindSoftgateByName(softgateMeta.Spec.SoftgateName); ok {
+			if softgate, ok := r.NStorage.HWsStorage.FindSoftgateByName(softgateMeta.Spec.NetrisName); ok {
 				debugLogger.Info("Imported yaml mode. Softgate found")
 				softgateMeta.Spec.ID = softgate.ID
 				softgateMeta.Spec.MainIP = softgate.MainIP.Address
@@ -121,18 +148,30 @@ func (r *SoftgateMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 					return u.patchSoftgateStatus(softgateCR, "Failure", err.Error())
 				}
 				debugLogger.Info("Imported yaml mode. ID patched")
+				u.event(softgateCR, EventImported, "Softgate imported from Netris, ID %d", softgateMeta.Spec.ID)
 				logger.Info("Softgate imported")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{}, nil
 			}
 			logger.Info("Softgate not found for import")
 			debugLogger.Info("Imported yaml mode. Softgate not found")
 		}
 
+		if msg := u.cacheStale(nil, netrisstorage.KindHWs); msg != "" {
+			return u.patchSoftgateStatus(softgateCR, "CacheStale", msg)
+		}
+		_, exists := r.NStorage.HWsStorage.FindSoftgateByName(softgateMeta.Spec.NetrisName)
+		if msg := u.nameCollision(softgateMeta, "Softgate", softgateMeta.Spec.NetrisName, exists); msg != "" {
+			u.warning(softgateCR, EventNameCollision, "%s", msg)
+			return u.patchSoftgateStatus(softgateCR, "NameCollision", msg)
+		}
 		logger.Info("Creating Softgate")
 		if _, err, errMsg := r.createSoftgate(softgateMeta); err != nil {
 			logger.Error(fmt.Errorf("{createSoftgate} %s", err), "")
+			u.warning(softgateCR, EventAPIError, "Couldn't create Softgate in Netris: %s", errMsg)
 			return u.patchSoftgateStatus(softgateCR, "Failure", errMsg.Error())
 		}
+		softgateCR.Status.AppliedGeneration = softgateMeta.Spec.SoftgateCRGeneration
+		u.event(softgateCR, EventCreated, "Softgate created in Netris")
 		logger.Info("Softgate Created")
 	} else {
 		if apiSoftgate, ok := r.NStorage.HWsStorage.FindSoftgateByID(softgateMeta.Spec.ID); ok {
@@ -145,9 +184,23 @@ func (r *SoftgateMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 				softgateMeta.Spec.MgmtIP = apiSoftgate.MgmtIP.Address
 			}
 
+			driftedFields := softgateCR.Status.DriftedFields
+			softgateCR.Status.DriftedFields = nil
 			if ok := compareSoftgateMetaAPIESoftgate(softgateMeta, apiSoftgate, u); ok {
 				debugLogger.Info("Nothing Changed")
+				softgateCR.Status.AppliedGeneration = softgateMeta.Spec.SoftgateCRGeneration
+			} else if keepsDrift(softgateCR, softgateCR.Status.AppliedGeneration) {
+				spec := softgateCR.Spec.DeepCopy()
+				d := softgateDrift(spec, softgateMeta, apiSoftgate)
+				if driftPolicyOf(softgateCR) == driftAdopt && d.adoptable() {
+					softgateCR.Spec = *spec
+					return u.adoptDrift(softgateCR, "Softgate", d)
+				}
+				u.reportDrift(softgateCR, "Softgate", d, driftedFields)
+				softgateCR.Status.DriftedFields = d.fields
+				return u.patchSoftgateStatus(softgateCR, "Drifted", d.String())
 			} else {
+				recordDrift("Softgate", softgateCR.Generation, softgateCR.Status.AppliedGeneration)
 				debugLogger.Info("Go to update Softgate in Netris")
 				logger.Info("Updating Softgate")
 				softgateUpdate, err := SoftgateMetaToNetrisUpdate(softgateMeta)
@@ -162,18 +215,29 @@ func (r *SoftgateMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 				_, err, errMsg := updateSoftgate(softgateMeta.Spec.ID, softgateUpdate, r.Cred)
 				if err != nil {
 					logger.Error(fmt.Errorf("{updateSoftgate} %s", err), "")
+					u.warning(softgateCR, EventAPIError, "Couldn't update Softgate in Netris: %s", errMsg)
 					return u.patchSoftgateStatus(softgateCR, "Failure", errMsg.Error())
 				}
+				u.updated(softgateCR, softgateCR.Generation, softgateCR.Status.AppliedGeneration, "Softgate")
+				softgateCR.Status.AppliedGeneration = softgateMeta.Spec.SoftgateCRGeneration
 				logger.Info("Softgate Updated")
 			}
 		} else {
 			debugLogger.Info("Softgate not found in Netris")
 			debugLogger.Info("Going to create Softgate")
+			if msg := u.cacheStale(func() (bool, error) {
+				return existsInNetris(r.Cred, "inventory", r.Cred.Inventory().Get, func(item *inventory.HW) bool { return item.ID == softgateMeta.Spec.ID })
+			}, netrisstorage.KindHWs); msg != "" {
+				return u.patchSoftgateStatus(softgateCR, "CacheStale", msg)
+			}
 			logger.Info("Creating Softgate")
 			if _, err, errMsg := r.createSoftgate(softgateMeta); err != nil {
 				logger.Error(fmt.Errorf("{createSoftgate} %s", err), "")
+				u.warning(softgateCR, EventAPIError, "Couldn't create Softgate in Netris: %s", errMsg)
 				return u.patchSoftgateStatus(softgateCR, "Failure", errMsg.Error())
 			}
+			softgateCR.Status.AppliedGeneration = softgateMeta.Spec.SoftgateCRGeneration
+			u.event(softgateCR, EventCreated, "Softgate created in Netris")
 			logger.Info("Softgate Created")
 		}
 	}
@@ -200,7 +264,9 @@ func (r *SoftgateMetaReconciler) createSoftgate(softgateMeta *k8sv1alpha1.Softga
 	js, _ := json.Marshal(softgateAdd)
 	debugLogger.Info("softgateToAdd", "payload", string(js))
 
-	reply, err := r.Cred.Inventory().AddSoftgate(softgateAdd)
+	reply, err := netrisapi.Create(r.Cred, "inventory", func() (http.HTTPReply, error) {
+		return r.Cred.Inventory().AddSoftgate(softgateAdd)
+	})
 	if err != nil {
 		return ctrl.Result{}, err, err
 	}
@@ -236,7 +302,9 @@ func (r *SoftgateMetaReconciler) createSoftgate(softgateMeta *k8sv1alpha1.Softga
 }
 
 func updateSoftgate(id int, softgate *inventory.HWSoftgateUpdate, cred *api.Clientset) (ctrl.Result, error, error) {
-	reply, err := cred.Inventory().UpdateSoftgate(id, softgate)
+	reply, err := netrisapi.Do(cred, "inventory", "update", func() (http.HTTPReply, error) {
+		return cred.Inventory().UpdateSoftgate(id, softgate)
+	})
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("{updateSoftgate} %s", err), err
 	}
@@ -253,8 +321,16 @@ func updateSoftgate(id int, softgate *inventory.HWSoftgateUpdate, cred *api.Clie
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SoftgateMetaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	src, hdl, err := watchStorage(mgr, r.Controllers.storageListeners(r.NStorage), &k8sv1alpha1.SoftgateMeta{}, &k8sv1alpha1.SoftgateMetaList{}, func(o runtime.Object) string {
+		return strconv.Itoa(o.(*k8sv1alpha1.SoftgateMeta).Spec.ID)
+	}, netrisstorage.KindHWs)
+	if err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.SoftgateMeta{}).
+		WithOptions(controllerOptions()).
+		Watches(src, hdl).
 		Complete(r)
 }
 
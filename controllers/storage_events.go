@@ -0,0 +1,162 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisstorage"
+)
+
+const (
+	// metaIDField is the field index of the NetrisController and the Netris
+	// object ID of the Meta CRs, see storageKey.
+	metaIDField = "spec.controllerRef/spec.id"
+
+	storageEventBuffer = 1024
+)
+
+// storageListener receives the deltas of the storage of the named
+// NetrisController, "" is the controller configured for the operator.
+type storageListener func(controller string, delta netrisstorage.Delta)
+
+// storageListeners is where the storage listeners are registered, a single
+// storage or the storages of all Netris controllers.
+type storageListeners interface {
+	addListener(listener storageListener)
+}
+
+// defaultStorage is the storage of the controller configured for the operator.
+type defaultStorage struct {
+	nStorage *netrisstorage.Storage
+}
+
+func (d defaultStorage) addListener(listener storageListener) {
+	d.nStorage.AddListener(bindListener("", listener))
+}
+
+// bindListener returns the netrisstorage listener of the storage of controller.
+func bindListener(controller string, listener storageListener) netrisstorage.Listener {
+	return func(delta netrisstorage.Delta) {
+		listener(controller, delta)
+	}
+}
+
+// storageKey identifies a Netris object across the NetrisControllers, the
+// same ID can be used by the objects of different controllers.
+func storageKey(controller, id string) string {
+	return controller + "/" + id
+}
+
+// storageEvents turns the netrisstorage deltas of the given kinds into
+// generic events. The event namespace is the NetrisController and the name
+// is the Netris object ID, the kinds of one channel never share an ID.
+func storageEvents(nStorage storageListeners, kinds ...string) <-chan event.GenericEvent {
+	events := make(chan event.GenericEvent, storageEventBuffer)
+	nStorage.addListener(func(controller string, delta netrisstorage.Delta) {
+		if !containsString(kinds, delta.Kind) {
+			return
+		}
+		ids := append(append(append([]int{}, delta.Added...), delta.Changed...), delta.Removed...)
+		for _, id := range ids {
+			evt := event.GenericEvent{Meta: &metav1.ObjectMeta{Namespace: controller, Name: strconv.Itoa(id)}}
+			select {
+			case events <- evt:
+			default:
+				// the periodic requeue still picks the change up.
+				ctrl.Log.WithName("StorageEvents").Info("Event buffer is full, dropping", "controller", controller, "kind", delta.Kind, "id", id)
+			}
+		}
+	})
+	return events
+}
+
+// watchStorage indexes the Meta CRs of the list type by their NetrisController
+// and the Netris object ID returned by idOf and returns the source and the
+// handler, which enqueue the Meta CRs whose Netris objects were changed. If
+// idOf is nil, every change enqueues all Meta CRs of the list type which are
+// managed by the controller of the changed storage.
+func watchStorage(mgr ctrl.Manager, nStorage storageListeners, obj, list runtime.Object, idOf func(runtime.Object) string, kinds ...string) (source.Source, handler.EventHandler, error) {
+	if idOf != nil {
+		if err := mgr.GetFieldIndexer().IndexField(context.Background(), obj, metaIDField, func(o runtime.Object) []string {
+			return []string{storageKey(controllerRefOf(o), idOf(o))}
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	src := &source.Channel{Source: storageEvents(nStorage, kinds...)}
+	hdl := &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+			l := list.DeepCopyObject()
+			opts := []client.ListOption{}
+			if idOf != nil {
+				opts = append(opts, client.MatchingFields{metaIDField: storageKey(o.Meta.GetNamespace(), o.Meta.GetName())})
+			}
+			ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
+			defer cancel()
+			if err := mgr.GetClient().List(ctx, l, opts...); err != nil {
+				return nil
+			}
+			requests := []reconcile.Request{}
+			_ = meta.EachListItem(l, func(item runtime.Object) error {
+				if controllerRefOf(item) != o.Meta.GetNamespace() {
+					return nil
+				}
+				m, err := meta.Accessor(item)
+				if err != nil {
+					return err
+				}
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: m.GetNamespace(), Name: m.GetName()}})
+				return nil
+			})
+			return requests
+		}),
+	}
+	return src, hdl, nil
+}
+
+// controllerRefOf returns the NetrisController of a Meta CR, "" is the
+// controller configured for the operator.
+func controllerRefOf(o runtime.Object) string {
+	if r, ok := o.(k8sv1alpha1.ControllerReferrer); ok {
+		return r.GetControllerRef()
+	}
+	return ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
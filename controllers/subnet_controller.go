@@ -23,11 +23,13 @@ import (
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -36,10 +38,12 @@ import (
 // SubnetReconciler reconciles a Subnet object
 type SubnetReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.netris.ai,resources=subnets,verbs=get;list;watch;create;update;patch;delete
@@ -53,6 +57,19 @@ type SubnetReconciler struct {
 // perform operations to make the cluster state reflect the state specified by
 // the user.
 func (r *SubnetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.Subnet{}, &k8sv1alpha1.SubnetMeta{})
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *SubnetReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	logger := r.Log.WithValues("name", req.NamespacedName)
 	debugLogger := logger.V(int(zapcore.WarnLevel))
 	subnet := &k8sv1alpha1.Subnet{}
@@ -63,6 +80,7 @@ func (r *SubnetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	subnetCtx, subnetCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -92,13 +110,29 @@ func (r *SubnetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 	}
 
+	if paused(subnet) {
+		if metaFound {
+			if err := u.annotateMeta(subnet, subnetMeta); err != nil {
+				logger.Error(err, "")
+				return ctrl.Result{Requeue: true}, nil
+			}
+		}
+		return u.patchSubnetStatus(subnet, "Paused", pausedMessage)
+	}
+
 	if subnet.DeletionTimestamp != nil {
+		if msg := deletionBlocked(subnet, false); msg != "" {
+			u.warning(subnet, EventDeleteBlocked, "%s", msg)
+			return u.patchSubnetStatus(subnet, "DeletionBlocked", msg)
+		}
 		logger.Info("Go to delete")
 		_, err := r.deleteSubnet(subnet, subnetMeta)
 		if err != nil {
 			logger.Error(fmt.Errorf("{deleteSubnet} %s", err), "")
+			u.warning(subnet, EventDeleteBlocked, "Couldn't delete Subnet from Netris: %s", err)
 			return u.patchSubnetStatus(subnet, "Failure", err.Error())
 		}
+		u.event(subnet, EventDeleted, "Subnet deleted from Netris")
 		logger.Info("Subnet deleted")
 		return ctrl.Result{}, nil
 	}
@@ -111,14 +145,14 @@ func (r *SubnetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		err := r.Patch(subnetPatchCtx, subnet.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 		if err != nil {
 			logger.Error(fmt.Errorf("{Patch Subnet default annotations} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 		return ctrl.Result{}, nil
 	}
 
 	if metaFound {
 		debugLogger.Info("Meta found")
-		if subnetCompareFieldsForNewMeta(subnet, subnetMeta) {
+		if ownerChanged, annotationsChanged := setOwner(subnet, subnetMeta, r.Scheme), syncAnnotations(subnet, subnetMeta); subnetCompareFieldsForNewMeta(subnet, subnetMeta) || ownerChanged || annotationsChanged {
 			debugLogger.Info("Generating New Meta")
 			subnetID := subnetMeta.Spec.ID
 			newSubnetMeta, err := r.SubnetToSubnetMeta(subnet)
@@ -126,8 +160,16 @@ func (r *SubnetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				logger.Error(fmt.Errorf("{SubnetToSubnetMeta} %s", err), "")
 				return u.patchSubnetStatus(subnet, "Failure", err.Error())
 			}
+			// the Netris controller of an existing resource never changes.
+			controllerRef := subnetMeta.Spec.ControllerRef
+			currentName := subnetMeta.Spec.NetrisName
+			if currentName == "" {
+				currentName = subnetMeta.Spec.SubnetName
+			}
 			subnetMeta.Spec = newSubnetMeta.DeepCopy().Spec
 			subnetMeta.Spec.ID = subnetID
+			subnetMeta.Spec.ControllerRef = controllerRef
+			subnetMeta.Spec.NetrisName = keptNetrisName(subnet.Spec.NetrisName, currentName, subnetMeta.Spec.NetrisName, subnetID)
 			subnetMeta.Spec.SubnetCRGeneration = subnet.GetGeneration()
 
 			subnetMetaUpdateCtx, subnetMetaUpdateCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -135,7 +177,7 @@ func (r *SubnetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err = r.Update(subnetMetaUpdateCtx, subnetMeta.DeepCopyObject(), &client.UpdateOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{subnetMeta Update} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 		}
 	} else {
@@ -148,7 +190,7 @@ func (r *SubnetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err := r.Patch(subnetPatchCtx, subnet.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{Patch Subnet Finalizer} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 			return ctrl.Result{}, nil
 		}
@@ -161,20 +203,24 @@ func (r *SubnetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 		subnetMeta.Spec.SubnetCRGeneration = subnet.GetGeneration()
 
+		setOwner(subnet, subnetMeta, r.Scheme)
+		syncAnnotations(subnet, subnetMeta)
 		subnetMetaCreateCtx, subnetMetaCreateCancel := context.WithTimeout(cntxt, contextTimeout)
 		defer subnetMetaCreateCancel()
 		if err := r.Create(subnetMetaCreateCtx, subnetMeta.DeepCopyObject(), &client.CreateOptions{}); err != nil {
 			logger.Error(fmt.Errorf("{subnetMeta Create} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (r *SubnetReconciler) deleteSubnet(subnet *k8sv1alpha1.Subnet, subnetMeta *k8sv1alpha1.SubnetMeta) (ctrl.Result, error) {
 	if subnetMeta != nil && subnetMeta.Spec.ID > 0 && !subnetMeta.Spec.Reclaim {
-		reply, err := r.Cred.IPAM().Delete("subnet", subnetMeta.Spec.ID)
+		reply, err := netrisapi.Do(r.Cred, "ipam", "delete", func() (http.HTTPReply, error) {
+			return r.Cred.IPAM().Delete("subnet", subnetMeta.Spec.ID)
+		})
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("{deleteSubnet} %s", err)
 		}
@@ -226,5 +272,7 @@ func (r *SubnetReconciler) deleteSubnetMetaCR(subnetMeta *k8sv1alpha1.SubnetMeta
 func (r *SubnetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.Subnet{}).
+		Owns(&k8sv1alpha1.SubnetMeta{}).
+		WithOptions(controllerOptions()).
 		Complete(r)
 }
@@ -63,8 +63,10 @@ func (r *SubnetReconciler) SubnetToSubnetMeta(subnet *k8sv1alpha1.Subnet) (*k8sv
 		TypeMeta: metav1.TypeMeta{},
 		Spec: k8sv1alpha1.SubnetMetaSpec{
 			Imported:       imported,
+			ControllerRef:  subnet.Spec.ControllerRef,
 			Reclaim:        reclaim,
 			SubnetName:     subnet.Name,
+			NetrisName:     netrisName(subnet, subnet.Spec.NetrisName),
 			Prefix:         subnet.Spec.Prefix,
 			TenantID:       tenantID,
 			Purpose:        subnet.Spec.Purpose,
@@ -121,7 +123,7 @@ func SubnetMetaToNetris(subnetMeta *k8sv1alpha1.SubnetMeta) (*ipam.Subnet, error
 		sites = append(sites, ipam.IDName{ID: site})
 	}
 	subnetAdd := &ipam.Subnet{
-		Name:           subnetMeta.Spec.SubnetName,
+		Name:           subnetMeta.Spec.NetrisName,
 		Prefix:         subnetMeta.Spec.Prefix,
 		Tenant:         ipam.IDName{ID: subnetMeta.Spec.TenantID},
 		Purpose:        subnetMeta.Spec.Purpose,
@@ -140,7 +142,7 @@ func SubnetMetaToNetrisUpdate(subnetMeta *k8sv1alpha1.SubnetMeta) (*ipam.Subnet,
 		sites = append(sites, ipam.IDName{ID: site})
 	}
 	subnetAdd := &ipam.Subnet{
-		Name:           subnetMeta.Spec.SubnetName,
+		Name:           subnetMeta.Spec.NetrisName,
 		Prefix:         subnetMeta.Spec.Prefix,
 		Tenant:         ipam.IDName{ID: subnetMeta.Spec.TenantID},
 		Purpose:        subnetMeta.Spec.Purpose,
@@ -152,8 +154,8 @@ func SubnetMetaToNetrisUpdate(subnetMeta *k8sv1alpha1.SubnetMeta) (*ipam.Subnet,
 }
 
 func compareSubnetMetaAPIESubnet(subnetMeta *k8sv1alpha1.SubnetMeta, apiSubnet *ipam.IPAM, u uniReconciler) bool {
-	if apiSubnet.Name != subnetMeta.Spec.SubnetName {
-		u.DebugLogger.Info("Name changed", "netrisValue", apiSubnet.Name, "k8sValue", subnetMeta.Spec.SubnetName)
+	if apiSubnet.Name != subnetMeta.Spec.NetrisName {
+		u.DebugLogger.Info("Name changed", "netrisValue", apiSubnet.Name, "k8sValue", subnetMeta.Spec.NetrisName)
 		return false
 	}
 
@@ -180,6 +182,32 @@ func compareSubnetMetaAPIESubnet(subnetMeta *k8sv1alpha1.SubnetMeta, apiSubnet *
 	return true
 }
 
+// subnetDrift returns the difference of the Subnet spec with the Netris
+// subnet and sets the spec to the Netris values.
+func subnetDrift(spec *k8sv1alpha1.SubnetSpec, subnetMeta *k8sv1alpha1.SubnetMeta, apiSubnet *ipam.IPAM) *drift {
+	d := &drift{}
+	if apiSubnet.Name != subnetMeta.Spec.NetrisName {
+		driftFixed(d, "metadata.name", subnetMeta.Spec.NetrisName, apiSubnet.Name)
+	}
+	if apiSubnet.Prefix != subnetMeta.Spec.Prefix {
+		driftSet(d, "spec.prefix", &spec.Prefix, apiSubnet.Prefix)
+	}
+	if apiSubnet.Purpose != subnetMeta.Spec.Purpose {
+		driftSet(d, "spec.purpose", &spec.Purpose, apiSubnet.Purpose)
+	}
+	if apiSubnet.DefaultGateway != subnetMeta.Spec.DefaultGateway {
+		driftSet(d, "spec.defaultGateway", &spec.DefaultGateway, apiSubnet.DefaultGateway)
+	}
+	if ok := compareSubnetMetaSiteAPISubnetSite(subnetMeta.Spec.Sites, apiSubnet.Sites); !ok {
+		sites := []string{}
+		for _, site := range apiSubnet.Sites {
+			sites = append(sites, site.Name)
+		}
+		driftSet(d, "spec.sites", &spec.Sites, sites)
+	}
+	return d
+}
+
 func compareSubnetMetaSiteAPISubnetSite(subnetMetaSites []int, apiSubnetSites []ipam.IDName) bool {
 	apiSites := []int{}
 
@@ -20,15 +20,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -38,10 +41,12 @@ import (
 // SubnetMetaReconciler reconciles a SubnetMeta object
 type SubnetMetaReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.netris.ai,resources=subnetmeta,verbs=get;list;watch;create;update;patch;delete
@@ -58,6 +63,19 @@ type SubnetMetaReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (r *SubnetMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.SubnetMeta{}, nil)
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *SubnetMetaReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	debugLogger := r.Log.WithValues("name", req.NamespacedName).V(int(zapcore.WarnLevel))
 
 	subnetMeta := &k8sv1alpha1.SubnetMeta{}
@@ -81,6 +99,7 @@ func (r *SubnetMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	provisionState := "OK"
@@ -97,16 +116,24 @@ func (r *SubnetMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		return ctrl.Result{}, err
 	}
 
+	if paused(subnetCR) {
+		return u.patchSubnetStatus(subnetCR, "Paused", pausedMessage)
+	}
+
 	if subnetMeta.DeletionTimestamp != nil {
 		return ctrl.Result{}, nil
 	}
 
+	if subnetMeta.Spec.NetrisName == "" {
+		subnetMeta.Spec.NetrisName = subnetMeta.Spec.SubnetName
+	}
+
 	if subnetMeta.Spec.ID == 0 {
 		debugLogger.Info("ID Not found in meta")
 		if subnetMeta.Spec.Imported {
 			logger.Info("Importing subnet")
 			debugLogger.Info("Imported yaml mode. Finding Subnet by name")
-			if subnet, ok := r.NStorage.SubnetsStorage.FindByName(subnetMeta.Spec.SubnetName); ok {
+			if subnet, ok := r.NStorage.SubnetsStorage.FindByName(subnetMeta.Spec.NetrisName); ok {
 				debugLogger.Info("Imported yaml mode. Subnet found")
 				subnetMeta.Spec.ID = subnet.ID
 
@@ -118,25 +145,51 @@ func (r *SubnetMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 					return u.patchSubnetStatus(subnetCR, "Failure", err.Error())
 				}
 				debugLogger.Info("Imported yaml mode. ID patched")
+				u.event(subnetCR, EventImported, "Subnet imported from Netris, ID %d", subnetMeta.Spec.ID)
 				logger.Info("Subnet imported")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{}, nil
 			}
 			logger.Info("Subnet not found for import")
 			debugLogger.Info("Imported yaml mode. Subnet not found")
 		}
 
+		if msg := u.cacheStale(nil, netrisstorage.KindSubnets); msg != "" {
+			return u.patchSubnetStatus(subnetCR, "CacheStale", msg)
+		}
+		_, exists := r.NStorage.SubnetsStorage.FindByName(subnetMeta.Spec.NetrisName)
+		if msg := u.nameCollision(subnetMeta, "Subnet", subnetMeta.Spec.NetrisName, exists); msg != "" {
+			u.warning(subnetCR, EventNameCollision, "%s", msg)
+			return u.patchSubnetStatus(subnetCR, "NameCollision", msg)
+		}
 		logger.Info("Creating Subnet")
 		if _, err, errMsg := r.createSubnet(subnetMeta); err != nil {
 			logger.Error(fmt.Errorf("{createSubnet} %s", err), "")
+			u.warning(subnetCR, EventAPIError, "Couldn't create Subnet in Netris: %s", errMsg)
 			return u.patchSubnetStatus(subnetCR, "Failure", errMsg.Error())
 		}
+		subnetCR.Status.AppliedGeneration = subnetMeta.Spec.SubnetCRGeneration
+		u.event(subnetCR, EventCreated, "Subnet created in Netris")
 		logger.Info("Subnet Created")
 	} else {
 		if apiSubnet, ok := r.NStorage.SubnetsStorage.FindByID(subnetMeta.Spec.ID, "subnet"); ok {
 			debugLogger.Info("Comparing SubnetMeta with Netris Subnet")
+			driftedFields := subnetCR.Status.DriftedFields
+			subnetCR.Status.DriftedFields = nil
 			if ok := compareSubnetMetaAPIESubnet(subnetMeta, apiSubnet, u); ok {
 				debugLogger.Info("Nothing Changed")
+				subnetCR.Status.AppliedGeneration = subnetMeta.Spec.SubnetCRGeneration
+			} else if keepsDrift(subnetCR, subnetCR.Status.AppliedGeneration) {
+				spec := subnetCR.Spec.DeepCopy()
+				d := subnetDrift(spec, subnetMeta, apiSubnet)
+				if driftPolicyOf(subnetCR) == driftAdopt && d.adoptable() {
+					subnetCR.Spec = *spec
+					return u.adoptDrift(subnetCR, "Subnet", d)
+				}
+				u.reportDrift(subnetCR, "Subnet", d, driftedFields)
+				subnetCR.Status.DriftedFields = d.fields
+				return u.patchSubnetStatus(subnetCR, "Drifted", d.String())
 			} else {
+				recordDrift("Subnet", subnetCR.Generation, subnetCR.Status.AppliedGeneration)
 				debugLogger.Info("Go to update Subnet in Netris")
 				logger.Info("Updating Subnet")
 				subnetUpdate, err := SubnetMetaToNetrisUpdate(subnetMeta)
@@ -151,18 +204,29 @@ func (r *SubnetMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 				_, err, errMsg := updateSubnet(subnetMeta.Spec.ID, subnetUpdate, r.Cred)
 				if err != nil {
 					logger.Error(fmt.Errorf("{updateSubnet} %s", err), "")
+					u.warning(subnetCR, EventAPIError, "Couldn't update Subnet in Netris: %s", errMsg)
 					return u.patchSubnetStatus(subnetCR, "Failure", errMsg.Error())
 				}
+				u.updated(subnetCR, subnetCR.Generation, subnetCR.Status.AppliedGeneration, "Subnet")
+				subnetCR.Status.AppliedGeneration = subnetMeta.Spec.SubnetCRGeneration
 				logger.Info("Subnet Updated")
 			}
 		} else {
 			debugLogger.Info("Subnet not found in Netris")
 			debugLogger.Info("Going to create Subnet")
+			if msg := u.cacheStale(func() (bool, error) {
+				return existsInNetris(r.Cred, "ipam", r.Cred.IPAM().Get, func(item *ipam.IPAM) bool { return findIPAM([]*ipam.IPAM{item}, subnetMeta.Spec.ID, "subnet") })
+			}, netrisstorage.KindSubnets); msg != "" {
+				return u.patchSubnetStatus(subnetCR, "CacheStale", msg)
+			}
 			logger.Info("Creating Subnet")
 			if _, err, errMsg := r.createSubnet(subnetMeta); err != nil {
 				logger.Error(fmt.Errorf("{createSubnet} %s", err), "")
+				u.warning(subnetCR, EventAPIError, "Couldn't create Subnet in Netris: %s", errMsg)
 				return u.patchSubnetStatus(subnetCR, "Failure", errMsg.Error())
 			}
+			subnetCR.Status.AppliedGeneration = subnetMeta.Spec.SubnetCRGeneration
+			u.event(subnetCR, EventCreated, "Subnet created in Netris")
 			logger.Info("Subnet Created")
 		}
 	}
@@ -183,7 +247,9 @@ func (r *SubnetMetaReconciler) createSubnet(subnetMeta *k8sv1alpha1.SubnetMeta)
 	js, _ := json.Marshal(subnetAdd)
 	debugLogger.Info("subnetToAdd", "payload", string(js))
 
-	reply, err := r.Cred.IPAM().AddSubnet(subnetAdd)
+	reply, err := netrisapi.Create(r.Cred, "ipam", func() (http.HTTPReply, error) {
+		return r.Cred.IPAM().AddSubnet(subnetAdd)
+	})
 	if err != nil {
 		return ctrl.Result{}, err, err
 	}
@@ -219,7 +285,9 @@ func (r *SubnetMetaReconciler) createSubnet(subnetMeta *k8sv1alpha1.SubnetMeta)
 }
 
 func updateSubnet(id int, subnet *ipam.Subnet, cred *api.Clientset) (ctrl.Result, error, error) {
-	reply, err := cred.IPAM().UpdateSubnet(id, subnet)
+	reply, err := netrisapi.Do(cred, "ipam", "update", func() (http.HTTPReply, error) {
+		return cred.IPAM().UpdateSubnet(id, subnet)
+	})
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("{updateSubnet} %s", err), err
 	}
@@ -236,7 +304,15 @@ func updateSubnet(id int, subnet *ipam.Subnet, cred *api.Clientset) (ctrl.Result
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SubnetMetaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	src, hdl, err := watchStorage(mgr, r.Controllers.storageListeners(r.NStorage), &k8sv1alpha1.SubnetMeta{}, &k8sv1alpha1.SubnetMetaList{}, func(o runtime.Object) string {
+		return strconv.Itoa(o.(*k8sv1alpha1.SubnetMeta).Spec.ID)
+	}, netrisstorage.KindSubnets)
+	if err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.SubnetMeta{}).
+		WithOptions(controllerOptions()).
+		Watches(src, hdl).
 		Complete(r)
 }
@@ -23,11 +23,13 @@ import (
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -36,10 +38,12 @@ import (
 // SwitchReconciler reconciles a Switch object
 type SwitchReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.netris.ai,resources=switches,verbs=get;list;watch;create;update;patch;delete
@@ -56,6 +60,19 @@ type SwitchReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (r *SwitchReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.Switch{}, &k8sv1alpha1.SwitchMeta{})
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *SwitchReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	logger := r.Log.WithValues("name", req.NamespacedName)
 	debugLogger := logger.V(int(zapcore.WarnLevel))
 	switchH := &k8sv1alpha1.Switch{}
@@ -66,6 +83,7 @@ func (r *SwitchReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	switchCtx, switchCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -95,13 +113,29 @@ func (r *SwitchReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 	}
 
+	if paused(switchH) {
+		if metaFound {
+			if err := u.annotateMeta(switchH, switchMeta); err != nil {
+				logger.Error(err, "")
+				return ctrl.Result{Requeue: true}, nil
+			}
+		}
+		return u.patchSwitchStatus(switchH, "Paused", pausedMessage)
+	}
+
 	if switchH.DeletionTimestamp != nil {
+		if msg := deletionBlocked(switchH, switchMeta != nil && switchMeta.Spec.ID > 0 && !switchMeta.Spec.Reclaim); msg != "" {
+			u.warning(switchH, EventDeleteBlocked, "%s", msg)
+			return u.patchSwitchStatus(switchH, "DeletionBlocked", msg)
+		}
 		logger.Info("Go to delete")
 		_, err := r.deleteSwitch(switchH, switchMeta)
 		if err != nil {
 			logger.Error(fmt.Errorf("{deleteSwitch} %s", err), "")
+			u.warning(switchH, EventDeleteBlocked, "Couldn't delete Switch from Netris: %s", err)
 			return u.patchSwitchStatus(switchH, "Failure", err.Error())
 		}
+		u.event(switchH, EventDeleted, "Switch deleted from Netris")
 		logger.Info("Switch deleted")
 		return ctrl.Result{}, nil
 	}
@@ -114,14 +148,14 @@ func (r *SwitchReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		err := r.Patch(switchPatchCtx, switchH.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 		if err != nil {
 			logger.Error(fmt.Errorf("{Patch Switch default annotations} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 		return ctrl.Result{}, nil
 	}
 
 	if metaFound {
 		debugLogger.Info("Meta found")
-		if switchCompareFieldsForNewMeta(switchH, switchMeta) {
+		if ownerChanged, annotationsChanged := setOwner(switchH, switchMeta, r.Scheme), syncAnnotations(switchH, switchMeta); switchCompareFieldsForNewMeta(switchH, switchMeta) || ownerChanged || annotationsChanged {
 			debugLogger.Info("Generating New Meta")
 			switchID := switchMeta.Spec.ID
 			newSwitchMeta, err := r.SwitchToSwitchMeta(switchH)
@@ -129,8 +163,16 @@ func (r *SwitchReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				logger.Error(fmt.Errorf("{SwitchToSwitchMeta} %s", err), "")
 				return u.patchSwitchStatus(switchH, "Failure", err.Error())
 			}
+			// the Netris controller of an existing resource never changes.
+			controllerRef := switchMeta.Spec.ControllerRef
+			currentName := switchMeta.Spec.NetrisName
+			if currentName == "" {
+				currentName = switchMeta.Spec.SwitchName
+			}
 			switchMeta.Spec = newSwitchMeta.DeepCopy().Spec
 			switchMeta.Spec.ID = switchID
+			switchMeta.Spec.ControllerRef = controllerRef
+			switchMeta.Spec.NetrisName = keptNetrisName(switchH.Spec.NetrisName, currentName, switchMeta.Spec.NetrisName, switchID)
 			switchMeta.Spec.SwitchCRGeneration = switchH.GetGeneration()
 
 			switchMetaUpdateCtx, switchMetaUpdateCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -138,7 +180,7 @@ func (r *SwitchReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err = r.Update(switchMetaUpdateCtx, switchMeta.DeepCopyObject(), &client.UpdateOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{switchMeta Update} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 		}
 	} else {
@@ -151,7 +193,7 @@ func (r *SwitchReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err := r.Patch(switchPatchCtx, switchH.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{Patch Switch Finalizer} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 			return ctrl.Result{}, nil
 		}
@@ -164,20 +206,24 @@ func (r *SwitchReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 		switchMeta.Spec.SwitchCRGeneration = switchH.GetGeneration()
 
+		setOwner(switchH, switchMeta, r.Scheme)
+		syncAnnotations(switchH, switchMeta)
 		switchMetaCreateCtx, switchMetaCreateCancel := context.WithTimeout(cntxt, contextTimeout)
 		defer switchMetaCreateCancel()
 		if err := r.Create(switchMetaCreateCtx, switchMeta.DeepCopyObject(), &client.CreateOptions{}); err != nil {
 			logger.Error(fmt.Errorf("{switchMeta Create} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (r *SwitchReconciler) deleteSwitch(switchH *k8sv1alpha1.Switch, switchMeta *k8sv1alpha1.SwitchMeta) (ctrl.Result, error) {
 	if switchMeta != nil && switchMeta.Spec.ID > 0 && !switchMeta.Spec.Reclaim {
-		reply, err := r.Cred.Inventory().Delete("switch", switchMeta.Spec.ID)
+		reply, err := netrisapi.Do(r.Cred, "inventory", "delete", func() (http.HTTPReply, error) {
+			return r.Cred.Inventory().Delete("switch", switchMeta.Spec.ID)
+		})
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("{deleteSwitch} %s", err)
 		}
@@ -229,5 +275,7 @@ func (r *SwitchReconciler) deleteSwitchMetaCR(switchMeta *k8sv1alpha1.SwitchMeta
 func (r *SwitchReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.Switch{}).
+		Owns(&k8sv1alpha1.SwitchMeta{}).
+		WithOptions(controllerOptions()).
 		Complete(r)
 }
@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netriswebapi/v2/types/inventory"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -31,7 +32,7 @@ func (r *SwitchReconciler) SwitchToSwitchMeta(switchH *k8sv1alpha1.Switch) (*k8s
 		reclaim  = false
 	)
 
-	nosList, err := r.Cred.Inventory().GetNOS()
+	nosList, err := netrisapi.Get(r.Cred, "inventory", "list", r.Cred.Inventory().GetNOS)
 	if err != nil {
 		return nil, err
 	}
@@ -65,7 +66,7 @@ func (r *SwitchReconciler) SwitchToSwitchMeta(switchH *k8sv1alpha1.Switch) (*k8s
 	}
 
 	profileID := 0
-	profiles, err := r.Cred.InventoryProfile().Get()
+	profiles, err := netrisapi.Get(r.Cred, "inventoryprofile", "list", r.Cred.InventoryProfile().Get)
 	if err != nil {
 		return nil, err
 	}
@@ -87,19 +88,21 @@ func (r *SwitchReconciler) SwitchToSwitchMeta(switchH *k8sv1alpha1.Switch) (*k8s
 		},
 		TypeMeta: metav1.TypeMeta{},
 		Spec: k8sv1alpha1.SwitchMetaSpec{
-			Imported:    imported,
-			Reclaim:     reclaim,
-			SwitchName:  switchH.Name,
-			Description: switchH.Spec.Description,
-			NOS:         nos,
-			TenantID:    tenantID,
-			SiteID:      siteID,
-			ASN:         switchH.Spec.ASN,
-			ProfileID:   profileID,
-			MainIP:      switchH.Spec.MainIP,
-			MgmtIP:      switchH.Spec.MgmtIP,
-			PortsCount:  switchH.Spec.PortsCount,
-			MacAddress:  switchH.Spec.MacAddress,
+			Imported:      imported,
+			ControllerRef: switchH.Spec.ControllerRef,
+			Reclaim:       reclaim,
+			SwitchName:    switchH.Name,
+			NetrisName:    netrisName(switchH, switchH.Spec.NetrisName),
+			Description:   switchH.Spec.Description,
+			NOS:           nos,
+			TenantID:      tenantID,
+			SiteID:        siteID,
+			ASN:           switchH.Spec.ASN,
+			ProfileID:     profileID,
+			MainIP:        switchH.Spec.MainIP,
+			MgmtIP:        switchH.Spec.MgmtIP,
+			PortsCount:    switchH.Spec.PortsCount,
+			MacAddress:    switchH.Spec.MacAddress,
 		},
 	}
 
@@ -162,7 +165,7 @@ func SwitchMetaToNetris(switchMeta *k8sv1alpha1.SwitchMeta) (*inventory.HWSwitch
 	}
 
 	switchAdd := &inventory.HWSwitchAdd{
-		Name:        switchMeta.Spec.SwitchName,
+		Name:        switchMeta.Spec.NetrisName,
 		Description: switchMeta.Spec.Description,
 		Tenant:      inventory.IDName{ID: switchMeta.Spec.TenantID},
 		Nos:         switchMeta.Spec.NOS,
@@ -197,7 +200,7 @@ func SwitchMetaToNetrisUpdate(switchMeta *k8sv1alpha1.SwitchMeta) (*inventory.HW
 	}
 
 	switchUpdate := &inventory.HWSwitchUpdate{
-		Name:        switchMeta.Spec.SwitchName,
+		Name:        switchMeta.Spec.NetrisName,
 		Description: switchMeta.Spec.Description,
 		Tenant:      inventory.IDName{ID: switchMeta.Spec.TenantID},
 		Nos:         switchMeta.Spec.NOS,
@@ -215,8 +218,8 @@ func SwitchMetaToNetrisUpdate(switchMeta *k8sv1alpha1.SwitchMeta) (*inventory.HW
 }
 
 func compareSwitchMetaAPIESwitch(switchMeta *k8sv1alpha1.SwitchMeta, apiSwitch *inventory.HW, u uniReconciler) bool {
-	if apiSwitch.Name != switchMeta.Spec.SwitchName {
-		u.DebugLogger.Info("Name changed", "netrisValue", apiSwitch.Name, "k8sValue", switchMeta.Spec.SwitchName)
+	if apiSwitch.Name != switchMeta.Spec.NetrisName {
+		u.DebugLogger.Info("Name changed", "netrisValue", apiSwitch.Name, "k8sValue", switchMeta.Spec.NetrisName)
 		return false
 	}
 
@@ -272,3 +275,43 @@ func compareSwitchMetaAPIESwitch(switchMeta *k8sv1alpha1.SwitchMeta, apiSwitch *
 
 	return true
 }
+
+// switchDrift returns the difference of the Switch spec with the Netris
+// switch and sets the spec to the Netris values.
+func switchDrift(spec *k8sv1alpha1.SwitchSpec, switchMeta *k8sv1alpha1.SwitchMeta, apiSwitch *inventory.HW) *drift {
+	d := &drift{}
+	if apiSwitch.Name != switchMeta.Spec.NetrisName {
+		driftFixed(d, "metadata.name", switchMeta.Spec.NetrisName, apiSwitch.Name)
+	}
+	if apiSwitch.Description != switchMeta.Spec.Description {
+		driftSet(d, "spec.description", &spec.Description, apiSwitch.Description)
+	}
+	if apiSwitch.Tenant.ID != switchMeta.Spec.TenantID {
+		driftSet(d, "spec.tenant", &spec.Tenant, apiSwitch.Tenant.Name)
+	}
+	if apiSwitch.Site.ID != switchMeta.Spec.SiteID {
+		driftSet(d, "spec.site", &spec.Site, apiSwitch.Site.Name)
+	}
+	if apiSwitch.Nos.Tag != switchMeta.Spec.NOS.Tag {
+		driftSet(d, "spec.nos", &spec.NOS, apiSwitch.Nos.Tag)
+	}
+	if apiSwitch.Asn != switchMeta.Spec.ASN {
+		driftSet(d, "spec.asn", &spec.ASN, apiSwitch.Asn)
+	}
+	if apiSwitch.PortCount != switchMeta.Spec.PortsCount {
+		driftSet(d, "spec.portsCount", &spec.PortsCount, apiSwitch.PortCount)
+	}
+	if apiSwitch.MacAddress != switchMeta.Spec.MacAddress {
+		driftSet(d, "spec.macAddress", &spec.MacAddress, apiSwitch.MacAddress)
+	}
+	if apiSwitch.Profile.ID != switchMeta.Spec.ProfileID {
+		driftSet(d, "spec.profile", &spec.Profile, apiSwitch.Profile.Name)
+	}
+	if apiSwitch.MainIP.Address != switchMeta.Spec.MainIP {
+		driftSet(d, "spec.mainIp", &spec.MainIP, apiSwitch.MainIP.Address)
+	}
+	if apiSwitch.MgmtIP.Address != switchMeta.Spec.MgmtIP {
+		driftSet(d, "spec.mgmtIp", &spec.MgmtIP, apiSwitch.MgmtIP.Address)
+	}
+	return d
+}
@@ -20,16 +20,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"time"
 
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -39,10 +43,12 @@ import (
 // SwitchMetaReconciler reconciles a SwitchMeta object
 type SwitchMetaReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.netris.ai,resources=switchmeta,verbs=get;list;watch;create;update;patch;delete
@@ -59,6 +65,19 @@ type SwitchMetaReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (r *SwitchMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.SwitchMeta{}, nil)
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *SwitchMetaReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	debugLogger := r.Log.WithValues("name", req.NamespacedName).V(int(zapcore.WarnLevel))
 
 	switchMeta := &k8sv1alpha1.SwitchMeta{}
@@ -82,6 +101,7 @@ func (r *SwitchMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	provisionState := "OK"
@@ -98,16 +118,24 @@ func (r *SwitchMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		return ctrl.Result{}, err
 	}
 
+	if paused(switchCR) {
+		return u.patchSwitchStatus(switchCR, "Paused", pausedMessage)
+	}
+
 	if switchMeta.DeletionTimestamp != nil {
 		return ctrl.Result{}, nil
 	}
 
+	if switchMeta.Spec.NetrisName == "" {
+		switchMeta.Spec.NetrisName = switchMeta.Spec.SwitchName
+	}
+
 	if switchMeta.Spec.ID == 0 {
 		debugLogger.Info("ID Not found in meta")
 		if switchMeta.Spec.Imported {
 			logger.Info("Importing switch")
 			debugLogger.Info("Imported yaml mode. Finding Switch by name")
-			if switchH, ok := r.NStorage.HWsStorage.FindSwitchByName(switchMeta.Spec.SwitchName); ok {
+			if switchH, ok := r.NStorage.HWsStorage.FindSwitchByName(switchMeta.Spec.NetrisName); ok {
 				debugLogger.Info("Imported yaml mode. Switch found")
 				switchMeta.Spec.ID = switchH.ID
 				switchMeta.Spec.MainIP = switchH.MainIP.Address
@@ -122,18 +150,30 @@ func (r *SwitchMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 					return u.patchSwitchStatus(switchCR, "Failure", err.Error())
 				}
 				debugLogger.Info("Imported yaml mode. ID patched")
+				u.event(switchCR, EventImported, "Switch imported from Netris, ID %d", switchMeta.Spec.ID)
 				logger.Info("Switch imported")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{}, nil
 			}
 			logger.Info("Switch not found for import")
 			debugLogger.Info("Imported yaml mode. Switch not found")
 		}
 
+		if msg := u.cacheStale(nil, netrisstorage.KindHWs); msg != "" {
+			return u.patchSwitchStatus(switchCR, "CacheStale", msg)
+		}
+		_, exists := r.NStorage.HWsStorage.FindSwitchByName(switchMeta.Spec.NetrisName)
+		if msg := u.nameCollision(switchMeta, "Switch", switchMeta.Spec.NetrisName, exists); msg != "" {
+			u.warning(switchCR, EventNameCollision, "%s", msg)
+			return u.patchSwitchStatus(switchCR, "NameCollision", msg)
+		}
 		logger.Info("Creating Switch")
 		if _, err, errMsg := r.createSwitch(switchMeta); err != nil {
 			logger.Error(fmt.Errorf("{createSwitch} %s", err), "")
+			u.warning(switchCR, EventAPIError, "Couldn't create Switch in Netris: %s", errMsg)
 			return u.patchSwitchStatus(switchCR, "Failure", errMsg.Error())
 		}
+		switchCR.Status.AppliedGeneration = switchMeta.Spec.SwitchCRGeneration
+		u.event(switchCR, EventCreated, "Switch created in Netris")
 		logger.Info("Switch Created")
 	} else {
 		if apiSwitch, ok := r.NStorage.HWsStorage.FindSwitchByID(switchMeta.Spec.ID); ok {
@@ -149,9 +189,23 @@ func (r *SwitchMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 				switchMeta.Spec.ASN = apiSwitch.Asn
 			}
 
+			driftedFields := switchCR.Status.DriftedFields
+			switchCR.Status.DriftedFields = nil
 			if ok := compareSwitchMetaAPIESwitch(switchMeta, apiSwitch, u); ok {
 				debugLogger.Info("Nothing Changed")
+				switchCR.Status.AppliedGeneration = switchMeta.Spec.SwitchCRGeneration
+			} else if keepsDrift(switchCR, switchCR.Status.AppliedGeneration) {
+				spec := switchCR.Spec.DeepCopy()
+				d := switchDrift(spec, switchMeta, apiSwitch)
+				if driftPolicyOf(switchCR) == driftAdopt && d.adoptable() {
+					switchCR.Spec = *spec
+					return u.adoptDrift(switchCR, "Switch", d)
+				}
+				u.reportDrift(switchCR, "Switch", d, driftedFields)
+				switchCR.Status.DriftedFields = d.fields
+				return u.patchSwitchStatus(switchCR, "Drifted", d.String())
 			} else {
+				recordDrift("Switch", switchCR.Generation, switchCR.Status.AppliedGeneration)
 				debugLogger.Info("Go to update Switch in Netris")
 				logger.Info("Updating Switch")
 				switchUpdate, err := SwitchMetaToNetrisUpdate(switchMeta)
@@ -163,21 +217,37 @@ func (r *SwitchMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 				js, _ := json.Marshal(switchUpdate)
 				debugLogger.Info("switchUpdate", "payload", string(js))
 
+				if ok, msg, result := deferred(time.Now()); ok {
+					logger.Info(msg)
+					u.patchSwitchStatus(switchCR, "MaintenanceWindow", msg)
+					return result, nil
+				}
 				_, err, errMsg := updateSwitch(switchMeta.Spec.ID, switchUpdate, r.Cred)
 				if err != nil {
 					logger.Error(fmt.Errorf("{updateSwitch} %s", err), "")
+					u.warning(switchCR, EventAPIError, "Couldn't update Switch in Netris: %s", errMsg)
 					return u.patchSwitchStatus(switchCR, "Failure", errMsg.Error())
 				}
+				u.updated(switchCR, switchCR.Generation, switchCR.Status.AppliedGeneration, "Switch")
+				switchCR.Status.AppliedGeneration = switchMeta.Spec.SwitchCRGeneration
 				logger.Info("Switch Updated")
 			}
 		} else {
 			debugLogger.Info("Switch not found in Netris")
 			debugLogger.Info("Going to create Switch")
+			if msg := u.cacheStale(func() (bool, error) {
+				return existsInNetris(r.Cred, "inventory", r.Cred.Inventory().Get, func(item *inventory.HW) bool { return item.ID == switchMeta.Spec.ID })
+			}, netrisstorage.KindHWs); msg != "" {
+				return u.patchSwitchStatus(switchCR, "CacheStale", msg)
+			}
 			logger.Info("Creating Switch")
 			if _, err, errMsg := r.createSwitch(switchMeta); err != nil {
 				logger.Error(fmt.Errorf("{createSwitch} %s", err), "")
+				u.warning(switchCR, EventAPIError, "Couldn't create Switch in Netris: %s", errMsg)
 				return u.patchSwitchStatus(switchCR, "Failure", errMsg.Error())
 			}
+			switchCR.Status.AppliedGeneration = switchMeta.Spec.SwitchCRGeneration
+			u.event(switchCR, EventCreated, "Switch created in Netris")
 			logger.Info("Switch Created")
 		}
 	}
@@ -204,7 +274,9 @@ func (r *SwitchMetaReconciler) createSwitch(switchMeta *k8sv1alpha1.SwitchMeta)
 	js, _ := json.Marshal(switchAdd)
 	debugLogger.Info("switchToAdd", "payload", string(js))
 
-	reply, err := r.Cred.Inventory().AddSwitch(switchAdd)
+	reply, err := netrisapi.Create(r.Cred, "inventory", func() (http.HTTPReply, error) {
+		return r.Cred.Inventory().AddSwitch(switchAdd)
+	})
 	if err != nil {
 		return ctrl.Result{}, err, err
 	}
@@ -240,7 +312,9 @@ func (r *SwitchMetaReconciler) createSwitch(switchMeta *k8sv1alpha1.SwitchMeta)
 }
 
 func updateSwitch(id int, switchH *inventory.HWSwitchUpdate, cred *api.Clientset) (ctrl.Result, error, error) {
-	reply, err := cred.Inventory().UpdateSwitch(id, switchH)
+	reply, err := netrisapi.Do(cred, "inventory", "update", func() (http.HTTPReply, error) {
+		return cred.Inventory().UpdateSwitch(id, switchH)
+	})
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("{updateSwitch} %s", err), err
 	}
@@ -257,8 +331,16 @@ func updateSwitch(id int, switchH *inventory.HWSwitchUpdate, cred *api.Clientset
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SwitchMetaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	src, hdl, err := watchStorage(mgr, r.Controllers.storageListeners(r.NStorage), &k8sv1alpha1.SwitchMeta{}, &k8sv1alpha1.SwitchMetaList{}, func(o runtime.Object) string {
+		return strconv.Itoa(o.(*k8sv1alpha1.SwitchMeta).Spec.ID)
+	}, netrisstorage.KindHWs)
+	if err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.SwitchMeta{}).
+		WithOptions(controllerOptions()).
+		Watches(src, hdl).
 		Complete(r)
 }
 
@@ -25,10 +25,12 @@ import (
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
@@ -38,10 +40,12 @@ import (
 // VNetReconciler reconciles a VNet object
 type VNetReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=k8s.netris.ai,resources=vnets,verbs=get;list;watch;create;update;patch;delete
@@ -50,6 +54,19 @@ type VNetReconciler struct {
 
 // Reconcile vnet events
 func (r *VNetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.VNet{}, &k8sv1alpha1.VNetMeta{})
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *VNetReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	logger := r.Log.WithValues("name", req.NamespacedName)
 	debugLogger := logger.V(int(zapcore.WarnLevel))
 	vnet := &k8sv1alpha1.VNet{}
@@ -60,6 +77,7 @@ func (r *VNetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	vnetCtx, vnetCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -88,13 +106,33 @@ func (r *VNetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 	}
 
+	if paused(vnet) {
+		if metaFound {
+			if err := u.annotateMeta(vnet, vnetMeta); err != nil {
+				logger.Error(err, "")
+				return ctrl.Result{Requeue: true}, nil
+			}
+		}
+		return u.patchVNetStatus(vnet, "Paused", pausedMessage)
+	}
+
 	if vnet.DeletionTimestamp != nil {
+		if msg := deletionBlocked(vnet, vnetMeta != nil && vnetMeta.Spec.ID > 0 && !vnetMeta.Spec.Reclaim && vnetHasActivePorts(vnet)); msg != "" {
+			u.warning(vnet, EventDeleteBlocked, "%s", msg)
+			return u.patchVNetStatus(vnet, "DeletionBlocked", msg)
+		}
+		if vnetMeta != nil && vnetMeta.Spec.ID > 0 && !vnetMeta.Spec.Reclaim && dryRun(vnet) {
+			vnet.Status.Plan = planOf(planDelete, vnetMeta.Spec.ID, nil, nil)
+			return u.patchVNetStatus(vnet, "DryRun", planMessage("VNet", vnet.Status.Plan))
+		}
 		logger.Info("Go to delete")
 		_, err := r.deleteVNet(vnet, vnetMeta)
 		if err != nil {
 			logger.Error(fmt.Errorf("{deleteVNet} %s", err), "")
+			u.warning(vnet, EventDeleteBlocked, "Couldn't delete VNet from Netris: %s", err)
 			return u.patchVNetStatus(vnet, "Failure", err.Error())
 		}
+		u.event(vnet, EventDeleted, "VNet deleted from Netris")
 		logger.Info("Vnet deleted")
 		return ctrl.Result{}, nil
 	}
@@ -107,7 +145,7 @@ func (r *VNetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		err := r.Patch(vnetUpdateCtx, vnet.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 		if err != nil {
 			logger.Error(fmt.Errorf("{Patch VNet default annotations} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 		return ctrl.Result{}, nil
 	}
@@ -121,7 +159,7 @@ func (r *VNetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	if metaFound {
 		debugLogger.Info("Meta found")
-		if vnetCompareFieldsForNewMeta(vnet, vnetMeta) {
+		if ownerChanged, annotationsChanged := setOwner(vnet, vnetMeta, r.Scheme), syncAnnotations(vnet, vnetMeta); vnetCompareFieldsForNewMeta(vnet, vnetMeta) || ownerChanged || annotationsChanged {
 			debugLogger.Info("Generating New Meta")
 			vnetID := vnetMeta.Spec.ID
 			newVnetMeta, err := r.VnetToVnetMeta(vnet)
@@ -129,8 +167,16 @@ func (r *VNetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				logger.Error(fmt.Errorf("{VnetToVnetMeta} %s", err), "")
 				return u.patchVNetStatus(vnet, "Failure", err.Error())
 			}
+			// the Netris controller of an existing resource never changes.
+			controllerRef := vnetMeta.Spec.ControllerRef
+			currentName := vnetMeta.Spec.NetrisName
+			if currentName == "" {
+				currentName = vnetMeta.Spec.VnetName
+			}
 			vnetMeta.Spec = newVnetMeta.DeepCopy().Spec
 			vnetMeta.Spec.ID = vnetID
+			vnetMeta.Spec.ControllerRef = controllerRef
+			vnetMeta.Spec.NetrisName = keptNetrisName(vnet.Spec.NetrisName, currentName, vnetMeta.Spec.NetrisName, vnetID)
 			vnetMeta.Spec.VnetCRGeneration = vnet.GetGeneration()
 
 			vnetMetaUpdateCtx, vnetMetaUpdateCancel := context.WithTimeout(cntxt, contextTimeout)
@@ -138,7 +184,7 @@ func (r *VNetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err = r.Update(vnetMetaUpdateCtx, vnetMeta.DeepCopyObject(), &client.UpdateOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{vnetMeta Update} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 		}
 	} else {
@@ -150,7 +196,7 @@ func (r *VNetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			err := r.Patch(vnetPatchCtx, vnet.DeepCopyObject(), client.Merge, &client.PatchOptions{})
 			if err != nil {
 				logger.Error(fmt.Errorf("{Patch VNet Finalizer} %s", err), "")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{Requeue: true}, nil
 			}
 			return ctrl.Result{}, nil
 		}
@@ -163,19 +209,23 @@ func (r *VNetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 		vnetMeta.Spec.VnetCRGeneration = vnet.GetGeneration()
 
+		setOwner(vnet, vnetMeta, r.Scheme)
+		syncAnnotations(vnet, vnetMeta)
 		vnetMetaCreateCtx, vnetMetaCreateCancel := context.WithTimeout(cntxt, contextTimeout)
 		defer vnetMetaCreateCancel()
 		if err := r.Create(vnetMetaCreateCtx, vnetMeta.DeepCopyObject(), &client.CreateOptions{}); err != nil {
 			logger.Error(fmt.Errorf("{vnetMeta Create} %s", err), "")
-			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
-	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	return ctrl.Result{}, nil
 }
 
 func (r *VNetMetaReconciler) updateVNet(id int, vnet *vnet.VNetUpdate) (ctrl.Result, error, error) {
-	reply, err := r.Cred.VNet().Update(id, vnet)
+	reply, err := netrisapi.Do(r.Cred, "vnet", "update", func() (http.HTTPReply, error) {
+		return r.Cred.VNet().Update(id, vnet)
+	})
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("{updateVNet} %s", err), err
 	}
@@ -192,7 +242,9 @@ func (r *VNetMetaReconciler) updateVNet(id int, vnet *vnet.VNetUpdate) (ctrl.Res
 
 func (r *VNetReconciler) deleteVNet(vnet *k8sv1alpha1.VNet, vnetMeta *k8sv1alpha1.VNetMeta) (ctrl.Result, error) {
 	if vnetMeta != nil && vnetMeta.Spec.ID > 0 && !vnetMeta.Spec.Reclaim {
-		reply, err := r.Cred.VNet().Delete(vnetMeta.Spec.ID)
+		reply, err := netrisapi.Do(r.Cred, "vnet", "delete", func() (http.HTTPReply, error) {
+			return r.Cred.VNet().Delete(vnetMeta.Spec.ID)
+		})
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("{deleteVNet} %s", err)
 		}
@@ -246,6 +298,8 @@ func (r *VNetReconciler) deleteVnetMetaCR(vnetMeta *k8sv1alpha1.VNetMeta) (ctrl.
 func (r *VNetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.VNet{}).
+		Owns(&k8sv1alpha1.VNetMeta{}).
+		WithOptions(controllerOptions()).
 		// WithEventFilter(ignoreDeletionPredicate()).
 		Complete(r)
 }
@@ -18,8 +18,10 @@ package controllers
 
 import (
 	"fmt"
+	"strconv"
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netriswebapi/v2/types/dhcp"
 	"github.com/netrisai/netriswebapi/v2/types/vnet"
 	"github.com/r3labs/diff/v2"
@@ -32,7 +34,7 @@ func (r *VNetReconciler) VnetToVnetMeta(vnet *k8sv1alpha1.VNet) (*k8sv1alpha1.VN
 	siteNames := []string{}
 	apiGateways := []k8sv1alpha1.VNetMetaGateway{}
 
-	dhcpOptionSetList, err := r.Cred.DHCP().Get()
+	dhcpOptionSetList, err := netrisapi.Get(r.Cred, "dhcp", "list", r.Cred.DHCP().Get)
 	if err != nil {
 		return nil, err
 	}
@@ -98,21 +100,23 @@ func (r *VNetReconciler) VnetToVnetMeta(vnet *k8sv1alpha1.VNet) (*k8sv1alpha1.VN
 		},
 		TypeMeta: metav1.TypeMeta{},
 		Spec: k8sv1alpha1.VNetMetaSpec{
-			Imported:     imported,
-			Reclaim:      reclaim,
-			Name:         string(vnet.GetUID()),
-			VnetName:     vnet.Name,
-			Sites:        sitesList,
-			State:        state,
-			Owner:        vnet.Spec.Owner,
-			Tenants:      vnet.Spec.GuestTenants,
-			Gateways:     apiGateways,
-			Members:      portsList,
-			Provisioning: 1,
-			VaMode:       false,
-			VaNativeVLAN: 1,
-			VaVLANs:      "",
-			VlanID:       vnet.Spec.VlanID,
+			Imported:      imported,
+			ControllerRef: vnet.Spec.ControllerRef,
+			Reclaim:       reclaim,
+			Name:          string(vnet.GetUID()),
+			VnetName:      vnet.Name,
+			NetrisName:    netrisName(vnet, vnet.Spec.NetrisName),
+			Sites:         sitesList,
+			State:         state,
+			Owner:         vnet.Spec.Owner,
+			Tenants:       vnet.Spec.GuestTenants,
+			Gateways:      apiGateways,
+			Members:       portsList,
+			Provisioning:  1,
+			VaMode:        false,
+			VaNativeVLAN:  1,
+			VaVLANs:       "",
+			VlanID:        vnet.Spec.VlanID,
 		},
 	}
 
@@ -191,7 +195,7 @@ func (r *VNetMetaReconciler) VnetMetaToNetris(vnetMeta *k8sv1alpha1.VNetMeta) (*
 	}
 
 	vnetAdd := &vnet.VNetAdd{
-		Name:         vnetMeta.Spec.VnetName,
+		Name:         vnetMeta.Spec.NetrisName,
 		Sites:        sites,
 		Tenant:       vnet.VNetAddTenant{Name: vnetMeta.Spec.Owner},
 		State:        vnetMeta.Spec.State,
@@ -277,7 +281,7 @@ func VnetMetaToNetrisUpdate(vnetMeta *k8sv1alpha1.VNetMeta) (*vnet.VNetUpdate, e
 	}
 
 	vnetUpdate := &vnet.VNetUpdate{
-		Name:         vnetMeta.Spec.VnetName,
+		Name:         vnetMeta.Spec.NetrisName,
 		Sites:        sites,
 		State:        vnetMeta.Spec.State,
 		GuestTenants: guestTenants,
@@ -441,7 +445,7 @@ func compareVNetMetaAPIVnet(vnetMeta *k8sv1alpha1.VNetMeta, apiVnet *vnet.VNetDe
 		return false
 	}
 
-	if vnetMeta.Spec.VnetName != apiVnet.Name {
+	if vnetMeta.Spec.NetrisName != apiVnet.Name {
 		return false
 	}
 
@@ -461,6 +465,120 @@ func compareVNetMetaAPIVnet(vnetMeta *k8sv1alpha1.VNetMeta, apiVnet *vnet.VNetDe
 	return true
 }
 
+// vnetDrift returns the difference of the VNet spec with the Netris VNet and
+// sets the spec to the Netris values.
+func vnetDrift(spec *k8sv1alpha1.VNetSpec, vnetMeta *k8sv1alpha1.VNetMeta, apiVnet *vnet.VNetDetailed) *drift {
+	d := &drift{}
+	if vnetMeta.Spec.NetrisName != apiVnet.Name {
+		driftFixed(d, "metadata.name", vnetMeta.Spec.NetrisName, apiVnet.Name)
+	}
+	if vnetMeta.Spec.Owner != apiVnet.Tenant.Name {
+		driftSet(d, "spec.ownerTenant", &spec.Owner, apiVnet.Tenant.Name)
+	}
+	if vnetMeta.Spec.State != apiVnet.State {
+		driftSet(d, "spec.state", &spec.State, apiVnet.State)
+	}
+	if ok := compareVNetMetaAPIVnetTenants(vnetMeta.Spec.Tenants, apiVnet.GuestTenants); !ok {
+		tenants := []string{}
+		for _, tenant := range apiVnet.GuestTenants {
+			tenants = append(tenants, tenant.Name)
+		}
+		driftSet(d, "spec.guestTenants", &spec.GuestTenants, tenants)
+	}
+
+	vlanAuto := false
+	for _, port := range vnetMeta.Spec.Members {
+		if port.Vlan == "auto" {
+			vlanAuto = true
+			break
+		}
+	}
+	if compareVNetMetaAPIVnetSites(vnetMeta.Spec.Sites, apiVnet.Sites) &&
+		compareVNetMetaAPIVnetGateways(vnetMeta.Spec.Gateways, apiVnet.Gateways) &&
+		(compareVNetMetaAPIVnetMembers(vnetMeta.Spec.Members, apiVnet.Ports) || vlanAuto) &&
+		compareVNetMetaAPIVnetMembersUntagged(vnetMeta.Spec, apiVnet.Ports) {
+		return d
+	}
+
+	sites := vnetNetrisSites(spec, apiVnet)
+	if len(sites) != len(spec.Sites) {
+		driftSet(d, "spec.sites", &spec.Sites, sites)
+		return d
+	}
+	for i := range sites {
+		field := fmt.Sprintf("spec.sites[%d]", i)
+		driftSet(d, field+".name", &spec.Sites[i].Name, sites[i].Name)
+		driftSet(d, field+".gateways", &spec.Sites[i].Gateways, sites[i].Gateways)
+		driftSet(d, field+".switchPorts", &spec.Sites[i].SwitchPorts, sites[i].SwitchPorts)
+	}
+	return d
+}
+
+// vnetNetrisSites returns the sites of the Netris VNet in the layout of the
+// spec: the gateways and the ports stay in the site they are declared in.
+func vnetNetrisSites(spec *k8sv1alpha1.VNetSpec, apiVnet *vnet.VNetDetailed) []k8sv1alpha1.VNetSite {
+	gateways := make(map[string]k8sv1alpha1.VNetGateway)
+	gatewaySites := make(map[string]string)
+	ports := make(map[string]k8sv1alpha1.VNetSwitchPort)
+	for _, site := range spec.Sites {
+		for _, gateway := range site.Gateways {
+			gateways[gateway.Prefix] = gateway
+			gatewaySites[gateway.Prefix] = site.Name
+		}
+		for _, port := range site.SwitchPorts {
+			ports[port.Name] = port
+		}
+	}
+
+	sites := []k8sv1alpha1.VNetSite{}
+	siteIndex := make(map[string]int)
+	for _, site := range apiVnet.Sites {
+		siteIndex[site.Name] = len(sites)
+		sites = append(sites, k8sv1alpha1.VNetSite{Name: site.Name})
+	}
+	if len(sites) == 0 {
+		return sites
+	}
+
+	for _, apiGateway := range apiVnet.Gateways {
+		gateway := gateways[apiGateway.Prefix]
+		gateway.Prefix = apiGateway.Prefix
+		if apiGateway.DHCPEnabled && apiGateway.DHCP != nil {
+			gateway.DHCP = "enabled"
+			gateway.DHCPOptionSet = apiGateway.DHCP.OptionSet.Name
+			gateway.DHCPStartIP = apiGateway.DHCP.Start
+			gateway.DHCPEndIP = apiGateway.DHCP.End
+		} else if gateway.DHCP == "enabled" {
+			gateway.DHCP = "disabled"
+		}
+		i := siteIndex[gatewaySites[apiGateway.Prefix]]
+		sites[i].Gateways = append(sites[i].Gateways, gateway)
+	}
+
+	for _, apiPort := range apiVnet.Ports {
+		name := fmt.Sprintf("%s@%s", apiPort.Port, apiPort.SwitchName)
+		port := ports[name]
+		port.Name = name
+		if strconv.Itoa(port.VlanID) != apiPort.Vlan {
+			port.VlanID = 0
+			if vlanID, err := strconv.Atoi(apiPort.Vlan); err == nil && vlanID > 1 && apiPort.Vlan != spec.VlanID {
+				port.VlanID = vlanID
+			}
+		}
+		switch {
+		case apiPort.AccessMode && port.Untagged == "no":
+			port.Untagged = "yes"
+		case !apiPort.AccessMode && port.Untagged == "yes":
+			port.Untagged = "no"
+		case !apiPort.AccessMode && port.Untagged == "" && spec.VlanID != "":
+			port.Untagged = "no"
+		}
+		i := siteIndex[apiPort.Site.Name]
+		sites[i].SwitchPorts = append(sites[i].SwitchPorts, port)
+	}
+	return sites
+}
+
 func findGatewayDuplicates(items []k8sv1alpha1.VNetGateway) (string, bool) {
 	tmpMap := make(map[string]int)
 	for _, s := range items {
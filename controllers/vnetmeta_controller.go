@@ -19,6 +19,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"go.uber.org/zap/zapcore"
@@ -27,22 +28,27 @@ import (
 	"github.com/go-logr/logr"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	"github.com/netrisai/netriswebapi/http"
 	api "github.com/netrisai/netriswebapi/v2"
+	vnetapi "github.com/netrisai/netriswebapi/v2/types/vnet"
 )
 
 // VNetMetaReconciler reconciles a VNetMeta object
 type VNetMetaReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Cred     *api.Clientset
-	NStorage *netrisstorage.Storage
+	Log         logr.Logger
+	Scheme      *runtime.Scheme
+	Cred        *api.Clientset
+	NStorage    *netrisstorage.Storage
+	Controllers *ControllerSet
+	Recorder    record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=k8s.netris.ai,resources=vnetmeta,verbs=get;list;watch;create;update;patch;delete
@@ -51,6 +57,19 @@ type VNetMetaReconciler struct {
 
 // Reconcile .
 func (r *VNetMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	conn, err := r.Controllers.connFor(r.Client, req.NamespacedName, &k8sv1alpha1.VNetMeta{}, nil)
+	if err != nil {
+		r.Log.WithValues("name", req.NamespacedName).Error(fmt.Errorf("{connFor} %s", err), "")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	rc := *r
+	if conn != nil {
+		rc.Cred, rc.NStorage = conn.Cred, conn.NStorage
+	}
+	return rc.reconcile(req)
+}
+
+func (r *VNetMetaReconciler) reconcile(req ctrl.Request) (ctrl.Result, error) {
 	debugLogger := r.Log.WithValues("name", req.NamespacedName).V(int(zapcore.WarnLevel))
 
 	vnetMeta := &k8sv1alpha1.VNetMeta{}
@@ -74,6 +93,7 @@ func (r *VNetMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		DebugLogger: debugLogger,
 		Cred:        r.Cred,
 		NStorage:    r.NStorage,
+		Recorder:    r.Recorder,
 	}
 
 	provisionState := "Provisioning"
@@ -89,17 +109,26 @@ func (r *VNetMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 		return ctrl.Result{}, err
 	}
+	vnetCR.Status.Plan = nil
+
+	if paused(vnetCR) {
+		return u.patchVNetStatus(vnetCR, "Paused", pausedMessage)
+	}
 
 	if vnetMeta.DeletionTimestamp != nil {
 		return ctrl.Result{}, nil
 	}
 
+	if vnetMeta.Spec.NetrisName == "" {
+		vnetMeta.Spec.NetrisName = vnetMeta.Spec.VnetName
+	}
+
 	if vnetMeta.Spec.ID == 0 {
 		debugLogger.Info("ID Not found in meta")
 		if vnetMeta.Spec.Imported {
 			logger.Info("Importing vnet")
 			debugLogger.Info("Imported yaml mode. Finding VNet by name")
-			if vnet, ok := r.NStorage.VNetStorage.FindByName(vnetMeta.Spec.VnetName); ok {
+			if vnet, ok := r.NStorage.VNetStorage.FindByName(vnetMeta.Spec.NetrisName); ok {
 				debugLogger.Info("Imported yaml mode. Vnet found")
 				vnetMeta.Spec.ID = vnet.ID
 				vnetCR.Status.ModifiedDate = metav1.NewTime(time.Unix(int64(vnet.ModifiedDate/1000), 0))
@@ -111,29 +140,60 @@ func (r *VNetMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 					return u.patchVNetStatus(vnetCR, "Failure", err.Error())
 				}
 				debugLogger.Info("Imported yaml mode. ID patched")
+				u.event(vnetCR, EventImported, "VNet imported from Netris, ID %d", vnetMeta.Spec.ID)
 				logger.Info("VNet imported")
-				return ctrl.Result{RequeueAfter: requeueInterval}, nil
+				return ctrl.Result{}, nil
 			}
 			logger.Info("VNet not found for import")
 			debugLogger.Info("Imported yaml mode. VNet not found")
 		}
 
+		if msg := u.cacheStale(nil, netrisstorage.KindVNets); msg != "" {
+			return u.patchVNetStatus(vnetCR, "CacheStale", msg)
+		}
+		_, exists := r.NStorage.VNetStorage.FindByName(vnetMeta.Spec.NetrisName)
+		if msg := u.nameCollision(vnetMeta, "VNet", vnetMeta.Spec.NetrisName, exists); msg != "" {
+			u.warning(vnetCR, EventNameCollision, "%s", msg)
+			return u.patchVNetStatus(vnetCR, "NameCollision", msg)
+		}
+		if dryRun(vnetCR) {
+			return r.planVNetCreate(&u, vnetCR, vnetMeta)
+		}
 		logger.Info("Creating VNet")
 		if _, err, errMsg := r.createVNet(vnetMeta); err != nil {
 			logger.Error(fmt.Errorf("{createVNet} %s", err), "")
+			u.warning(vnetCR, EventAPIError, "Couldn't create VNet in Netris: %s", errMsg)
 			return u.patchVNetStatus(vnetCR, "Failure", errMsg.Error())
 		}
+		vnetCR.Status.AppliedGeneration = vnetMeta.Spec.VnetCRGeneration
+		u.event(vnetCR, EventCreated, "VNet created in Netris")
 		logger.Info("VNet Created")
 	} else {
-		vnet, _ := r.Cred.VNet().GetByID(vnetMeta.Spec.ID)
+		vnet, err := netrisapi.Get(r.Cred, "vnet", "get", func() (*vnetapi.VNetDetailed, error) {
+			return r.Cred.VNet().GetByID(vnetMeta.Spec.ID)
+		})
+		if netrisapi.IsUnavailable(err) {
+			return u.patchVNetStatus(vnetCR, "Failure", err.Error())
+		}
 		if vnet == nil {
 			debugLogger.Info("VNet not found in Netris")
 			debugLogger.Info("Going to create VNet")
+			if msg := u.cacheStale(func() (bool, error) {
+				return existsInNetris(r.Cred, "vnet", r.Cred.VNet().Get, func(item *vnetapi.VNet) bool { return item.ID == vnetMeta.Spec.ID })
+			}, netrisstorage.KindVNets); msg != "" {
+				return u.patchVNetStatus(vnetCR, "CacheStale", msg)
+			}
+			if dryRun(vnetCR) {
+				return r.planVNetCreate(&u, vnetCR, vnetMeta)
+			}
 			logger.Info("Creating VNet")
 			if _, err, errMsg := r.createVNet(vnetMeta); err != nil {
 				logger.Error(fmt.Errorf("{createVNet} %s", err), "")
+				u.warning(vnetCR, EventAPIError, "Couldn't create VNet in Netris: %s", errMsg)
 				return u.patchVNetStatus(vnetCR, "Failure", errMsg.Error())
 			}
+			vnetCR.Status.AppliedGeneration = vnetMeta.Spec.VnetCRGeneration
+			u.event(vnetCR, EventCreated, "VNet created in Netris")
 			logger.Info("VNet Created")
 		} else {
 			if !vnet.Provisioning {
@@ -144,9 +204,23 @@ func (r *VNetMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			}
 			vnetCR.Status.ModifiedDate = metav1.NewTime(time.Unix(int64(vnet.ModifiedDate/1000), 0))
 			debugLogger.Info("Comparing VnetMeta with Netris Vnet")
+			driftedFields := vnetCR.Status.DriftedFields
+			vnetCR.Status.DriftedFields = nil
 			if ok := compareVNetMetaAPIVnet(vnetMeta, vnet); ok {
 				debugLogger.Info("Nothing Changed")
+				vnetCR.Status.AppliedGeneration = vnetMeta.Spec.VnetCRGeneration
+			} else if keepsDrift(vnetCR, vnetCR.Status.AppliedGeneration) {
+				spec := vnetCR.Spec.DeepCopy()
+				d := vnetDrift(spec, vnetMeta, vnet)
+				if driftPolicyOf(vnetCR) == driftAdopt && d.adoptable() {
+					vnetCR.Spec = *spec
+					return u.adoptDrift(vnetCR, "VNet", d)
+				}
+				u.reportDrift(vnetCR, "VNet", d, driftedFields)
+				vnetCR.Status.DriftedFields = d.fields
+				return u.patchVNetStatus(vnetCR, "Drifted", d.String())
 			} else {
+				recordDrift("VNet", vnetCR.Generation, vnetCR.Status.AppliedGeneration)
 				debugLogger.Info("Something changed")
 				debugLogger.Info("Go to update Vnet in Netris")
 				logger.Info("Updating VNet")
@@ -155,11 +229,24 @@ func (r *VNetMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 					logger.Error(fmt.Errorf("{VnetMetaToNetrisUpdate} %s", err), "")
 					return u.patchVNetStatus(vnetCR, "Failure", err.Error())
 				}
+				if dryRun(vnetCR) {
+					d := vnetDrift(vnetCR.Spec.DeepCopy(), vnetMeta, vnet)
+					vnetCR.Status.Plan = planOf(planUpdate, vnetMeta.Spec.ID, updateVnet, d.fields)
+					return u.patchVNetStatus(vnetCR, "DryRun", planMessage("VNet", vnetCR.Status.Plan))
+				}
+				if ok, msg, result := deferred(time.Now()); ok {
+					logger.Info(msg)
+					u.patchVNetStatus(vnetCR, "MaintenanceWindow", msg)
+					return result, nil
+				}
 				_, err, errMsg := r.updateVNet(vnetMeta.Spec.ID, updateVnet)
 				if err != nil {
 					logger.Error(fmt.Errorf("{updateVNet} %s", err), "")
+					u.warning(vnetCR, EventAPIError, "Couldn't update VNet in Netris: %s", errMsg)
 					return u.patchVNetStatus(vnetCR, "Failure", errMsg.Error())
 				}
+				u.updated(vnetCR, vnetCR.Generation, vnetCR.Status.AppliedGeneration, "VNet")
+				vnetCR.Status.AppliedGeneration = vnetMeta.Spec.VnetCRGeneration
 				logger.Info("VNet Updated")
 			}
 		}
@@ -169,11 +256,29 @@ func (r *VNetMetaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 // SetupWithManager .
 func (r *VNetMetaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	src, hdl, err := watchStorage(mgr, r.Controllers.storageListeners(r.NStorage), &k8sv1alpha1.VNetMeta{}, &k8sv1alpha1.VNetMetaList{}, func(o runtime.Object) string {
+		return strconv.Itoa(o.(*k8sv1alpha1.VNetMeta).Spec.ID)
+	}, netrisstorage.KindVNets)
+	if err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&k8sv1alpha1.VNetMeta{}).
+		WithOptions(controllerOptions()).
+		Watches(src, hdl).
 		Complete(r)
 }
 
+// planVNetCreate records the create request of the VNet in its status instead of sending it.
+func (r *VNetMetaReconciler) planVNetCreate(u *uniReconciler, vnetCR *k8sv1alpha1.VNet, vnetMeta *k8sv1alpha1.VNetMeta) (ctrl.Result, error) {
+	vnetAdd, err := r.VnetMetaToNetris(vnetMeta)
+	if err != nil {
+		return u.patchVNetStatus(vnetCR, "Failure", err.Error())
+	}
+	vnetCR.Status.Plan = planOf(planCreate, 0, vnetAdd, nil)
+	return u.patchVNetStatus(vnetCR, "DryRun", planMessage("VNet", vnetCR.Status.Plan))
+}
+
 func (r *VNetMetaReconciler) createVNet(vnetMeta *k8sv1alpha1.VNetMeta) (ctrl.Result, error, error) {
 	debugLogger := r.Log.WithValues(
 		"name", fmt.Sprintf("%s/%s", vnetMeta.Namespace, vnetMeta.Spec.VnetName),
@@ -184,7 +289,9 @@ func (r *VNetMetaReconciler) createVNet(vnetMeta *k8sv1alpha1.VNetMeta) (ctrl.Re
 	if err != nil {
 		return ctrl.Result{}, err, err
 	}
-	reply, err := r.Cred.VNet().Add(vnetAdd)
+	reply, err := netrisapi.Create(r.Cred, "vnet", func() (http.HTTPReply, error) {
+		return r.Cred.VNet().Add(vnetAdd)
+	})
 	if err != nil {
 		return ctrl.Result{}, err, err
 	}
@@ -0,0 +1,249 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credwatcher
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/netrisai/netriswebapi/http"
+	api "github.com/netrisai/netriswebapi/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const serviceAccountNSFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Keys of the credentials Secret. The same keys are used by the netris-creds Secret.
+const (
+	KeyHost     = "host"
+	KeyLogin    = "login"
+	KeyPassword = "password"
+	KeyInsecure = "insecure"
+)
+
+// Credentials of the Netris controller.
+type Credentials struct {
+	Host     string
+	Login    string
+	Password string
+	Insecure bool
+}
+
+// Options is the main options struct.
+type Options struct {
+	// Secret is "[namespace/]name" of the credentials Secret. The namespace
+	// defaults to the namespace of the operator pod.
+	Secret string
+	// Timeout is the Netris API client timeout in seconds.
+	Timeout int
+}
+
+// Watcher watches the credentials Secret and swaps the credentials of the
+// shared Netris API client, so the operator never has to be restarted on
+// a password rotation. New credentials are applied only after a successful
+// login, so a broken Secret doesn't cut the operator off from Netris.
+type Watcher struct {
+	Cred      *api.Clientset
+	Options   Options
+	MGR       manager.Manager
+	namespace string
+	name      string
+	logger    logr.Logger
+	recorder  record.EventRecorder
+
+	mu      sync.Mutex
+	current Credentials
+}
+
+// NewWatcher is the main initialization function. current are the
+// credentials the shared client was created with.
+func NewWatcher(cred *api.Clientset, mgr manager.Manager, current Credentials, options Options) (*Watcher, error) {
+	if cred == nil {
+		return nil, fmt.Errorf("please provide Cred")
+	}
+	namespace, name, err := secretRef(options.Secret)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{
+		Cred:      cred,
+		Options:   options,
+		MGR:       mgr,
+		namespace: namespace,
+		name:      name,
+		logger:    ctrl.Log.WithName("CredWatcher"),
+		recorder:  mgr.GetEventRecorderFor("netris-operator"),
+		current:   current,
+	}, nil
+}
+
+// Load reads the credentials from the Secret "[namespace/]name", so the
+// operator can log in before the manager is started. insecure is used when
+// the Secret has no insecure key.
+func Load(c client.Reader, secret string, insecure bool) (Credentials, error) {
+	namespace, name, err := secretRef(secret)
+	if err != nil {
+		return Credentials{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, s); err != nil {
+		return Credentials{}, fmt.Errorf("{credwatcher.Load} %s", err)
+	}
+	creds := credentialsOf(s, insecure)
+	if creds.Host == "" || creds.Login == "" {
+		return Credentials{}, fmt.Errorf("secret %s/%s must contain the %q and %q keys", namespace, name, KeyHost, KeyLogin)
+	}
+	return creds, nil
+}
+
+func secretRef(secret string) (namespace, name string, err error) {
+	namespace, name = "", secret
+	if nn := strings.SplitN(secret, "/", 2); len(nn) == 2 {
+		namespace, name = nn[0], nn[1]
+	}
+	if name == "" {
+		return "", "", fmt.Errorf("please provide the credentials secret name")
+	}
+	if namespace == "" {
+		ns, err := ioutil.ReadFile(serviceAccountNSFile)
+		if err != nil {
+			return "", "", fmt.Errorf("can't detect the operator namespace for the credentials secret: %s", err)
+		}
+		namespace = strings.TrimSpace(string(ns))
+	}
+	return namespace, name, nil
+}
+
+func credentialsOf(secret *corev1.Secret, insecure bool) Credentials {
+	creds := Credentials{
+		Host:     string(secret.Data[KeyHost]),
+		Login:    string(secret.Data[KeyLogin]),
+		Password: string(secret.Data[KeyPassword]),
+		Insecure: insecure,
+	}
+	if v, ok := secret.Data[KeyInsecure]; ok {
+		creds.Insecure = strings.TrimSpace(string(v)) == "true"
+	}
+	return creds
+}
+
+// NeedLeaderElection is false, because every replica talks to Netris.
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Start watches the Secret until stop is closed.
+func (w *Watcher) Start(stop <-chan struct{}) error {
+	clientset, err := kubernetes.NewForConfig(w.MGR.GetConfig())
+	if err != nil {
+		return fmt.Errorf("{credwatcher} %s", err)
+	}
+	lw := cache.NewListWatchFromClient(clientset.CoreV1().RESTClient(), "secrets", w.namespace, fields.OneTermEqualSelector("metadata.name", w.name))
+	informer := cache.NewSharedIndexInformer(lw, &corev1.Secret{}, 0, cache.Indexers{})
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if secret, ok := obj.(*corev1.Secret); ok {
+				w.reload(secret)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if secret, ok := obj.(*corev1.Secret); ok {
+				w.reload(secret)
+			}
+		},
+	})
+	w.logger.Info("Watching credentials", "secret", fmt.Sprintf("%s/%s", w.namespace, w.name))
+	informer.Run(stop)
+	return nil
+}
+
+func (w *Watcher) reload(secret *corev1.Secret) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	creds := credentialsOf(secret, w.current.Insecure)
+	if creds == w.current {
+		return
+	}
+	if creds.Host == "" || creds.Login == "" {
+		w.fail(secret, fmt.Errorf("secret must contain the %q and %q keys", KeyHost, KeyLogin))
+		return
+	}
+	if creds.Host != w.current.Host {
+		// the API clients read the address without a lock, so it can't be swapped at runtime.
+		w.fail(secret, fmt.Errorf("the host can't be changed from %s to %s without a restart of the operator", w.current.Host, creds.Host))
+		return
+	}
+
+	cred, err := api.Client(creds.Host, creds.Login, creds.Password, w.Options.Timeout)
+	if err != nil {
+		w.fail(secret, err)
+		return
+	}
+	cred.Client.InsecureVerify(creds.Insecure)
+	if err := cred.Client.LoginUser(); err != nil {
+		w.fail(secret, err)
+		return
+	}
+
+	swapCredentials(w.Cred.Client, cred.Client)
+	w.current = creds
+	reloads.WithLabelValues("success").Inc()
+	lastReload.SetToCurrentTime()
+	w.logger.Info("Credentials reloaded", "host", creds.Host, "login", creds.Login)
+	w.recorder.Event(secret, "Normal", "CredentialsReloaded", fmt.Sprintf("Netris controller credentials reloaded for %s", creds.Host))
+}
+
+func (w *Watcher) fail(secret *corev1.Secret, err error) {
+	reloads.WithLabelValues("failure").Inc()
+	w.logger.Error(err, "Credentials reload failed, keeping the previous credentials")
+	w.recorder.Event(secret, "Warning", "CredentialsReloadFailed", err.Error())
+}
+
+// swapCredentials moves the login data and session of the freshly logged in
+// src into dst, which is shared by all API clients of the operator. Both must
+// point to the same host.
+func swapCredentials(dst, src *http.HTTPCred) {
+	src.Lock()
+	loginData := src.LoginData
+	cookies := append(src.Cookies[:0:0], src.Cookies...)
+	connectSID := src.ConnectSID
+	insecure := src.InsecureSkipVerify
+	src.Unlock()
+
+	dst.Lock()
+	defer dst.Unlock()
+	dst.LoginData = loginData
+	dst.Cookies = cookies
+	dst.ConnectSID = connectSID
+	dst.InsecureSkipVerify = insecure
+}
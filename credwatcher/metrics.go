@@ -0,0 +1,42 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credwatcher
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	reloads = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "netris_credentials_reloads_total",
+			Help: "Number of Netris controller credentials reloads from the Secret by result (success, failure).",
+		},
+		[]string{"result"},
+	)
+	lastReload = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "netris_credentials_last_reload_timestamp_seconds",
+			Help: "Time of the last successful Netris controller credentials reload.",
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(reloads, lastReload)
+}
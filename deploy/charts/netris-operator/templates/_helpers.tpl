@@ -103,6 +103,10 @@ Create netris-opeator controller envs
       name: {{ .Values.controllerCreds.password.secretName }}
       key: {{ .Values.controllerCreds.password.key }}
 {{- end }}
+{{- if and (not .Values.controller.host) .Values.controllerCreds.watchSecret }}
+- name: CONTROLLER_CREDENTIALS_SECRET
+  value: {{ .Values.controllerCreds.watchSecret | quote }}
+{{- end }}
 {{- if or (eq (lower (toString .Values.controller.insecure )) "true") (eq (lower (toString .Values.controller.insecure )) "false")  }}
 - name: CONTROLLER_INSECURE
   value: {{ .Values.controller.insecure | quote }}
@@ -115,10 +119,28 @@ Create netris-opeator controller envs
 {{- end }}
 - name: NOPERATOR_REQUEUE_INTERVAL
   value: {{ .Values.requeueInterval | default 15 | quote }}
+- name: NOPERATOR_DRIFT_CHECK_INTERVAL
+  value: {{ .Values.driftCheckInterval | default 300 | quote }}
+- name: NOPERATOR_DRY_RUN
+  value: {{ .Values.dryRun | default false | quote }}
+- name: NOPERATOR_MAINTENANCE_WINDOWS
+  value: {{ .Values.maintenanceWindows | default "" | quote }}
+- name: NOPERATOR_ENABLE_WEBHOOKS
+  value: {{ .Values.webhook.enabled | default false | quote }}
+- name: NOPERATOR_NAMING_POLICY
+  value: {{ .Values.namingPolicy | default "" | quote }}
+- name: NOPERATOR_CLUSTER_ID
+  value: {{ .Values.clusterID | default "" | quote }}
 - name: NOPERATOR_CALICO_ASN_RANGE
   value: {{ .Values.calicoASNRange | default "4230000000-4239999999" }}
 - name: NOPERATOR_L4LB_TENANT
   value: {{ .Values.l4lbTenant | default "" | quote }}
 - name: NOPERATOR_VPC_ID
   value: {{ .Values.vpcid | default 1 | quote }}
+- name: NOPERATOR_STORAGE_REFRESH_INTERVALS
+  value: {{ .Values.storageRefreshIntervals | default "" | quote }}
+- name: NOPERATOR_STORAGE_SNAPSHOT
+  value: {{ .Values.storageSnapshot.location | default "" | quote }}
+- name: NOPERATOR_STORAGE_SNAPSHOT_INTERVAL
+  value: {{ .Values.storageSnapshot.interval | default 60 | quote }}
 {{- end -}}
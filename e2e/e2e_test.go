@@ -0,0 +1,564 @@
+//go:build e2e
+// +build e2e
+
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v2address "github.com/netrisai/netriswebapi/http/addresses/v2"
+
+	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/controllers"
+	"github.com/netrisai/netris-operator/fakenetris"
+)
+
+const (
+	timeout  = 60 * time.Second
+	interval = 500 * time.Millisecond
+)
+
+var ctx = context.Background()
+
+func newVNet(name, gateway string) *k8sv1alpha1.VNet {
+	return &k8sv1alpha1.VNet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: k8sv1alpha1.VNetSpec{
+			Owner:        fakenetris.DefaultTenant,
+			State:        "active",
+			GuestTenants: []string{},
+			Sites: []k8sv1alpha1.VNetSite{{
+				Name:     fakenetris.DefaultSite,
+				Gateways: []k8sv1alpha1.VNetGateway{{Prefix: gateway}},
+			}},
+		},
+	}
+}
+
+func vnetStatus(name string) func() string {
+	return func() string {
+		vnet := &k8sv1alpha1.VNet{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, vnet); err != nil {
+			return ""
+		}
+		return vnet.Status.Status
+	}
+}
+
+func eventReasons(name string) func() []string {
+	return func() []string {
+		events := &v1.EventList{}
+		if err := k8sClient.List(ctx, events, client.InNamespace("default")); err != nil {
+			return nil
+		}
+		reasons := []string{}
+		for _, event := range events.Items {
+			if event.InvolvedObject.Name == name {
+				reasons = append(reasons, event.Reason)
+			}
+		}
+		return reasons
+	}
+}
+
+func inNetris(path, name string) func() bool {
+	return func() bool {
+		_, ok := netris.Find(path, name)
+		return ok
+	}
+}
+
+var _ = Describe("VNet", func() {
+	It("is created in Netris and deleted with the resource", func() {
+		vnet := newVNet("vnet-e2e", "10.5.0.1/24")
+		Expect(k8sClient.Create(ctx, vnet)).To(Succeed())
+
+		Eventually(inNetris(v2address.VNetBase, vnet.Name), timeout, interval).Should(BeTrue())
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Active"))
+
+		obj, _ := netris.Find(v2address.VNetBase, vnet.Name)
+		Expect(obj["state"]).To(Equal("active"))
+		Eventually(eventReasons(vnet.Name), timeout, interval).Should(ContainElement(controllers.EventCreated))
+
+		Expect(k8sClient.Delete(ctx, vnet)).To(Succeed())
+		Eventually(inNetris(v2address.VNetBase, vnet.Name), timeout, interval).Should(BeFalse())
+	})
+
+	It("reports NetrisUnavailable while Netris is down and recovers", func() {
+		vnet := newVNet("vnet-outage", "10.6.0.1/24")
+		Expect(k8sClient.Create(ctx, vnet)).To(Succeed())
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Active"))
+
+		netris.SetDown(true)
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("NetrisUnavailable"))
+
+		netris.SetDown(false)
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Active"))
+
+		Expect(k8sClient.Delete(ctx, vnet)).To(Succeed())
+		Eventually(inNetris(v2address.VNetBase, vnet.Name), timeout, interval).Should(BeFalse())
+	})
+
+	It("retries a create refused with 503", func() {
+		netris.FailNext(http.MethodPost, v2address.VNetBase, http.StatusServiceUnavailable, 2)
+
+		vnet := newVNet("vnet-retry", "10.7.0.1/24")
+		Expect(k8sClient.Create(ctx, vnet)).To(Succeed())
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Active"))
+		Expect(netris.Objects(v2address.VNetBase)).To(ContainElement(HaveKeyWithValue("name", vnet.Name)))
+
+		Expect(k8sClient.Delete(ctx, vnet)).To(Succeed())
+		Eventually(inNetris(v2address.VNetBase, vnet.Name), timeout, interval).Should(BeFalse())
+	})
+
+	It("keeps a change made in Netris with the report drift policy", func() {
+		vnet := newVNet("vnet-report", "10.8.0.1/24")
+		vnet.Annotations = map[string]string{"resource.k8s.netris.ai/driftPolicy": "report"}
+		Expect(k8sClient.Create(ctx, vnet)).To(Succeed())
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Active"))
+
+		obj, _ := netris.Find(v2address.VNetBase, vnet.Name)
+		Expect(netris.Update(v2address.VNetBase, obj.ID(), fakenetris.Object{"state": "disabled"})).To(BeTrue())
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Drifted"))
+		Eventually(eventReasons(vnet.Name), timeout, interval).Should(ContainElement(controllers.EventDriftDetected))
+
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: vnet.Name, Namespace: "default"}, vnet)).To(Succeed())
+		Expect(vnet.Status.DriftedFields).To(ContainElement(k8sv1alpha1.DriftedField{Field: "spec.state", Spec: `"active"`, Netris: `"disabled"`}))
+		Consistently(func() interface{} {
+			obj, _ := netris.Find(v2address.VNetBase, vnet.Name)
+			return obj["state"]
+		}, 5*time.Second, interval).Should(Equal("disabled"))
+
+		Expect(k8sClient.Delete(ctx, vnet)).To(Succeed())
+		Eventually(inNetris(v2address.VNetBase, vnet.Name), timeout, interval).Should(BeFalse())
+	})
+
+	It("writes a change made in Netris into the spec with the adopt drift policy", func() {
+		vnet := newVNet("vnet-adopt", "10.9.0.1/24")
+		vnet.Annotations = map[string]string{"resource.k8s.netris.ai/driftPolicy": "adopt"}
+		Expect(k8sClient.Create(ctx, vnet)).To(Succeed())
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Active"))
+
+		obj, _ := netris.Find(v2address.VNetBase, vnet.Name)
+		Expect(netris.Update(v2address.VNetBase, obj.ID(), fakenetris.Object{"state": "disabled"})).To(BeTrue())
+		Eventually(func() string {
+			vnet := &k8sv1alpha1.VNet{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: "vnet-adopt", Namespace: "default"}, vnet); err != nil {
+				return ""
+			}
+			return vnet.Spec.State
+		}, timeout, interval).Should(Equal("disabled"))
+		Eventually(eventReasons(vnet.Name), timeout, interval).Should(ContainElement(controllers.EventDriftAdopted))
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Disabled"))
+
+		Expect(k8sClient.Delete(ctx, vnet)).To(Succeed())
+		Eventually(inNetris(v2address.VNetBase, vnet.Name), timeout, interval).Should(BeFalse())
+	})
+
+	It("applies a spec edit refused with 503 on retry with the adopt drift policy", func() {
+		vnet := newVNet("vnet-adopt-retry", "10.14.0.1/24")
+		vnet.Annotations = map[string]string{"resource.k8s.netris.ai/driftPolicy": "adopt"}
+		Expect(k8sClient.Create(ctx, vnet)).To(Succeed())
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Active"))
+
+		netris.FailNext(http.MethodPut, v2address.VNetBase, http.StatusServiceUnavailable, 1)
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: vnet.Name, Namespace: "default"}, vnet)).To(Succeed())
+		vnet.Spec.State = "disabled"
+		Expect(k8sClient.Update(ctx, vnet)).To(Succeed())
+
+		Eventually(func() interface{} {
+			obj, _ := netris.Find(v2address.VNetBase, vnet.Name)
+			return obj["state"]
+		}, timeout, interval).Should(Equal("disabled"))
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Disabled"))
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: vnet.Name, Namespace: "default"}, vnet)).To(Succeed())
+		Expect(vnet.Spec.State).To(Equal("disabled"))
+		Expect(eventReasons(vnet.Name)()).NotTo(ContainElement(controllers.EventDriftAdopted))
+
+		Expect(k8sClient.Delete(ctx, vnet)).To(Succeed())
+		Eventually(inNetris(v2address.VNetBase, vnet.Name), timeout, interval).Should(BeFalse())
+	})
+
+	It("plans the create in status and sends nothing to Netris with the dry-run annotation", func() {
+		vnet := newVNet("vnet-dry-run", "10.10.0.1/24")
+		vnet.Annotations = map[string]string{"resource.k8s.netris.ai/dry-run": "true"}
+		Expect(k8sClient.Create(ctx, vnet)).To(Succeed())
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("DryRun"))
+
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: vnet.Name, Namespace: "default"}, vnet)).To(Succeed())
+		Expect(vnet.Status.Plan).NotTo(BeNil())
+		Expect(vnet.Status.Plan.Action).To(Equal("create"))
+		Expect(vnet.Status.Plan.Payload).To(ContainSubstring("10.10.0.1/24"))
+		Consistently(inNetris(v2address.VNetBase, vnet.Name), 5*time.Second, interval).Should(BeFalse())
+
+		delete(vnet.Annotations, "resource.k8s.netris.ai/dry-run")
+		Expect(k8sClient.Update(ctx, vnet)).To(Succeed())
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Active"))
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: vnet.Name, Namespace: "default"}, vnet)).To(Succeed())
+		Expect(vnet.Status.Plan).To(BeNil())
+
+		Expect(k8sClient.Delete(ctx, vnet)).To(Succeed())
+		Eventually(inNetris(v2address.VNetBase, vnet.Name), timeout, interval).Should(BeFalse())
+	})
+
+	It("sends nothing to Netris while paused and resumes when the annotation is removed", func() {
+		vnet := newVNet("vnet-paused", "10.11.0.1/24")
+		vnet.Annotations = map[string]string{"resource.k8s.netris.ai/paused": "true"}
+		Expect(k8sClient.Create(ctx, vnet)).To(Succeed())
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Paused"))
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: vnet.Name, Namespace: "default"}, vnet)).To(Succeed())
+		Expect(k8sv1alpha1.IsConditionTrue(vnet.Status.Conditions, k8sv1alpha1.ConditionPaused)).To(BeTrue())
+		Consistently(inNetris(v2address.VNetBase, vnet.Name), 5*time.Second, interval).Should(BeFalse())
+
+		delete(vnet.Annotations, "resource.k8s.netris.ai/paused")
+		Expect(k8sClient.Update(ctx, vnet)).To(Succeed())
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Active"))
+		Expect(inNetris(v2address.VNetBase, vnet.Name)()).To(BeTrue())
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: vnet.Name, Namespace: "default"}, vnet)).To(Succeed())
+		Expect(k8sv1alpha1.FindCondition(vnet.Status.Conditions, k8sv1alpha1.ConditionPaused)).To(BeNil())
+
+		Expect(k8sClient.Delete(ctx, vnet)).To(Succeed())
+		Eventually(inNetris(v2address.VNetBase, vnet.Name), timeout, interval).Should(BeFalse())
+	})
+
+	It("keeps a protected VNet in Netris until the protection is removed", func() {
+		vnet := newVNet("vnet-protected", "10.12.0.1/24")
+		vnet.Annotations = map[string]string{"resource.k8s.netris.ai/deletion-protection": "true"}
+		Expect(k8sClient.Create(ctx, vnet)).To(Succeed())
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Active"))
+
+		Expect(k8sClient.Delete(ctx, vnet)).To(Succeed())
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("DeletionBlocked"))
+		Eventually(eventReasons(vnet.Name), timeout, interval).Should(ContainElement(controllers.EventDeleteBlocked))
+		Consistently(inNetris(v2address.VNetBase, vnet.Name), 5*time.Second, interval).Should(BeTrue())
+
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: vnet.Name, Namespace: "default"}, vnet)).To(Succeed())
+		delete(vnet.Annotations, "resource.k8s.netris.ai/deletion-protection")
+		Expect(k8sClient.Update(ctx, vnet)).To(Succeed())
+		Eventually(inNetris(v2address.VNetBase, vnet.Name), timeout, interval).Should(BeFalse())
+	})
+
+	It("is named by spec.netrisName and reports a taken name before create", func() {
+		vnet := newVNet("vnet-named", "10.13.0.1/24")
+		vnet.Spec.NetrisName = "shared-name"
+		Expect(k8sClient.Create(ctx, vnet)).To(Succeed())
+		Eventually(vnetStatus(vnet.Name), timeout, interval).Should(Equal("Active"))
+		Expect(inNetris(v2address.VNetBase, "shared-name")()).To(BeTrue())
+		Expect(inNetris(v2address.VNetBase, vnet.Name)()).To(BeFalse())
+
+		other := newVNet("vnet-collides", "10.13.1.1/24")
+		other.Spec.NetrisName = "shared-name"
+		Expect(k8sClient.Create(ctx, other)).To(Succeed())
+		Eventually(vnetStatus(other.Name), timeout, interval).Should(Equal("NameCollision"))
+		Eventually(eventReasons(other.Name), timeout, interval).Should(ContainElement(controllers.EventNameCollision))
+
+		Expect(k8sClient.Delete(ctx, other)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, vnet)).To(Succeed())
+		Eventually(inNetris(v2address.VNetBase, "shared-name"), timeout, interval).Should(BeFalse())
+	})
+})
+
+var _ = Describe("lbwatcher", func() {
+	It("creates an L4LB for a LoadBalancer Service and assigns its IP", func() {
+		labels := map[string]string{"app": "web"}
+		node := createReadyNode("web-node", "10.1.0.10")
+
+		// there's no endpointslice controller in envtest, the endpoint is placed by hand.
+		slice := &discoveryv1beta1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "web-1",
+				Namespace: "default",
+				Labels:    map[string]string{discoveryv1beta1.LabelServiceName: "web"},
+			},
+			AddressType: discoveryv1beta1.AddressTypeIPv4,
+			Endpoints: []discoveryv1beta1.Endpoint{{
+				Addresses: []string{"192.168.1.10"},
+				Topology:  map[string]string{v1.LabelHostname: "web-node"},
+			}},
+		}
+		Expect(k8sClient.Create(ctx, slice)).To(Succeed())
+
+		svc := &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: v1.ServiceSpec{
+				Type:     v1.ServiceTypeLoadBalancer,
+				Selector: labels,
+				Ports:    []v1.ServicePort{{Name: "http", Protocol: v1.ProtocolTCP, Port: 80, TargetPort: intstr.FromInt(80)}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, svc)).To(Succeed())
+
+		_, lbNet, _ := net.ParseCIDR(fakenetris.LBSubnet)
+		Eventually(func() string {
+			current := &v1.Service{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace}, current); err != nil {
+				return ""
+			}
+			if len(current.Status.LoadBalancer.Ingress) == 0 {
+				return ""
+			}
+			return current.Status.LoadBalancer.Ingress[0].IP
+		}, timeout, interval).Should(WithTransform(func(ip string) bool {
+			return lbNet.Contains(net.ParseIP(ip))
+		}, BeTrue()))
+
+		lbs := netris.Objects(v2address.L4LB)
+		Expect(lbs).To(HaveLen(1))
+		Expect(lbs[0]["siteName"]).To(Equal(fakenetris.DefaultSite))
+
+		Expect(k8sClient.Delete(ctx, svc)).To(Succeed())
+		Eventually(func() int {
+			return len(netris.Objects(v2address.L4LB))
+		}, timeout, interval).Should(BeZero())
+		Expect(k8sClient.Delete(ctx, slice)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, node)).To(Succeed())
+	})
+
+	It("uses only the nodes of the ready endpoints for the Local policy", func() {
+		nodeA := createReadyNode("local-a", "10.1.0.11")
+		nodeB := createReadyNode("local-b", "10.1.0.12")
+
+		ready, notReady := true, false
+		// a Service without a selector, its endpoints are managed by hand.
+		slice := &discoveryv1beta1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "local-1",
+				Namespace: "default",
+				Labels:    map[string]string{discoveryv1beta1.LabelServiceName: "local"},
+			},
+			AddressType: discoveryv1beta1.AddressTypeIPv4,
+			Endpoints: []discoveryv1beta1.Endpoint{{
+				Addresses:  []string{"192.168.1.11"},
+				Conditions: discoveryv1beta1.EndpointConditions{Ready: &ready},
+				Topology:   map[string]string{v1.LabelHostname: "local-a"},
+			}, {
+				Addresses:  []string{"192.168.1.12"},
+				Conditions: discoveryv1beta1.EndpointConditions{Ready: &notReady},
+				Topology:   map[string]string{v1.LabelHostname: "local-b"},
+			}},
+		}
+		Expect(k8sClient.Create(ctx, slice)).To(Succeed())
+
+		svc := &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "local", Namespace: "default"},
+			Spec: v1.ServiceSpec{
+				Type:                  v1.ServiceTypeLoadBalancer,
+				ExternalTrafficPolicy: v1.ServiceExternalTrafficPolicyTypeLocal,
+				Ports:                 []v1.ServicePort{{Name: "http", Protocol: v1.ProtocolTCP, Port: 80, TargetPort: intstr.FromInt(80)}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, svc)).To(Succeed())
+
+		Eventually(func() []string {
+			ips := []string{}
+			for _, lb := range netris.Objects(v2address.L4LB) {
+				backends, _ := lb["backendIps"].([]interface{})
+				for _, backend := range backends {
+					if b, ok := backend.(map[string]interface{}); ok {
+						ips = append(ips, fmt.Sprint(b["ip"]))
+					}
+				}
+			}
+			return ips
+		}, timeout, interval).Should(Equal([]string{"10.1.0.11"}))
+
+		Expect(k8sClient.Delete(ctx, svc)).To(Succeed())
+		Eventually(func() int {
+			return len(netris.Objects(v2address.L4LB))
+		}, timeout, interval).Should(BeZero())
+		Expect(k8sClient.Delete(ctx, slice)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, nodeA)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, nodeB)).To(Succeed())
+	})
+
+	It("applies the L4LB annotations of a Service and reports the invalid ones", func() {
+		node := createReadyNode("tuned-node", "10.1.0.13")
+		slice := &discoveryv1beta1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "tuned-1",
+				Namespace: "default",
+				Labels:    map[string]string{discoveryv1beta1.LabelServiceName: "tuned"},
+			},
+			AddressType: discoveryv1beta1.AddressTypeIPv4,
+			Endpoints: []discoveryv1beta1.Endpoint{{
+				Addresses: []string{"192.168.1.13"},
+				Topology:  map[string]string{v1.LabelHostname: "tuned-node"},
+			}},
+		}
+		Expect(k8sClient.Create(ctx, slice)).To(Succeed())
+
+		// addresses of the subnet used outside of the cluster.
+		subnet, ok := netris.Find(v2address.IPAMBase, "load-balancers")
+		Expect(ok).To(BeTrue())
+		hostIDs := netris.Seed(v2address.IPAMHosts, fakenetris.Object{"address": "10.2.0.1", "subnetID": subnet.ID()})
+		natIDs := netris.Seed(v2address.NAT, fakenetris.Object{"name": "tuned-snat", "action": "SNAT", "snatToIP": "10.2.0.2/32"})
+
+		svc := &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "tuned",
+				Namespace: "default",
+				Annotations: map[string]string{
+					"l4lb.k8s.netris.ai/check-type":         "http",
+					"l4lb.k8s.netris.ai/check-request-path": "/healthz",
+					"l4lb.k8s.netris.ai/frontend-subnet":    "load-balancers",
+				},
+			},
+			Spec: v1.ServiceSpec{
+				Type:  v1.ServiceTypeLoadBalancer,
+				Ports: []v1.ServicePort{{Name: "http", Protocol: v1.ProtocolTCP, Port: 80, TargetPort: intstr.FromInt(80)}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, svc)).To(Succeed())
+
+		_, lbNet, _ := net.ParseCIDR(fakenetris.LBSubnet)
+		Eventually(func() int {
+			return len(netris.Objects(v2address.L4LB))
+		}, timeout, interval).Should(Equal(1))
+		lb := netris.Objects(v2address.L4LB)[0]
+		Expect(lbNet.Contains(net.ParseIP(fmt.Sprint(lb["ip"])))).To(BeTrue())
+		Expect(lb["ip"]).NotTo(BeElementOf("10.2.0.1", "10.2.0.2"))
+		Expect(lb["healthCheck"]).To(HaveKeyWithValue("HTTP", HaveKeyWithValue("requestPath", "/healthz")))
+
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace}, svc)).To(Succeed())
+		svc.Annotations["l4lb.k8s.netris.ai/check-type"] = "icmp"
+		Expect(k8sClient.Update(ctx, svc)).To(Succeed())
+		Eventually(eventReasons(svc.Name), timeout, interval).Should(ContainElement("InvalidL4LBAnnotation"))
+		Consistently(func() int {
+			return len(netris.Objects(v2address.L4LB))
+		}, "2s", interval).Should(Equal(1))
+
+		Expect(k8sClient.Delete(ctx, svc)).To(Succeed())
+		Eventually(func() int {
+			return len(netris.Objects(v2address.L4LB))
+		}, timeout, interval).Should(BeZero())
+		Expect(k8sClient.Delete(ctx, slice)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, node)).To(Succeed())
+		netris.Delete(v2address.IPAMHosts, hostIDs[0])
+		netris.Delete(v2address.NAT, natIDs[0])
+	})
+})
+
+// createReadyNode creates a ready Node with the InternalIP, there's no
+// kubelet in envtest to report it.
+func createReadyNode(name, ip string) *v1.Node {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   name,
+		Labels: map[string]string{v1.LabelHostname: name},
+	}}
+	Expect(k8sClient.Create(ctx, node)).To(Succeed())
+	node.Status.Addresses = []v1.NodeAddress{{Type: v1.NodeInternalIP, Address: ip}}
+	node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+	Expect(k8sClient.Status().Update(ctx, node)).To(Succeed())
+	return node
+}
+
+var _ = Describe("calicowatcher", func() {
+	It("peers the calico nodes with Netris", func() {
+		// the VNet of the node subnet, the nodes peer with its gateway.
+		netris.Seed(v2address.VNetBase, fakenetris.Object{
+			"name":     "vnet-nodes",
+			"state":    "active",
+			"vlan":     "auto",
+			"tenant":   map[string]interface{}{"name": fakenetris.DefaultTenant},
+			"sites":    []interface{}{map[string]interface{}{"name": fakenetris.DefaultSite}},
+			"gateways": []interface{}{map[string]interface{}{"prefix": "10.1.0.1/24"}},
+		})
+
+		ipPool := calicoObject("IPPool", "default-ipv4-ippool", map[string]interface{}{
+			"cidr":      "192.168.0.0/16",
+			"blockSize": int64(26),
+			"ipipMode":  "Always",
+		})
+		Expect(k8sClient.Create(ctx, ipPool)).To(Succeed())
+
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name: "node1",
+			Annotations: map[string]string{
+				"projectcalico.org/IPv4Address":        "10.1.0.10/24",
+				"projectcalico.org/IPv4IPIPTunnelAddr": "192.168.1.0",
+			},
+		}}
+		Expect(k8sClient.Create(ctx, node)).To(Succeed())
+
+		bgpConf := calicoObject("BGPConfiguration", "default", map[string]interface{}{
+			"nodeToNodeMeshEnabled": true,
+			"asNumber":              int64(64512),
+		})
+		bgpConf.SetAnnotations(map[string]string{"manage.k8s.netris.ai/calico": "true"})
+		Expect(k8sClient.Create(ctx, bgpConf)).To(Succeed())
+
+		Eventually(func() []k8sv1alpha1.BGP {
+			bgps := &k8sv1alpha1.BGPList{}
+			if err := k8sClient.List(ctx, bgps, client.InNamespace("default")); err != nil {
+				return nil
+			}
+			generated := []k8sv1alpha1.BGP{}
+			for _, bgp := range bgps.Items {
+				if bgp.GetAnnotations()["k8s.netris.ai/calicowatcher"] == "true" {
+					generated = append(generated, bgp)
+				}
+			}
+			return generated
+		}, timeout, interval).Should(ConsistOf(WithTransform(func(bgp k8sv1alpha1.BGP) k8sv1alpha1.BGPSpec {
+			return bgp.Spec
+		}, And(
+			HaveField("Site", fakenetris.DefaultSite),
+			HaveField("RemoteIP", "10.1.0.10/24"),
+			HaveField("LocalIP", "10.1.0.1/24"),
+			HaveField("Transport.Name", "vnet-nodes"),
+		))))
+
+		Eventually(func() error {
+			return k8sClient.Get(ctx, types.NamespacedName{Name: "netris-controller"}, calicoObject("BGPPeer", "", nil))
+		}, timeout, interval).Should(Succeed())
+
+		Eventually(func() string {
+			current := &v1.Node{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, current); err != nil {
+				return ""
+			}
+			return current.GetAnnotations()["projectcalico.org/ASNumber"]
+		}, timeout, interval).ShouldNot(BeEmpty())
+	})
+})
+
+func calicoObject(kind, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetAPIVersion("crd.projectcalico.org/v1")
+	obj.SetKind(kind)
+	obj.SetName(name)
+	if spec != nil {
+		obj.Object["spec"] = spec
+	}
+	return obj
+}
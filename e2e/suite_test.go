@@ -0,0 +1,238 @@
+//go:build e2e
+// +build e2e
+
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The end-to-end suite runs the reconcilers, the lbwatcher and the
+// calicowatcher against an envtest API server and a fake Netris controller.
+// Run it with "make e2e".
+package e2e
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/printer"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	v2address "github.com/netrisai/netriswebapi/http/addresses/v2"
+	api "github.com/netrisai/netriswebapi/v2"
+
+	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/calicowatcher"
+	"github.com/netrisai/netris-operator/controllers"
+	"github.com/netrisai/netris-operator/fakenetris"
+	"github.com/netrisai/netris-operator/lbwatcher"
+	"github.com/netrisai/netris-operator/netrisstorage"
+)
+
+var (
+	cfg       *rest.Config
+	k8sClient client.Client
+	testEnv   *envtest.Environment
+	scheme    = runtime.NewScheme()
+
+	netris   *fakenetris.Server
+	cred     *api.Clientset
+	nStorage *netrisstorage.Storage
+	vpcID    int
+
+	stop       = make(chan struct{})
+	kubeconfig string
+)
+
+// watcherInterval is the requeue interval of the reconcilers and the watchers
+// in seconds, short to keep the suite fast.
+const watcherInterval = 1
+
+func TestE2E(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecsWithDefaultAndCustomReporters(t,
+		"End-to-End Suite",
+		[]Reporter{printer.NewlineReporter{}})
+}
+
+var _ = BeforeSuite(func(done Done) {
+	logf.SetLogger(zap.New(zap.UseDevMode(true), zap.WriteTo(GinkgoWriter)))
+
+	By("bootstrapping test environment")
+	testEnv = &envtest.Environment{
+		CRDDirectoryPaths: []string{
+			filepath.Join("..", "config", "crd", "bases"),
+			filepath.Join("testdata"),
+		},
+	}
+
+	var err error
+	cfg, err = testEnv.Start()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(cfg).ToNot(BeNil())
+
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(k8sv1alpha1.AddToScheme(scheme))
+
+	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme})
+	Expect(err).ToNot(HaveOccurred())
+
+	// the watchers take the rest config from ctrl.GetConfigOrDie.
+	kubeconfig, err = writeKubeconfig(cfg)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(os.Setenv("KUBECONFIG", kubeconfig)).To(Succeed())
+
+	By("starting the fake Netris controller")
+	netris = fakenetris.New()
+	netris.SeedDefaults()
+	vpc, ok := netris.Find(v2address.VPC, fakenetris.DefaultVPC)
+	Expect(ok).To(BeTrue())
+	vpcID = vpc.ID()
+
+	cred, err = netris.Clientset()
+	Expect(err).ToNot(HaveOccurred())
+
+	intervals := map[string]int{}
+	for _, kind := range []string{
+		netrisstorage.KindPorts, netrisstorage.KindSites, netrisstorage.KindTenants, netrisstorage.KindVNets,
+		netrisstorage.KindVPCs, netrisstorage.KindBGPs, netrisstorage.KindL4LBs, netrisstorage.KindSubnets,
+		netrisstorage.KindHWs, netrisstorage.KindLinks, netrisstorage.KindNATs, netrisstorage.KindInventoryProfiles,
+	} {
+		intervals[kind] = watcherInterval
+	}
+	nStorage = netrisstorage.NewStorageWithOptions(cred, netrisstorage.Options{VPCID: vpcID, RefreshIntervals: intervals})
+	Expect(nStorage.Download()).To(Succeed())
+	go nStorage.DownloadUntil(stop)
+
+	By("starting the manager")
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:             scheme,
+		MetricsBindAddress: "0",
+	})
+	Expect(err).ToNot(HaveOccurred())
+	Expect(setupReconcilers(mgr)).To(Succeed())
+
+	By("adding the watchers")
+	lbWatcher, err := lbwatcher.NewWatcher(nStorage, cred, mgr, lbwatcher.Options{LogLevel: "debug", RequeueInterval: watcherInterval, ResyncInterval: watcherInterval})
+	Expect(err).ToNot(HaveOccurred())
+	Expect(mgr.Add(lbWatcher)).To(Succeed())
+
+	cWatcher, err := calicowatcher.NewWatcher(nStorage, mgr, calicowatcher.Options{LogLevel: "debug", RequeueInterval: watcherInterval})
+	Expect(err).ToNot(HaveOccurred())
+	Expect(mgr.Add(cWatcher)).To(Succeed())
+
+	go func() {
+		defer GinkgoRecover()
+		Expect(mgr.Start(stop)).To(Succeed())
+	}()
+
+	close(done)
+}, 60)
+
+var _ = AfterSuite(func() {
+	By("tearing down the test environment")
+	close(stop)
+	if netris != nil {
+		netris.Close()
+	}
+	if kubeconfig != "" {
+		os.Remove(kubeconfig)
+	}
+	err := testEnv.Stop()
+	Expect(err).ToNot(HaveOccurred())
+})
+
+// setupReconcilers registers the reconcilers the way main does, with the
+// clientset of the fake Netris controller.
+func setupReconcilers(mgr ctrl.Manager) error {
+	controllerSet := controllers.NewControllerSet(nStorage)
+	log := ctrl.Log.WithName("controllers")
+	cl := mgr.GetClient()
+	s := mgr.GetScheme()
+	recorder := mgr.GetEventRecorderFor("netris-operator")
+
+	reconcilers := []interface {
+		SetupWithManager(ctrl.Manager) error
+	}{
+		&controllers.NetrisControllerReconciler{Client: cl, Log: log.WithName("NetrisController"), Scheme: s, Controllers: controllerSet, Timeout: 5},
+		&controllers.VNetReconciler{Client: cl, Log: log.WithName("VNet"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.VNetMetaReconciler{Client: cl, Log: log.WithName("VNetMeta"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.BGPReconciler{Client: cl, Log: log.WithName("BGP"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.BGPMetaReconciler{Client: cl, Log: log.WithName("BGPMeta"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.L4LBReconciler{Client: cl, Log: log.WithName("L4LB"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, L4LBTenant: fakenetris.DefaultTenant, VPCID: vpcID},
+		&controllers.L4LBMetaReconciler{Client: cl, Log: log.WithName("L4LBMeta"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, VPCID: vpcID},
+		&controllers.SiteReconciler{Client: cl, Log: log.WithName("Site"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.SiteMetaReconciler{Client: cl, Log: log.WithName("SiteMeta"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.AllocationReconciler{Client: cl, Log: log.WithName("Allocation"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.AllocationMetaReconciler{Client: cl, Log: log.WithName("AllocationMeta"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.SubnetReconciler{Client: cl, Log: log.WithName("Subnet"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.SubnetMetaReconciler{Client: cl, Log: log.WithName("SubnetMeta"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.SoftgateReconciler{Client: cl, Log: log.WithName("Softgate"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.SoftgateMetaReconciler{Client: cl, Log: log.WithName("SoftgateMeta"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.SwitchReconciler{Client: cl, Log: log.WithName("Switch"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.SwitchMetaReconciler{Client: cl, Log: log.WithName("SwitchMeta"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.ControllerReconciler{Client: cl, Log: log.WithName("Controller"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.ControllerMetaReconciler{Client: cl, Log: log.WithName("ControllerMeta"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.LinkReconciler{Client: cl, Log: log.WithName("Link"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.LinkMetaReconciler{Client: cl, Log: log.WithName("LinkMeta"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.NatReconciler{Client: cl, Log: log.WithName("Nat"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.NatMetaReconciler{Client: cl, Log: log.WithName("NatMeta"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.InventoryProfileReconciler{Client: cl, Log: log.WithName("InventoryProfile"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+		&controllers.InventoryProfileMetaReconciler{Client: cl, Log: log.WithName("InventoryProfileMeta"), Scheme: s, Cred: cred, NStorage: nStorage, Controllers: controllerSet, Recorder: recorder},
+	}
+	for _, r := range reconcilers {
+		if err := r.SetupWithManager(mgr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeKubeconfig(cfg *rest.Config) (string, error) {
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters["envtest"] = &clientcmdapi.Cluster{
+		Server:                   cfg.Host,
+		CertificateAuthorityData: cfg.CAData,
+		InsecureSkipTLSVerify:    cfg.Insecure,
+	}
+	kubeconfig.AuthInfos["envtest"] = &clientcmdapi.AuthInfo{
+		ClientCertificateData: cfg.CertData,
+		ClientKeyData:         cfg.KeyData,
+		Token:                 cfg.BearerToken,
+	}
+	kubeconfig.Contexts["envtest"] = &clientcmdapi.Context{Cluster: "envtest", AuthInfo: "envtest"}
+	kubeconfig.CurrentContext = "envtest"
+
+	f, err := ioutil.TempFile("", "e2e-kubeconfig-")
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	return f.Name(), clientcmd.WriteToFile(*kubeconfig, f.Name())
+}
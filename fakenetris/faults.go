@@ -0,0 +1,87 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakenetris
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Fault changes the response to the matching requests.
+type Fault struct {
+	// Method matches the request method, every method if empty.
+	Method string
+	// Path matches the requests with the path prefix, every path if empty.
+	Path string
+	// Status is the reply status. With a zero status the request is served
+	// normally after the Delay.
+	Status int
+	// Delay is applied before the reply, e.g. to trigger client timeouts.
+	Delay time.Duration
+	// Drop closes the connection without a reply.
+	Drop bool
+	// Times is how many requests the fault applies to, zero means until cleared.
+	Times int
+
+	hits int
+}
+
+// Inject adds a fault. The first matching fault applies to a request.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// FailNext makes the next n requests of the method and the path prefix fail
+// with the status.
+func (s *Server) FailNext(method, path string, status, n int) {
+	s.Inject(Fault{Method: method, Path: path, Status: status, Times: n})
+}
+
+// ClearFaults removes the injected faults and brings the server back up.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+	s.down = false
+}
+
+// SetDown makes every request fail with 503 Service Unavailable.
+func (s *Server) SetDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+func (s *Server) matchFault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+		f.hits++
+		if f.Times > 0 && f.hits >= f.Times {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+		}
+		return f
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakenetris
+
+import (
+	"fmt"
+
+	v1address "github.com/netrisai/netriswebapi/http/addresses/v1"
+	v2address "github.com/netrisai/netriswebapi/http/addresses/v2"
+)
+
+// Names and prefixes of the objects seeded by SeedDefaults.
+const (
+	DefaultTenant  = "Admin"
+	DefaultVPC     = "Default"
+	DefaultSite    = "Default"
+	DefaultProfile = "default"
+	DefaultDHCP    = "Default"
+	DefaultSwitch  = "leaf1"
+
+	// Allocation holds the subnets below.
+	Allocation = "10.0.0.0/8"
+	// NodeSubnet is the common subnet of the Kubernetes nodes.
+	NodeSubnet = "10.1.0.0/24"
+	// LBSubnet is the load-balancer subnet of the automatic L4LB IPs.
+	LBSubnet = "10.2.0.0/24"
+	// ManagementSubnet is the subnet of the inventory management addresses.
+	ManagementSubnet = "10.3.0.0/24"
+	// LoopbackSubnet is the subnet of the inventory loopback addresses.
+	LoopbackSubnet = "10.4.0.0/24"
+)
+
+// SeedDefaults seeds the objects every Netris controller has after the
+// initial setup: a tenant, the default VPC, a site, a switch with ports, and
+// the IPAM subnets of the nodes and of the load balancers.
+func (s *Server) SeedDefaults() {
+	s.Seed(v1address.Tenants, Object{"name": DefaultTenant, "description": "Admin Tenant"})
+	s.Seed(v2address.VPC, Object{
+		"name":        DefaultVPC,
+		"isDefault":   true,
+		"adminTenant": map[string]interface{}{"name": DefaultTenant},
+	})
+	s.Seed(v2address.Sites, Object{
+		"name":         DefaultSite,
+		"publicAsn":    65001,
+		"rohAsn":       65502,
+		"vmAsn":        65503,
+		"vlanRange":    "2-4094",
+		"switchFabric": "netris",
+		"aclPolicy":    "permit",
+	})
+	s.Seed(v2address.InventoryNOS,
+		Object{"name": "Cumulus Linux", "tag": "cumulus_linux"},
+		Object{"name": "SONiC", "tag": "sonic"},
+		Object{"name": "Ubuntu SwitchDev", "tag": "ubuntu_switch_dev"},
+	)
+	s.Seed(v1address.InventoryProfiles, Object{"name": DefaultProfile, "timezone": map[string]interface{}{"tzCode": "UTC"}})
+	s.Seed(v2address.DHCP, Object{"name": DefaultDHCP})
+
+	site := map[string]interface{}{"name": DefaultSite}
+	tenant := map[string]interface{}{"name": DefaultTenant}
+	s.Seed(v2address.IPAMBase,
+		Object{"type": "allocation", "name": "default-allocation", "prefix": Allocation, "tenant": tenant},
+		Object{"type": "subnet", "name": "nodes", "prefix": NodeSubnet, "purpose": "common", "tenant": tenant, "sites": []interface{}{site}},
+		Object{"type": "subnet", "name": "load-balancers", "prefix": LBSubnet, "purpose": "load-balancer", "tenant": tenant, "sites": []interface{}{site}},
+		Object{"type": "subnet", "name": "management", "prefix": ManagementSubnet, "purpose": "management", "tenant": tenant, "sites": []interface{}{site}},
+		Object{"type": "subnet", "name": "loopbacks", "prefix": LoopbackSubnet, "purpose": "loopback", "tenant": tenant, "sites": []interface{}{site}},
+	)
+
+	switchID := s.Seed(v2address.InventoryBase, Object{
+		"type":        "switch",
+		"name":        DefaultSwitch,
+		"site":        site,
+		"tenant":      tenant,
+		"asn":         4200000001,
+		"mainAddress": "10.4.0.11",
+		"mgmtAddress": "10.3.0.11",
+		"nos":         map[string]interface{}{"name": "Cumulus Linux", "tag": "cumulus_linux"},
+		"profile":     map[string]interface{}{"name": DefaultProfile},
+	})[0]
+	for i := 1; i <= 4; i++ {
+		port := fmt.Sprintf("swp%d", i)
+		s.Seed(v2address.Ports, Object{
+			"name":       fmt.Sprintf("%s@%s", port, DefaultSwitch),
+			"port":       port,
+			"_port":      port,
+			"shortName":  port,
+			"switchName": DefaultSwitch,
+			"switch":     map[string]interface{}{"id": switchID, "name": DefaultSwitch},
+			"site":       site,
+			"tenant":     tenant,
+		})
+	}
+}
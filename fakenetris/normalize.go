@@ -0,0 +1,345 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakenetris
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+
+	v1address "github.com/netrisai/netriswebapi/http/addresses/v1"
+	v2address "github.com/netrisai/netriswebapi/http/addresses/v2"
+)
+
+// The write and the read shapes of the Netris objects differ. The
+// normalizers keep the stored objects decodable into the read types of
+// netriswebapi, and fill in what Netris resolves on its own: ids of named
+// references, the default VPC, automatic IPs.
+
+func normalizeVNet(s *Server, obj Object) {
+	if vlan, ok := obj["vlan"].(string); ok {
+		n, _ := strconv.Atoi(vlan)
+		obj["vlan"] = n
+	}
+	// the fake provisions instantly.
+	obj["provisioning"] = false
+	s.resolve(obj, "tenant", v1address.Tenants)
+	obj["tenantID"] = obj.ref("tenant")
+	s.resolveAll(obj, "sites", v2address.Sites)
+	s.resolveAll(obj, "guestTenants", v1address.Tenants)
+	s.defaultVPC(obj)
+}
+
+func normalizeL4LB(s *Server, obj Object) {
+	if info, ok := obj["kubenet_info"].(string); ok {
+		decoded := map[string]interface{}{}
+		_ = json.Unmarshal([]byte(info), &decoded)
+		obj["kubenet_info"] = decoded
+	}
+	if check, ok := obj["healthCheck"].(string); ok {
+		hc := map[string]interface{}{}
+		if check == "TCP" || check == "HTTP" {
+			hc[check] = map[string]interface{}{
+				"timeOut":     obj["timeOut"],
+				"requestPath": obj["requestPath"],
+			}
+		}
+		obj["healthCheck"] = hc
+	}
+	s.resolve(obj, "site", v2address.Sites)
+	if site, ok := obj["site"].(map[string]interface{}); ok {
+		obj["siteName"] = site["name"]
+	}
+	s.resolve(obj, "tenant", v1address.Tenants)
+	s.defaultVPC(obj)
+	if automatic, _ := obj["automatic"].(bool); automatic && (obj["ip"] == nil || obj["ip"] == "") {
+		obj["ip"] = s.freeLBIP(obj.ID())
+	}
+}
+
+func normalizeNAT(s *Server, obj Object) {
+	for _, key := range []string{"action", "protocol", "state"} {
+		if v, ok := obj[key].(string); ok {
+			obj[key] = map[string]interface{}{"label": v, "value": v, "status": v}
+		}
+	}
+	s.resolve(obj, "site", v2address.Sites)
+	s.defaultVPC(obj)
+}
+
+// bgpReadKeys maps the fields of a BGP create request to the fields Netris
+// returns them in.
+var bgpReadKeys = map[string]string{
+	"allowAsIn":          "allowas_in",
+	"bgpCommunity":       "community",
+	"bgpPassword":        "bgp_password",
+	"defaultOriginate":   "default_originate",
+	"ipFamily":           "ip_version",
+	"localIP":            "local_ip",
+	"localPreference":    "local_preference",
+	"neighborAS":         "neighbor_as",
+	"neighborAddress":    "neighbor_address",
+	"prefixInboundMax":   "prefix_limit",
+	"prefixLength":       "prefix_length",
+	"prefixListInbound":  "prefix_list_inbound",
+	"prefixListOutbound": "prefix_list_outbound",
+	"prependInbound":     "prepend_inbound",
+	"prependOutbound":    "prepend_outbound",
+	"remoteIP":           "remote_ip",
+	"state":              "status",
+	"updateSource":       "update_source",
+}
+
+func normalizeBGP(s *Server, obj Object) {
+	for from, to := range bgpReadKeys {
+		if v, ok := obj[from]; ok {
+			obj[to] = v
+		}
+	}
+	s.resolve(obj, "site", v2address.Sites)
+	obj["site_id"] = obj.ref("site")
+	if site, ok := obj["site"].(map[string]interface{}); ok {
+		obj["site_name"] = site["name"]
+	}
+	if hw, ok := obj["hardware"].(map[string]interface{}); ok {
+		obj["term_switch_id"] = toInt(hw["id"])
+		obj["term_sw_name"] = hw["name"]
+	}
+	s.defaultVPC(obj)
+}
+
+func normalizeVPC(s *Server, obj Object) {
+	s.resolve(obj, "adminTenant", v1address.Tenants)
+	s.resolveAll(obj, "guestTenant", v1address.Tenants)
+}
+
+func normalizePort(s *Server, obj Object) {
+	s.resolve(obj, "site", v2address.Sites)
+	s.resolve(obj, "tenant", v1address.Tenants)
+}
+
+func normalizeHW(s *Server, obj Object) {
+	switch asn := obj["asn"].(type) {
+	case string:
+		n, _ := strconv.Atoi(asn)
+		obj["asn"] = n
+	case nil:
+		obj["asn"] = 0
+	}
+	obj["asnNumber"] = map[string]interface{}{"asn": obj["asn"]}
+	if addr, ok := obj["mainAddress"].(string); ok {
+		obj["mainIP"] = map[string]interface{}{"address": addr}
+	}
+	if addr, ok := obj["mgmtAddress"].(string); ok {
+		obj["mgmtIP"] = map[string]interface{}{"address": addr}
+	}
+	s.resolve(obj, "site", v2address.Sites)
+	s.resolve(obj, "tenant", v1address.Tenants)
+	s.resolve(obj, "profile", v1address.InventoryProfiles)
+}
+
+func normalizeIPAM(s *Server, obj Object) {
+	delete(obj, "children")
+	s.resolve(obj, "tenant", v1address.Tenants)
+	s.resolveAll(obj, "sites", v2address.Sites)
+	s.defaultVPC(obj)
+}
+
+func normalizeInventoryProfile(s *Server, obj Object) {
+	if tz, ok := obj["timezone"].(map[string]interface{}); ok {
+		obj["timezone"] = tz["tzCode"]
+	}
+	if v, ok := obj["ipv4_list"]; ok {
+		obj["ipv4_ssh"] = v
+	}
+	if v, ok := obj["ipv6_list"]; ok {
+		obj["ipv6_ssh"] = v
+	}
+}
+
+// ipamTree returns the allocations with their subnets as children, the way
+// Netris lists IPAM.
+func (s *Server) ipamTree(c *collection, vpcid int) []Object {
+	allocations := []Object{}
+	orphans := []Object{}
+	var subnets []Object
+	for _, id := range c.ids() {
+		obj := c.items[id]
+		if vpcid > 0 && obj.ref("vpc") != 0 && obj.ref("vpc") != vpcid {
+			continue
+		}
+		if obj["type"] == "allocation" {
+			item := obj.copy()
+			item["children"] = []Object{}
+			allocations = append(allocations, item)
+		} else {
+			subnets = append(subnets, obj)
+		}
+	}
+	for _, subnet := range subnets {
+		parent := -1
+		for i, allocation := range allocations {
+			if contains(allocation["prefix"], subnet["prefix"]) {
+				parent = i
+				break
+			}
+		}
+		item := subnet.copy()
+		if parent < 0 {
+			orphans = append(orphans, item)
+			continue
+		}
+		item["allocationID"] = allocations[parent].ID()
+		item["parentID"] = allocations[parent].ID()
+		allocations[parent]["children"] = append(allocations[parent]["children"].([]Object), item)
+	}
+	return append(allocations, orphans...)
+}
+
+// subnetHosts returns the addresses used in the IPAM subnet the way Netris
+// lists its hosts: the seeded hosts, the default gateway, the L4LB IPs and
+// the NAT addresses inside the subnet.
+func (s *Server) subnetHosts(id int) ([]Object, bool) {
+	subnet, ok := s.collections[v2address.IPAMBase].items[id]
+	if !ok || subnet["type"] != "subnet" {
+		return nil, false
+	}
+	hosts := []Object{}
+	add := func(address interface{}, typ string) {
+		a, _ := address.(string)
+		a = strings.TrimSuffix(a, "/32")
+		if a != "" && contains(subnet["prefix"], a+"/32") {
+			hosts = append(hosts, Object{"address": a, "subnetID": id, "type": typ})
+		}
+	}
+	for _, hostID := range s.collections[v2address.IPAMHosts].ids() {
+		if host := s.collections[v2address.IPAMHosts].items[hostID]; toInt(host["subnetID"]) == id {
+			add(host["address"], "host")
+		}
+	}
+	add(subnet["defaultGateway"], "gateway")
+	for _, lbID := range s.collections[v2address.L4LB].ids() {
+		add(s.collections[v2address.L4LB].items[lbID]["ip"], "l4lb")
+	}
+	for _, natID := range s.collections[v2address.NAT].ids() {
+		nat := s.collections[v2address.NAT].items[natID]
+		add(nat["snatToIP"], "nat")
+		add(nat["destinationAddress"], "nat")
+	}
+	return hosts, true
+}
+
+// freeLBIP returns the first address of the load-balancer subnets which isn't
+// used by another L4LB.
+func (s *Server) freeLBIP(self int) string {
+	used := map[string]bool{}
+	for id, lb := range s.collections[v2address.L4LB].items {
+		if ip, ok := lb["ip"].(string); ok && id != self {
+			used[ip] = true
+		}
+	}
+	ipamItems := s.collections[v2address.IPAMBase]
+	for _, id := range ipamItems.ids() {
+		subnet := ipamItems.items[id]
+		if subnet["type"] != "subnet" || subnet["purpose"] != "load-balancer" {
+			continue
+		}
+		prefix, _ := subnet["prefix"].(string)
+		ip, ipNet, err := net.ParseCIDR(prefix)
+		if err != nil {
+			continue
+		}
+		for ip = nextIP(ip.Mask(ipNet.Mask)); ipNet.Contains(ip); ip = nextIP(ip) {
+			if !used[ip.String()] {
+				return ip.String()
+			}
+		}
+	}
+	return ""
+}
+
+// resolve fills in the id or the name of a {"id": ..., "name": ...} reference.
+func (s *Server) resolve(obj Object, key, path string) {
+	if ref, ok := obj[key].(map[string]interface{}); ok {
+		s.resolveRef(ref, path)
+	}
+}
+
+func (s *Server) resolveAll(obj Object, key, path string) {
+	if refs, ok := obj[key].([]interface{}); ok {
+		for _, r := range refs {
+			if ref, ok := r.(map[string]interface{}); ok {
+				s.resolveRef(ref, path)
+			}
+		}
+	}
+}
+
+func (s *Server) resolveRef(ref map[string]interface{}, path string) {
+	name, _ := ref["name"].(string)
+	if toInt(ref["id"]) == 0 && name != "" {
+		ref["id"] = s.lookup(path, name)
+	} else if name == "" {
+		if obj, ok := s.collections[path].items[toInt(ref["id"])]; ok {
+			ref["name"] = obj.Name()
+		}
+	}
+}
+
+// defaultVPC puts the objects created without a VPC in the default one.
+func (s *Server) defaultVPC(obj Object) {
+	if obj.ref("vpc") != 0 {
+		s.resolve(obj, "vpc", v2address.VPC)
+		return
+	}
+	vpcs := s.collections[v2address.VPC]
+	for _, id := range vpcs.ids() {
+		if isDefault, _ := vpcs.items[id]["isDefault"].(bool); isDefault {
+			obj["vpc"] = map[string]interface{}{"id": id, "name": vpcs.items[id].Name()}
+			return
+		}
+	}
+}
+
+func contains(prefix, sub interface{}) bool {
+	p, _ := prefix.(string)
+	c, _ := sub.(string)
+	_, outer, err := net.ParseCIDR(p)
+	if err != nil {
+		return false
+	}
+	ip, inner, err := net.ParseCIDR(c)
+	if err != nil {
+		return false
+	}
+	outerLen, _ := outer.Mask.Size()
+	innerLen, _ := inner.Mask.Size()
+	return outer.Contains(ip) && innerLen >= outerLen
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
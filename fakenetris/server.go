@@ -0,0 +1,465 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fakenetris is an in-memory Netris controller API for the tests.
+// It serves the endpoints the operator calls with a stateful CRUD store, and
+// faults can be injected to exercise the retries and the circuit breaker.
+package fakenetris
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	v1address "github.com/netrisai/netriswebapi/http/addresses/v1"
+	v2address "github.com/netrisai/netriswebapi/http/addresses/v2"
+	api "github.com/netrisai/netriswebapi/v2"
+)
+
+// Default credentials of a new Server.
+const (
+	DefaultLogin    = "netris"
+	DefaultPassword = "newNet0ps"
+)
+
+const sessionCookie = "connect.sid"
+
+// Server is a fake Netris controller.
+type Server struct {
+	// URL is the base address of the server, e.g. http://127.0.0.1:34567
+	URL      string
+	Login    string
+	Password string
+
+	srv *httptest.Server
+
+	mu          sync.Mutex
+	lastID      int
+	collections map[string]*collection
+	sessions    map[string]bool
+	faults      []*Fault
+	down        bool
+	requests    map[string]int
+}
+
+// New starts a fake Netris controller with the default credentials and an
+// empty store. Close it when done.
+func New() *Server {
+	s := &Server{
+		Login:       DefaultLogin,
+		Password:    DefaultPassword,
+		collections: make(map[string]*collection),
+		sessions:    make(map[string]bool),
+		requests:    make(map[string]int),
+	}
+	for _, r := range resources {
+		s.collections[r.path] = &collection{resource: r, items: make(map[int]Object)}
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Clientset returns a logged in Netris API client of the server.
+func (s *Server) Clientset() (*api.Clientset, error) {
+	cred, err := api.Client(s.URL, s.Login, s.Password, 5)
+	if err != nil {
+		return nil, fmt.Errorf("{Clientset} %s", err)
+	}
+	if err := cred.Client.LoginUser(); err != nil {
+		return nil, fmt.Errorf("{Clientset} %s", err)
+	}
+	return cred, nil
+}
+
+// Requests returns how many requests were received for the method and path.
+// An empty method counts every method.
+func (s *Server) Requests(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if method != "" {
+		return s.requests[method+" "+path]
+	}
+	count := 0
+	for key, n := range s.requests {
+		if strings.SplitN(key, " ", 2)[1] == path {
+			count += n
+		}
+	}
+	return count
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	s.mu.Lock()
+	s.requests[r.Method+" "+r.URL.Path]++
+	fault := s.matchFault(r)
+	down := s.down
+	s.mu.Unlock()
+
+	if down {
+		writeReply(w, http.StatusServiceUnavailable, nil, "Service Unavailable")
+		return
+	}
+	if fault != nil {
+		if fault.Delay > 0 {
+			time.Sleep(fault.Delay)
+		}
+		if fault.Drop {
+			dropConnection(w)
+			return
+		}
+		if fault.Status != 0 {
+			writeReply(w, fault.Status, nil, http.StatusText(fault.Status))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == v2address.Auth {
+		s.serveAuth(w, r, body)
+		return
+	}
+	if !s.authorized(r) {
+		writeReply(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	switch {
+	case r.URL.Path == v2address.InventoryNOS:
+		s.serveList(w, r, s.collections[v2address.InventoryNOS])
+	case strings.HasPrefix(r.URL.Path, v2address.InventoryBase):
+		s.serveTyped(w, r, body, v2address.InventoryBase)
+	case strings.HasPrefix(r.URL.Path, v2address.IPAMHosts+"/") && r.Method == http.MethodGet:
+		s.serveHosts(w, r)
+	case strings.HasPrefix(r.URL.Path, v2address.IPAMBase):
+		s.serveTyped(w, r, body, v2address.IPAMBase)
+	case r.URL.Path == v1address.Sites && r.Method == http.MethodGet:
+		// the BGP list still filters by the sites of the old API.
+		s.serveList(w, r, s.collections[v2address.Sites])
+	case r.URL.Path == v1address.Tenants || r.URL.Path == v1address.InventoryProfiles:
+		s.serveV1(w, r, body, s.collections[r.URL.Path])
+	default:
+		s.serveREST(w, r, body)
+	}
+}
+
+func (s *Server) serveAuth(w http.ResponseWriter, r *http.Request, body []byte) {
+	switch r.Method {
+	case http.MethodPost:
+		login := struct {
+			User     string `json:"user"`
+			Password string `json:"password"`
+		}{}
+		if err := json.Unmarshal(body, &login); err != nil || login.User != s.Login || login.Password != s.Password {
+			writeReply(w, http.StatusUnauthorized, nil, "Authentication failed")
+			return
+		}
+		sid := newSessionID()
+		s.sessions[sid] = true
+		http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: sid, Path: "/"})
+		writeReply(w, http.StatusOK, nil, "")
+	case http.MethodGet:
+		if !s.authorized(r) {
+			writeReply(w, http.StatusUnauthorized, nil, "not authorized")
+			return
+		}
+		writeReply(w, http.StatusOK, nil, "")
+	default:
+		writeReply(w, http.StatusMethodNotAllowed, nil, "Method Not Allowed")
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	cookie, err := r.Cookie(sessionCookie)
+	return err == nil && s.sessions[cookie.Value]
+}
+
+// ExpireSessions logs every client out, like a restarted Netris controller.
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]bool)
+}
+
+// serveREST serves the collections with the usual layout: the list and the
+// create on the base path, the rest on base/{id}.
+func (s *Server) serveREST(w http.ResponseWriter, r *http.Request, body []byte) {
+	c, id, ok := s.route(r.URL.Path)
+	if !ok {
+		writeReply(w, http.StatusNotFound, nil, "Not Found")
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && id == 0:
+		s.serveList(w, r, c)
+	case r.Method == http.MethodGet:
+		if obj, ok := c.items[id]; ok {
+			writeReply(w, http.StatusOK, obj.copy(), "")
+			return
+		}
+		writeReply(w, c.notFound, nil, c.notFoundMsg)
+	case r.Method == http.MethodPost && id == 0:
+		s.serveCreate(w, c, body, "")
+	case r.Method == http.MethodPut && id > 0:
+		s.serveUpdate(w, c, id, body)
+	case r.Method == http.MethodDelete && id > 0:
+		s.serveDelete(w, c, id)
+	case r.Method == http.MethodDelete && c.path == v2address.Links:
+		// links are deleted by their ends.
+		s.serveDelete(w, c, s.findLink(c, body))
+	default:
+		writeReply(w, http.StatusMethodNotAllowed, nil, "Method Not Allowed")
+	}
+}
+
+// serveTyped serves the collections keeping several object types, where the
+// type is the path segment after the base: base/{type} and base/{type}/{id}.
+func (s *Server) serveTyped(w http.ResponseWriter, r *http.Request, body []byte, base string) {
+	c := s.collections[base]
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, base), "/"), "/")
+	if parts[0] == "" {
+		if r.Method != http.MethodGet {
+			writeReply(w, http.StatusMethodNotAllowed, nil, "Method Not Allowed")
+			return
+		}
+		s.serveList(w, r, c)
+		return
+	}
+	typ := parts[0]
+	id := 0
+	if len(parts) > 1 {
+		var err error
+		if id, err = strconv.Atoi(parts[1]); err != nil {
+			writeReply(w, http.StatusBadRequest, nil, "Invalid ID")
+			return
+		}
+	}
+	switch {
+	case r.Method == http.MethodGet && id > 0 && typ != "":
+		if obj, ok := c.items[id]; ok {
+			writeReply(w, http.StatusOK, obj.copy(), "")
+			return
+		}
+		writeReply(w, c.notFound, nil, c.notFoundMsg)
+	case r.Method == http.MethodPost && id == 0:
+		s.serveCreate(w, c, body, typ)
+	case r.Method == http.MethodPut && id > 0:
+		s.serveUpdate(w, c, id, body)
+	case r.Method == http.MethodDelete && id > 0:
+		if obj, ok := c.items[id]; ok && obj["type"] != typ {
+			writeReply(w, c.notFound, nil, c.notFoundMsg)
+			return
+		}
+		s.serveDelete(w, c, id)
+	default:
+		writeReply(w, http.StatusMethodNotAllowed, nil, "Method Not Allowed")
+	}
+}
+
+// serveHosts serves the addresses used in an IPAM subnet, base/{id}.
+func (s *Server) serveHosts(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, v2address.IPAMHosts+"/"))
+	if err != nil {
+		writeReply(w, http.StatusBadRequest, nil, "Invalid ID")
+		return
+	}
+	hosts, ok := s.subnetHosts(id)
+	if !ok {
+		writeReply(w, http.StatusNotFound, nil, "Not Found")
+		return
+	}
+	writeReply(w, http.StatusOK, hosts, "")
+}
+
+// serveV1 serves the old API layout, where the id is in the body of the
+// update and the delete requests.
+func (s *Server) serveV1(w http.ResponseWriter, r *http.Request, body []byte, c *collection) {
+	switch r.Method {
+	case http.MethodGet:
+		s.serveList(w, r, c)
+	case http.MethodPost:
+		s.serveCreate(w, c, body, "")
+	case http.MethodPut:
+		obj := Object{}
+		if err := json.Unmarshal(body, &obj); err != nil {
+			writeReply(w, http.StatusBadRequest, nil, err.Error())
+			return
+		}
+		s.serveUpdate(w, c, obj.ID(), body)
+	case http.MethodDelete:
+		ids := struct {
+			ID []int `json:"id"`
+		}{}
+		if err := json.Unmarshal(body, &ids); err != nil || len(ids.ID) == 0 {
+			writeReply(w, http.StatusBadRequest, nil, "Invalid ID")
+			return
+		}
+		s.serveDelete(w, c, ids.ID[0])
+	default:
+		writeReply(w, http.StatusMethodNotAllowed, nil, "Method Not Allowed")
+	}
+}
+
+func (s *Server) serveList(w http.ResponseWriter, r *http.Request, c *collection) {
+	vpcid, _ := strconv.Atoi(r.URL.Query().Get("filterByVpc"))
+	writeReply(w, http.StatusOK, s.list(c, vpcid), "")
+}
+
+func (s *Server) serveCreate(w http.ResponseWriter, c *collection, body []byte, typ string) {
+	obj := Object{}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		writeReply(w, http.StatusBadRequest, nil, err.Error())
+		return
+	}
+	if typ != "" {
+		obj["type"] = typ
+	}
+	if msg := s.validate(c, 0, obj); msg != "" {
+		writeReply(w, http.StatusBadRequest, nil, msg)
+		return
+	}
+	obj = s.create(c, obj)
+	writeReply(w, http.StatusOK, s.createReply(c, obj), "")
+}
+
+func (s *Server) serveUpdate(w http.ResponseWriter, c *collection, id int, body []byte) {
+	old, ok := c.items[id]
+	if !ok {
+		writeReply(w, c.notFound, nil, c.notFoundMsg)
+		return
+	}
+	obj := Object{}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		writeReply(w, http.StatusBadRequest, nil, err.Error())
+		return
+	}
+	merged := old.copy()
+	for k, v := range obj {
+		merged[k] = v
+	}
+	if msg := s.validate(c, id, merged); msg != "" {
+		writeReply(w, http.StatusBadRequest, nil, msg)
+		return
+	}
+	s.store(c, id, merged)
+	writeReply(w, http.StatusOK, nil, "")
+}
+
+func (s *Server) serveDelete(w http.ResponseWriter, c *collection, id int) {
+	if _, ok := c.items[id]; !ok {
+		writeReply(w, c.notFound, nil, c.notFoundMsg)
+		return
+	}
+	delete(c.items, id)
+	writeReply(w, http.StatusOK, nil, "")
+}
+
+// route finds the collection and the id of a base/{id} path.
+func (s *Server) route(path string) (*collection, int, bool) {
+	if c, ok := s.collections[path]; ok {
+		return c, 0, true
+	}
+	i := strings.LastIndex(path, "/")
+	c, ok := s.collections[path[:i]]
+	if !ok {
+		return nil, 0, false
+	}
+	id, err := strconv.Atoi(path[i+1:])
+	if err != nil {
+		return nil, 0, false
+	}
+	return c, id, true
+}
+
+func (s *Server) findLink(c *collection, body []byte) int {
+	link := struct {
+		ID     int              `json:"id"`
+		Local  struct{ ID int } `json:"local"`
+		Remote struct{ ID int } `json:"remote"`
+	}{}
+	if err := json.Unmarshal(body, &link); err != nil {
+		return 0
+	}
+	if link.ID > 0 {
+		return link.ID
+	}
+	for id, obj := range c.items {
+		if obj.ref("local") == link.Local.ID && obj.ref("remote") == link.Remote.ID {
+			return id
+		}
+	}
+	return 0
+}
+
+type reply struct {
+	Data      interface{} `json:"data"`
+	IsSuccess bool        `json:"isSuccess"`
+	Message   string      `json:"message"`
+	Meta      replyMeta   `json:"meta"`
+}
+
+type replyMeta struct {
+	APIVersion string `json:"apiVersion"`
+	StatusCode int    `json:"statusCode"`
+}
+
+func writeReply(w http.ResponseWriter, status int, data interface{}, message string) {
+	js, _ := json.Marshal(reply{
+		Data:      data,
+		IsSuccess: status < 300,
+		Message:   message,
+		Meta:      replyMeta{APIVersion: "2", StatusCode: status},
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(js)
+}
+
+func dropConnection(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		writeReply(w, http.StatusBadGateway, nil, "Bad Gateway")
+		return
+	}
+	conn, _, err := hj.Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakenetris
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/netrisai/netris-operator/netrisapi"
+	webhttp "github.com/netrisai/netriswebapi/http"
+	v2address "github.com/netrisai/netriswebapi/http/addresses/v2"
+	api "github.com/netrisai/netriswebapi/v2"
+	"github.com/netrisai/netriswebapi/v2/types/l4lb"
+	"github.com/netrisai/netriswebapi/v2/types/vnet"
+)
+
+func newSeeded(t *testing.T) (*Server, *api.Clientset) {
+	t.Helper()
+	s := New()
+	t.Cleanup(s.Close)
+	s.SeedDefaults()
+	cred, err := s.Clientset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, cred
+}
+
+func TestLogin(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	cred, err := api.Client(s.URL, s.Login, "wrong", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cred.Client.LoginUser(); err == nil {
+		t.Fatal("login with a wrong password succeeded")
+	}
+	if _, err := cred.Site().Get(); err == nil {
+		t.Fatal("request without a session succeeded")
+	}
+
+	cred, err = s.Clientset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cred.Client.CheckAuth(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestDefaultsDecode checks that every list the operator downloads decodes
+// into the netriswebapi types.
+func TestDefaultsDecode(t *testing.T) {
+	_, cred := newSeeded(t)
+
+	sites, err := cred.Site().Get()
+	if err != nil || len(sites) != 1 || sites[0].Name != DefaultSite || sites[0].PublicAsn != 65001 {
+		t.Fatalf("sites %v %v", sites, err)
+	}
+	tenants, err := cred.Tenant().Get()
+	if err != nil || len(tenants) != 1 || tenants[0].Name != DefaultTenant {
+		t.Fatalf("tenants %v %v", tenants, err)
+	}
+	vpcs, err := cred.VPC().Get()
+	if err != nil || len(vpcs) != 1 || !vpcs[0].IsDefault || vpcs[0].AdminTenant.ID != tenants[0].ID {
+		t.Fatalf("vpcs %v %v", vpcs, err)
+	}
+	hws, err := cred.Inventory().Get()
+	if err != nil || len(hws) != 1 || hws[0].Type != "switch" || hws[0].Site.ID != sites[0].ID || hws[0].MainIP.Address != "10.4.0.11" {
+		t.Fatalf("inventory %v %v", hws, err)
+	}
+	ports, err := cred.Port().Get()
+	if err != nil || len(ports) != 4 || ports[0].SwitchName != DefaultSwitch || ports[0].Port_ != "swp1" {
+		t.Fatalf("ports %v %v", ports, err)
+	}
+	if nos, err := cred.Inventory().GetNOS(); err != nil || len(nos) == 0 {
+		t.Fatalf("nos %v %v", nos, err)
+	}
+	if profiles, err := cred.InventoryProfile().Get(); err != nil || len(profiles) != 1 || profiles[0].Timezone != "UTC" {
+		t.Fatalf("inventory profiles %v %v", profiles, err)
+	}
+	if dhcps, err := cred.DHCP().Get(); err != nil || len(dhcps) != 1 {
+		t.Fatalf("dhcp option sets %v %v", dhcps, err)
+	}
+	for _, get := range []func() error{
+		func() error { _, err := cred.VNet().Get(); return err },
+		func() error { _, err := cred.BGP().Get(); return err },
+		func() error { _, err := cred.L4LB().Get(); return err },
+		func() error { _, err := cred.NAT().Get(); return err },
+		func() error { _, err := cred.Link().Get(); return err },
+	} {
+		if err := get(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ipams, err := cred.IPAM().GetByVPC(vpcs[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ipams) != 1 || ipams[0].Prefix != Allocation || len(ipams[0].Children) != 4 {
+		t.Fatalf("ipam tree %v", ipams)
+	}
+	if child := ipams[0].Children[0]; child.Prefix != NodeSubnet || len(child.Sites) != 1 || child.Sites[0].ID != sites[0].ID {
+		t.Fatalf("ipam subnet %v", child)
+	}
+}
+
+func TestVNetCRUD(t *testing.T) {
+	s, cred := newSeeded(t)
+
+	add := &vnet.VNetAdd{
+		Name:     "vnet-customer",
+		Sites:    []vnet.VNetAddSite{{Name: DefaultSite}},
+		Tenant:   vnet.VNetAddTenant{Name: DefaultTenant},
+		State:    "active",
+		Gateways: []vnet.VNetAddGateway{{Prefix: "10.1.0.1/24"}},
+		Vlan:     "auto",
+	}
+	reply, err := cred.VNet().Add(add)
+	id := createdID(t, reply, err)
+
+	got, err := cred.VNet().GetByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != add.Name || got.Provisioning || len(got.Sites) != 1 || got.Sites[0].ID == 0 || got.Tenant.ID == 0 || got.Vpc.ID == 0 {
+		t.Fatalf("vnet %+v", got)
+	}
+
+	reply, err = cred.VNet().Add(add)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := webhttp.ParseAPIResponse(reply.Data); reply.StatusCode != http.StatusBadRequest || resp.IsSuccess {
+		t.Fatalf("duplicate vnet created: %s", reply.Data)
+	}
+
+	if reply, err := cred.VNet().Update(id, &vnet.VNetUpdate{Name: add.Name, State: "disabled"}); err != nil || reply.StatusCode != http.StatusOK {
+		t.Fatalf("update %s %v", reply.Data, err)
+	}
+	if obj, ok := s.Find(v2address.VNetBase, add.Name); !ok || obj["state"] != "disabled" {
+		t.Fatalf("vnet not updated: %v", obj)
+	}
+
+	if reply, err := cred.VNet().Delete(id); err != nil || reply.StatusCode != http.StatusOK {
+		t.Fatalf("delete %s %v", reply.Data, err)
+	}
+	if _, ok := s.Find(v2address.VNetBase, add.Name); ok {
+		t.Fatal("vnet not deleted")
+	}
+	if reply, _ := cred.VNet().Delete(id); reply.StatusCode != http.StatusNotFound {
+		t.Fatalf("delete of a missing vnet: %d", reply.StatusCode)
+	}
+}
+
+func TestL4LBAutomaticIP(t *testing.T) {
+	_, cred := newSeeded(t)
+	_, lbNet, _ := net.ParseCIDR(LBSubnet)
+
+	ips := map[string]bool{}
+	for _, name := range []string{"lb1", "lb2"} {
+		reply, err := cred.L4LB().Add(&l4lb.LoadBalancerAdd{
+			Name:              name,
+			Tenant:            l4lb.IDName{Name: DefaultTenant},
+			Site:              l4lb.IDName{Name: DefaultSite},
+			Automatic:         true,
+			Protocol:          "tcp",
+			Port:              80,
+			Status:            "enable",
+			HealthCheck:       "TCP",
+			Timeout:           "2000",
+			KubenetInfoString: `{"service_uid":"uid"}`,
+			Backend:           []l4lb.LBAddBackend{{IP: "10.1.0.10", Port: 30080}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := webhttp.ParseAPIResponse(reply.Data)
+		if err != nil || !resp.IsSuccess {
+			t.Fatalf("create %s %v", reply.Data, err)
+		}
+		lb := l4lb.LoadBalancer{}
+		if err := webhttp.Decode(resp.Data, &lb); err != nil {
+			t.Fatal(err)
+		}
+		if !lbNet.Contains(net.ParseIP(lb.IP)) || ips[lb.IP] {
+			t.Fatalf("automatic ip %q", lb.IP)
+		}
+		ips[lb.IP] = true
+	}
+
+	lbs, err := cred.L4LB().Get()
+	if err != nil || len(lbs) != 2 {
+		t.Fatalf("l4lbs %v %v", lbs, err)
+	}
+	if lbs[0].HealthCheck.TCP.Timeout != "2000" || lbs[0].KubenetInfo.ServiceUID != "uid" || lbs[0].BackendIPs[0].Port != "30080" {
+		t.Fatalf("l4lb %+v", lbs[0])
+	}
+}
+
+func TestSubnetHosts(t *testing.T) {
+	s, cred := newSeeded(t)
+	subnet, ok := s.Find(v2address.IPAMBase, "load-balancers")
+	if !ok {
+		t.Fatal("no load-balancer subnet")
+	}
+	s.Seed(v2address.IPAMHosts, Object{"address": "10.2.0.1", "subnetID": subnet.ID()})
+	s.Seed(v2address.NAT, Object{"name": "snat", "action": "SNAT", "snatToIP": "10.2.0.5/32", "destinationAddress": "0.0.0.0/0"})
+	s.Seed(v2address.L4LB, Object{"name": "lb", "ip": "10.2.0.9"})
+
+	hosts, err := cred.IPAM().GetHosts(subnet.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, host := range hosts {
+		got[host.Address] = true
+	}
+	if len(got) != 3 || !got["10.2.0.1"] || !got["10.2.0.5"] || !got["10.2.0.9"] {
+		t.Fatalf("hosts %v", got)
+	}
+	if _, err := cred.IPAM().GetHosts(12345); err == nil {
+		t.Fatal("hosts of a missing subnet")
+	}
+}
+
+func TestFaults(t *testing.T) {
+	s, cred := newSeeded(t)
+
+	s.FailNext(http.MethodGet, v2address.Sites, http.StatusServiceUnavailable, 2)
+	if sites, err := netrisapi.Get(cred, "site", "list", cred.Site().Get); err != nil || len(sites) != 1 {
+		t.Fatalf("retried get %v %v", sites, err)
+	}
+	if n := s.Requests(http.MethodGet, v2address.Sites); n != 3 {
+		t.Fatalf("%d requests, want 3", n)
+	}
+
+	s.ExpireSessions()
+	if _, err := netrisapi.Get(cred, "site", "list", cred.Site().Get); err != nil {
+		t.Fatalf("get after the session expired: %v", err)
+	}
+
+	s.Inject(Fault{Path: v2address.VNetBase, Drop: true, Times: 1})
+	if _, err := cred.VNet().Get(); err == nil {
+		t.Fatal("dropped request succeeded")
+	}
+	if _, err := cred.VNet().Get(); err != nil {
+		t.Fatalf("fault applied more than once: %v", err)
+	}
+
+	s.SetDown(true)
+	if _, err := netrisapi.Get(cred, "site", "list", cred.Site().Get); !netrisapi.IsUnavailable(err) {
+		t.Fatalf("down server: %v", err)
+	}
+	s.ClearFaults()
+	if _, err := cred.Site().Get(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createdID(t *testing.T, reply webhttp.HTTPReply, err error) int {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := webhttp.ParseAPIResponse(reply.Data)
+	if err != nil || !resp.IsSuccess {
+		t.Fatalf("create %s %v", reply.Data, err)
+	}
+	return toInt(resp.Data.(map[string]interface{})["id"])
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakenetris
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	v1address "github.com/netrisai/netriswebapi/http/addresses/v1"
+	v2address "github.com/netrisai/netriswebapi/http/addresses/v2"
+)
+
+// Object is a Netris object as it's stored, the decoded JSON of the request
+// which created it plus the fields Netris fills in.
+type Object map[string]interface{}
+
+// ID .
+func (o Object) ID() int {
+	return toInt(o["id"])
+}
+
+// Name .
+func (o Object) Name() string {
+	name, _ := o["name"].(string)
+	return name
+}
+
+// ref returns the id of a nested {"id": ..., "name": ...} field.
+func (o Object) ref(key string) int {
+	if m, ok := o[key].(map[string]interface{}); ok {
+		return toInt(m["id"])
+	}
+	return 0
+}
+
+func (o Object) copy() Object {
+	js, _ := json.Marshal(o)
+	c := Object{}
+	_ = json.Unmarshal(js, &c)
+	return c
+}
+
+type resource struct {
+	path string
+	// unnamed resources skip the name checks.
+	unnamed bool
+	// notFound is the reply of Netris for a missing object.
+	notFound    int
+	notFoundMsg string
+	// normalize turns the stored request into the shape Netris returns.
+	normalize func(s *Server, obj Object)
+}
+
+var resources = []*resource{
+	{path: v2address.VNetBase, normalize: normalizeVNet},
+	{path: v2address.Sites},
+	{path: v2address.L4LB, notFoundMsg: "Invalid load balancer", normalize: normalizeL4LB},
+	{path: v2address.NAT, normalize: normalizeNAT},
+	{path: v2address.BGP, notFound: http.StatusBadRequest, normalize: normalizeBGP},
+	{path: v2address.VPC, normalize: normalizeVPC},
+	{path: v2address.DHCP},
+	{path: v2address.Ports, unnamed: true, normalize: normalizePort},
+	{path: v2address.Links, unnamed: true},
+	{path: v2address.InventoryBase, normalize: normalizeHW},
+	{path: v2address.InventoryNOS},
+	{path: v2address.IPAMBase, normalize: normalizeIPAM},
+	// the addresses used in a subnet besides the L4LBs and the NAT rules.
+	{path: v2address.IPAMHosts, unnamed: true},
+	{path: v1address.Tenants},
+	{path: v1address.InventoryProfiles, normalize: normalizeInventoryProfile},
+}
+
+type collection struct {
+	*resource
+	items map[int]Object
+}
+
+func init() {
+	for _, r := range resources {
+		if r.notFound == 0 {
+			r.notFound = http.StatusNotFound
+		}
+		if r.notFoundMsg == "" {
+			r.notFoundMsg = "Not Found"
+		}
+	}
+}
+
+func (s *Server) collection(path string) *collection {
+	c, ok := s.collections[path]
+	if !ok {
+		panic(fmt.Sprintf("fakenetris: unknown collection %s", path))
+	}
+	return c
+}
+
+// Seed stores objects in the collection of the given API path, e.g.
+// v2address.Sites, as if they were created through the API. It returns the
+// ids of the new objects.
+func (s *Server) Seed(path string, objs ...Object) []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.collection(path)
+	ids := make([]int, 0, len(objs))
+	for _, obj := range objs {
+		ids = append(ids, s.create(c, obj.copy()).ID())
+	}
+	return ids
+}
+
+// Objects returns the objects of a collection, sorted by id.
+func (s *Server) Objects(path string) []Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.collection(path)
+	objs := make([]Object, 0, len(c.items))
+	for _, id := range c.ids() {
+		objs = append(objs, c.items[id].copy())
+	}
+	return objs
+}
+
+// Find returns the object of a collection by name.
+func (s *Server) Find(path, name string) (Object, bool) {
+	for _, obj := range s.Objects(path) {
+		if obj.Name() == name {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// Update changes the fields of a stored object behind the operator's back,
+// e.g. to simulate a change made in the Netris web console.
+func (s *Server) Update(path string, id int, fields Object) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.collection(path)
+	obj, ok := c.items[id]
+	if !ok {
+		return false
+	}
+	for k, v := range fields.copy() {
+		obj[k] = v
+	}
+	s.store(c, id, obj)
+	return true
+}
+
+// Delete removes a stored object behind the operator's back.
+func (s *Server) Delete(path string, id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.collection(path)
+	if _, ok := c.items[id]; !ok {
+		return false
+	}
+	delete(c.items, id)
+	return true
+}
+
+func (c *collection) ids() []int {
+	ids := make([]int, 0, len(c.items))
+	for id := range c.items {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func (s *Server) create(c *collection, obj Object) Object {
+	s.lastID++
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	obj["id"] = s.lastID
+	obj["createdDate"] = now
+	s.store(c, s.lastID, obj)
+	return c.items[s.lastID]
+}
+
+func (s *Server) store(c *collection, id int, obj Object) {
+	obj["id"] = id
+	obj["modifiedDate"] = time.Now().UnixNano() / int64(time.Millisecond)
+	if c.normalize != nil {
+		c.normalize(s, obj)
+	}
+	c.items[id] = obj
+}
+
+func (s *Server) validate(c *collection, id int, obj Object) string {
+	if c.unnamed {
+		return ""
+	}
+	name := obj.Name()
+	if name == "" {
+		return "Name is required"
+	}
+	for otherID, other := range c.items {
+		if otherID != id && other.Name() == name && other["type"] == obj["type"] {
+			return fmt.Sprintf("Name '%s' already exists", name)
+		}
+	}
+	return ""
+}
+
+func (s *Server) list(c *collection, vpcid int) []Object {
+	if c.path == v2address.IPAMBase {
+		return s.ipamTree(c, vpcid)
+	}
+	objs := []Object{}
+	for _, id := range c.ids() {
+		obj := c.items[id]
+		if vpcid > 0 && obj.ref("vpc") != 0 && obj.ref("vpc") != vpcid {
+			continue
+		}
+		objs = append(objs, obj.copy())
+	}
+	return objs
+}
+
+// createReply is the data of a create reply: the id, or for an L4LB with
+// an automatic IP the whole load balancer, and a bare id for the other L4LBs.
+func (s *Server) createReply(c *collection, obj Object) interface{} {
+	if c.path == v2address.L4LB {
+		if automatic, _ := obj["automatic"].(bool); automatic {
+			return obj.copy()
+		}
+		return obj.ID()
+	}
+	return map[string]interface{}{"id": obj.ID()}
+}
+
+// lookup returns the id of the object with the given name.
+func (s *Server) lookup(path, name string) int {
+	for id, obj := range s.collections[path].items {
+		if obj.Name() == name {
+			return id
+		}
+	}
+	return 0
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	case json.Number:
+		i, _ := n.Int64()
+		return int(i)
+	}
+	return 0
+}
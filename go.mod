@@ -8,6 +8,7 @@ require (
 	github.com/netrisai/netriswebapi v0.0.0-20251111091559-5848d9e0fc36
 	github.com/onsi/ginkgo v1.16.4
 	github.com/onsi/gomega v1.19.0
+	github.com/prometheus/client_golang v1.0.0
 	github.com/r3labs/diff/v2 v2.9.1
 	github.com/sirupsen/logrus v1.8.1
 	go.uber.org/zap v1.10.0
@@ -42,7 +43,6 @@ require (
 	github.com/modern-go/reflect2 v1.0.1 // indirect
 	github.com/nxadm/tail v1.4.8 // indirect
 	github.com/pkg/errors v0.8.1 // indirect
-	github.com/prometheus/client_golang v1.0.0 // indirect
 	github.com/prometheus/client_model v0.2.0 // indirect
 	github.com/prometheus/common v0.4.1 // indirect
 	github.com/prometheus/procfs v0.0.11 // indirect
@@ -0,0 +1,88 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package healthcheck implements the readiness checks of the operator.
+package healthcheck
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netrisai/netris-operator/netrisstorage"
+	api "github.com/netrisai/netriswebapi/v2"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// Netris returns a check of the Netris session of cred. The session is
+// checked in the background at most once per interval and the probes get the
+// last result, so a slow Netris controller doesn't time out the probes.
+func Netris(cred *api.Clientset, interval time.Duration) healthz.Checker {
+	var (
+		mu       sync.Mutex
+		checked  time.Time
+		checking bool
+		last     = fmt.Errorf("netris session: not checked yet")
+	)
+	return func(_ *http.Request) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !checking && time.Since(checked) >= interval {
+			checking = true
+			go func() {
+				err := cred.Client.CheckAuth()
+				mu.Lock()
+				defer mu.Unlock()
+				checked, checking, last = time.Now(), false, nil
+				if err != nil {
+					last = fmt.Errorf("netris session: %s", err)
+				}
+			}()
+		}
+		return last
+	}
+}
+
+// Storage returns a check of the freshness of every sub-storage. It fails
+// with the kinds which aren't refreshed from Netris, and when the latest
+// checkpoint of the storage snapshot failed.
+func Storage(storage *netrisstorage.Storage) healthz.Checker {
+	return func(_ *http.Request) error {
+		stale := []string{}
+		for _, h := range storage.Health() {
+			if !h.Stale() {
+				continue
+			}
+			switch {
+			case h.LastError != nil:
+				stale = append(stale, fmt.Sprintf("%s (refresh failed: %s)", h.Kind, h.LastError))
+			case !h.Live():
+				stale = append(stale, fmt.Sprintf("%s (not downloaded yet)", h.Kind))
+			default:
+				stale = append(stale, fmt.Sprintf("%s (refreshed %s ago)", h.Kind, h.Age().Round(time.Second)))
+			}
+		}
+		if len(stale) > 0 {
+			return fmt.Errorf("netris storage is stale: %s", strings.Join(stale, ", "))
+		}
+		if checkpoint := storage.CheckpointHealth(); checkpoint.LastError != nil {
+			return fmt.Errorf("netris storage checkpoint failed: %s", checkpoint.LastError)
+		}
+		return nil
+	}
+}
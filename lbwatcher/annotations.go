@@ -0,0 +1,272 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lbwatcher
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	"github.com/netrisai/netris-operator/netrisapi"
+	"github.com/netrisai/netriswebapi/v2/types/ipam"
+	v1 "k8s.io/api/core/v1"
+)
+
+// The Service annotations customizing its L4LBs.
+const (
+	annotationCheckType      = "l4lb.k8s.netris.ai/check-type"
+	annotationCheckPath      = "l4lb.k8s.netris.ai/check-request-path"
+	annotationCheckTimeout   = "l4lb.k8s.netris.ai/check-timeout"
+	annotationOwnerTenant    = "l4lb.k8s.netris.ai/owner-tenant"
+	annotationSite           = "l4lb.k8s.netris.ai/site"
+	annotationFrontendSubnet = "l4lb.k8s.netris.ai/frontend-subnet"
+)
+
+// EventInvalidAnnotation is the reason of the Service events for the
+// annotations which can't be applied.
+const EventInvalidAnnotation = "InvalidL4LBAnnotation"
+
+const defaultCheckTimeout = 2000
+
+// serviceOptions are the L4LB settings of a Service.
+type serviceOptions struct {
+	check          k8sv1alpha1.L4LBCheck
+	ownerTenant    string
+	site           string
+	frontendSubnet *ipam.IPAM
+	frontendIP     string
+}
+
+// parseServiceOptions validates the annotations of the Service against the
+// Netris storage and returns its L4LB settings.
+func (w *Watcher) parseServiceOptions(svc *v1.Service) (serviceOptions, error) {
+	anns := svc.GetAnnotations()
+	opts := serviceOptions{
+		check: k8sv1alpha1.L4LBCheck{Type: "tcp", Timeout: defaultCheckTimeout},
+	}
+
+	if checkType, ok := anns[annotationCheckType]; ok {
+		switch checkType {
+		case "tcp", "http", "none":
+			opts.check.Type = checkType
+		default:
+			return opts, fmt.Errorf("%s: '%s' isn't one of tcp, http, none", annotationCheckType, checkType)
+		}
+	}
+
+	if path, ok := anns[annotationCheckPath]; ok {
+		if opts.check.Type != "http" {
+			return opts, fmt.Errorf("%s is only used with the http check type", annotationCheckPath)
+		}
+		if !strings.HasPrefix(path, "/") {
+			return opts, fmt.Errorf("%s: '%s' doesn't start with '/'", annotationCheckPath, path)
+		}
+		opts.check.RequestPath = path
+	} else if opts.check.Type == "http" {
+		opts.check.RequestPath = "/"
+	}
+
+	if timeout, ok := anns[annotationCheckTimeout]; ok {
+		if opts.check.Type == "none" {
+			return opts, fmt.Errorf("%s isn't used with the none check type", annotationCheckTimeout)
+		}
+		t, err := strconv.Atoi(timeout)
+		if err != nil || t <= 0 {
+			return opts, fmt.Errorf("%s: '%s' isn't a positive number of milliseconds", annotationCheckTimeout, timeout)
+		}
+		opts.check.Timeout = t
+	}
+	if opts.check.Type == "none" {
+		opts.check.Timeout = 0
+	}
+
+	if tenant, ok := anns[annotationOwnerTenant]; ok {
+		if _, ok := w.NStorage.TenantsStorage.FindByName(tenant); !ok {
+			return opts, fmt.Errorf("%s: tenant '%s' not found", annotationOwnerTenant, tenant)
+		}
+		opts.ownerTenant = tenant
+	}
+
+	if site, ok := anns[annotationSite]; ok {
+		if _, ok := w.NStorage.SitesStorage.FindByName(site); !ok {
+			return opts, fmt.Errorf("%s: site '%s' not found", annotationSite, site)
+		}
+		opts.site = site
+	}
+
+	if name, ok := anns[annotationFrontendSubnet]; ok {
+		if svc.Spec.LoadBalancerIP != "" {
+			return opts, fmt.Errorf("%s can't be used with spec.loadBalancerIP", annotationFrontendSubnet)
+		}
+		subnet := w.findLBSubnet(name)
+		if subnet == nil {
+			return opts, fmt.Errorf("%s: load-balancer subnet '%s' not found", annotationFrontendSubnet, name)
+		}
+		if opts.site != "" && !subnetOnSite(subnet, opts.site) {
+			return opts, fmt.Errorf("%s: subnet '%s' isn't on site '%s'", annotationFrontendSubnet, name, opts.site)
+		}
+		if opts.site == "" && len(subnet.Sites) > 0 {
+			opts.site = subnet.Sites[0].Name
+		}
+		opts.frontendSubnet = subnet
+	}
+
+	return opts, nil
+}
+
+// findLBSubnet finds the load-balancer subnet by its name or prefix.
+func (w *Watcher) findLBSubnet(name string) *ipam.IPAM {
+	var find func(subnets []*ipam.IPAM) *ipam.IPAM
+	find = func(subnets []*ipam.IPAM) *ipam.IPAM {
+		for _, subnet := range subnets {
+			if subnet.Type == "subnet" && subnet.Purpose == "load-balancer" && (subnet.Name == name || subnet.Prefix == name) {
+				return subnet
+			}
+			if found := find(subnet.Children); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return find(w.NStorage.SubnetsStorage.GetAll())
+}
+
+func subnetOnSite(subnet *ipam.IPAM, site string) bool {
+	for _, s := range subnet.Sites {
+		if s.Name == site {
+			return true
+		}
+	}
+	return false
+}
+
+// frontendIP returns the current frontend IP of the Service if it's in the
+// subnet, otherwise the first address of the subnet which isn't used.
+func (w *Watcher) frontendIP(subnet *ipam.IPAM, current string) (string, error) {
+	_, ipNet, err := net.ParseCIDR(subnet.Prefix)
+	if err != nil {
+		return "", fmt.Errorf("{frontendIP} %s", err)
+	}
+	if ip := net.ParseIP(current); ip != nil && ipNet.Contains(ip) {
+		return current, nil
+	}
+	base := ipNet.IP.To4()
+	if base == nil {
+		return "", fmt.Errorf("{frontendIP} subnet %s isn't IPv4", subnet.Prefix)
+	}
+
+	used, err := w.usedSubnetIPs(subnet)
+	if err != nil {
+		return "", err
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	first, last := uint32(0), uint32(1)<<uint(bits-ones)-1
+	// the network and broadcast addresses.
+	if last > 1 {
+		first, last = first+1, last-1
+	}
+	for i := first; i <= last; i++ {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, binary.BigEndian.Uint32(base)+i)
+		if !used.has(ip) {
+			return ip.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no free address left in subnet '%s' (%s)", subnet.Name, subnet.Prefix)
+}
+
+// usedAddresses are single addresses and address pools.
+type usedAddresses struct {
+	ips   map[string]int
+	pools []*net.IPNet
+}
+
+// add takes an address or a prefix, a /32 prefix is a single address.
+func (u *usedAddresses) add(address string) {
+	if ip := net.ParseIP(address); ip != nil {
+		u.ips[ip.String()] = 1
+		return
+	}
+	ip, ipNet, err := net.ParseCIDR(address)
+	if err != nil {
+		return
+	}
+	if ones, bits := ipNet.Mask.Size(); ones == bits {
+		u.ips[ip.String()] = 1
+		return
+	}
+	u.pools = append(u.pools, ipNet)
+}
+
+func (u *usedAddresses) has(ip net.IP) bool {
+	if _, ok := u.ips[ip.String()]; ok {
+		return true
+	}
+	for _, pool := range u.pools {
+		if pool.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// usedSubnetIPs returns the addresses which can't be allocated from the
+// subnet: its IPAM hosts and default gateway, the L4LBs and the NAT rules of
+// all tenants in Netris, and the L4LBs of the cluster. The hosts are read
+// from Netris and the L4LBs of the cluster from the API server, not from the
+// caches, so an address taken a moment ago is seen.
+func (w *Watcher) usedSubnetIPs(subnet *ipam.IPAM) (*usedAddresses, error) {
+	used := &usedAddresses{ips: map[string]int{}}
+
+	hosts, err := netrisapi.Get(w.Cred, "ipam", "hosts", func() ([]*ipam.Host, error) {
+		return w.Cred.IPAM().GetHosts(subnet.ID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("{usedSubnetIPs} %s", err)
+	}
+	for _, host := range hosts {
+		used.add(host.Address)
+	}
+	used.add(subnet.DefaultGateway)
+
+	for _, lb := range w.NStorage.L4LBStorage.GetAll() {
+		used.add(lb.IP)
+	}
+	for _, nat := range w.NStorage.NATStorage.GetAll() {
+		used.add(nat.SnatToIP)
+		used.add(nat.SnatToPool)
+		if nat.Action.Label == "DNAT" {
+			used.add(nat.DestinationAddress)
+		}
+	}
+
+	l4lbs := &k8sv1alpha1.L4LBList{}
+	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
+	defer cancel()
+	if err := w.MGR.GetAPIReader().List(ctx, l4lbs); err != nil {
+		return nil, fmt.Errorf("{usedSubnetIPs} %s", err)
+	}
+	for _, lb := range l4lbs.Items {
+		used.add(lb.Spec.Frontend.IP)
+	}
+	return used, nil
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lbwatcher
+
+import (
+	"fmt"
+	"sort"
+
+	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// setupInformers registers the event handlers of the Services,
+// EndpointSlices, Nodes and L4LBs and starts the informers. It returns the
+// functions reporting whether their caches are synced.
+func (w *Watcher) setupInformers(stop <-chan struct{}) ([]cache.InformerSynced, error) {
+	factory := informers.NewSharedInformerFactory(w.clientset, 0)
+
+	services := factory.Core().V1().Services()
+	services.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if svc, ok := obj.(*v1.Service); ok && isLoadBalancer(svc) {
+				w.enqueue(svc.Namespace, svc.Name)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSvc, _ := oldObj.(*v1.Service)
+			newSvc, ok := newObj.(*v1.Service)
+			if ok && (isLoadBalancer(oldSvc) || isLoadBalancer(newSvc)) {
+				w.enqueue(newSvc.Namespace, newSvc.Name)
+			}
+		},
+		DeleteFunc: w.enqueueObject,
+	})
+
+	slices := factory.Discovery().V1beta1().EndpointSlices()
+	slices.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    w.enqueueSliceService,
+		UpdateFunc: func(_, newObj interface{}) { w.enqueueSliceService(newObj) },
+		DeleteFunc: w.enqueueSliceService,
+	})
+
+	nodes := factory.Core().V1().Nodes()
+	nodes.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(_ interface{}) { w.enqueueLoadBalancers() },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, _ := oldObj.(*v1.Node)
+			newNode, _ := newObj.(*v1.Node)
+			if nodeInternalIP(oldNode) != nodeInternalIP(newNode) || nodeEligible(oldNode) != nodeEligible(newNode) {
+				w.enqueueLoadBalancers()
+			}
+		},
+		DeleteFunc: func(_ interface{}) { w.enqueueLoadBalancers() },
+	})
+
+	l4lbInformer, err := w.MGR.GetCache().GetInformer(cntxt, &k8sv1alpha1.L4LB{})
+	if err != nil {
+		return nil, fmt.Errorf("{setupInformers} %s", err)
+	}
+	l4lbInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: w.enqueueL4LBService,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldLB, _ := oldObj.(*k8sv1alpha1.L4LB)
+			newLB, ok := newObj.(*k8sv1alpha1.L4LB)
+			if ok && oldLB != nil && (oldLB.Status.Status != newLB.Status.Status || oldLB.Generation != newLB.Generation) {
+				w.enqueueL4LBService(newObj)
+			}
+		},
+		DeleteFunc: w.enqueueL4LBService,
+	})
+
+	w.services = services.Lister()
+	w.slices = slices.Lister()
+	w.nodes = nodes.Lister()
+
+	factory.Start(stop)
+
+	return []cache.InformerSynced{
+		services.Informer().HasSynced,
+		slices.Informer().HasSynced,
+		nodes.Informer().HasSynced,
+		l4lbInformer.HasSynced,
+	}, nil
+}
+
+func (w *Watcher) enqueue(namespace, name string) {
+	if name == "" {
+		return
+	}
+	w.queue.Add(namespace + "/" + name)
+}
+
+func (w *Watcher) enqueueObject(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		logger.Error(fmt.Errorf("{enqueueObject} %s", err), "")
+		return
+	}
+	w.queue.Add(key)
+}
+
+// enqueueSliceService queues the LoadBalancer Service the EndpointSlice
+// belongs to.
+func (w *Watcher) enqueueSliceService(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	slice, ok := obj.(*discoveryv1beta1.EndpointSlice)
+	if !ok {
+		return
+	}
+	name := slice.Labels[discoveryv1beta1.LabelServiceName]
+	if name == "" {
+		return
+	}
+	if svc, err := w.services.Services(slice.Namespace).Get(name); err == nil && isLoadBalancer(svc) {
+		w.enqueue(slice.Namespace, name)
+	}
+}
+
+// enqueueL4LBService queues the Service the L4LB was generated for.
+func (w *Watcher) enqueueL4LBService(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	lb, ok := obj.(*k8sv1alpha1.L4LB)
+	if !ok || lb.GetServiceUID() == "" {
+		return
+	}
+	w.enqueue(lb.GetServiceNamespace(), lb.GetServiceName())
+}
+
+// enqueueLoadBalancers queues every LoadBalancer Service, as a Node change
+// can move the backends of any of them.
+func (w *Watcher) enqueueLoadBalancers() {
+	services, err := w.services.List(labels.Everything())
+	if err != nil {
+		logger.Error(fmt.Errorf("{enqueueLoadBalancers} %s", err), "")
+		return
+	}
+	for _, svc := range services {
+		if isLoadBalancer(svc) {
+			w.enqueue(svc.Namespace, svc.Name)
+		}
+	}
+}
+
+// serviceHostIPs returns the sorted InternalIPs of the backend Nodes of the
+// Service. With the Local external traffic policy these are the Nodes
+// hosting its ready endpoints, with Cluster all the ready and schedulable
+// Nodes once the Service has a ready endpoint anywhere, which also covers
+// the Services without a selector.
+func (w *Watcher) serviceHostIPs(svc *v1.Service) ([]string, error) {
+	selector := labels.SelectorFromSet(labels.Set{discoveryv1beta1.LabelServiceName: svc.Name})
+	slices, err := w.slices.EndpointSlices(svc.Namespace).List(selector)
+	if err != nil {
+		return nil, fmt.Errorf("{serviceHostIPs} %s", err)
+	}
+	nodes, err := w.nodes.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("{serviceHostIPs} %s", err)
+	}
+
+	readyHosts := map[string]int{}
+	for _, slice := range slices {
+		for _, endpoint := range slice.Endpoints {
+			if endpointReady(endpoint) {
+				readyHosts[endpoint.Topology[v1.LabelHostname]] = 1
+			}
+		}
+	}
+	if len(readyHosts) == 0 {
+		return []string{}, nil
+	}
+
+	local := svc.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal
+	hostIPs := map[string]int{}
+	for _, node := range nodes {
+		ip := nodeInternalIP(node)
+		if ip == "" {
+			continue
+		}
+		if local {
+			if _, ok := readyHosts[nodeHostname(node)]; !ok {
+				continue
+			}
+		} else if !nodeEligible(node) {
+			continue
+		}
+		hostIPs[ip] = 1
+	}
+
+	ips := []string{}
+	for ip := range hostIPs {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips, nil
+}
+
+// endpointReady treats an unknown readiness as ready, as kube-proxy does.
+func endpointReady(endpoint discoveryv1beta1.Endpoint) bool {
+	return endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+}
+
+func nodeHostname(node *v1.Node) string {
+	if hostname := node.Labels[v1.LabelHostname]; hostname != "" {
+		return hostname
+	}
+	return node.Name
+}
+
+// nodeEligible is true for a ready and not cordoned Node.
+func nodeEligible(node *v1.Node) bool {
+	if node == nil || node.Spec.Unschedulable {
+		return false
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func nodeInternalIP(node *v1.Node) string {
+	if node == nil {
+		return ""
+	}
+	for _, address := range node.Status.Addresses {
+		if address.Type == v1.NodeInternalIP {
+			return address.Address
+		}
+	}
+	return ""
+}
+
+func isLoadBalancer(svc *v1.Service) bool {
+	return svc != nil && svc.Spec.Type == v1.ServiceTypeLoadBalancer
+}
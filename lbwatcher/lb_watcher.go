@@ -20,18 +20,25 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"reflect"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	k8sv1alpha1 "github.com/netrisai/netris-operator/api/v1alpha1"
 	"github.com/netrisai/netris-operator/netrisstorage"
+	api "github.com/netrisai/netriswebapi/v2"
 	"go.uber.org/zap/zapcore"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -40,31 +47,96 @@ import (
 
 var (
 	requeueInterval = time.Duration(10 * time.Second)
+	resyncInterval  = time.Duration(10 * time.Minute)
 	logger          logr.Logger
 	debugLogger     logr.InfoLogger
 	cntxt           = context.Background()
 	contextTimeout  = requeueInterval
 )
 
+// stuckAfterIntervals is how many requeue intervals without a finished cycle
+// make the watcher unready.
+const stuckAfterIntervals = 5
+
+// workers is how many Services are processed in parallel.
+const workers = 2
+
 // NewWatcher initializes the new lb watcher.
-func NewWatcher(nStorage *netrisstorage.Storage, mgr manager.Manager, options Options) (*Watcher, error) {
+func NewWatcher(nStorage *netrisstorage.Storage, cred *api.Clientset, mgr manager.Manager, options Options) (*Watcher, error) {
 	if nStorage == nil {
 		return nil, fmt.Errorf("Please provide NStorage")
 	}
+	if cred == nil {
+		return nil, fmt.Errorf("Please provide Cred")
+	}
 	watcher := &Watcher{
 		NStorage: nStorage,
+		Cred:     cred,
 		MGR:      mgr,
 		Options:  options,
+		warnings: make(map[string]string),
 	}
 	return watcher, nil
 }
 
-func (w *Watcher) start(clientset *kubernetes.Clientset, cl client.Client, recorder record.EventRecorder) {
-	w.loadBalancerProcess(clientset, cl, recorder)
+// NeedLeaderElection is true, so only the leader replica creates and deletes
+// the L4LBs of the Services.
+func (w *Watcher) NeedLeaderElection() bool {
+	return true
+}
+
+// Ready fails until the informers are synced once the watcher is started,
+// and when its workers are stuck for several intervals. The replicas which
+// aren't the leader don't run it and are ready.
+func (w *Watcher) Ready(_ *http.Request) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.running {
+		return nil
+	}
+	if w.lastCycle.IsZero() {
+		return fmt.Errorf("lbwatcher hasn't processed the Services yet")
+	}
+	if since := time.Since(w.lastCycle); since > stuckAfterIntervals*requeueInterval+contextTimeout {
+		return fmt.Errorf("lbwatcher loop is stuck, the last cycle finished %s ago", since.Round(time.Second))
+	}
+	return nil
+}
+
+func (w *Watcher) markCycle() {
+	w.mu.Lock()
+	w.lastCycle = time.Now()
+	w.mu.Unlock()
+}
+
+// markIdle marks the cycle when the queue is empty, so a watcher without
+// work stays ready between the resyncs.
+func (w *Watcher) markIdle() {
+	if w.queue.Len() == 0 {
+		w.markCycle()
+	}
+}
+
+// warningChanged records warning as the last one of key and reports whether
+// it is a new one. An empty warning clears the state of key.
+func (w *Watcher) warningChanged(key, warning string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.warnings[key] == warning {
+		return false
+	}
+	if warning == "" {
+		delete(w.warnings, key)
+		return false
+	}
+	w.warnings[key] = warning
+	return true
 }
 
-// Start .
-func (w *Watcher) Start() {
+// Start runs the informers of the Services, EndpointSlices and Nodes and
+// processes the L4LBs of the changed Services until stop is closed. Every
+// resync interval all the Services are resynced.
+func (w *Watcher) Start(stop <-chan struct{}) error {
 	if w.Options.LogLevel == "debug" {
 		logger = zap.New(zap.Level(zapcore.DebugLevel), zap.UseDevMode(false))
 	} else {
@@ -78,20 +150,114 @@ func (w *Watcher) Start() {
 		requeueInterval = time.Duration(time.Duration(w.Options.RequeueInterval) * time.Second)
 		contextTimeout = requeueInterval
 	}
+	if w.Options.ResyncInterval > 0 {
+		resyncInterval = time.Duration(time.Duration(w.Options.ResyncInterval) * time.Second)
+	}
 
 	clientset, err := getClientset()
 	if err != nil {
-		logger.Error(err, "")
+		return fmt.Errorf("{lbwatcher} %s", err)
+	}
+	w.clientset = clientset
+	w.client = w.MGR.GetClient()
+	w.recorder, _, _ = eventRecorder(clientset)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cntxt = ctx
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	w.queue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "lbwatcher")
+	defer w.queue.ShutDown()
+
+	synced, err := w.setupInformers(stop)
+	if err != nil {
+		return fmt.Errorf("{lbwatcher} %s", err)
+	}
+
+	w.mu.Lock()
+	w.running = true
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		w.running = false
+		w.mu.Unlock()
+	}()
+
+	if !cache.WaitForCacheSync(stop, synced...) {
+		return nil
+	}
+	for i := 0; i < workers; i++ {
+		go wait.Until(w.worker, time.Second, stop)
 	}
-	cl := w.MGR.GetClient()
-	recorder, _, _ := eventRecorder(clientset)
 
 	ticker := time.NewTicker(requeueInterval)
-	w.start(clientset, cl, recorder)
+	defer ticker.Stop()
+	resync := time.NewTicker(resyncInterval)
+	defer resync.Stop()
+	w.resync()
 	for {
-		<-ticker.C
-		w.start(clientset, cl, recorder)
+		select {
+		case <-ticker.C:
+			w.markIdle()
+		case <-resync.C:
+			w.resync()
+		case <-stop:
+			logger.Info("LB Watcher stopped")
+			return nil
+		}
+	}
+}
+
+// resync queues every LoadBalancer Service and the Services of the L4LBs, so
+// a missed event or an L4LB of a Service deleted meanwhile is caught up.
+func (w *Watcher) resync() {
+	w.markIdle()
+	services, err := w.services.List(labels.Everything())
+	if err != nil {
+		logger.Error(fmt.Errorf("{resync} %s", err), "")
+	}
+	for _, svc := range services {
+		if isLoadBalancer(svc) {
+			w.enqueue(svc.Namespace, svc.Name)
+		}
+	}
+	l4lbs, err := getL4LBs(w.client)
+	if err != nil {
+		logger.Error(err, "")
+		return
+	}
+	for _, lb := range filterL4LBs(l4lbs.Items) {
+		w.enqueue(lb.GetServiceNamespace(), lb.GetServiceName())
+	}
+}
+
+func (w *Watcher) worker() {
+	for w.processNext() {
+	}
+}
+
+func (w *Watcher) processNext() bool {
+	key, quit := w.queue.Get()
+	if quit {
+		return false
+	}
+	defer w.queue.Done(key)
+
+	started := time.Now()
+	err := w.processService(key.(string))
+	observeCycle(started, err)
+	w.markCycle()
+	if err != nil {
+		logger.Error(err, "", "service", key)
+		w.queue.AddRateLimited(key)
+		return true
 	}
+	w.queue.Forget(key)
+	return true
 }
 
 func getClientset() (*kubernetes.Clientset, error) {
@@ -108,37 +274,76 @@ func filterL4LBs(LBs []k8sv1alpha1.L4LB) []k8sv1alpha1.L4LB {
 	return lbList
 }
 
-func (w *Watcher) loadBalancerProcess(clientset *kubernetes.Clientset, cl client.Client, recorder record.EventRecorder) {
-	debugLogger.Info("Generating load balancers from k8s...")
-	var errors []error = nil
-	lbTimeout := "2000"
-
-	l4lbs, err := getL4LBs(cl)
+// processService creates, updates and deletes the L4LBs of the Service with
+// the given "namespace/name" key and assigns their IPs to the Service.
+func (w *Watcher) processService(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
 	if err != nil {
-		logger.Error(err, "")
+		return fmt.Errorf("{processService} %s", err)
 	}
+	debugLogger.Info("Generating load balancers from k8s...", "service", name, "namespace", namespace)
+	var errors []error = nil
 
-	if l4lbs == nil {
-		logger.Error(fmt.Errorf("CRD Not found"), "")
-		return
+	l4lbs, err := getServiceL4LBs(w.client, namespace, name)
+	if err != nil {
+		return err
 	}
 
-	filteerdL4LBs := filterL4LBs(l4lbs.Items)
-
 	ipAuto := make(map[string]string)
-	for _, lb := range filteerdL4LBs {
+	for _, lb := range l4lbs {
 		if uid := lb.GetServiceUID(); uid != "" {
 			ipAuto[uid] = lb.Spec.Frontend.IP
 		}
 	}
 
-	serviceLBs, err := w.generateLoadBalancers(clientset, ipAuto, lbTimeout)
-	if err != nil {
-		logger.Error(err, "")
-		return
+	serviceLBs := []*k8sv1alpha1.L4LB{}
+	svc, err := w.services.Services(namespace).Get(name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("{processService} %s", err)
+	}
+	annotationKey := EventInvalidAnnotation + "/" + key
+	if err == nil && isLoadBalancer(svc) {
+		opts, err := w.parseServiceOptions(svc)
+		if err != nil {
+			// the L4LBs are kept as they are until the annotations are fixed.
+			if !w.warningChanged(annotationKey, err.Error()) {
+				return nil
+			}
+			if err := createEvent(w.clientset, w.recorder, namespace, name, EventInvalidAnnotation, err.Error()); err != nil {
+				w.warningChanged(annotationKey, "")
+				return err
+			}
+			return nil
+		}
+		w.warningChanged(annotationKey, "")
+		if opts.frontendSubnet != nil {
+			// one allocation at a time, until its L4LBs are created.
+			w.allocMu.Lock()
+			defer w.allocMu.Unlock()
+			if opts.frontendIP, err = w.frontendIP(opts.frontendSubnet, ipAuto[string(svc.GetUID())]); err != nil {
+				return err
+			}
+		}
+		debugLogger.Info("Getting k8s endpoints...", "service", name, "namespace", namespace)
+		hostIPs, err := w.serviceHostIPs(svc)
+		if err != nil {
+			return err
+		}
+		if len(hostIPs) == 0 {
+			// the L4LBs keep their last backends until an endpoint is ready again,
+			// deleting them would give up the frontend IP of the Service.
+			debugLogger.Info("No ready endpoints, keeping the load balancers", "service", name, "namespace", namespace)
+			return nil
+		}
+		serviceLBs, err = w.generateLoadBalancers(svc, opts, ipAuto, hostIPs)
+		if err != nil {
+			return err
+		}
+	} else {
+		w.warningChanged(annotationKey, "")
 	}
 
-	lbsToCreate, lbsToUpdate, lbsToDelete, ingressIPsMap := compareLoadBalancers(filteerdL4LBs, serviceLBs)
+	lbsToCreate, lbsToUpdate, lbsToDelete, ingressIPsMap := compareLoadBalancers(l4lbs, serviceLBs)
 
 	js, _ := json.Marshal(lbsToCreate)
 	debugLogger.Info("Load balancers for create", "List", string(js))
@@ -149,46 +354,41 @@ func (w *Watcher) loadBalancerProcess(clientset *kubernetes.Clientset, cl client
 	js, _ = json.Marshal(ingressIPsMap)
 	debugLogger.Info("Ingress addresses for k8s", "List", string(js))
 
-	lbsByUID := make(map[string][]*k8sv1alpha1.L4LB)
-	for _, lb := range lbsToCreate {
-		lbsByUID[lb.GetServiceUID()] = append(lbsByUID[lb.GetServiceUID()], lb)
-	}
-
-	errors = append(errors, deleteL4LBs(cl, lbsToDelete)...)
-
-	errs := updateL4LBs(cl, lbsToUpdate, ipAuto)
-	errors = append(errors, errs...)
+	errors = append(errors, deleteL4LBs(w.client, lbsToDelete)...)
+	errors = append(errors, updateL4LBs(w.client, lbsToUpdate, ipAuto)...)
+	errors = append(errors, createL4LBs(w.client, lbsToCreate, ipAuto)...)
 
-	for _, lbs := range lbsByUID {
-		errs = createL4LBs(cl, lbs, ipAuto)
-		errors = append(errors, errs...)
-	}
-
-	for _, serviceLB := range serviceLBs {
-		ingressIPs := []string{}
-		if ingress, ok := ingressIPsMap[serviceLB.GetServiceUID()]; ok {
+	if len(serviceLBs) > 0 {
+		if ingress, ok := ingressIPsMap[serviceLBs[0].GetServiceUID()]; ok {
+			ingressIPs := []string{}
 			for ip := range ingress {
 				ingressIPs = append(ingressIPs, ip)
 			}
-			_, err := assignIngress(clientset, ingressIPs, serviceLB.GetServiceNamespace(), serviceLB.GetServiceName())
+			_, err := assignIngress(w.clientset, ingressIPs, namespace, name)
 			if err != nil {
 				errors = append(errors, err)
 			}
 		}
 	}
 
-	for _, lb := range l4lbs.Items {
+	for _, lb := range l4lbs {
+		failureKey, failure := "Failure/"+lb.Namespace+"/"+lb.Name, ""
 		if lb.Status.Status == "Failure" {
-			err := createEvent(clientset, recorder, lb.GetServiceNamespace(), lb.GetServiceName(), lb.Status.Status, lb.Status.Message)
+			failure = lb.Status.Status + ": " + lb.Status.Message
+		}
+		if w.warningChanged(failureKey, failure) {
+			err := createEvent(w.clientset, w.recorder, namespace, name, lb.Status.Status, lb.Status.Message)
 			if err != nil {
+				w.warningChanged(failureKey, "")
 				errors = append(errors, fmt.Errorf("{lbEventsPatcher} %s", err))
 			}
 		}
 	}
-
-	for _, err := range errors {
-		logger.Error(err, "")
+	for _, lb := range lbsToDelete {
+		w.warningChanged("Failure/"+lb.Namespace+"/"+lb.Name, "")
 	}
+
+	return utilerrors.NewAggregate(errors)
 }
 
 func deleteL4LBs(cl client.Client, lbs []k8sv1alpha1.L4LB) []error {
@@ -291,8 +491,19 @@ func compareLoadBalancers(LBs []k8sv1alpha1.L4LB, serviceLBs []*k8sv1alpha1.L4LB
 					update = true
 				}
 
-				if serviceLB.Spec.Check.Timeout != lb.Spec.Check.Timeout {
-					lb.Spec.Check.Timeout = serviceLB.Spec.Check.Timeout
+				if serviceLB.Spec.Check != lb.Spec.Check {
+					lb.Spec.Check = serviceLB.Spec.Check
+					update = true
+				}
+
+				if serviceLB.Spec.OwnerTenant != lb.Spec.OwnerTenant {
+					lb.Spec.OwnerTenant = serviceLB.Spec.OwnerTenant
+					update = true
+				}
+
+				// the site inferred from the backends is kept when they're gone.
+				if serviceLB.Spec.Site != "" && serviceLB.Spec.Site != lb.Spec.Site {
+					lb.Spec.Site = serviceLB.Spec.Site
 					update = true
 				}
 
@@ -358,129 +569,128 @@ func getL4LBs(cl client.Client) (*k8sv1alpha1.L4LBList, error) {
 	return l4lb, nil
 }
 
-func (w *Watcher) generateLoadBalancers(clientset *kubernetes.Clientset, autoIPs map[string]string, lbTimeout string) ([]*k8sv1alpha1.L4LB, error) {
-	lbList := []*k8sv1alpha1.L4LB{}
-	serviceList, err := getServices(clientset, "")
+// getServiceL4LBs returns the L4LBs generated for the Service.
+func getServiceL4LBs(cl client.Client, namespace, name string) ([]k8sv1alpha1.L4LB, error) {
+	l4lb := &k8sv1alpha1.L4LBList{}
+
+	ctx, cancel := context.WithTimeout(cntxt, contextTimeout)
+	defer cancel()
+	err := cl.List(ctx, l4lb, client.InNamespace(namespace))
 	if err != nil {
-		return lbList, fmt.Errorf("{generateLoadBalancers} %s", err)
+		return nil, fmt.Errorf("{getServiceL4LBs} %s", err)
 	}
 
-	timeout, err := strconv.Atoi(lbTimeout)
-	if err != nil {
-		return lbList, fmt.Errorf("{generateLoadBalancers} %s", err)
+	lbList := []k8sv1alpha1.L4LB{}
+	for _, lb := range filterL4LBs(l4lb.Items) {
+		if lb.GetServiceNamespace() == namespace && lb.GetServiceName() == name {
+			lbList = append(lbList, lb)
+		}
 	}
+	return lbList, nil
+}
 
-	var siteName string
+// generateLoadBalancers builds the L4LBs the Service should have: one per
+// port, with the Nodes of its endpoints as backends.
+func (w *Watcher) generateLoadBalancers(svc *v1.Service, opts serviceOptions, autoIPs map[string]string, hostIPS []string) ([]*k8sv1alpha1.L4LB, error) {
+	lbList := []*k8sv1alpha1.L4LB{}
 
-	for _, svc := range serviceList.Items {
-		if svc.Spec.Type == "LoadBalancer" {
-			selectors := []string{}
-			hostIPs := map[string]int{}
-			for key, value := range svc.Spec.Selector {
-				selectors = append(selectors, fmt.Sprintf("%s=%s", key, value))
+	siteName := opts.site
+	if siteName == "" {
+		// the site of the first backend which is in a Netris subnet.
+		var siteErr error
+		for _, hostIP := range hostIPS {
+			site, _, err := w.findSiteByIP(hostIP)
+			if err == nil {
+				siteName = site.Name
+				break
 			}
+			siteErr = err
+		}
+		if siteName == "" && siteErr != nil {
+			return lbList, fmt.Errorf("{generateLoadBalancers} %s", siteErr)
+		}
+	}
 
-			debugLogger.Info("Getting k8s pods...", "service", svc.Name, "namespace", svc.Namespace)
-			podList, err := getPodsByLabelSeector(clientset, svc.Namespace, strings.Join(selectors, ","))
-			if err != nil {
-				return lbList, fmt.Errorf("{generateLoadBalancers} %s", err)
-			}
+	loadBalancerIP := svc.Spec.LoadBalancerIP
+	if opts.frontendIP != "" {
+		loadBalancerIP = opts.frontendIP
+	}
 
-			for _, pod := range podList.Items {
-				if pod.Status.HostIP != "" {
-					hostIPs[pod.Status.HostIP] = 1
-				}
-			}
+	var lbIPs []lbIP
 
-			var lbIPs []lbIP
+	var ingressIPs []string
 
-			var ingressIPs []string
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		ingressIPs = append(ingressIPs, ingress.IP)
+	}
+	ingressIPsString := strings.Join(ingressIPs, ",")
+
+	for _, port := range svc.Spec.Ports {
+		lbIP := lbIP{
+			Name:     port.Name,
+			IP:       loadBalancerIP,
+			Port:     int(port.Port),
+			NodePort: int(port.NodePort),
+			Protocol: string(port.Protocol),
+		}
+		if len(loadBalancerIP) == 0 {
+			lbIP.Automatic = true
+		}
+		lbIPs = append(lbIPs, lbIP)
+	}
 
-			for _, ingress := range svc.Status.LoadBalancer.Ingress {
-				ingressIPs = append(ingressIPs, ingress.IP)
-			}
-			ingressIPsString := strings.Join(ingressIPs, ",")
-
-			for _, port := range svc.Spec.Ports {
-				lbIP := lbIP{
-					Name:     port.Name,
-					IP:       svc.Spec.LoadBalancerIP,
-					Port:     int(port.Port),
-					NodePort: int(port.NodePort),
-					Protocol: string(port.Protocol),
+	if len(lbIPs) > 0 && len(hostIPS) > 0 {
+		for i, lbIP := range lbIPs {
+			frontendIP := lbIP.IP
+			if lbIP.IP == "" {
+				if ip, ok := autoIPs[string(svc.GetUID())]; ok && ip != "" {
+					frontendIP = ip
+				} else if i > 0 {
+					break
 				}
-				if len(svc.Spec.LoadBalancerIP) > 0 {
-					lbIP.IP = svc.Spec.LoadBalancerIP
-				} else {
-					lbIP.Automatic = true
-				}
-				lbIPs = append(lbIPs, lbIP)
 			}
-
-			var hostIPS []string
-
-			for hostIP := range hostIPs {
-				hostIPS = append(hostIPS, hostIP)
+			backends := []k8sv1alpha1.L4LBBackend{}
+			for _, hostIP := range hostIPS {
+				backend := fmt.Sprintf("%s:%d", hostIP, lbIP.NodePort)
+				backends = append(backends, k8sv1alpha1.L4LBBackend(backend))
 			}
 
-			if len(lbIPs) > 0 && len(hostIPS) > 0 {
-				for i, lbIP := range lbIPs {
-					frontendIP := lbIP.IP
-					if lbIP.IP == "" {
-						if ip, ok := autoIPs[string(svc.GetUID())]; ok && ip != "" {
-							frontendIP = ip
-						} else if i > 0 {
-							break
-						}
-					}
-					backends := []k8sv1alpha1.L4LBBackend{}
-					for _, hostIP := range hostIPS {
-						if siteName == "" {
-							site, _, err := w.findSiteByIP(hostIP)
-							if err != nil {
-								fmt.Println(err)
-							}
-							siteName = site.Name
-						}
-						backend := fmt.Sprintf("%s:%d", hostIP, lbIP.NodePort)
-						backends = append(backends, k8sv1alpha1.L4LBBackend(backend))
-					}
-
-					lb := &k8sv1alpha1.L4LB{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:        strings.ToLower(fmt.Sprintf("%s-%s-%s-%s-%d", svc.GetName(), svc.GetNamespace(), svc.GetUID(), lbIP.Protocol, lbIP.Port)),
-							Namespace:   svc.GetNamespace(),
-							Annotations: make(map[string]string),
-						},
-						TypeMeta: metav1.TypeMeta{
-							Kind:       "L4LB",
-							APIVersion: "k8s.netris.ai/v1alpha1",
-						},
-						Spec: k8sv1alpha1.L4LBSpec{
-							Site:     siteName,
-							Protocol: strings.ToLower(lbIP.Protocol),
-							Frontend: k8sv1alpha1.L4LBFrontend{
-								Port: lbIP.Port,
-								IP:   frontendIP,
-							},
-							State: "active",
-							Check: k8sv1alpha1.L4LBCheck{
-								Type:    "tcp",
-								Timeout: timeout,
-							},
-							Backend: backends,
-						},
-					}
-
-					lb.SetServiceName(svc.GetName())
-					lb.SetServiceNamespace(svc.GetNamespace())
-					lb.SetServiceUID(string(svc.GetUID()))
-					lb.SetServiceIngressIPs(ingressIPsString)
-					lb.SetImportFlag("true")
-
-					lbList = append(lbList, lb)
-				}
+			lb := &k8sv1alpha1.L4LB{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        strings.ToLower(fmt.Sprintf("%s-%s-%s-%s-%d", svc.GetName(), svc.GetNamespace(), svc.GetUID(), lbIP.Protocol, lbIP.Port)),
+					Namespace:   svc.GetNamespace(),
+					Annotations: make(map[string]string),
+				},
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "L4LB",
+					APIVersion: "k8s.netris.ai/v1alpha1",
+				},
+				Spec: k8sv1alpha1.L4LBSpec{
+					Site:     siteName,
+					Protocol: strings.ToLower(lbIP.Protocol),
+					Frontend: k8sv1alpha1.L4LBFrontend{
+						Port: lbIP.Port,
+						IP:   frontendIP,
+					},
+					State: "active",
+					// a Netris L4LB has no health check port, the check always probes
+					// the backend node port and healthCheckNodePort isn't supported.
+					// The backends of a Local Service are only the Nodes of its ready
+					// endpoints instead, kube-proxy drops its node port traffic on the
+					// others.
+					Check:       opts.check,
+					OwnerTenant: opts.ownerTenant,
+					Backend:     backends,
+				},
 			}
+
+			lb.SetServiceName(svc.GetName())
+			lb.SetServiceNamespace(svc.GetNamespace())
+			lb.SetServiceUID(string(svc.GetUID()))
+			lb.SetServiceIngressIPs(ingressIPsString)
+			lb.SetImportFlag("true")
+
+			lbList = append(lbList, lb)
 		}
 	}
 	return lbList, nil
@@ -17,7 +17,17 @@ limitations under the License.
 package lbwatcher
 
 import (
+	"sync"
+	"time"
+
 	"github.com/netrisai/netris-operator/netrisstorage"
+	api "github.com/netrisai/netriswebapi/v2"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1beta1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
@@ -25,7 +35,26 @@ import (
 type Watcher struct {
 	Options  Options
 	NStorage *netrisstorage.Storage
+	Cred     *api.Clientset
 	MGR      manager.Manager
+
+	clientset *kubernetes.Clientset
+	client    client.Client
+	recorder  record.EventRecorder
+	queue     workqueue.RateLimitingInterface
+	services  corelisters.ServiceLister
+	slices    discoverylisters.EndpointSliceLister
+	nodes     corelisters.NodeLister
+
+	// allocMu serializes the frontend IP allocations from the subnets.
+	allocMu sync.Mutex
+
+	mu        sync.Mutex
+	running   bool
+	lastCycle time.Time
+	// warnings are the last warning events of the Services and the L4LBs, so
+	// an unchanged state isn't reported again on every pass.
+	warnings map[string]string
 }
 
 type lbIP struct {
@@ -41,4 +70,6 @@ type lbIP struct {
 type Options struct {
 	LogLevel        string
 	RequeueInterval int
+	// ResyncInterval is how often in seconds all the Services are resynced.
+	ResyncInterval int
 }
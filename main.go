@@ -20,6 +20,7 @@ import (
 	"flag"
 	"log"
 	"os"
+	"time"
 
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -27,7 +28,10 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	api "github.com/netrisai/netriswebapi/v2"
 
@@ -35,7 +39,10 @@ import (
 	"github.com/netrisai/netris-operator/calicowatcher"
 	"github.com/netrisai/netris-operator/configloader"
 	"github.com/netrisai/netris-operator/controllers"
+	"github.com/netrisai/netris-operator/credwatcher"
+	"github.com/netrisai/netris-operator/healthcheck"
 	"github.com/netrisai/netris-operator/lbwatcher"
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netris-operator/netrisstorage"
 	// +kubebuilder:scaffold:imports
 )
@@ -59,12 +66,17 @@ func init() {
 
 func main() {
 	var metricsAddr string
+	var probeAddr string
 	var enableLeaderElection bool
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&probeAddr, "health-probe-addr", ":8081", "The address the health and readiness probe endpoints bind to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&configloader.Root.DryRun, "dry-run", configloader.Root.DryRun,
+		"Record the planned Netris changes in the status of the resources instead of sending them.")
 	flag.Parse()
+	netrisapi.SetDryRun(configloader.Root.DryRun)
 
 	if configloader.Root.LogDevMode {
 		ctrl.SetLogger(zap.New(zap.Level(zapcore.DebugLevel), zap.UseDevMode(false)))
@@ -72,76 +84,148 @@ func main() {
 		ctrl.SetLogger(zap.New(zap.UseDevMode(false), zap.StacktraceLevel(zapcore.DPanicLevel)))
 	}
 
+	if err := controllers.SetMaintenanceWindows(configloader.Root.MaintenanceWindows); err != nil {
+		setupLog.Error(err, "invalid maintenancewindows")
+		os.Exit(1)
+	}
+	if err := controllers.SetNamingPolicy(configloader.Root.NamingPolicy); err != nil {
+		setupLog.Error(err, "invalid namingpolicy")
+		os.Exit(1)
+	}
+
 	var err error
-	cred, err = api.Client(configloader.Root.Controller.Host, configloader.Root.Controller.Login, configloader.Root.Controller.Password, configloader.Root.RequeueInterval)
+	creds := credwatcher.Credentials{
+		Host:     configloader.Root.Controller.Host,
+		Login:    configloader.Root.Controller.Login,
+		Password: configloader.Root.Controller.Password,
+		Insecure: configloader.Root.Controller.Insecure,
+	}
+	if secret := configloader.Root.Controller.CredentialsSecret; secret != "" {
+		secretClient, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+		if err != nil {
+			log.Panicf("credentials secret client error %v", err)
+		}
+		creds, err = credwatcher.Load(secretClient, secret, creds.Insecure)
+		if err != nil {
+			setupLog.Error(err, "unable to load the credentials secret")
+			os.Exit(1)
+		}
+	}
+	cred, err = api.Client(creds.Host, creds.Login, creds.Password, configloader.Root.RequeueInterval)
 	if err != nil {
 		log.Panicf("newHTTPCredentials error %v", err)
 	}
-	cred.Client.InsecureVerify(configloader.Root.Controller.Insecure)
+	cred.Client.InsecureVerify(creds.Insecure)
 	err = cred.Client.LoginUser()
 	if err != nil {
 		log.Printf("LoginUser error %v", err)
-		os.Exit(1)
+		// with a storage snapshot the operator starts warm and logs in once Netris is reachable.
+		if configloader.Root.StorageSnapshot == "" {
+			os.Exit(1)
+		}
 	}
 	go cred.Client.CheckAuthWithInterval()
 
 	nStorage = netrisstorage.NewStorage(cred)
+	var snapshotStore netrisstorage.SnapshotStore
+	if location := configloader.Root.StorageSnapshot; location != "" {
+		snapshotClient, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+		if err != nil {
+			log.Panicf("snapshot client error %v", err)
+		}
+		snapshotStore, err = netrisstorage.NewSnapshotStore(location, snapshotClient)
+		if err != nil {
+			log.Printf("NewSnapshotStore error %v", err)
+		} else if err = nStorage.Restore(snapshotStore); err != nil {
+			log.Printf("Storage.Restore() error %v", err)
+		}
+	}
 	err = nStorage.Download()
 	if err != nil {
 		log.Printf("Storage.Download() error %v", err)
 	}
 	go nStorage.DownloadWithInterval()
+	if snapshotStore != nil {
+		snapshotInterval := configloader.Root.StorageSnapshotInterval
+		if snapshotInterval <= 0 {
+			snapshotInterval = 60
+		}
+		go nStorage.CheckpointWithInterval(snapshotStore, time.Duration(snapshotInterval)*time.Second)
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Namespace:          "",
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		Port:               9443,
-		LeaderElection:     enableLeaderElection,
-		LeaderElectionID:   "abac3abe.netris.ai",
+		Namespace:              "",
+		Scheme:                 scheme,
+		MetricsBindAddress:     metricsAddr,
+		HealthProbeBindAddress: probeAddr,
+		Port:                   9443,
+		LeaderElection:         enableLeaderElection,
+		LeaderElectionID:       "abac3abe.netris.ai",
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	// resources with a controllerRef are managed through the connections of the NetrisController resources.
+	controllerSet := controllers.NewControllerSet(nStorage)
+	if err = (&controllers.NetrisControllerReconciler{
+		Client:           mgr.GetClient(),
+		Log:              ctrl.Log.WithName("NetrisController"),
+		Scheme:           mgr.GetScheme(),
+		Controllers:      controllerSet,
+		Timeout:          configloader.Root.RequeueInterval,
+		RefreshIntervals: configloader.Root.StorageRefreshIntervals,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "NetrisController")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.VNetReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("VNet"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("VNet"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "VNet")
 		os.Exit(1)
 	}
 	if err = (&controllers.VNetMetaReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("VNetMeta"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("VNetMeta"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "VNetMeta")
 		os.Exit(1)
 	}
 
 	if err = (&controllers.BGPReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("BGP"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("BGP"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "BGP")
 		os.Exit(1)
 	}
 	if err = (&controllers.BGPMetaReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("BGPMeta"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("BGPMeta"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "BGPMeta")
 		os.Exit(1)
@@ -152,209 +236,252 @@ func main() {
 	}
 
 	if err = (&controllers.L4LBReconciler{
-		Client:     mgr.GetClient(),
-		Log:        ctrl.Log.WithName("L4LB"),
-		Scheme:     mgr.GetScheme(),
-		Cred:       cred,
-		NStorage:   nStorage,
-		L4LBTenant: configloader.Root.L4lbTenant,
-		VPCID:      vpcid,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("L4LB"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
+		L4LBTenant:  configloader.Root.L4lbTenant,
+		VPCID:       vpcid,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "L4LB")
 		os.Exit(1)
 	}
 	if err = (&controllers.L4LBMetaReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("L4LBMeta"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
-		VPCID:    vpcid,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("L4LBMeta"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
+		VPCID:       vpcid,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "L4LBMeta")
 		os.Exit(1)
 	}
 	if err = (&controllers.SiteReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("Site"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("Site"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Site")
 		os.Exit(1)
 	}
 	if err = (&controllers.SiteMetaReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("SiteMeta"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("SiteMeta"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SiteMeta")
 		os.Exit(1)
 	}
 	if err = (&controllers.AllocationReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("Allocation"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("Allocation"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Allocation")
 		os.Exit(1)
 	}
 	if err = (&controllers.AllocationMetaReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("AllocationMeta"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("AllocationMeta"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AllocationMeta")
 		os.Exit(1)
 	}
 	if err = (&controllers.SubnetReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("Subnet"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("Subnet"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Subnet")
 		os.Exit(1)
 	}
 	if err = (&controllers.SubnetMetaReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("SubnetMeta"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("SubnetMeta"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SubnetMeta")
 		os.Exit(1)
 	}
 	if err = (&controllers.SoftgateReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("Softgate"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("Softgate"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Softgate")
 		os.Exit(1)
 	}
 	if err = (&controllers.SoftgateMetaReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("SoftgateMeta"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("SoftgateMeta"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SoftgateMeta")
 		os.Exit(1)
 	}
 	if err = (&controllers.SwitchReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("Switch"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("Switch"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Switch")
 		os.Exit(1)
 	}
 	if err = (&controllers.SwitchMetaReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("SwitchMeta"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("SwitchMeta"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SwitchMeta")
 		os.Exit(1)
 	}
 	if err = (&controllers.ControllerReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("Controller"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("Controller"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Controller")
 		os.Exit(1)
 	}
 	if err = (&controllers.ControllerMetaReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("ControllerMeta"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("ControllerMeta"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ControllerMeta")
 		os.Exit(1)
 	}
 	if err = (&controllers.LinkReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("Link"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("Link"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Link")
 		os.Exit(1)
 	}
 	if err = (&controllers.LinkMetaReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("LinkMeta"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("LinkMeta"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "LinkMeta")
 		os.Exit(1)
 	}
 	if err = (&controllers.NatReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("Nat"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("Nat"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Nat")
 		os.Exit(1)
 	}
 	if err = (&controllers.NatMetaReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("NatMeta"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("NatMeta"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "NatMeta")
 		os.Exit(1)
 	}
 	if err = (&controllers.InventoryProfileReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("InventoryProfile"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("InventoryProfile"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "InventoryProfile")
 		os.Exit(1)
 	}
 	if err = (&controllers.InventoryProfileMetaReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("InventoryProfileMeta"),
-		Scheme:   mgr.GetScheme(),
-		Cred:     cred,
-		NStorage: nStorage,
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("InventoryProfileMeta"),
+		Scheme:      mgr.GetScheme(),
+		Cred:        cred,
+		NStorage:    nStorage,
+		Controllers: controllerSet,
+		Recorder:    mgr.GetEventRecorderFor("netris-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "InventoryProfileMeta")
 		os.Exit(1)
 	}
 
+	if configloader.Root.EnableWebhooks {
+		mgr.GetWebhookServer().Register(controllers.DeletionWebhookPath, &webhook.Admission{Handler: &controllers.DeletionValidator{}})
+	}
 	// +kubebuilder:scaffold:builder
 
 	watcherLogLevel := "info"
@@ -362,19 +489,58 @@ func main() {
 		watcherLogLevel = "debug"
 	}
 
-	lbWatcher, err := lbwatcher.NewWatcher(nStorage, mgr, lbwatcher.Options{LogLevel: watcherLogLevel, RequeueInterval: configloader.Root.RequeueInterval})
+	lbWatcher, err := lbwatcher.NewWatcher(nStorage, cred, mgr, lbwatcher.Options{LogLevel: watcherLogLevel, RequeueInterval: configloader.Root.RequeueInterval, ResyncInterval: configloader.Root.LBResyncInterval})
 	if err != nil {
 		setupLog.Error(err, "problem running lbwatcher")
 		os.Exit(1)
 	}
-	go lbWatcher.Start()
+	if err := mgr.Add(lbWatcher); err != nil {
+		setupLog.Error(err, "problem running lbwatcher")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("lbwatcher", lbWatcher.Ready); err != nil {
+		setupLog.Error(err, "unable to set up ready check", "check", "lbwatcher")
+		os.Exit(1)
+	}
 
 	cWatcher, err := calicowatcher.NewWatcher(nStorage, mgr, calicowatcher.Options{LogLevel: watcherLogLevel, RequeueInterval: configloader.Root.RequeueInterval})
 	if err != nil {
 		setupLog.Error(err, "problem running calicowatcher")
 		os.Exit(1)
 	}
-	go cWatcher.Start()
+	if err := mgr.Add(cWatcher); err != nil {
+		setupLog.Error(err, "problem running calicowatcher")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("calicowatcher", cWatcher.Ready); err != nil {
+		setupLog.Error(err, "unable to set up ready check", "check", "calicowatcher")
+		os.Exit(1)
+	}
+
+	if configloader.Root.Controller.CredentialsSecret != "" {
+		credWatcher, err := credwatcher.NewWatcher(cred, mgr, creds, credwatcher.Options{Secret: configloader.Root.Controller.CredentialsSecret, Timeout: configloader.Root.RequeueInterval})
+		if err != nil {
+			setupLog.Error(err, "problem running credwatcher")
+			os.Exit(1)
+		}
+		if err := mgr.Add(credWatcher); err != nil {
+			setupLog.Error(err, "problem running credwatcher")
+			os.Exit(1)
+		}
+	}
+
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up health check", "check", "ping")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("netris", healthcheck.Netris(cred, 10*time.Second)); err != nil {
+		setupLog.Error(err, "unable to set up ready check", "check", "netris")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("storage", healthcheck.Storage(nStorage)); err != nil {
+		setupLog.Error(err, "unable to set up ready check", "check", "storage")
+		os.Exit(1)
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -0,0 +1,251 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package maintenance parses the maintenance windows of the operator. A
+// window is a cron schedule of its start and a duration, e.g. "0 2 * * SAT 4h"
+// opens every Saturday at 02:00 UTC for four hours. The disruptive Netris
+// updates are deferred while no window is open.
+package maintenance
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDuration bounds a window, the open check scans its minutes.
+const maxDuration = 7 * 24 * time.Hour
+
+// lookahead is how far the start of the next window is searched for.
+const lookahead = 366 * 24 * time.Hour
+
+var (
+	monthNames = map[string]int{"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6, "JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12}
+	dayNames   = map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}
+)
+
+// Window is a recurring maintenance window.
+type Window struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are set for a "*" day of month and day of week, the
+	// cron rule for two restricted day fields is to match either of them.
+	domAny, dowAny bool
+	duration       time.Duration
+	text           string
+}
+
+// Windows are the maintenance windows of the operator. No windows mean no
+// restriction.
+type Windows []Window
+
+// Parse parses the windows separated by ";". A window is the five fields of
+// a cron schedule (minute, hour, day of month, month, day of week) followed
+// by a duration.
+func Parse(s string) (Windows, error) {
+	windows := Windows{}
+	for _, text := range strings.Split(s, ";") {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		w, err := parseWindow(text)
+		if err != nil {
+			return nil, fmt.Errorf("{Parse} window %q: %s", text, err)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func parseWindow(text string) (Window, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 6 {
+		return Window{}, fmt.Errorf("expected 5 cron fields and a duration, got %d fields", len(fields))
+	}
+	w := Window{text: text}
+	var err error
+	if w.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return w, fmt.Errorf("minute: %s", err)
+	}
+	if w.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return w, fmt.Errorf("hour: %s", err)
+	}
+	if w.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return w, fmt.Errorf("day of month: %s", err)
+	}
+	if w.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return w, fmt.Errorf("month: %s", err)
+	}
+	if w.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return w, fmt.Errorf("day of week: %s", err)
+	}
+	// 7 is Sunday too.
+	if w.dow&(1<<7) != 0 {
+		w.dow |= 1
+	}
+	w.domAny = fields[2] == "*" || fields[2] == "?"
+	w.dowAny = fields[4] == "*" || fields[4] == "?"
+	if w.duration, err = time.ParseDuration(fields[5]); err != nil {
+		return w, err
+	}
+	if w.duration < time.Minute || w.duration > maxDuration {
+		return w, fmt.Errorf("duration must be between 1m and %s", maxDuration)
+	}
+	return w, nil
+}
+
+// parseField parses a cron field: "*", a value, a range "a-b", any of them
+// with a step "/n", or a list of them separated by ",". It returns the bit
+// set of the matching values.
+func parseField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+		from, to := min, max
+		switch {
+		case part == "*" || part == "?":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if from, err = parseValue(bounds[0], min, max, names); err != nil {
+				return 0, err
+			}
+			if to, err = parseValue(bounds[1], min, max, names); err != nil {
+				return 0, err
+			}
+			// SUN closing a day of week range is 7, so MON-SUN is MON to Sunday.
+			if to == 0 && max == 7 && strings.ToUpper(bounds[1]) == "SUN" {
+				to = 7
+			}
+			if from > to {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			v, err := parseValue(part, min, max, names)
+			if err != nil {
+				return 0, err
+			}
+			from = v
+			if step == 1 {
+				to = v
+			}
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(s string, min, max int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("invalid value %q, expected %d-%d", s, min, max)
+	}
+	return v, nil
+}
+
+// starts reports whether the window starts at the minute of t.
+func (w Window) starts(t time.Time) bool {
+	if w.minute&(1<<uint(t.Minute())) == 0 || w.hour&(1<<uint(t.Hour())) == 0 || w.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	return w.dayMatches(t)
+}
+
+func (w Window) dayMatches(t time.Time) bool {
+	dom := w.dom&(1<<uint(t.Day())) != 0
+	dow := w.dow&(1<<uint(t.Weekday())) != 0
+	if w.domAny || w.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Open reports whether the window is open at t.
+func (w Window) Open(t time.Time) bool {
+	t = t.UTC()
+	start := t.Truncate(time.Minute)
+	for s := start; t.Sub(s) < w.duration; s = s.Add(-time.Minute) {
+		if w.starts(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the start of the window after t, zero if it doesn't start
+// within a year.
+func (w Window) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	end := t.Add(lookahead)
+	for t.Before(end) {
+		switch {
+		case w.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !w.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case w.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case w.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (w Window) String() string {
+	return w.text
+}
+
+// Open reports whether a window is open at t. It's always true without windows.
+func (ws Windows) Open(t time.Time) bool {
+	if len(ws) == 0 {
+		return true
+	}
+	for _, w := range ws {
+		if w.Open(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the start of the first window after t, zero if none starts
+// within a year.
+func (ws Windows) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range ws {
+		if n := w.Next(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		"0 2 * * SAT",
+		"60 2 * * * 1h",
+		"0 2 * 13 * 1h",
+		"0 2 * * FOO 1h",
+		"0 5-2 * * * 1h",
+		"*/0 2 * * * 1h",
+		"0 2 * * * 10s",
+		"0 2 * * * 1000h",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	// every Saturday at 02:00 for 4 hours, and the 1st and 15th of a month at 23:30 for an hour.
+	ws, err := Parse("0 2 * * SAT 4h; 30 23 1,15 * * 1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		at   string
+		open bool
+	}{
+		{"2026-10-17 01:59", false}, // Saturday
+		{"2026-10-17 02:00", true},
+		{"2026-10-17 05:59", true},
+		{"2026-10-17 06:00", false},
+		{"2026-10-18 03:00", false}, // Sunday
+		{"2026-10-15 23:45", true},
+		{"2026-10-16 00:29", true}, // past midnight
+		{"2026-10-16 00:30", false},
+	}
+	for _, c := range cases {
+		if got := ws.Open(date(c.at)); got != c.open {
+			t.Errorf("Open(%s) = %v, want %v", c.at, got, c.open)
+		}
+	}
+	if !(Windows{}).Open(date("2026-10-16 12:00")) {
+		t.Error("no windows must always be open")
+	}
+}
+
+func TestNext(t *testing.T) {
+	ws, err := Parse("0 2 * * SAT 4h; 30 23 1,15 * * 1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct{ from, next string }{
+		{"2026-10-16 12:00", "2026-10-17 02:00"},
+		{"2026-10-17 02:00", "2026-10-24 02:00"},
+		{"2026-10-28 10:00", "2026-10-31 02:00"},
+		{"2026-10-31 07:00", "2026-11-01 23:30"},
+	}
+	for _, c := range cases {
+		if got := ws.Next(date(c.from)); !got.Equal(date(c.next)) {
+			t.Errorf("Next(%s) = %s, want %s", c.from, got, c.next)
+		}
+	}
+	// the day of week and the day of month both restricted match either.
+	ws, _ = Parse("0 0 13 * FRI 1h")
+	if got := ws.Next(date("2026-10-17 12:00")); !got.Equal(date("2026-10-23 00:00")) {
+		t.Errorf("Next = %s, want the next Friday", got)
+	}
+}
+
+func TestSundayRanges(t *testing.T) {
+	// 2026-10-17 is a Saturday, 2026-10-18 a Sunday and 2026-10-19 a Monday.
+	for _, s := range []string{"0 2 * * MON-SUN 1h", "0 2 * * 1-7 1h", "0 2 * * SAT-SUN 1h", "0 2 * * 6,7 1h"} {
+		ws, err := Parse(s)
+		if err != nil {
+			t.Errorf("Parse(%q) error %s", s, err)
+			continue
+		}
+		for _, at := range []string{"2026-10-17 02:30", "2026-10-18 02:30"} {
+			if !ws.Open(date(at)) {
+				t.Errorf("%q isn't open at %s", s, at)
+			}
+		}
+	}
+	ws, _ := Parse("0 2 * * SAT-SUN 1h")
+	if ws.Open(date("2026-10-19 02:30")) {
+		t.Error("SAT-SUN is open on Monday")
+	}
+	if _, err := Parse("0 2 * * SUN-MON 1h"); err != nil {
+		t.Errorf("SUN-MON error %s", err)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netrisapi
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	api "github.com/netrisai/netriswebapi/v2"
+)
+
+var (
+	// failureThreshold is how many transient failures in a row open the breaker.
+	failureThreshold = 5
+	// openTimeout is how long the breaker stays open before a probe call is let through.
+	openTimeout = 30 * time.Second
+)
+
+// ErrUnavailable is returned without calling Netris while the breaker is open.
+var ErrUnavailable = errors.New("Netris controller is unavailable, the circuit breaker is open")
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// Breaker is the circuit breaker of a Netris controller.
+type Breaker struct {
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+var breakers sync.Map
+
+// For returns the breaker of the Netris controller the clientset talks to.
+func For(cred *api.Clientset) *Breaker {
+	if b, ok := breakers.Load(cred); ok {
+		return b.(*Breaker)
+	}
+	b, _ := breakers.LoadOrStore(cred, &Breaker{})
+	return b.(*Breaker)
+}
+
+// Forget drops the breaker of a clientset which is no longer used.
+func Forget(cred *api.Clientset) {
+	breakers.Delete(cred)
+}
+
+// Unavailable reports whether the breaker is open or the latest call failed
+// transiently.
+func (b *Breaker) Unavailable() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state != stateClosed || b.failures > 0
+}
+
+// Open reports whether the calls are being refused.
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state != stateClosed
+}
+
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case stateOpen:
+		if time.Since(b.openedAt) < openTimeout {
+			return ErrUnavailable
+		}
+		b.state = stateHalfOpen
+		b.probing = true
+		return nil
+	case stateHalfOpen:
+		// a single probe call decides whether the breaker closes.
+		if b.probing {
+			return ErrUnavailable
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record counts the outcome of a call. Only transient failures count, a
+// refused request still proves Netris is up.
+func (b *Breaker) record(class Class) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if class != ClassTransient {
+		b.failures = 0
+		b.state = stateClosed
+		return
+	}
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= failureThreshold {
+		b.state = stateOpen
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netrisapi
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	api "github.com/netrisai/netriswebapi/v2"
+)
+
+// expire moves the opening of the breaker past the open timeout.
+func (b *Breaker) expire() {
+	b.mu.Lock()
+	b.openedAt = time.Now().Add(-openTimeout - time.Second)
+	b.mu.Unlock()
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	b := &Breaker{}
+	for i := 0; i < failureThreshold-1; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("closed breaker refused call %d: %v", i, err)
+		}
+		b.record(ClassTransient)
+	}
+	if b.Open() || !b.Unavailable() {
+		t.Fatalf("breaker opened before %d failures", failureThreshold)
+	}
+
+	// closed -> open
+	b.record(ClassTransient)
+	if !b.Open() {
+		t.Fatalf("breaker is closed after %d failures", failureThreshold)
+	}
+	if err := b.allow(); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("open breaker allowed a call: %v", err)
+	}
+
+	// open -> half-open, a single probe goes through.
+	b.expire()
+	if err := b.allow(); err != nil {
+		t.Fatalf("expired breaker refused the probe: %v", err)
+	}
+	if b.state != stateHalfOpen {
+		t.Fatalf("state %d after the open timeout, want half-open", b.state)
+	}
+	if err := b.allow(); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("half-open breaker allowed a second call during the probe: %v", err)
+	}
+
+	// a failed probe opens it again.
+	b.record(ClassTransient)
+	if b.state != stateOpen {
+		t.Fatalf("state %d after a failed probe, want open", b.state)
+	}
+
+	// half-open -> closed on a successful probe.
+	b.expire()
+	if err := b.allow(); err != nil {
+		t.Fatalf("expired breaker refused the probe: %v", err)
+	}
+	b.record(ClassOK)
+	if b.Open() || b.Unavailable() {
+		t.Fatal("breaker isn't closed after a successful probe")
+	}
+	if err := b.allow(); err != nil {
+		t.Fatalf("closed breaker refused a call: %v", err)
+	}
+}
+
+func TestBreakerRefusedRequestCloses(t *testing.T) {
+	b := &Breaker{}
+	for i := 0; i < failureThreshold-1; i++ {
+		b.record(ClassTransient)
+	}
+	// a refused request still proves Netris is up.
+	b.record(ClassValidation)
+	b.record(ClassTransient)
+	if b.Open() {
+		t.Fatal("breaker opened although the failures weren't in a row")
+	}
+}
+
+func TestBreakerPerClientset(t *testing.T) {
+	a, b := &api.Clientset{}, &api.Clientset{}
+	defer Forget(a)
+	defer Forget(b)
+	if For(a) != For(a) {
+		t.Fatal("For returned different breakers for the same clientset")
+	}
+	if For(a) == For(b) {
+		t.Fatal("For returned the same breaker for different clientsets")
+	}
+	for i := 0; i < failureThreshold; i++ {
+		For(a).record(ClassTransient)
+	}
+	if !For(a).Open() || For(b).Open() {
+		t.Fatal("the failures of one controller opened the breaker of another")
+	}
+}
+
+func TestCallWhileOpen(t *testing.T) {
+	cred := &api.Clientset{}
+	defer Forget(cred)
+	for i := 0; i < failureThreshold; i++ {
+		For(cred).record(ClassTransient)
+	}
+	called := false
+	_, err := Get(cred, "vnet", "list", func() ([]int, error) {
+		called = true
+		return nil, nil
+	})
+	if !errors.Is(err, ErrUnavailable) || !IsUnavailable(err) || called {
+		t.Fatalf("call through an open breaker: err %v, called %v", err, called)
+	}
+}
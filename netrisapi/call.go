@@ -0,0 +1,240 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package netrisapi guards the calls to the Netris API: errors are
+// classified, transient ones are retried with a jittered exponential backoff,
+// and a circuit breaker per Netris controller stops the calls after repeated
+// failures, so a down controller isn't hammered by every reconciler at once.
+package netrisapi
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/netrisai/netriswebapi/http"
+	api "github.com/netrisai/netriswebapi/v2"
+)
+
+var (
+	// maxRetries is how many times a transient failure is retried.
+	maxRetries   = 3
+	retryBaseGap = 250 * time.Millisecond
+	retryMaxGap  = 2 * time.Second
+
+	// dryRun is set in the operator-wide dry-run mode.
+	dryRun int32
+
+	// statusCodeRe finds the status code in the reply envelope of a failed read.
+	statusCodeRe = regexp.MustCompile(`"statusCode":\s*(\d+)`)
+)
+
+// Class of the outcome of a Netris API call.
+type Class int
+
+// Classes of the outcome of a Netris API call.
+const (
+	ClassOK Class = iota
+	// ClassTransient is a network error, a timeout or a 5xx response. It's retried.
+	ClassTransient
+	// ClassAuth is a rejected session. It's retried once after a new login.
+	ClassAuth
+	// ClassValidation is a request Netris refused. It's never retried.
+	ClassValidation
+)
+
+func (c Class) String() string {
+	switch c {
+	case ClassOK:
+		return "ok"
+	case ClassTransient:
+		return "transient"
+	case ClassAuth:
+		return "auth"
+	case ClassValidation:
+		return "validation"
+	}
+	return "unknown"
+}
+
+// Error is a failed Netris API call.
+type Error struct {
+	Class Class
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap .
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrDryRun is returned by the create, update and delete calls in the
+// operator-wide dry-run mode, without calling Netris.
+var ErrDryRun = errors.New("dry-run mode, the change isn't sent to Netris")
+
+// SetDryRun turns the operator-wide dry-run mode on or off. It's a safety net
+// for the reconcilers, which plan the changes without calling Create or Do.
+func SetDryRun(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&dryRun, v)
+}
+
+// IsUnavailable reports whether err means Netris couldn't be reached, as
+// opposed to Netris refusing the request.
+func IsUnavailable(err error) bool {
+	if errors.Is(err, ErrUnavailable) {
+		return true
+	}
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Class == ClassTransient
+}
+
+// Get runs a read call of the resource type with the verb (get, list).
+// Reads are retried on every transient failure.
+func Get[T any](cred *api.Clientset, resource, verb string, fn func() (T, error)) (T, error) {
+	return call(cred, resource, verb, false, fn)
+}
+
+// Do runs an update or a delete call of the resource type, verb is update or
+// delete. They are idempotent, so they are retried on every transient failure
+// like the reads.
+func Do(cred *api.Clientset, resource, verb string, fn func() (http.HTTPReply, error)) (http.HTTPReply, error) {
+	if atomic.LoadInt32(&dryRun) == 1 {
+		return http.HTTPReply{}, ErrDryRun
+	}
+	return call(cred, resource, verb, false, fn)
+}
+
+// Create runs a create call. A create is retried only if the request surely
+// didn't reach Netris, otherwise a retry could create a duplicate.
+func Create(cred *api.Clientset, resource string, fn func() (http.HTTPReply, error)) (http.HTTPReply, error) {
+	if atomic.LoadInt32(&dryRun) == 1 {
+		return http.HTTPReply{}, ErrDryRun
+	}
+	return call(cred, resource, "create", true, fn)
+}
+
+func call[T any](cred *api.Clientset, resource, verb string, create bool, fn func() (T, error)) (T, error) {
+	started := time.Now()
+	breaker := For(cred)
+	var (
+		result T
+		err    error
+		class  Class
+	)
+	for attempt := 0; ; attempt++ {
+		if err := breaker.allow(); err != nil {
+			observe(resource, verb, started, "unavailable")
+			return result, err
+		}
+		result, err = fn()
+		class = classify(result, err)
+		breaker.record(class)
+
+		if class == ClassAuth && attempt == 0 {
+			if loginErr := cred.Client.LoginUser(); loginErr == nil {
+				continue
+			}
+		}
+		if class != ClassTransient || attempt >= maxRetries {
+			break
+		}
+		if create && !notDelivered(result, err) {
+			break
+		}
+		time.Sleep(backoff(attempt))
+	}
+	observe(resource, verb, started, class.String())
+	if err != nil && class != ClassOK {
+		err = &Error{Class: class, Err: err}
+	}
+	return result, err
+}
+
+// backoff returns the jittered delay before the retry after the given attempt.
+func backoff(attempt int) time.Duration {
+	gap := retryBaseGap << uint(attempt)
+	if gap > retryMaxGap || gap <= 0 {
+		gap = retryMaxGap
+	}
+	return gap/2 + time.Duration(rand.Int63n(int64(gap/2)+1))
+}
+
+func classify(result interface{}, err error) Class {
+	if err != nil {
+		return classifyError(err)
+	}
+	if reply, ok := result.(http.HTTPReply); ok {
+		return ClassifyStatus(reply.StatusCode)
+	}
+	return ClassOK
+}
+
+// ClassifyStatus classifies an HTTP status code of a Netris API response.
+func ClassifyStatus(code int) Class {
+	switch {
+	case code == 401 || code == 403:
+		return ClassAuth
+	case code == 408 || code == 429 || code >= 500:
+		return ClassTransient
+	case code >= 400:
+		return ClassValidation
+	}
+	return ClassOK
+}
+
+func classifyError(err error) Class {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ClassTransient
+	}
+	msg := err.Error()
+	if m := statusCodeRe.FindStringSubmatch(msg); m != nil && strings.Contains(msg, "{http.Get}") {
+		code, _ := strconv.Atoi(m[1])
+		return ClassifyStatus(code)
+	}
+	// netriswebapi flattens the net/http errors into strings tagged with the failed request function.
+	if strings.Contains(msg, "{http.get}") || strings.Contains(msg, "{CustomBodyRequest}") {
+		return ClassTransient
+	}
+	lower := strings.ToLower(msg)
+	if strings.Contains(lower, "unauthorized") || strings.Contains(lower, "not authorized") {
+		return ClassAuth
+	}
+	return ClassValidation
+}
+
+// notDelivered reports whether the failed request surely wasn't processed by Netris.
+func notDelivered(result interface{}, err error) bool {
+	if err != nil {
+		msg := err.Error()
+		return strings.Contains(msg, "connection refused") || strings.Contains(msg, "no such host") || strings.Contains(msg, "dial tcp")
+	}
+	reply, ok := result.(http.HTTPReply)
+	return ok && reply.StatusCode == 503
+}
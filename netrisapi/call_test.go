@@ -0,0 +1,163 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netrisapi
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/netrisai/netriswebapi/http"
+	api "github.com/netrisai/netriswebapi/v2"
+)
+
+func TestClassifyStatus(t *testing.T) {
+	for _, tt := range []struct {
+		code int
+		want Class
+	}{
+		{200, ClassOK},
+		{204, ClassOK},
+		{400, ClassValidation},
+		{404, ClassValidation},
+		{409, ClassValidation},
+		{401, ClassAuth},
+		{403, ClassAuth},
+		{408, ClassTransient},
+		{429, ClassTransient},
+		{500, ClassTransient},
+		{503, ClassTransient},
+	} {
+		if got := ClassifyStatus(tt.code); got != tt.want {
+			t.Errorf("ClassifyStatus(%d) = %s, want %s", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		err  error
+		want Class
+	}{
+		{"net error", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, ClassTransient},
+		{"flattened get", errors.New(`{http.get} Get "https://netris/api": dial tcp: i/o timeout`), ClassTransient},
+		{"flattened body request", errors.New(`{CustomBodyRequest} Post "https://netris/api": EOF`), ClassTransient},
+		{"read reply 500", errors.New(`{http.Get} {"isSuccess":false,"statusCode": 500}`), ClassTransient},
+		{"read reply 401", errors.New(`{http.Get} {"isSuccess":false,"statusCode": 401}`), ClassAuth},
+		{"read reply 404", errors.New(`{http.Get} {"isSuccess":false,"statusCode": 404}`), ClassValidation},
+		{"unauthorized", errors.New("User is Not Authorized"), ClassAuth},
+		{"refused", errors.New("VNet name already exists"), ClassValidation},
+	} {
+		if got := classifyError(tt.err); got != tt.want {
+			t.Errorf("%s: classifyError() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNotDelivered(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		result interface{}
+		err    error
+		want   bool
+	}{
+		{"connection refused", nil, errors.New("dial tcp 10.0.0.1:443: connect: connection refused"), true},
+		{"no such host", nil, errors.New("dial tcp: lookup netris: no such host"), true},
+		{"timeout", nil, errors.New("net/http: request canceled (Client.Timeout exceeded)"), false},
+		{"503", http.HTTPReply{StatusCode: 503}, nil, true},
+		{"502", http.HTTPReply{StatusCode: 502}, nil, false},
+		{"not a reply", []int{}, nil, false},
+	} {
+		if got := notDelivered(tt.result, tt.err); got != tt.want {
+			t.Errorf("%s: notDelivered() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 0; attempt < 10; attempt++ {
+		gap := retryBaseGap << uint(attempt)
+		if gap > retryMaxGap {
+			gap = retryMaxGap
+		}
+		if got := backoff(attempt); got < gap/2 || got > gap {
+			t.Errorf("backoff(%d) = %s, want within [%s, %s]", attempt, got, gap/2, gap)
+		}
+	}
+}
+
+func fastRetries(t *testing.T) {
+	t.Helper()
+	base, max := retryBaseGap, retryMaxGap
+	retryBaseGap, retryMaxGap = time.Millisecond, time.Millisecond
+	t.Cleanup(func() { retryBaseGap, retryMaxGap = base, max })
+}
+
+func TestRetries(t *testing.T) {
+	fastRetries(t)
+	timeout := errors.New(`{CustomBodyRequest} Post "https://netris/api": context deadline exceeded`)
+	refused := errors.New(`{CustomBodyRequest} Post "https://netris/api": dial tcp 10.0.0.1:443: connect: connection refused`)
+
+	for _, tt := range []struct {
+		name      string
+		create    bool
+		errs      []error
+		wantCalls int
+		wantClass Class
+	}{
+		{"transient then ok", false, []error{timeout, nil}, 2, ClassOK},
+		{"transient until the limit", false, []error{timeout, timeout, timeout, timeout, timeout}, maxRetries + 1, ClassTransient},
+		{"validation isn't retried", false, []error{errors.New("invalid vlan"), nil}, 1, ClassValidation},
+		{"create delivered isn't retried", true, []error{timeout, nil}, 1, ClassTransient},
+		{"create not delivered is retried", true, []error{refused, nil}, 2, ClassOK},
+	} {
+		cred := &api.Clientset{}
+		calls := 0
+		_, err := call(cred, "vnet", "test", tt.create, func() (http.HTTPReply, error) {
+			err := tt.errs[calls]
+			calls++
+			return http.HTTPReply{}, err
+		})
+		Forget(cred)
+
+		if calls != tt.wantCalls {
+			t.Errorf("%s: %d calls, want %d", tt.name, calls, tt.wantCalls)
+		}
+		var apiErr *Error
+		switch {
+		case tt.wantClass == ClassOK && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.wantClass != ClassOK && (!errors.As(err, &apiErr) || apiErr.Class != tt.wantClass):
+			t.Errorf("%s: error %v, want class %s", tt.name, err, tt.wantClass)
+		}
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	SetDryRun(true)
+	defer SetDryRun(false)
+	called := false
+	_, err := Create(&api.Clientset{}, "vnet", func() (http.HTTPReply, error) {
+		called = true
+		return http.HTTPReply{}, nil
+	})
+	if !errors.Is(err, ErrDryRun) || called {
+		t.Fatalf("Create in the dry-run mode: err %v, called %v", err, called)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netrisapi
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	requests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "netris_api_requests_total",
+			Help: "Number of Netris API calls by resource type and verb. A call counts once however many times it was retried.",
+		},
+		[]string{"resource", "verb"},
+	)
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "netris_api_request_duration_seconds",
+			Help:    "Duration of the Netris API calls, retries included, by resource type and verb.",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"resource", "verb"},
+	)
+	requestErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "netris_api_errors_total",
+			Help: "Number of failed Netris API calls by resource type, verb and class (transient, auth, validation, unavailable).",
+		},
+		[]string{"resource", "verb", "class"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(requests, requestDuration, requestErrors)
+}
+
+// observe records a finished call, class is the class of its outcome or
+// "unavailable" if the circuit breaker refused it.
+func observe(resource, verb string, started time.Time, class string) {
+	requests.WithLabelValues(resource, verb).Inc()
+	requestDuration.WithLabelValues(resource, verb).Observe(time.Since(started).Seconds())
+	if class != ClassOK.String() {
+		requestErrors.WithLabelValues(resource, verb, class).Inc()
+	}
+}
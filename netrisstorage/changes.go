@@ -0,0 +1,126 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netrisstorage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Delta holds the IDs of the items which were added, changed or removed
+// in a sub-storage by the last refresh.
+type Delta struct {
+	Kind    string
+	Added   []int
+	Changed []int
+	Removed []int
+}
+
+// Empty reports whether the refresh didn't change anything.
+func (d Delta) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// Listener is called with the delta of every refresh that changed something.
+type Listener func(delta Delta)
+
+type trackedItem struct {
+	// Key must be unique inside the sub-storage, ID is what gets published.
+	Key    string
+	ID     int
+	Object interface{}
+}
+
+type trackedHash struct {
+	id   int
+	hash string
+}
+
+// changeTracker remembers the content hash of every item from the previous
+// refresh, so that only the difference is published to the listeners.
+type changeTracker struct {
+	sync.Mutex
+	kind   string
+	hashes map[string]trackedHash
+	notify func(Delta)
+	// generation is incremented on every stored snapshot.
+	generation uint64
+}
+
+func newChangeTracker(kind string) *changeTracker {
+	return &changeTracker{
+		kind:   kind,
+		hashes: make(map[string]trackedHash),
+	}
+}
+
+func (t *changeTracker) setNotify(notify func(Delta)) {
+	t.Lock()
+	defer t.Unlock()
+	t.notify = notify
+}
+
+func (t *changeTracker) getGeneration() uint64 {
+	t.Lock()
+	defer t.Unlock()
+	return t.generation
+}
+
+func (t *changeTracker) track(items []trackedItem) Delta {
+	t.Lock()
+	delta := Delta{Kind: t.kind}
+	hashes := make(map[string]trackedHash, len(items))
+	for _, item := range items {
+		hash := hashObject(item.Object)
+		hashes[item.Key] = trackedHash{id: item.ID, hash: hash}
+		if old, ok := t.hashes[item.Key]; !ok {
+			delta.Added = append(delta.Added, item.ID)
+		} else if old.hash != hash {
+			delta.Changed = append(delta.Changed, item.ID)
+		}
+	}
+	for key, old := range t.hashes {
+		if _, ok := hashes[key]; !ok {
+			delta.Removed = append(delta.Removed, old.id)
+		}
+	}
+	t.hashes = hashes
+	t.generation++
+	notify := t.notify
+	t.Unlock()
+
+	if notify != nil && !delta.Empty() {
+		notify(delta)
+	}
+	return delta
+}
+
+func hashObject(obj interface{}) string {
+	js, err := json.Marshal(obj)
+	if err != nil {
+		js = []byte(fmt.Sprintf("%+v", obj))
+	}
+	sum := sha256.Sum256(js)
+	return hex.EncodeToString(sum[:])
+}
+
+func idKey(id int) string {
+	return fmt.Sprintf("%d", id)
+}
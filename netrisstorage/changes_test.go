@@ -0,0 +1,105 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netrisstorage
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type testItem struct {
+	Name string
+	Vlan int
+}
+
+func tracked(items ...testItem) []trackedItem {
+	list := []trackedItem{}
+	for i, item := range items {
+		list = append(list, trackedItem{Key: item.Name, ID: i + 1, Object: item})
+	}
+	return list
+}
+
+func sorted(ids []int) []int {
+	sort.Ints(ids)
+	return ids
+}
+
+func TestTrackDeltas(t *testing.T) {
+	tracker := newChangeTracker(KindVNets)
+	notified := []Delta{}
+	tracker.setNotify(func(delta Delta) { notified = append(notified, delta) })
+
+	delta := tracker.track(tracked(testItem{"a", 1}, testItem{"b", 2}, testItem{"c", 3}))
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(sorted(delta.Added), want) || len(delta.Changed) > 0 || len(delta.Removed) > 0 {
+		t.Fatalf("first refresh delta %+v, want all added", delta)
+	}
+
+	// a is unchanged, b changes, c is removed, d is added.
+	delta = tracker.track([]trackedItem{
+		{Key: "a", ID: 1, Object: testItem{"a", 1}},
+		{Key: "b", ID: 2, Object: testItem{"b", 20}},
+		{Key: "d", ID: 4, Object: testItem{"d", 4}},
+	})
+	want := Delta{Kind: KindVNets, Added: []int{4}, Changed: []int{2}, Removed: []int{3}}
+	if !reflect.DeepEqual(delta, want) {
+		t.Fatalf("second refresh delta %+v, want %+v", delta, want)
+	}
+
+	// nothing changed, the listener isn't called.
+	delta = tracker.track([]trackedItem{
+		{Key: "a", ID: 1, Object: testItem{"a", 1}},
+		{Key: "b", ID: 2, Object: testItem{"b", 20}},
+		{Key: "d", ID: 4, Object: testItem{"d", 4}},
+	})
+	if !delta.Empty() {
+		t.Fatalf("unchanged refresh delta %+v, want empty", delta)
+	}
+	if len(notified) != 2 {
+		t.Fatalf("listener called %d times, want 2", len(notified))
+	}
+	if !reflect.DeepEqual(notified[1], want) {
+		t.Fatalf("listener got %+v, want %+v", notified[1], want)
+	}
+	if generation := tracker.getGeneration(); generation != 3 {
+		t.Fatalf("generation %d after 3 refreshes", generation)
+	}
+}
+
+func TestTrackEmptiedStorage(t *testing.T) {
+	tracker := newChangeTracker(KindSites)
+	tracker.track(tracked(testItem{"a", 1}, testItem{"b", 2}))
+	delta := tracker.track(nil)
+	if want := []int{1, 2}; !reflect.DeepEqual(sorted(delta.Removed), want) || len(delta.Added) > 0 || len(delta.Changed) > 0 {
+		t.Fatalf("delta %+v, want all removed", delta)
+	}
+}
+
+func TestStorageListeners(t *testing.T) {
+	s := &Storage{}
+	got := []Delta{}
+	s.AddListener(func(delta Delta) { got = append(got, delta) })
+	s.AddListener(func(delta Delta) { got = append(got, delta) })
+
+	tracker := newChangeTracker(KindL4LBs)
+	tracker.setNotify(s.publish)
+	tracker.track(tracked(testItem{"lb", 1}))
+	if len(got) != 2 || got[0].Kind != KindL4LBs || !reflect.DeepEqual(got[0].Added, []int{1}) {
+		t.Fatalf("listeners got %+v, want the added L4LB twice", got)
+	}
+}
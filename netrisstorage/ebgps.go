@@ -17,79 +17,98 @@ limitations under the License.
 package netrisstorage
 
 import (
+	"encoding/json"
 	"sync"
+	"sync/atomic"
 
+	"github.com/netrisai/netris-operator/netrisapi"
+	api "github.com/netrisai/netriswebapi/v2"
 	"github.com/netrisai/netriswebapi/v2/types/bgp"
 )
 
 // BGPStorage .
 type BGPStorage struct {
 	sync.Mutex
-	BGPs []*bgp.EBGP
+	cred     *api.Clientset
+	snapshot atomic.Value
+	changes  *changeTracker
+	misses   *missRefresher
+}
+
+type bgpSnapshot struct {
+	items  []*bgp.EBGP
+	byName map[string]*bgp.EBGP
+	byID   map[int]*bgp.EBGP
+}
+
+func newBgpSnapshot(items []*bgp.EBGP) *bgpSnapshot {
+	s := &bgpSnapshot{
+		items:  items,
+		byName: make(map[string]*bgp.EBGP, len(items)),
+		byID:   make(map[int]*bgp.EBGP, len(items)),
+	}
+	for _, item := range items {
+		if _, ok := s.byName[item.Name]; !ok {
+			s.byName[item.Name] = item
+		}
+		if _, ok := s.byID[item.ID]; !ok {
+			s.byID[item.ID] = item
+		}
+	}
+	return s
 }
 
 // NewBGPStorage .
-func NewBGPStorage() *BGPStorage {
+func NewBGPStorage(cred *api.Clientset) *BGPStorage {
+	changes := newChangeTracker(KindBGPs)
 	return &BGPStorage{
-		BGPs: []*bgp.EBGP{},
+		cred:    cred,
+		changes: changes,
+		misses:  newMissRefresher(KindBGPs, changes),
 	}
 }
 
-func (p *BGPStorage) storeAll(items []*bgp.EBGP) {
-	p.BGPs = items
+func (p *BGPStorage) load() *bgpSnapshot {
+	if s, ok := p.snapshot.Load().(*bgpSnapshot); ok {
+		return s
+	}
+	return newBgpSnapshot(nil)
 }
 
 // GetAll .
 func (p *BGPStorage) GetAll() []*bgp.EBGP {
-	p.Lock()
-	defer p.Unlock()
-	return p.getAll()
-}
-
-func (p *BGPStorage) getAll() []*bgp.EBGP {
-	return p.BGPs
-}
-
-// FindByID .
-func (p *BGPStorage) FindByID(id int) (*bgp.EBGP, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.findByID(id)
-	if !ok {
-		_ = p.download()
-		return p.findByID(id)
-	}
-	return item, ok
+	return p.load().items
 }
 
-func (p *BGPStorage) findByID(id int) (*bgp.EBGP, bool) {
-	for _, item := range p.BGPs {
-		if item.ID == id {
-			return item, true
-		}
+func (p *BGPStorage) storeAll(items []*bgp.EBGP) {
+	p.snapshot.Store(newBgpSnapshot(items))
+	tracked := make([]trackedItem, 0, len(items))
+	for _, item := range items {
+		tracked = append(tracked, trackedItem{Key: idKey(item.ID), ID: item.ID, Object: item})
 	}
-	return nil, false
+	p.changes.track(tracked)
 }
 
 // FindByName .
 func (p *BGPStorage) FindByName(name string) (*bgp.EBGP, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findByName(name)
+	item, ok := p.load().byName[name]
+	return item, ok
 }
 
-func (p *BGPStorage) findByName(name string) (*bgp.EBGP, bool) {
-	for _, item := range p.BGPs {
-		if item.Name == name {
-			return item, true
-		}
-	}
-	return nil, false
+// FindByID .
+func (p *BGPStorage) FindByID(id int) (*bgp.EBGP, bool) {
+	var item *bgp.EBGP
+	ok := p.misses.find(idKey(id), func() bool {
+		var found bool
+		item, found = p.load().byID[id]
+		return found
+	}, p.Download)
+	return item, ok
 }
 
 // Download .
 func (p *BGPStorage) download() error {
-	items, err := Cred.BGP().Get()
+	items, err := netrisapi.Get(p.cred, "bgp", "list", p.cred.BGP().Get)
 	if err != nil {
 		return err
 	}
@@ -103,3 +122,18 @@ func (p *BGPStorage) Download() error {
 	defer p.Unlock()
 	return p.download()
 }
+
+func (p *BGPStorage) dump() interface{} {
+	return p.load().items
+}
+
+func (p *BGPStorage) restore(data []byte) error {
+	items := []*bgp.EBGP{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.storeAll(items)
+	return nil
+}
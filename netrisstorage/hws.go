@@ -17,207 +17,166 @@ limitations under the License.
 package netrisstorage
 
 import (
+	"encoding/json"
 	"sync"
+	"sync/atomic"
 
+	"github.com/netrisai/netris-operator/netrisapi"
+	api "github.com/netrisai/netriswebapi/v2"
 	"github.com/netrisai/netriswebapi/v2/types/inventory"
 )
 
 // HWsStorage .
 type HWsStorage struct {
 	sync.Mutex
-	HWs []*inventory.HW
+	cred     *api.Clientset
+	snapshot atomic.Value
+	changes  *changeTracker
+	misses   *missRefresher
+}
+
+type hwSnapshot struct {
+	items      []*inventory.HW
+	byName     map[string]*inventory.HW
+	byID       map[int]*inventory.HW
+	byTypeName map[string]map[string]*inventory.HW
+	byTypeID   map[string]map[int]*inventory.HW
+	bySite     map[int][]inventory.HW
+}
+
+func newHWSnapshot(items []*inventory.HW) *hwSnapshot {
+	s := &hwSnapshot{
+		items:      items,
+		byName:     make(map[string]*inventory.HW, len(items)),
+		byID:       make(map[int]*inventory.HW, len(items)),
+		byTypeName: make(map[string]map[string]*inventory.HW),
+		byTypeID:   make(map[string]map[int]*inventory.HW),
+		bySite:     make(map[int][]inventory.HW),
+	}
+	for _, hw := range items {
+		if _, ok := s.byName[hw.Name]; !ok {
+			s.byName[hw.Name] = hw
+		}
+		if _, ok := s.byID[hw.ID]; !ok {
+			s.byID[hw.ID] = hw
+		}
+		if _, ok := s.byTypeName[hw.Type]; !ok {
+			s.byTypeName[hw.Type] = make(map[string]*inventory.HW)
+			s.byTypeID[hw.Type] = make(map[int]*inventory.HW)
+		}
+		if _, ok := s.byTypeName[hw.Type][hw.Name]; !ok {
+			s.byTypeName[hw.Type][hw.Name] = hw
+		}
+		if _, ok := s.byTypeID[hw.Type][hw.ID]; !ok {
+			s.byTypeID[hw.Type][hw.ID] = hw
+		}
+		s.bySite[hw.Site.ID] = append(s.bySite[hw.Site.ID], *hw)
+	}
+	return s
 }
 
 // NewHWsStorage .
-func NewHWsStorage() *HWsStorage {
-	return &HWsStorage{}
+func NewHWsStorage(cred *api.Clientset) *HWsStorage {
+	changes := newChangeTracker(KindHWs)
+	return &HWsStorage{
+		cred:    cred,
+		changes: changes,
+		misses:  newMissRefresher(KindHWs, changes),
+	}
 }
 
-// GetAll .
-func (p *HWsStorage) GetAll() []*inventory.HW {
-	p.Lock()
-	defer p.Unlock()
-	return p.getAll()
+func (p *HWsStorage) load() *hwSnapshot {
+	if s, ok := p.snapshot.Load().(*hwSnapshot); ok {
+		return s
+	}
+	return newHWSnapshot(nil)
 }
 
-func (p *HWsStorage) getAll() []*inventory.HW {
-	return p.HWs
+// GetAll .
+func (p *HWsStorage) GetAll() []*inventory.HW {
+	return p.load().items
 }
 
 func (p *HWsStorage) storeAll(items []*inventory.HW) {
-	p.HWs = items
+	p.snapshot.Store(newHWSnapshot(items))
+	tracked := make([]trackedItem, 0, len(items))
+	for _, item := range items {
+		tracked = append(tracked, trackedItem{Key: idKey(item.ID), ID: item.ID, Object: item})
+	}
+	p.changes.track(tracked)
 }
 
 // FindByName .
 func (p *HWsStorage) FindByName(name string) (*inventory.HW, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findByName(name)
+	hw, ok := p.load().byName[name]
+	return hw, ok
 }
 
-func (p *HWsStorage) findByName(name string) (*inventory.HW, bool) {
-	for _, hw := range p.HWs {
-		if hw.Name == name {
-			return hw, true
-		}
-	}
-	return nil, false
+func (p *HWsStorage) findByTypeName(typo, name string) (*inventory.HW, bool) {
+	hw, ok := p.load().byTypeName[typo][name]
+	return hw, ok
 }
 
 // FindSoftgateByName .
 func (p *HWsStorage) FindSoftgateByName(name string) (*inventory.HW, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findSoftgateByName(name)
-}
-
-func (p *HWsStorage) findSoftgateByName(name string) (*inventory.HW, bool) {
-	for _, hw := range p.HWs {
-		if hw.Name == name && hw.Type == "softgate" {
-			return hw, true
-		}
-	}
-	return nil, false
+	return p.findByTypeName("softgate", name)
 }
 
 // FindSwitchByName .
 func (p *HWsStorage) FindSwitchByName(name string) (*inventory.HW, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findSwitchByName(name)
-}
-
-func (p *HWsStorage) findSwitchByName(name string) (*inventory.HW, bool) {
-	for _, hw := range p.HWs {
-		if hw.Name == name && hw.Type == "switch" {
-			return hw, true
-		}
-	}
-	return nil, false
+	return p.findByTypeName("switch", name)
 }
 
 // FindControllerByName .
 func (p *HWsStorage) FindControllerByName(name string) (*inventory.HW, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findControllerByName(name)
-}
-
-func (p *HWsStorage) findControllerByName(name string) (*inventory.HW, bool) {
-	for _, hw := range p.HWs {
-		if hw.Name == name && hw.Type == "controller" {
-			return hw, true
-		}
-	}
-	return nil, false
+	return p.findByTypeName("controller", name)
 }
 
 // FindByID .
 func (p *HWsStorage) FindByID(id int) (*inventory.HW, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.findByID(id)
-	if !ok {
-		_ = p.download()
-		return p.findByID(id)
-	}
-	return item, ok
+	var hw *inventory.HW
+	ok := p.misses.find(idKey(id), func() bool {
+		var found bool
+		hw, found = p.load().byID[id]
+		return found
+	}, p.Download)
+	return hw, ok
 }
 
-func (p *HWsStorage) findByID(id int) (*inventory.HW, bool) {
-	for _, hw := range p.HWs {
-		if hw.ID == id {
-			return hw, true
-		}
-	}
-	return nil, false
+func (p *HWsStorage) findByTypeID(typo string, id int) (*inventory.HW, bool) {
+	var hw *inventory.HW
+	ok := p.misses.find(typo+"/"+idKey(id), func() bool {
+		var found bool
+		hw, found = p.load().byTypeID[typo][id]
+		return found
+	}, p.Download)
+	return hw, ok
 }
 
 // FindSoftgateByID .
 func (p *HWsStorage) FindSoftgateByID(id int) (*inventory.HW, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.findSoftgateByID(id)
-	if !ok {
-		_ = p.download()
-		return p.findSoftgateByID(id)
-	}
-	return item, ok
-}
-
-func (p *HWsStorage) findSoftgateByID(id int) (*inventory.HW, bool) {
-	for _, hw := range p.HWs {
-		if hw.ID == id && hw.Type == "softgate" {
-			return hw, true
-		}
-	}
-	return nil, false
+	return p.findByTypeID("softgate", id)
 }
 
 // FindControllerByID .
 func (p *HWsStorage) FindControllerByID(id int) (*inventory.HW, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.findControllerByID(id)
-	if !ok {
-		_ = p.download()
-		return p.findControllerByID(id)
-	}
-	return item, ok
-}
-
-func (p *HWsStorage) findControllerByID(id int) (*inventory.HW, bool) {
-	for _, hw := range p.HWs {
-		if hw.ID == id && hw.Type == "controller" {
-			return hw, true
-		}
-	}
-	return nil, false
+	return p.findByTypeID("controller", id)
 }
 
 // FindSwitchByID .
 func (p *HWsStorage) FindSwitchByID(id int) (*inventory.HW, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.findSwitchByID(id)
-	if !ok {
-		_ = p.download()
-		return p.findSwitchByID(id)
-	}
-	return item, ok
-}
-
-func (p *HWsStorage) findSwitchByID(id int) (*inventory.HW, bool) {
-	for _, hw := range p.HWs {
-		if hw.ID == id && hw.Type == "switch" {
-			return hw, true
-		}
-	}
-	return nil, false
+	return p.findByTypeID("switch", id)
 }
 
 // FindHWsBySite .
 func (p *HWsStorage) FindHWsBySite(siteID int) []inventory.HW {
-	p.Lock()
-	defer p.Unlock()
-	return p.findHWsBySite(siteID)
-}
-
-func (p *HWsStorage) findHWsBySite(siteID int) []inventory.HW {
-	hws := []inventory.HW{}
-	for _, hw := range p.HWs {
-		if hw.Site.ID == siteID {
-			hws = append(hws, *hw)
-		}
-	}
-	return hws
+	hws := p.load().bySite[siteID]
+	return append([]inventory.HW{}, hws...)
 }
 
 // FindSpineBySite .
 func (p *HWsStorage) FindSpineBySite(siteID int) *inventory.HW {
-	p.Lock()
-	defer p.Unlock()
-	for _, hw := range p.findHWsBySite(siteID) {
+	for _, hw := range p.load().bySite[siteID] {
 		if hw.Type == "spine" {
 			sw := hw
 			return &sw
@@ -228,7 +187,7 @@ func (p *HWsStorage) FindSpineBySite(siteID int) *inventory.HW {
 
 // Download .
 func (p *HWsStorage) download() error {
-	items, err := Cred.Inventory().Get()
+	items, err := netrisapi.Get(p.cred, "inventory", "list", p.cred.Inventory().Get)
 	if err != nil {
 		return err
 	}
@@ -242,3 +201,18 @@ func (p *HWsStorage) Download() error {
 	defer p.Unlock()
 	return p.download()
 }
+
+func (p *HWsStorage) dump() interface{} {
+	return p.load().items
+}
+
+func (p *HWsStorage) restore(data []byte) error {
+	items := []*inventory.HW{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.storeAll(items)
+	return nil
+}
@@ -17,77 +17,98 @@ limitations under the License.
 package netrisstorage
 
 import (
+	"encoding/json"
 	"sync"
+	"sync/atomic"
 
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netriswebapi/v1/types/inventoryprofile"
+	api "github.com/netrisai/netriswebapi/v2"
 )
 
 // InventoryProfileStorage .
 type InventoryProfileStorage struct {
 	sync.Mutex
-	InventoryProfile []*inventoryprofile.Profile
+	cred     *api.Clientset
+	snapshot atomic.Value
+	changes  *changeTracker
+	misses   *missRefresher
+}
+
+type inventoryProfileSnapshot struct {
+	items  []*inventoryprofile.Profile
+	byName map[string]*inventoryprofile.Profile
+	byID   map[int]*inventoryprofile.Profile
+}
+
+func newInventoryProfileSnapshot(items []*inventoryprofile.Profile) *inventoryProfileSnapshot {
+	s := &inventoryProfileSnapshot{
+		items:  items,
+		byName: make(map[string]*inventoryprofile.Profile, len(items)),
+		byID:   make(map[int]*inventoryprofile.Profile, len(items)),
+	}
+	for _, item := range items {
+		if _, ok := s.byName[item.Name]; !ok {
+			s.byName[item.Name] = item
+		}
+		if _, ok := s.byID[item.ID]; !ok {
+			s.byID[item.ID] = item
+		}
+	}
+	return s
 }
 
 // NewInventoryProfileStorage .
-func NewInventoryProfileStorage() *InventoryProfileStorage {
-	return &InventoryProfileStorage{}
+func NewInventoryProfileStorage(cred *api.Clientset) *InventoryProfileStorage {
+	changes := newChangeTracker(KindInventoryProfiles)
+	return &InventoryProfileStorage{
+		cred:    cred,
+		changes: changes,
+		misses:  newMissRefresher(KindInventoryProfiles, changes),
+	}
 }
 
-// GetAll .
-func (p *InventoryProfileStorage) GetAll() []*inventoryprofile.Profile {
-	p.Lock()
-	defer p.Unlock()
-	return p.getAll()
+func (p *InventoryProfileStorage) load() *inventoryProfileSnapshot {
+	if s, ok := p.snapshot.Load().(*inventoryProfileSnapshot); ok {
+		return s
+	}
+	return newInventoryProfileSnapshot(nil)
 }
 
-func (p *InventoryProfileStorage) getAll() []*inventoryprofile.Profile {
-	return p.InventoryProfile
+// GetAll .
+func (p *InventoryProfileStorage) GetAll() []*inventoryprofile.Profile {
+	return p.load().items
 }
 
 func (p *InventoryProfileStorage) storeAll(items []*inventoryprofile.Profile) {
-	p.InventoryProfile = items
+	p.snapshot.Store(newInventoryProfileSnapshot(items))
+	tracked := make([]trackedItem, 0, len(items))
+	for _, item := range items {
+		tracked = append(tracked, trackedItem{Key: idKey(item.ID), ID: item.ID, Object: item})
+	}
+	p.changes.track(tracked)
 }
 
 // FindByName .
 func (p *InventoryProfileStorage) FindByName(name string) (*inventoryprofile.Profile, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findByName(name)
-}
-
-func (p *InventoryProfileStorage) findByName(name string) (*inventoryprofile.Profile, bool) {
-	for _, inventoryProfile := range p.InventoryProfile {
-		if inventoryProfile.Name == name {
-			return inventoryProfile, true
-		}
-	}
-	return nil, false
+	item, ok := p.load().byName[name]
+	return item, ok
 }
 
 // FindByID .
 func (p *InventoryProfileStorage) FindByID(id int) (*inventoryprofile.Profile, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.findByID(id)
-	if !ok {
-		_ = p.download()
-		return p.findByID(id)
-	}
+	var item *inventoryprofile.Profile
+	ok := p.misses.find(idKey(id), func() bool {
+		var found bool
+		item, found = p.load().byID[id]
+		return found
+	}, p.Download)
 	return item, ok
 }
 
-func (p *InventoryProfileStorage) findByID(id int) (*inventoryprofile.Profile, bool) {
-	for _, inventoryProfile := range p.InventoryProfile {
-		if inventoryProfile.ID == id {
-			return inventoryProfile, true
-		}
-	}
-	return nil, false
-}
-
 // Download .
 func (p *InventoryProfileStorage) download() error {
-	items, err := Cred.InventoryProfile().Get()
+	items, err := netrisapi.Get(p.cred, "inventoryprofile", "list", p.cred.InventoryProfile().Get)
 	if err != nil {
 		return err
 	}
@@ -101,3 +122,18 @@ func (p *InventoryProfileStorage) Download() error {
 	defer p.Unlock()
 	return p.download()
 }
+
+func (p *InventoryProfileStorage) dump() interface{} {
+	return p.load().items
+}
+
+func (p *InventoryProfileStorage) restore(data []byte) error {
+	items := []*inventoryprofile.Profile{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.storeAll(items)
+	return nil
+}
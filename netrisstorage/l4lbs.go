@@ -17,77 +17,98 @@ limitations under the License.
 package netrisstorage
 
 import (
+	"encoding/json"
 	"sync"
+	"sync/atomic"
 
+	"github.com/netrisai/netris-operator/netrisapi"
+	api "github.com/netrisai/netriswebapi/v2"
 	"github.com/netrisai/netriswebapi/v2/types/l4lb"
 )
 
 // L4LBStorage .
 type L4LBStorage struct {
 	sync.Mutex
-	L4LBs []*l4lb.LoadBalancer
+	cred     *api.Clientset
+	snapshot atomic.Value
+	changes  *changeTracker
+	misses   *missRefresher
+}
+
+type l4lbSnapshot struct {
+	items  []*l4lb.LoadBalancer
+	byName map[string]*l4lb.LoadBalancer
+	byID   map[int]*l4lb.LoadBalancer
+}
+
+func newL4lbSnapshot(items []*l4lb.LoadBalancer) *l4lbSnapshot {
+	s := &l4lbSnapshot{
+		items:  items,
+		byName: make(map[string]*l4lb.LoadBalancer, len(items)),
+		byID:   make(map[int]*l4lb.LoadBalancer, len(items)),
+	}
+	for _, item := range items {
+		if _, ok := s.byName[item.Name]; !ok {
+			s.byName[item.Name] = item
+		}
+		if _, ok := s.byID[item.ID]; !ok {
+			s.byID[item.ID] = item
+		}
+	}
+	return s
 }
 
 // NewL4LBStorage .
-func NewL4LBStorage() *L4LBStorage {
-	return &L4LBStorage{}
+func NewL4LBStorage(cred *api.Clientset) *L4LBStorage {
+	changes := newChangeTracker(KindL4LBs)
+	return &L4LBStorage{
+		cred:    cred,
+		changes: changes,
+		misses:  newMissRefresher(KindL4LBs, changes),
+	}
 }
 
-// GetAll .
-func (p *L4LBStorage) GetAll() []*l4lb.LoadBalancer {
-	p.Lock()
-	defer p.Unlock()
-	return p.getAll()
+func (p *L4LBStorage) load() *l4lbSnapshot {
+	if s, ok := p.snapshot.Load().(*l4lbSnapshot); ok {
+		return s
+	}
+	return newL4lbSnapshot(nil)
 }
 
-func (p *L4LBStorage) getAll() []*l4lb.LoadBalancer {
-	return p.L4LBs
+// GetAll .
+func (p *L4LBStorage) GetAll() []*l4lb.LoadBalancer {
+	return p.load().items
 }
 
 func (p *L4LBStorage) storeAll(items []*l4lb.LoadBalancer) {
-	p.L4LBs = items
+	p.snapshot.Store(newL4lbSnapshot(items))
+	tracked := make([]trackedItem, 0, len(items))
+	for _, item := range items {
+		tracked = append(tracked, trackedItem{Key: idKey(item.ID), ID: item.ID, Object: item})
+	}
+	p.changes.track(tracked)
 }
 
 // FindByName .
 func (p *L4LBStorage) FindByName(name string) (*l4lb.LoadBalancer, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findByName(name)
-}
-
-func (p *L4LBStorage) findByName(name string) (*l4lb.LoadBalancer, bool) {
-	for _, item := range p.L4LBs {
-		if item.Name == name {
-			return item, true
-		}
-	}
-	return nil, false
+	item, ok := p.load().byName[name]
+	return item, ok
 }
 
 // FindByID .
 func (p *L4LBStorage) FindByID(id int) (*l4lb.LoadBalancer, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.findByID(id)
-	if !ok {
-		_ = p.download()
-		return p.findByID(id)
-	}
+	var item *l4lb.LoadBalancer
+	ok := p.misses.find(idKey(id), func() bool {
+		var found bool
+		item, found = p.load().byID[id]
+		return found
+	}, p.Download)
 	return item, ok
 }
 
-func (p *L4LBStorage) findByID(id int) (*l4lb.LoadBalancer, bool) {
-	for _, item := range p.L4LBs {
-		if item.ID == id {
-			return item, true
-		}
-	}
-	return nil, false
-}
-
 // Download .
 func (p *L4LBStorage) download() error {
-	items, err := Cred.L4LB().Get()
+	items, err := netrisapi.Get(p.cred, "l4lb", "list", p.cred.L4LB().Get)
 	if err != nil {
 		return err
 	}
@@ -101,3 +122,18 @@ func (p *L4LBStorage) Download() error {
 	defer p.Unlock()
 	return p.download()
 }
+
+func (p *L4LBStorage) dump() interface{} {
+	return p.load().items
+}
+
+func (p *L4LBStorage) restore(data []byte) error {
+	items := []*l4lb.LoadBalancer{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.storeAll(items)
+	return nil
+}
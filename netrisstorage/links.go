@@ -17,61 +17,95 @@ limitations under the License.
 package netrisstorage
 
 import (
+	"encoding/json"
+	"fmt"
 	"sync"
+	"sync/atomic"
 
+	"github.com/netrisai/netris-operator/netrisapi"
+	api "github.com/netrisai/netriswebapi/v2"
 	"github.com/netrisai/netriswebapi/v2/types/link"
 )
 
 // LinksStorage .
 type LinksStorage struct {
 	sync.Mutex
-	Links []*link.Link
+	cred     *api.Clientset
+	snapshot atomic.Value
+	changes  *changeTracker
+	misses   *missRefresher
+}
+
+type linkSnapshot struct {
+	items []*link.Link
+	// byPorts is keyed by both "local-remote" and "remote-local" port IDs.
+	byPorts map[string]*link.Link
+}
+
+func newLinkSnapshot(items []*link.Link) *linkSnapshot {
+	s := &linkSnapshot{
+		items:   items,
+		byPorts: make(map[string]*link.Link, 2*len(items)),
+	}
+	for _, link := range items {
+		for _, key := range []string{linkKey(link.Local.ID, link.Remote.ID), linkKey(link.Remote.ID, link.Local.ID)} {
+			if _, ok := s.byPorts[key]; !ok {
+				s.byPorts[key] = link
+			}
+		}
+	}
+	return s
+}
+
+func linkKey(local, remote int) string {
+	return fmt.Sprintf("%d-%d", local, remote)
 }
 
 // NewLinksStorage .
-func NewLinksStorage() *LinksStorage {
-	return &LinksStorage{}
+func NewLinksStorage(cred *api.Clientset) *LinksStorage {
+	changes := newChangeTracker(KindLinks)
+	return &LinksStorage{
+		cred:    cred,
+		changes: changes,
+		misses:  newMissRefresher(KindLinks, changes),
+	}
 }
 
-// GetAll .
-func (p *LinksStorage) GetAll() []*link.Link {
-	p.Lock()
-	defer p.Unlock()
-	return p.getAll()
+func (p *LinksStorage) load() *linkSnapshot {
+	if s, ok := p.snapshot.Load().(*linkSnapshot); ok {
+		return s
+	}
+	return newLinkSnapshot(nil)
 }
 
-func (p *LinksStorage) getAll() []*link.Link {
-	return p.Links
+// GetAll .
+func (p *LinksStorage) GetAll() []*link.Link {
+	return p.load().items
 }
 
 func (p *LinksStorage) storeAll(items []*link.Link) {
-	p.Links = items
+	p.snapshot.Store(newLinkSnapshot(items))
+	tracked := make([]trackedItem, 0, len(items))
+	for _, item := range items {
+		tracked = append(tracked, trackedItem{Key: idKey(item.ID), ID: item.ID, Object: item})
+	}
+	p.changes.track(tracked)
 }
 
 // Find .
 func (p *LinksStorage) Find(local, remote int) (*link.Link, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.find(local, remote)
-	if !ok {
-		_ = p.download()
-		return p.find(local, remote)
-	}
+	var item *link.Link
+	ok := p.misses.find(linkKey(local, remote), func() bool {
+		var found bool
+		item, found = p.load().byPorts[linkKey(local, remote)]
+		return found
+	}, p.Download)
 	return item, ok
 }
 
-func (p *LinksStorage) find(local, remote int) (*link.Link, bool) {
-	for _, link := range p.Links {
-		if (link.Local.ID == local && link.Remote.ID == remote) || (link.Local.ID == remote && link.Remote.ID == local) {
-			return link, true
-		}
-	}
-	return nil, false
-}
-
 // Download .
 func (p *LinksStorage) download() error {
-	items, err := Cred.Link().Get()
+	items, err := netrisapi.Get(p.cred, "link", "list", p.cred.Link().Get)
 	if err != nil {
 		return err
 	}
@@ -85,3 +119,18 @@ func (p *LinksStorage) Download() error {
 	defer p.Unlock()
 	return p.download()
 }
+
+func (p *LinksStorage) dump() interface{} {
+	return p.load().items
+}
+
+func (p *LinksStorage) restore(data []byte) error {
+	items := []*link.Link{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.storeAll(items)
+	return nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netrisstorage
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	missRefreshes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "netris_storage_miss_refreshes_total",
+			Help: "Number of cache-miss lookups in netrisstorage by sub-storage kind and result (refreshed, coalesced, ratelimited, negative).",
+		},
+		[]string{"kind", "result"},
+	)
+	downloadDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "netris_storage_download_duration_seconds",
+			Help:    "Duration of the sub-storage downloads by Netris controller, kind and result (success, failure).",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"controller", "kind", "result"},
+	)
+
+	ageDesc = prometheus.NewDesc(
+		"netris_storage_age_seconds",
+		"Seconds since the content of the sub-storage was downloaded from Netris.",
+		[]string{"controller", "kind"}, nil,
+	)
+	sizeDesc = prometheus.NewDesc(
+		"netris_storage_items",
+		"Number of items in the sub-storage.",
+		[]string{"controller", "kind"}, nil,
+	)
+	staleDesc = prometheus.NewDesc(
+		"netris_storage_stale",
+		"1 if the sub-storage is stale and creates and deletes based on it wait, 0 otherwise.",
+		[]string{"controller", "kind"}, nil,
+	)
+
+	collector = &storageCollector{storages: map[*Storage]struct{}{}}
+)
+
+func init() {
+	metrics.Registry.MustRegister(missRefreshes, downloadDuration, collector)
+}
+
+// storageCollector reports the health of the storages which are being refreshed.
+type storageCollector struct {
+	mu       sync.Mutex
+	storages map[*Storage]struct{}
+}
+
+func (c *storageCollector) add(s *Storage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.storages[s] = struct{}{}
+}
+
+func (c *storageCollector) remove(s *Storage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.storages, s)
+}
+
+// Describe .
+func (c *storageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ageDesc
+	ch <- sizeDesc
+	ch <- staleDesc
+}
+
+// Collect .
+func (c *storageCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for s := range c.storages {
+		for _, entry := range s.subStorages {
+			h := entry.getHealth()
+			stale := 0.0
+			if h.Stale() {
+				stale = 1
+			}
+			ch <- prometheus.MustNewConstMetric(ageDesc, prometheus.GaugeValue, h.Age().Seconds(), s.name, entry.kind)
+			ch <- prometheus.MustNewConstMetric(sizeDesc, prometheus.GaugeValue, float64(entry.size()), s.name, entry.kind)
+			ch <- prometheus.MustNewConstMetric(staleDesc, prometheus.GaugeValue, stale, s.name, entry.kind)
+		}
+	}
+}
+
+// size returns the number of items in the sub-storage.
+func (e *subStorageEntry) size() int {
+	v := reflect.ValueOf(e.storage.dump())
+	if v.Kind() != reflect.Slice {
+		return 0
+	}
+	return v.Len()
+}
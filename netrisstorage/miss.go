@@ -0,0 +1,135 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netrisstorage
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	// missRefreshInterval is the minimum time between two miss-triggered refreshes of the same sub-storage.
+	missRefreshInterval = 2 * time.Second
+
+	// negativeCacheTTL is how long an item confirmed absent is answered as a miss without refreshing.
+	negativeCacheTTL = 10 * time.Second
+)
+
+type missCall struct {
+	done chan struct{}
+	err  error
+}
+
+type absentItem struct {
+	expires    time.Time
+	generation uint64
+}
+
+// missRefresher coalesces the cache-miss refreshes of a sub-storage. Concurrent
+// callers share one in-flight download, downloads are rate-limited and the
+// keys confirmed absent are remembered until the TTL expires or a new
+// snapshot is stored.
+type missRefresher struct {
+	sync.Mutex
+	kind        string
+	changes     *changeTracker
+	inflight    *missCall
+	lastRefresh time.Time
+	absent      map[string]absentItem
+}
+
+func newMissRefresher(kind string, changes *changeTracker) *missRefresher {
+	return &missRefresher{
+		kind:    kind,
+		changes: changes,
+		absent:  make(map[string]absentItem),
+	}
+}
+
+// find calls lookup and, on a miss, refreshes the sub-storage with download
+// and calls lookup once more.
+func (m *missRefresher) find(key string, lookup func() bool, download func() error) bool {
+	if lookup() {
+		return true
+	}
+	if m.isAbsent(key) {
+		missRefreshes.WithLabelValues(m.kind, "negative").Inc()
+		return false
+	}
+	_ = m.refresh(download)
+	if lookup() {
+		return true
+	}
+	m.markAbsent(key)
+	return false
+}
+
+func (m *missRefresher) refresh(download func() error) error {
+	m.Lock()
+	if call := m.inflight; call != nil {
+		m.Unlock()
+		missRefreshes.WithLabelValues(m.kind, "coalesced").Inc()
+		<-call.done
+		return call.err
+	}
+	call := &missCall{done: make(chan struct{})}
+	m.inflight = call
+	wait := missRefreshInterval - time.Since(m.lastRefresh)
+	m.Unlock()
+
+	// Callers arriving while we wait share this call as well.
+	if wait > 0 {
+		missRefreshes.WithLabelValues(m.kind, "ratelimited").Inc()
+		time.Sleep(wait)
+	}
+	missRefreshes.WithLabelValues(m.kind, "refreshed").Inc()
+	call.err = download()
+
+	m.Lock()
+	m.inflight = nil
+	m.lastRefresh = time.Now()
+	m.Unlock()
+	close(call.done)
+	return call.err
+}
+
+func (m *missRefresher) isAbsent(key string) bool {
+	m.Lock()
+	defer m.Unlock()
+	item, ok := m.absent[key]
+	if !ok {
+		return false
+	}
+	if time.Now().After(item.expires) || item.generation != m.changes.getGeneration() {
+		delete(m.absent, key)
+		return false
+	}
+	return true
+}
+
+func (m *missRefresher) markAbsent(key string) {
+	m.Lock()
+	defer m.Unlock()
+	now := time.Now()
+	generation := m.changes.getGeneration()
+	for k, item := range m.absent {
+		if now.After(item.expires) || item.generation != generation {
+			delete(m.absent, k)
+		}
+	}
+	m.absent[key] = absentItem{expires: now.Add(negativeCacheTTL), generation: generation}
+}
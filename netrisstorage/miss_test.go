@@ -0,0 +1,175 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netrisstorage
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// setMissTimings overrides the refresh interval and the negative cache TTL
+// for the duration of the test.
+func setMissTimings(t *testing.T, interval, ttl time.Duration) {
+	oldInterval, oldTTL := missRefreshInterval, negativeCacheTTL
+	missRefreshInterval, negativeCacheTTL = interval, ttl
+	t.Cleanup(func() {
+		missRefreshInterval, negativeCacheTTL = oldInterval, oldTTL
+	})
+}
+
+func missCount(kind, result string) int {
+	return int(testutil.ToFloat64(missRefreshes.WithLabelValues(kind, result)))
+}
+
+func TestMissRefreshCoalesced(t *testing.T) {
+	setMissTimings(t, 0, time.Minute)
+	const kind, callers = "test-coalesced", 5
+	m := newMissRefresher(kind, newChangeTracker(kind))
+
+	var downloads int32
+	started, release := make(chan struct{}), make(chan struct{})
+	download := func() error {
+		if atomic.AddInt32(&downloads, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return nil
+	}
+
+	coalesced := missCount(kind, "coalesced")
+	var wg sync.WaitGroup
+	wg.Add(callers)
+	go func() {
+		defer wg.Done()
+		_ = m.refresh(download)
+	}()
+	<-started
+	for i := 1; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			_ = m.refresh(download)
+		}()
+	}
+	// hold the download until every caller waits for it.
+	deadline := time.Now().Add(5 * time.Second)
+	for missCount(kind, "coalesced")-coalesced < callers-1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d callers coalesced, want %d", missCount(kind, "coalesced")-coalesced, callers-1)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if downloads != 1 {
+		t.Fatalf("%d downloads for %d concurrent callers, want 1", downloads, callers)
+	}
+}
+
+func TestMissRefreshRateLimited(t *testing.T) {
+	interval := 50 * time.Millisecond
+	setMissTimings(t, interval, time.Minute)
+	const kind = "test-ratelimited"
+	m := newMissRefresher(kind, newChangeTracker(kind))
+
+	downloads, ratelimited := 0, missCount(kind, "ratelimited")
+	download := func() error {
+		downloads++
+		return nil
+	}
+
+	_ = m.refresh(download)
+	start := time.Now()
+	_ = m.refresh(download)
+	if elapsed := time.Since(start); elapsed < interval/2 {
+		t.Fatalf("second refresh after %s, want it delayed by about %s", elapsed, interval)
+	}
+	if downloads != 2 {
+		t.Fatalf("%d downloads, want 2", downloads)
+	}
+	if n := missCount(kind, "ratelimited") - ratelimited; n != 1 {
+		t.Fatalf("%d rate-limited refreshes, want 1", n)
+	}
+}
+
+func TestMissNegativeCache(t *testing.T) {
+	ttl := 50 * time.Millisecond
+	setMissTimings(t, 0, ttl)
+	const kind = "test-negative"
+	changes := newChangeTracker(kind)
+	m := newMissRefresher(kind, changes)
+
+	downloads, negative := 0, missCount(kind, "negative")
+	download := func() error {
+		downloads++
+		return nil
+	}
+	missing := func() bool { return false }
+
+	if m.find("1", missing, download) {
+		t.Fatal("found a missing item")
+	}
+	if downloads != 1 {
+		t.Fatalf("%d downloads on the first miss, want 1", downloads)
+	}
+
+	m.find("1", missing, download)
+	if downloads != 1 {
+		t.Fatal("a key confirmed absent was refreshed again")
+	}
+	if n := missCount(kind, "negative") - negative; n != 1 {
+		t.Fatalf("%d negative cache hits, want 1", n)
+	}
+
+	// a new snapshot invalidates the negative cache.
+	changes.track(nil)
+	m.find("1", missing, download)
+	if downloads != 2 {
+		t.Fatalf("%d downloads after a new snapshot, want 2", downloads)
+	}
+
+	// so does the TTL.
+	time.Sleep(ttl + 10*time.Millisecond)
+	m.find("1", missing, download)
+	if downloads != 3 {
+		t.Fatalf("%d downloads after the TTL expired, want 3", downloads)
+	}
+}
+
+func TestMissFoundAfterRefresh(t *testing.T) {
+	setMissTimings(t, 0, time.Minute)
+	const kind = "test-found"
+	m := newMissRefresher(kind, newChangeTracker(kind))
+
+	stored := false
+	download := func() error {
+		stored = true
+		return nil
+	}
+	lookup := func() bool { return stored }
+
+	if !m.find("1", lookup, download) {
+		t.Fatal("the item downloaded by the refresh wasn't found")
+	}
+	if m.isAbsent("1") {
+		t.Fatal("a found item is remembered as absent")
+	}
+}
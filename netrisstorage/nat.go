@@ -17,77 +17,98 @@ limitations under the License.
 package netrisstorage
 
 import (
+	"encoding/json"
 	"sync"
+	"sync/atomic"
 
+	"github.com/netrisai/netris-operator/netrisapi"
+	api "github.com/netrisai/netriswebapi/v2"
 	"github.com/netrisai/netriswebapi/v2/types/nat"
 )
 
 // NATStorage .
 type NATStorage struct {
 	sync.Mutex
-	NAT []*nat.NAT
+	cred     *api.Clientset
+	snapshot atomic.Value
+	changes  *changeTracker
+	misses   *missRefresher
+}
+
+type natSnapshot struct {
+	items  []*nat.NAT
+	byName map[string]*nat.NAT
+	byID   map[int]*nat.NAT
+}
+
+func newNatSnapshot(items []*nat.NAT) *natSnapshot {
+	s := &natSnapshot{
+		items:  items,
+		byName: make(map[string]*nat.NAT, len(items)),
+		byID:   make(map[int]*nat.NAT, len(items)),
+	}
+	for _, item := range items {
+		if _, ok := s.byName[item.Name]; !ok {
+			s.byName[item.Name] = item
+		}
+		if _, ok := s.byID[item.ID]; !ok {
+			s.byID[item.ID] = item
+		}
+	}
+	return s
 }
 
 // NewNATStorage .
-func NewNATStorage() *NATStorage {
-	return &NATStorage{}
+func NewNATStorage(cred *api.Clientset) *NATStorage {
+	changes := newChangeTracker(KindNATs)
+	return &NATStorage{
+		cred:    cred,
+		changes: changes,
+		misses:  newMissRefresher(KindNATs, changes),
+	}
 }
 
-// GetAll .
-func (p *NATStorage) GetAll() []*nat.NAT {
-	p.Lock()
-	defer p.Unlock()
-	return p.getAll()
+func (p *NATStorage) load() *natSnapshot {
+	if s, ok := p.snapshot.Load().(*natSnapshot); ok {
+		return s
+	}
+	return newNatSnapshot(nil)
 }
 
-func (p *NATStorage) getAll() []*nat.NAT {
-	return p.NAT
+// GetAll .
+func (p *NATStorage) GetAll() []*nat.NAT {
+	return p.load().items
 }
 
 func (p *NATStorage) storeAll(items []*nat.NAT) {
-	p.NAT = items
+	p.snapshot.Store(newNatSnapshot(items))
+	tracked := make([]trackedItem, 0, len(items))
+	for _, item := range items {
+		tracked = append(tracked, trackedItem{Key: idKey(item.ID), ID: item.ID, Object: item})
+	}
+	p.changes.track(tracked)
 }
 
 // FindByName .
 func (p *NATStorage) FindByName(name string) (*nat.NAT, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findByName(name)
-}
-
-func (p *NATStorage) findByName(name string) (*nat.NAT, bool) {
-	for _, nat := range p.NAT {
-		if nat.Name == name {
-			return nat, true
-		}
-	}
-	return nil, false
+	item, ok := p.load().byName[name]
+	return item, ok
 }
 
 // FindByID .
 func (p *NATStorage) FindByID(id int) (*nat.NAT, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.findByID(id)
-	if !ok {
-		_ = p.download()
-		return p.findByID(id)
-	}
+	var item *nat.NAT
+	ok := p.misses.find(idKey(id), func() bool {
+		var found bool
+		item, found = p.load().byID[id]
+		return found
+	}, p.Download)
 	return item, ok
 }
 
-func (p *NATStorage) findByID(id int) (*nat.NAT, bool) {
-	for _, nat := range p.NAT {
-		if nat.ID == id {
-			return nat, true
-		}
-	}
-	return nil, false
-}
-
 // Download .
 func (p *NATStorage) download() error {
-	items, err := Cred.NAT().Get()
+	items, err := netrisapi.Get(p.cred, "nat", "list", p.cred.NAT().Get)
 	if err != nil {
 		return err
 	}
@@ -101,3 +122,18 @@ func (p *NATStorage) Download() error {
 	defer p.Unlock()
 	return p.download()
 }
+
+func (p *NATStorage) dump() interface{} {
+	return p.load().items
+}
+
+func (p *NATStorage) restore(data []byte) error {
+	items := []*nat.NAT{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.storeAll(items)
+	return nil
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netrisstorage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	snapshotConfigMapKey = "snapshot.json.gz"
+	serviceAccountNSFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// SnapshotStore keeps the serialized storage snapshot between operator restarts.
+type SnapshotStore interface {
+	Save(data []byte) error
+	// Load returns nil data without error if nothing was saved yet.
+	Load() ([]byte, error)
+}
+
+// NewSnapshotStore parses the snapshot location, which is either
+// "file:<path>" or "configmap:[<namespace>/]<name>". The ConfigMap
+// namespace defaults to the namespace of the operator pod.
+func NewSnapshotStore(location string, c client.Client) (SnapshotStore, error) {
+	parts := strings.SplitN(location, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid storage snapshot location %q", location)
+	}
+	switch parts[0] {
+	case "file":
+		return &FileSnapshotStore{Path: parts[1]}, nil
+	case "configmap":
+		namespace, name := "", parts[1]
+		if nn := strings.SplitN(parts[1], "/", 2); len(nn) == 2 {
+			namespace, name = nn[0], nn[1]
+		}
+		if namespace == "" {
+			ns, err := ioutil.ReadFile(serviceAccountNSFile)
+			if err != nil {
+				return nil, fmt.Errorf("can't detect the operator namespace for the storage snapshot: %s", err)
+			}
+			namespace = strings.TrimSpace(string(ns))
+		}
+		return &ConfigMapSnapshotStore{Client: c, Namespace: namespace, Name: name}, nil
+	}
+	return nil, fmt.Errorf("unknown storage snapshot location type %q", parts[0])
+}
+
+// FileSnapshotStore keeps the snapshot in a local file.
+type FileSnapshotStore struct {
+	Path string
+}
+
+// Save .
+func (f *FileSnapshotStore) Save(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+		return err
+	}
+	// write to a temporary file first, so a crash never leaves a truncated snapshot.
+	tmp := f.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.Path)
+}
+
+// Load .
+func (f *FileSnapshotStore) Load() ([]byte, error) {
+	data, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// ConfigMapSnapshotStore keeps the snapshot in a ConfigMap. The client must
+// not be cache-backed, because the snapshot is loaded before the manager starts.
+type ConfigMapSnapshotStore struct {
+	Client    client.Client
+	Namespace string
+	Name      string
+}
+
+// Save .
+func (c *ConfigMapSnapshotStore) Save(data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	configMap := &corev1.ConfigMap{}
+	err := c.Client.Get(ctx, types.NamespacedName{Namespace: c.Namespace, Name: c.Name}, configMap)
+	if errors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: c.Namespace,
+				Name:      c.Name,
+			},
+			BinaryData: map[string][]byte{snapshotConfigMapKey: data},
+		}
+		return c.Client.Create(ctx, configMap)
+	} else if err != nil {
+		return err
+	}
+	if configMap.BinaryData == nil {
+		configMap.BinaryData = map[string][]byte{}
+	}
+	configMap.BinaryData[snapshotConfigMapKey] = data
+	return c.Client.Update(ctx, configMap)
+}
+
+// Load .
+func (c *ConfigMapSnapshotStore) Load() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	configMap := &corev1.ConfigMap{}
+	err := c.Client.Get(ctx, types.NamespacedName{Namespace: c.Namespace, Name: c.Name}, configMap)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return configMap.BinaryData[snapshotConfigMapKey], nil
+}
+
+type storageSnapshot struct {
+	SavedAt time.Time                  `json:"savedAt"`
+	Kinds   map[string]json.RawMessage `json:"kinds"`
+}
+
+// Checkpoint saves the current content of all sub-storages. Nothing is saved
+// until every sub-storage holds data, so an empty cache never overwrites a
+// good snapshot. The snapshot is dated by its oldest sub-storage.
+func (s *Storage) Checkpoint(store SnapshotStore) error {
+	snapshot := storageSnapshot{
+		SavedAt: time.Now(),
+		Kinds:   make(map[string]json.RawMessage, len(s.subStorages)),
+	}
+	for _, entry := range s.subStorages {
+		if !entry.hasData() {
+			return fmt.Errorf("%s: nothing to checkpoint yet", entry.kind)
+		}
+		if dataTime := time.Now().Add(-entry.getHealth().Age()); dataTime.Before(snapshot.SavedAt) {
+			snapshot.SavedAt = dataTime
+		}
+		js, err := json.Marshal(entry.storage.dump())
+		if err != nil {
+			return fmt.Errorf("%s: %s", entry.kind, err)
+		}
+		snapshot.Kinds[entry.kind] = js
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(snapshot); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return store.Save(buf.Bytes())
+}
+
+// CheckpointHealth is the result of the latest periodic checkpoint.
+type CheckpointHealth struct {
+	LastAttempt time.Time
+	LastSuccess time.Time
+	LastError   error
+}
+
+// CheckpointHealth returns the result of the latest periodic checkpoint, it's
+// zero when the storage isn't checkpointed.
+func (s *Storage) CheckpointHealth() CheckpointHealth {
+	s.checkpointMu.Lock()
+	defer s.checkpointMu.Unlock()
+	return s.checkpoint
+}
+
+// CheckpointWithInterval periodically saves the storage content to the store.
+func (s *Storage) CheckpointWithInterval(store SnapshotStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		<-ticker.C
+		err := s.Checkpoint(store)
+		if err != nil {
+			logger.Error(err, "Checkpoint failed")
+		}
+		s.checkpointMu.Lock()
+		s.checkpoint.LastAttempt = time.Now()
+		s.checkpoint.LastError = err
+		if err == nil {
+			s.checkpoint.LastSuccess = s.checkpoint.LastAttempt
+		}
+		s.checkpointMu.Unlock()
+	}
+}
+
+// Restore loads the snapshot from the store into the sub-storages which
+// weren't downloaded from Netris yet. It is meant to be called before the
+// first Download. Restored sub-storages stay stale until their first
+// successful download.
+func (s *Storage) Restore(store SnapshotStore) error {
+	data, err := store.Load()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	snapshot := storageSnapshot{}
+	if err := json.NewDecoder(zr).Decode(&snapshot); err != nil {
+		return err
+	}
+	for _, entry := range s.subStorages {
+		js, ok := snapshot.Kinds[entry.kind]
+		if !ok {
+			continue
+		}
+		if err := entry.restore(js, snapshot.SavedAt); err != nil {
+			return fmt.Errorf("%s: %s", entry.kind, err)
+		}
+	}
+	return nil
+}
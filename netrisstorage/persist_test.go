@@ -0,0 +1,181 @@
+/*
+Copyright 2021. Netris, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netrisstorage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netrisai/netriswebapi/v2/types/site"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// restoredStorage returns a storage restored from a snapshot holding one site
+// and nothing else, as if it was checkpointed by another operator process.
+func restoredStorage(t *testing.T) *Storage {
+	s := NewStorageWithOptions(nil, Options{})
+	snapshot := storageSnapshot{SavedAt: time.Now(), Kinds: map[string]json.RawMessage{}}
+	for _, entry := range s.subStorages {
+		snapshot.Kinds[entry.kind] = json.RawMessage("[]")
+	}
+	sites, err := json.Marshal([]*site.Site{{ID: 1, Name: "site1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot.Kinds[KindSites] = sites
+	js, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &FileSnapshotStore{Path: filepath.Join(t.TempDir(), "source.json.gz")}
+	if err := store.Save(gzipped(t, js)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Restore(store); err != nil {
+		t.Fatalf("restore: %s", err)
+	}
+	return s
+}
+
+func testRoundTrip(t *testing.T, store SnapshotStore) {
+	s := restoredStorage(t)
+	if err := s.Checkpoint(store); err != nil {
+		t.Fatalf("checkpoint: %s", err)
+	}
+
+	restored := NewStorageWithOptions(nil, Options{})
+	if err := restored.Restore(store); err != nil {
+		t.Fatalf("restore: %s", err)
+	}
+	item, ok := restored.SitesStorage.FindByName("site1")
+	if !ok || item.ID != 1 {
+		t.Fatalf("site1 not restored from the checkpoint, got %+v", item)
+	}
+	for _, health := range restored.Health() {
+		if health.Live() || health.RestoredFrom.IsZero() {
+			t.Fatalf("%s: health %+v, want restored and not live", health.Kind, health)
+		}
+	}
+}
+
+func TestCheckpointFileRoundTrip(t *testing.T) {
+	testRoundTrip(t, &FileSnapshotStore{Path: filepath.Join(t.TempDir(), "snapshot", "storage.json.gz")})
+}
+
+func TestCheckpointConfigMapRoundTrip(t *testing.T) {
+	store := &ConfigMapSnapshotStore{Client: fake.NewFakeClient(), Namespace: "netris-operator", Name: "storage-snapshot"}
+	testRoundTrip(t, store)
+	// the second checkpoint updates the existing ConfigMap.
+	testRoundTrip(t, store)
+}
+
+func TestCheckpointWithoutData(t *testing.T) {
+	store := &FileSnapshotStore{Path: filepath.Join(t.TempDir(), "storage.json.gz")}
+	if err := NewStorageWithOptions(nil, Options{}).Checkpoint(store); err == nil {
+		t.Fatal("an empty storage was checkpointed")
+	}
+	if data, err := store.Load(); err != nil || data != nil {
+		t.Fatalf("store holds %q (%v), want nothing", data, err)
+	}
+}
+
+func TestRestoreNothingSaved(t *testing.T) {
+	for _, store := range []SnapshotStore{
+		&FileSnapshotStore{Path: filepath.Join(t.TempDir(), "storage.json.gz")},
+		&ConfigMapSnapshotStore{Client: fake.NewFakeClient(), Namespace: "netris-operator", Name: "storage-snapshot"},
+	} {
+		if err := NewStorageWithOptions(nil, Options{}).Restore(store); err != nil {
+			t.Fatalf("%T: %s", store, err)
+		}
+	}
+}
+
+func TestRestoreCorrupt(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "not gzip", data: []byte(`{"kinds":{}}`)},
+		{name: "truncated", data: gzipped(t, []byte(`{"kinds":{"sites":[`))},
+		{name: "bad kind", data: gzipped(t, []byte(`{"kinds":{"sites":{"id":1}}}`))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &FileSnapshotStore{Path: filepath.Join(t.TempDir(), "storage.json.gz")}
+			if err := store.Save(tt.data); err != nil {
+				t.Fatal(err)
+			}
+			s := NewStorageWithOptions(nil, Options{})
+			if err := s.Restore(store); err == nil {
+				t.Fatal("a corrupt snapshot was restored")
+			}
+			if _, ok := s.SitesStorage.FindByName("site1"); ok {
+				t.Fatal("the storage holds data from a corrupt snapshot")
+			}
+		})
+	}
+}
+
+func TestNewSnapshotStore(t *testing.T) {
+	tests := []struct {
+		location string
+		want     SnapshotStore
+		err      string
+	}{
+		{location: "file:/var/lib/netris/storage.json.gz", want: &FileSnapshotStore{Path: "/var/lib/netris/storage.json.gz"}},
+		{location: "configmap:netris-operator/storage-snapshot", want: &ConfigMapSnapshotStore{Namespace: "netris-operator", Name: "storage-snapshot"}},
+		{location: "file:", err: "invalid"},
+		{location: "storage.json.gz", err: "invalid"},
+		{location: "s3:bucket/storage.json.gz", err: "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			got, err := NewSnapshotStore(tt.location, nil)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
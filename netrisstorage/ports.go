@@ -17,79 +17,112 @@ limitations under the License.
 package netrisstorage
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
+	"github.com/netrisai/netris-operator/netrisapi"
+	api "github.com/netrisai/netriswebapi/v2"
 	"github.com/netrisai/netriswebapi/v2/types/port"
 )
 
 // PortsStorage .
 type PortsStorage struct {
 	sync.Mutex
-	Ports []*port.Port
+	cred     *api.Clientset
+	snapshot atomic.Value
+	changes  *changeTracker
+}
+
+type portSnapshot struct {
+	items  []*port.Port
+	byName map[string]*port.Port
+	byID   map[int]*port.Port
+}
+
+func newPortSnapshot(items []*port.Port) *portSnapshot {
+	s := &portSnapshot{
+		items:  items,
+		byName: make(map[string]*port.Port, len(items)),
+		byID:   make(map[int]*port.Port, len(items)),
+	}
+	for _, port := range items {
+		portName := fmt.Sprintf("%s@%s", port.Port_, port.SwitchName)
+		if _, ok := s.byName[portName]; !ok {
+			s.byName[portName] = port
+		}
+		if _, ok := s.byID[port.ID]; !ok {
+			s.byID[port.ID] = port
+		}
+	}
+	return s
 }
 
 // NewPortStorage .
-func NewPortStorage() *PortsStorage {
-	return &PortsStorage{}
+func NewPortStorage(cred *api.Clientset) *PortsStorage {
+	return &PortsStorage{
+		cred:    cred,
+		changes: newChangeTracker(KindPorts),
+	}
+}
+
+func (p *PortsStorage) load() *portSnapshot {
+	if s, ok := p.snapshot.Load().(*portSnapshot); ok {
+		return s
+	}
+	return newPortSnapshot(nil)
 }
 
 func (p *PortsStorage) storeAll(ports []*port.Port) {
-	p.Ports = ports
+	p.snapshot.Store(newPortSnapshot(ports))
+	tracked := make([]trackedItem, 0, len(ports))
+	for _, item := range ports {
+		tracked = append(tracked, trackedItem{Key: idKey(item.ID), ID: item.ID, Object: item})
+	}
+	p.changes.track(tracked)
 }
 
 // GetAll .
 func (p *PortsStorage) GetAll() []*port.Port {
-	p.Lock()
-	defer p.Unlock()
-	return p.getAll()
-}
-
-func (p *PortsStorage) getAll() []*port.Port {
-	return p.Ports
+	return p.load().items
 }
 
 // FindByName .
 func (p *PortsStorage) FindByName(name string) (*port.Port, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findByName(name)
-}
-
-func (p *PortsStorage) findByName(name string) (*port.Port, bool) {
-	for _, port := range p.Ports {
-		portName := fmt.Sprintf("%s@%s", port.Port_, port.SwitchName)
-		if portName == name {
-			return port, true
-		}
-	}
-	return nil, false
+	port, ok := p.load().byName[name]
+	return port, ok
 }
 
 // FindByID .
 func (p *PortsStorage) FindByID(id int) (*port.Port, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findByID(id)
-}
-
-func (p *PortsStorage) findByID(id int) (*port.Port, bool) {
-	for _, port := range p.Ports {
-		if id == port.ID {
-			return port, true
-		}
-	}
-	return nil, false
+	port, ok := p.load().byID[id]
+	return port, ok
 }
 
 // Download .
 func (p *PortsStorage) Download() error {
 	p.Lock()
 	defer p.Unlock()
-	ports, err := Cred.Port().Get()
+	ports, err := netrisapi.Get(p.cred, "port", "list", p.cred.Port().Get)
 	if err != nil {
 		return err
 	}
 	p.storeAll(ports)
 	return nil
 }
+
+func (p *PortsStorage) dump() interface{} {
+	return p.load().items
+}
+
+func (p *PortsStorage) restore(data []byte) error {
+	items := []*port.Port{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.storeAll(items)
+	return nil
+}
@@ -17,77 +17,98 @@ limitations under the License.
 package netrisstorage
 
 import (
+	"encoding/json"
 	"sync"
+	"sync/atomic"
 
+	"github.com/netrisai/netris-operator/netrisapi"
+	api "github.com/netrisai/netriswebapi/v2"
 	"github.com/netrisai/netriswebapi/v2/types/site"
 )
 
 // SitesStorage .
 type SitesStorage struct {
 	sync.Mutex
-	Sites []*site.Site
+	cred     *api.Clientset
+	snapshot atomic.Value
+	changes  *changeTracker
+	misses   *missRefresher
+}
+
+type siteSnapshot struct {
+	items  []*site.Site
+	byName map[string]*site.Site
+	byID   map[int]*site.Site
+}
+
+func newSiteSnapshot(items []*site.Site) *siteSnapshot {
+	s := &siteSnapshot{
+		items:  items,
+		byName: make(map[string]*site.Site, len(items)),
+		byID:   make(map[int]*site.Site, len(items)),
+	}
+	for _, item := range items {
+		if _, ok := s.byName[item.Name]; !ok {
+			s.byName[item.Name] = item
+		}
+		if _, ok := s.byID[item.ID]; !ok {
+			s.byID[item.ID] = item
+		}
+	}
+	return s
 }
 
 // NewSitesStorage .
-func NewSitesStorage() *SitesStorage {
-	return &SitesStorage{}
+func NewSitesStorage(cred *api.Clientset) *SitesStorage {
+	changes := newChangeTracker(KindSites)
+	return &SitesStorage{
+		cred:    cred,
+		changes: changes,
+		misses:  newMissRefresher(KindSites, changes),
+	}
 }
 
-// GetAll .
-func (p *SitesStorage) GetAll() []*site.Site {
-	p.Lock()
-	defer p.Unlock()
-	return p.getAll()
+func (p *SitesStorage) load() *siteSnapshot {
+	if s, ok := p.snapshot.Load().(*siteSnapshot); ok {
+		return s
+	}
+	return newSiteSnapshot(nil)
 }
 
-func (p *SitesStorage) getAll() []*site.Site {
-	return p.Sites
+// GetAll .
+func (p *SitesStorage) GetAll() []*site.Site {
+	return p.load().items
 }
 
 func (p *SitesStorage) storeAll(items []*site.Site) {
-	p.Sites = items
+	p.snapshot.Store(newSiteSnapshot(items))
+	tracked := make([]trackedItem, 0, len(items))
+	for _, item := range items {
+		tracked = append(tracked, trackedItem{Key: idKey(item.ID), ID: item.ID, Object: item})
+	}
+	p.changes.track(tracked)
 }
 
 // FindByName .
 func (p *SitesStorage) FindByName(name string) (*site.Site, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findByName(name)
-}
-
-func (p *SitesStorage) findByName(name string) (*site.Site, bool) {
-	for _, site := range p.Sites {
-		if site.Name == name {
-			return site, true
-		}
-	}
-	return nil, false
+	item, ok := p.load().byName[name]
+	return item, ok
 }
 
 // FindByID .
 func (p *SitesStorage) FindByID(id int) (*site.Site, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.findByID(id)
-	if !ok {
-		_ = p.download()
-		return p.findByID(id)
-	}
+	var item *site.Site
+	ok := p.misses.find(idKey(id), func() bool {
+		var found bool
+		item, found = p.load().byID[id]
+		return found
+	}, p.Download)
 	return item, ok
 }
 
-func (p *SitesStorage) findByID(id int) (*site.Site, bool) {
-	for _, site := range p.Sites {
-		if site.ID == id {
-			return site, true
-		}
-	}
-	return nil, false
-}
-
 // Download .
 func (p *SitesStorage) download() error {
-	items, err := Cred.Site().Get()
+	items, err := netrisapi.Get(p.cred, "site", "list", p.cred.Site().Get)
 	if err != nil {
 		return err
 	}
@@ -101,3 +122,18 @@ func (p *SitesStorage) Download() error {
 	defer p.Unlock()
 	return p.download()
 }
+
+func (p *SitesStorage) dump() interface{} {
+	return p.load().items
+}
+
+func (p *SitesStorage) restore(data []byte) error {
+	items := []*site.Site{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.storeAll(items)
+	return nil
+}
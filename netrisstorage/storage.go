@@ -21,16 +21,160 @@ import (
 	"sync"
 	"time"
 
+	"github.com/netrisai/netris-operator/configloader"
 	api "github.com/netrisai/netriswebapi/v2"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
 )
 
-// Cred is the Netris api client structure.
-var Cred *api.Clientset
+var logger = ctrl.Log.WithName("NetrisStorage")
+
+// Kinds of the sub-storages. They are used as keys for refresh intervals and in published deltas.
+const (
+	KindPorts             = "ports"
+	KindSites             = "sites"
+	KindTenants           = "tenants"
+	KindVNets             = "vnets"
+	KindVPCs              = "vpcs"
+	KindBGPs              = "bgps"
+	KindL4LBs             = "l4lbs"
+	KindSubnets           = "subnets"
+	KindHWs               = "hws"
+	KindLinks             = "links"
+	KindNATs              = "nats"
+	KindInventoryProfiles = "inventoryprofiles"
+)
+
+var (
+	defaultRefreshInterval = 10 * time.Second
+
+	// staleAfterRefreshes is how many refresh intervals a sub-storage may go
+	// without a successful refresh before it is considered stale.
+	staleAfterRefreshes = 3
+
+	// slow-changing data is polled less often by default.
+	defaultRefreshIntervals = map[string]time.Duration{
+		KindSites:             60 * time.Second,
+		KindTenants:           60 * time.Second,
+		KindVPCs:              60 * time.Second,
+		KindInventoryProfiles: 60 * time.Second,
+	}
+)
 
 /********************************************************************************
 	Storage
 *********************************************************************************/
 
+type subStorage interface {
+	Download() error
+	dump() interface{}
+	restore(data []byte) error
+}
+
+type subStorageEntry struct {
+	kind       string
+	controller string
+	storage    subStorage
+	changes    *changeTracker
+	interval   time.Duration
+
+	mu     sync.Mutex
+	health Health
+}
+
+// Health describes the result of the latest refreshes of a sub-storage.
+type Health struct {
+	Kind        string
+	Interval    time.Duration
+	LastAttempt time.Time
+	LastSuccess time.Time
+	LastError   error
+	// RestoredFrom is the save time of the snapshot the sub-storage was restored from.
+	RestoredFrom time.Time
+}
+
+// Healthy reports whether the latest refresh succeeded.
+func (h Health) Healthy() bool {
+	return h.LastError == nil && !h.LastSuccess.IsZero()
+}
+
+// Live reports whether the sub-storage was downloaded from Netris by this
+// process. Otherwise its content is either restored from a snapshot or empty.
+func (h Health) Live() bool {
+	return !h.LastSuccess.IsZero()
+}
+
+// Age returns how long ago the content of the sub-storage was downloaded.
+func (h Health) Age() time.Duration {
+	if h.Live() {
+		return time.Since(h.LastSuccess)
+	}
+	if !h.RestoredFrom.IsZero() {
+		return time.Since(h.RestoredFrom)
+	}
+	return 0
+}
+
+// Stale reports whether create or delete decisions must not be based on the
+// sub-storage: it isn't live, its latest refresh failed or it wasn't
+// refreshed for several intervals.
+func (h Health) Stale() bool {
+	if !h.Live() || h.LastError != nil {
+		return true
+	}
+	return h.Interval > 0 && h.Age() > time.Duration(staleAfterRefreshes)*h.Interval
+}
+
+// refresh downloads the sub-storage and records the outcome. On failure the
+// sub-storage keeps serving its last good snapshot.
+func (e *subStorageEntry) refresh() error {
+	started := time.Now()
+	err := e.storage.Download()
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	downloadDuration.WithLabelValues(e.controller, e.kind, result).Observe(time.Since(started).Seconds())
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.health.LastAttempt = started
+	e.health.LastError = err
+	if err == nil {
+		e.health.LastSuccess = started
+	}
+	return err
+}
+
+// restore must not race with the first refresh, otherwise the restored
+// snapshot could replace the downloaded one.
+func (e *subStorageEntry) restore(data []byte, savedAt time.Time) error {
+	if e.getHealth().Live() {
+		return nil
+	}
+	if err := e.storage.restore(data); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.health.RestoredFrom = savedAt
+	return nil
+}
+
+func (e *subStorageEntry) hasData() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return !e.health.LastSuccess.IsZero() || !e.health.RestoredFrom.IsZero()
+}
+
+func (e *subStorageEntry) getHealth() Health {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	h := e.health
+	h.Kind = e.kind
+	h.Interval = e.interval
+	return h
+}
+
 // Storage .
 type Storage struct {
 	sync.Mutex
@@ -46,90 +190,202 @@ type Storage struct {
 	*LinksStorage
 	*NATStorage
 	*InventoryProfileStorage
+
+	// name is the Netris controller the storage belongs to in the metrics.
+	name        string
+	subStorages []*subStorageEntry
+
+	listenersMu sync.RWMutex
+	listeners   []Listener
+
+	checkpointMu sync.Mutex
+	checkpoint   CheckpointHealth
+}
+
+// Options of a Storage.
+type Options struct {
+	// VPCID is the VPC whose subnets are downloaded, 0 means the default VPC.
+	VPCID int
+	// RefreshIntervals are the refresh intervals in seconds by kind.
+	RefreshIntervals map[string]int
+	// Name is the Netris controller in the metrics, empty means "default".
+	Name string
 }
 
-// NewStorage .
+// NewStorage creates the storage of the Netris controller from the operator config.
 func NewStorage(cred *api.Clientset) *Storage {
-	Cred = cred
-	return &Storage{
-		PortsStorage:            NewPortStorage(),
-		SitesStorage:            NewSitesStorage(),
-		TenantsStorage:          NewTenantsStorage(),
-		VNetStorage:             NewVNetStorage(),
-		VPCStorage:              NewVPCStorage(),
-		BGPStorage:              NewBGPStorage(),
-		L4LBStorage:             NewL4LBStorage(),
-		SubnetsStorage:          NewSubnetsStorage(),
-		HWsStorage:              NewHWsStorage(),
-		LinksStorage:            NewLinksStorage(),
-		NATStorage:              NewNATStorage(),
-		InventoryProfileStorage: NewInventoryProfileStorage(),
-	}
-}
-
-// Download .
-func (s *Storage) Download() error {
-	s.Lock()
-	defer s.Unlock()
-	if err := s.PortsStorage.Download(); err != nil {
-		fmt.Println("PortsStorage", err)
-		return err
+	return NewStorageWithOptions(cred, Options{
+		VPCID:            configloader.Root.VPCID,
+		RefreshIntervals: configloader.Root.StorageRefreshIntervals,
+	})
+}
+
+// NewStorageWithOptions creates a storage which downloads through cred.
+// Every Netris controller needs its own storage.
+func NewStorageWithOptions(cred *api.Clientset, options Options) *Storage {
+	s := &Storage{
+		PortsStorage:            NewPortStorage(cred),
+		SitesStorage:            NewSitesStorage(cred),
+		TenantsStorage:          NewTenantsStorage(cred),
+		VNetStorage:             NewVNetStorage(cred),
+		VPCStorage:              NewVPCStorage(cred),
+		BGPStorage:              NewBGPStorage(cred),
+		L4LBStorage:             NewL4LBStorage(cred),
+		SubnetsStorage:          NewSubnetsStorage(cred, options.VPCID),
+		HWsStorage:              NewHWsStorage(cred),
+		LinksStorage:            NewLinksStorage(cred),
+		NATStorage:              NewNATStorage(cred),
+		InventoryProfileStorage: NewInventoryProfileStorage(cred),
+		name:                    options.Name,
 	}
-	if err := s.SitesStorage.Download(); err != nil {
-		fmt.Println("SitesStorage", err)
-		return err
+	if s.name == "" {
+		s.name = "default"
 	}
-	if err := s.TenantsStorage.Download(); err != nil {
-		fmt.Println("TenantsStorage", err)
-		return err
+	s.subStorages = []*subStorageEntry{
+		{kind: KindPorts, storage: s.PortsStorage, changes: s.PortsStorage.changes},
+		{kind: KindSites, storage: s.SitesStorage, changes: s.SitesStorage.changes},
+		{kind: KindTenants, storage: s.TenantsStorage, changes: s.TenantsStorage.changes},
+		{kind: KindVNets, storage: s.VNetStorage, changes: s.VNetStorage.changes},
+		{kind: KindVPCs, storage: s.VPCStorage, changes: s.VPCStorage.changes},
+		{kind: KindBGPs, storage: s.BGPStorage, changes: s.BGPStorage.changes},
+		{kind: KindL4LBs, storage: s.L4LBStorage, changes: s.L4LBStorage.changes},
+		{kind: KindSubnets, storage: s.SubnetsStorage, changes: s.SubnetsStorage.changes},
+		{kind: KindHWs, storage: s.HWsStorage, changes: s.HWsStorage.changes},
+		{kind: KindLinks, storage: s.LinksStorage, changes: s.LinksStorage.changes},
+		{kind: KindNATs, storage: s.NATStorage, changes: s.NATStorage.changes},
+		{kind: KindInventoryProfiles, storage: s.InventoryProfileStorage, changes: s.InventoryProfileStorage.changes},
 	}
-	if err := s.VNetStorage.Download(); err != nil {
-		fmt.Println("VNetStorage", err)
-		return err
+	for _, entry := range s.subStorages {
+		entry.interval = refreshInterval(entry.kind, options.RefreshIntervals)
+		entry.controller = s.name
+		entry.changes.setNotify(s.publish)
 	}
-	if err := s.VPCStorage.Download(); err != nil {
-		fmt.Println("VPCStorage", err)
-		return err
+	return s
+}
+
+func refreshInterval(kind string, intervals map[string]int) time.Duration {
+	if seconds, ok := intervals[kind]; ok && seconds > 0 {
+		return time.Duration(seconds) * time.Second
 	}
-	if err := s.BGPStorage.Download(); err != nil {
-		fmt.Println("BGPStorage", err)
-		return err
+	if interval, ok := defaultRefreshIntervals[kind]; ok {
+		return interval
 	}
-	if err := s.L4LBStorage.Download(); err != nil {
-		fmt.Println("L4LBStorage", err)
-		return err
+	return defaultRefreshInterval
+}
+
+// AddListener registers the function which receives the added, changed and
+// removed items of every refresh.
+func (s *Storage) AddListener(listener Listener) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *Storage) publish(delta Delta) {
+	s.listenersMu.RLock()
+	defer s.listenersMu.RUnlock()
+	for _, listener := range s.listeners {
+		listener(delta)
 	}
-	if err := s.SubnetsStorage.Download(); err != nil {
-		fmt.Println("SubnetsStorage", err)
-		return err
+}
+
+// Download refreshes all sub-storages concurrently. A failing sub-storage
+// doesn't prevent the others from being refreshed, the returned error
+// aggregates the errors of all failed sub-storages.
+func (s *Storage) Download() error {
+	s.Lock()
+	defer s.Unlock()
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errors []error
+	)
+	for _, entry := range s.subStorages {
+		wg.Add(1)
+		go func(entry *subStorageEntry) {
+			defer wg.Done()
+			if err := entry.refresh(); err != nil {
+				logger.Error(err, "Download failed", "kind", entry.kind)
+				mu.Lock()
+				errors = append(errors, fmt.Errorf("%s: %s", entry.kind, err))
+				mu.Unlock()
+			}
+		}(entry)
 	}
-	if err := s.HWsStorage.Download(); err != nil {
-		fmt.Println("HWsStorage", err)
-		return err
+	wg.Wait()
+	return utilerrors.NewAggregate(errors)
+}
+
+// DownloadWithInterval refreshes every sub-storage with its own interval.
+func (s *Storage) DownloadWithInterval() {
+	s.DownloadUntil(nil)
+}
+
+// DownloadUntil refreshes every sub-storage with its own interval until stop
+// is closed. The storage is reported in the metrics meanwhile.
+func (s *Storage) DownloadUntil(stop <-chan struct{}) {
+	collector.add(s)
+	defer collector.remove(s)
+	var wg sync.WaitGroup
+	for _, entry := range s.subStorages {
+		wg.Add(1)
+		go func(entry *subStorageEntry) {
+			defer wg.Done()
+			ticker := time.NewTicker(entry.interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+				}
+				if err := entry.refresh(); err != nil {
+					logger.Error(err, "Refresh failed", "kind", entry.kind)
+				}
+			}
+		}(entry)
 	}
-	if err := s.LinksStorage.Download(); err != nil {
-		fmt.Println("LinksStorage", err)
-		return err
+	wg.Wait()
+}
+
+// Health returns the refresh health of every sub-storage.
+func (s *Storage) Health() []Health {
+	health := []Health{}
+	for _, entry := range s.subStorages {
+		health = append(health, entry.getHealth())
 	}
-	if err := s.NATStorage.Download(); err != nil {
-		fmt.Println("NATStorage", err)
-		return err
+	return health
+}
+
+// HealthOf returns the refresh health of the sub-storage with the given kind.
+func (s *Storage) HealthOf(kind string) (Health, bool) {
+	for _, entry := range s.subStorages {
+		if entry.kind == kind {
+			return entry.getHealth(), true
+		}
 	}
-	if err := s.InventoryProfileStorage.Download(); err != nil {
-		fmt.Println("InventoryProfileStorage", err)
-		return err
+	return Health{}, false
+}
+
+// IsStale reports whether any of the given sub-storages is stale, or any
+// sub-storage at all if no kinds are given.
+func (s *Storage) IsStale(kinds ...string) bool {
+	for _, entry := range s.subStorages {
+		if len(kinds) > 0 && !containsKind(kinds, entry.kind) {
+			continue
+		}
+		if entry.getHealth().Stale() {
+			return true
+		}
 	}
-	return nil
+	return false
 }
 
-// DownloadWithInterval .
-func (s *Storage) DownloadWithInterval() {
-	ticker := time.NewTicker(10 * time.Second)
-	for {
-		<-ticker.C
-		err := s.Download()
-		if err != nil {
-			fmt.Println(err)
+func containsKind(kinds []string, kind string) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
 		}
 	}
+	return false
 }
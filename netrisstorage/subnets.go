@@ -17,115 +17,132 @@ limitations under the License.
 package netrisstorage
 
 import (
+	"encoding/json"
+	"fmt"
 	"sync"
+	"sync/atomic"
 
-	"github.com/netrisai/netris-operator/configloader"
+	"github.com/netrisai/netris-operator/netrisapi"
+	api "github.com/netrisai/netriswebapi/v2"
 	"github.com/netrisai/netriswebapi/v2/types/ipam"
 )
 
 // SubnetsStorage .
 type SubnetsStorage struct {
 	sync.Mutex
-	Subnets []*ipam.IPAM
+	cred     *api.Clientset
+	vpcid    int
+	snapshot atomic.Value
+	changes  *changeTracker
+	misses   *missRefresher
+}
+
+type subnetSnapshot struct {
+	items []*ipam.IPAM
+	// indexes cover the whole IPAM tree, children included.
+	byName   map[string]*ipam.IPAM
+	byTypeID map[string]*ipam.IPAM
+}
+
+func newSubnetSnapshot(items []*ipam.IPAM) *subnetSnapshot {
+	s := &subnetSnapshot{
+		items:    items,
+		byName:   make(map[string]*ipam.IPAM),
+		byTypeID: make(map[string]*ipam.IPAM),
+	}
+	s.index(items)
+	return s
+}
+
+func (s *subnetSnapshot) index(items []*ipam.IPAM) {
+	for _, item := range items {
+		if _, ok := s.byName[item.Name]; !ok {
+			s.byName[item.Name] = item
+		}
+		if _, ok := s.byTypeID[subnetKey(item.ID, item.Type)]; !ok {
+			s.byTypeID[subnetKey(item.ID, item.Type)] = item
+		}
+		s.index(item.Children)
+	}
+}
+
+func subnetKey(id int, typo string) string {
+	return fmt.Sprintf("%s/%d", typo, id)
 }
 
 // NewSubnetsStorage .
-func NewSubnetsStorage() *SubnetsStorage {
-	return &SubnetsStorage{}
+func NewSubnetsStorage(cred *api.Clientset, vpcid int) *SubnetsStorage {
+	changes := newChangeTracker(KindSubnets)
+	return &SubnetsStorage{
+		cred:    cred,
+		vpcid:   vpcid,
+		changes: changes,
+		misses:  newMissRefresher(KindSubnets, changes),
+	}
 }
 
-// GetAll .
-func (p *SubnetsStorage) GetAll() []*ipam.IPAM {
-	p.Lock()
-	defer p.Unlock()
-	return p.getAll()
+func (p *SubnetsStorage) load() *subnetSnapshot {
+	if s, ok := p.snapshot.Load().(*subnetSnapshot); ok {
+		return s
+	}
+	return newSubnetSnapshot(nil)
 }
 
-func (p *SubnetsStorage) getAll() []*ipam.IPAM {
+// GetAll .
+func (p *SubnetsStorage) GetAll() []*ipam.IPAM {
 	subnets := []*ipam.IPAM{}
-	subnets = append(subnets, p.Subnets...)
+	subnets = append(subnets, p.load().items...)
 	return subnets
 }
 
 func (p *SubnetsStorage) storeAll(items []*ipam.IPAM) {
-	p.Subnets = items
+	p.snapshot.Store(newSubnetSnapshot(items))
+	p.changes.track(trackSubnets(items))
 }
 
 // FindByName .
 func (p *SubnetsStorage) FindByName(name string) (*ipam.IPAM, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findByName(name)
-}
-
-func (p *SubnetsStorage) findByName(name string) (*ipam.IPAM, bool) {
-	for _, item := range p.Subnets {
-		if item.Name == name {
-			return item, true
-		}
-		if s, ok := p.findByNameInChildren(item, name); ok {
-			return s, true
-		}
-	}
-	return nil, false
-}
-
-func (p *SubnetsStorage) findByNameInChildren(ipam *ipam.IPAM, name string) (*ipam.IPAM, bool) {
-	for _, item := range ipam.Children {
-		if item.Name == name {
-			return item, true
-		}
-		if s, ok := p.findByNameInChildren(item, name); ok {
-			return s, true
-		}
-	}
-	return nil, false
+	item, ok := p.load().byName[name]
+	return item, ok
 }
 
 // FindByID .
 func (p *SubnetsStorage) FindByID(id int, typo string) (*ipam.IPAM, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.findByID(id, typo)
-	if !ok {
-		_ = p.download()
-		return p.findByID(id, typo)
-	}
+	var item *ipam.IPAM
+	ok := p.misses.find(subnetKey(id, typo), func() bool {
+		var found bool
+		item, found = p.load().byTypeID[subnetKey(id, typo)]
+		return found
+	}, p.Download)
 	return item, ok
 }
 
-func (p *SubnetsStorage) findInChildren(ipam *ipam.IPAM, id int, typo string) (*ipam.IPAM, bool) {
-	for _, item := range ipam.Children {
-		if item.ID == id && item.Type == typo {
-			return item, true
-		}
-
-		if s, ok := p.findInChildren(item, id, typo); ok {
-			return s, true
-		}
+// trackSubnets flattens the IPAM tree, so that a change in a child doesn't
+// mark the whole parent chain as changed.
+func trackSubnets(items []*ipam.IPAM) []trackedItem {
+	tracked := []trackedItem{}
+	for _, item := range items {
+		shallow := *item
+		shallow.Children = nil
+		tracked = append(tracked, trackedItem{
+			Key:    subnetKey(item.ID, item.Type),
+			ID:     item.ID,
+			Object: shallow,
+		})
+		tracked = append(tracked, trackSubnets(item.Children)...)
 	}
-	return nil, false
-}
-
-func (p *SubnetsStorage) findByID(id int, typo string) (*ipam.IPAM, bool) {
-	for _, item := range p.Subnets {
-		if item.ID == id && item.Type == typo {
-			return item, true
-		}
-		if s, ok := p.findInChildren(item, id, typo); ok {
-			return s, true
-		}
-	}
-	return nil, false
+	return tracked
 }
 
 // Download .
 func (p *SubnetsStorage) download() error {
-	vpcid := configloader.Root.VPCID
+	vpcid := p.vpcid
 	if vpcid == 0 {
 		vpcid = 1
 	}
-	items, err := Cred.IPAM().GetByVPC(vpcid)
+	items, err := netrisapi.Get(p.cred, "ipam", "list", func() ([]*ipam.IPAM, error) {
+		return p.cred.IPAM().GetByVPC(vpcid)
+	})
 	if err != nil {
 		return err
 	}
@@ -139,3 +156,18 @@ func (p *SubnetsStorage) Download() error {
 	defer p.Unlock()
 	return p.download()
 }
+
+func (p *SubnetsStorage) dump() interface{} {
+	return p.load().items
+}
+
+func (p *SubnetsStorage) restore(data []byte) error {
+	items := []*ipam.IPAM{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.storeAll(items)
+	return nil
+}
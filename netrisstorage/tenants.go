@@ -17,77 +17,98 @@ limitations under the License.
 package netrisstorage
 
 import (
+	"encoding/json"
 	"sync"
+	"sync/atomic"
 
+	"github.com/netrisai/netris-operator/netrisapi"
 	"github.com/netrisai/netriswebapi/v1/types/tenant"
+	api "github.com/netrisai/netriswebapi/v2"
 )
 
 // TenantsStorage .
 type TenantsStorage struct {
 	sync.Mutex
-	Tenants []*tenant.Tenant
+	cred     *api.Clientset
+	snapshot atomic.Value
+	changes  *changeTracker
+	misses   *missRefresher
+}
+
+type tenantSnapshot struct {
+	items  []*tenant.Tenant
+	byName map[string]*tenant.Tenant
+	byID   map[int]*tenant.Tenant
+}
+
+func newTenantSnapshot(items []*tenant.Tenant) *tenantSnapshot {
+	s := &tenantSnapshot{
+		items:  items,
+		byName: make(map[string]*tenant.Tenant, len(items)),
+		byID:   make(map[int]*tenant.Tenant, len(items)),
+	}
+	for _, item := range items {
+		if _, ok := s.byName[item.Name]; !ok {
+			s.byName[item.Name] = item
+		}
+		if _, ok := s.byID[item.ID]; !ok {
+			s.byID[item.ID] = item
+		}
+	}
+	return s
 }
 
 // NewTenantsStorage .
-func NewTenantsStorage() *TenantsStorage {
-	return &TenantsStorage{}
+func NewTenantsStorage(cred *api.Clientset) *TenantsStorage {
+	changes := newChangeTracker(KindTenants)
+	return &TenantsStorage{
+		cred:    cred,
+		changes: changes,
+		misses:  newMissRefresher(KindTenants, changes),
+	}
 }
 
-// GetAll .
-func (p *TenantsStorage) GetAll() []*tenant.Tenant {
-	p.Lock()
-	defer p.Unlock()
-	return p.getAll()
+func (p *TenantsStorage) load() *tenantSnapshot {
+	if s, ok := p.snapshot.Load().(*tenantSnapshot); ok {
+		return s
+	}
+	return newTenantSnapshot(nil)
 }
 
-func (p *TenantsStorage) getAll() []*tenant.Tenant {
-	return p.Tenants
+// GetAll .
+func (p *TenantsStorage) GetAll() []*tenant.Tenant {
+	return p.load().items
 }
 
 func (p *TenantsStorage) storeAll(items []*tenant.Tenant) {
-	p.Tenants = items
+	p.snapshot.Store(newTenantSnapshot(items))
+	tracked := make([]trackedItem, 0, len(items))
+	for _, item := range items {
+		tracked = append(tracked, trackedItem{Key: idKey(item.ID), ID: item.ID, Object: item})
+	}
+	p.changes.track(tracked)
 }
 
 // FindByName .
 func (p *TenantsStorage) FindByName(name string) (*tenant.Tenant, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findByName(name)
-}
-
-func (p *TenantsStorage) findByName(name string) (*tenant.Tenant, bool) {
-	for _, item := range p.Tenants {
-		if item.Name == name {
-			return item, true
-		}
-	}
-	return nil, false
+	item, ok := p.load().byName[name]
+	return item, ok
 }
 
 // FindByID .
 func (p *TenantsStorage) FindByID(id int) (*tenant.Tenant, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.findByID(id)
-	if !ok {
-		_ = p.download()
-		return p.findByID(id)
-	}
+	var item *tenant.Tenant
+	ok := p.misses.find(idKey(id), func() bool {
+		var found bool
+		item, found = p.load().byID[id]
+		return found
+	}, p.Download)
 	return item, ok
 }
 
-func (p *TenantsStorage) findByID(id int) (*tenant.Tenant, bool) {
-	for _, item := range p.Tenants {
-		if item.ID == id {
-			return item, true
-		}
-	}
-	return nil, false
-}
-
 // Download .
 func (p *TenantsStorage) download() error {
-	items, err := Cred.Tenant().Get()
+	items, err := netrisapi.Get(p.cred, "tenant", "list", p.cred.Tenant().Get)
 	if err != nil {
 		return err
 	}
@@ -101,3 +122,18 @@ func (p *TenantsStorage) Download() error {
 	defer p.Unlock()
 	return p.download()
 }
+
+func (p *TenantsStorage) dump() interface{} {
+	return p.load().items
+}
+
+func (p *TenantsStorage) restore(data []byte) error {
+	items := []*tenant.Tenant{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.storeAll(items)
+	return nil
+}
@@ -17,97 +17,127 @@ limitations under the License.
 package netrisstorage
 
 import (
+	"encoding/json"
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 
+	"github.com/netrisai/netris-operator/netrisapi"
+	api "github.com/netrisai/netriswebapi/v2"
 	"github.com/netrisai/netriswebapi/v2/types/vnet"
 )
 
 // VNetStorage .
 type VNetStorage struct {
 	sync.Mutex
-	VNets []*vnet.VNet
+	cred     *api.Clientset
+	snapshot atomic.Value
+	changes  *changeTracker
+	misses   *missRefresher
+}
+
+type vnetSnapshot struct {
+	items     []*vnet.VNet
+	byName    map[string]*vnet.VNet
+	byID      map[int]*vnet.VNet
+	byNetwork map[string]*vnet.VNet
+}
+
+func newVNetSnapshot(items []*vnet.VNet) *vnetSnapshot {
+	s := &vnetSnapshot{
+		items:     items,
+		byName:    make(map[string]*vnet.VNet, len(items)),
+		byID:      make(map[int]*vnet.VNet, len(items)),
+		byNetwork: make(map[string]*vnet.VNet),
+	}
+	for _, item := range items {
+		if _, ok := s.byName[item.Name]; !ok {
+			s.byName[item.Name] = item
+		}
+		if _, ok := s.byID[item.ID]; !ok {
+			s.byID[item.ID] = item
+		}
+		for _, gway := range item.Gateways {
+			_, ipNet, err := net.ParseCIDR(gway.Prefix)
+			if err != nil {
+				continue
+			}
+			if _, ok := s.byNetwork[ipNet.String()]; !ok {
+				s.byNetwork[ipNet.String()] = item
+			}
+		}
+	}
+	return s
 }
 
 // NewVNetStorage .
-func NewVNetStorage() *VNetStorage {
-	return &VNetStorage{}
+func NewVNetStorage(cred *api.Clientset) *VNetStorage {
+	changes := newChangeTracker(KindVNets)
+	return &VNetStorage{
+		cred:    cred,
+		changes: changes,
+		misses:  newMissRefresher(KindVNets, changes),
+	}
 }
 
-// GetAll .
-func (p *VNetStorage) GetAll() []vnet.VNet {
-	p.Lock()
-	defer p.Unlock()
-	return p.getAll()
+func (p *VNetStorage) load() *vnetSnapshot {
+	if s, ok := p.snapshot.Load().(*vnetSnapshot); ok {
+		return s
+	}
+	return newVNetSnapshot(nil)
 }
 
-func (p *VNetStorage) getAll() []vnet.VNet {
+// GetAll .
+func (p *VNetStorage) GetAll() []vnet.VNet {
 	vnets := []vnet.VNet{}
-	for _, vnet := range p.VNets {
+	for _, vnet := range p.load().items {
 		vnets = append(vnets, *vnet)
 	}
 	return vnets
 }
 
 func (p *VNetStorage) storeAll(items []*vnet.VNet) {
-	p.VNets = items
+	p.snapshot.Store(newVNetSnapshot(items))
+	tracked := make([]trackedItem, 0, len(items))
+	for _, item := range items {
+		tracked = append(tracked, trackedItem{Key: idKey(item.ID), ID: item.ID, Object: item})
+	}
+	p.changes.track(tracked)
 }
 
 // FindByName .
 func (p *VNetStorage) FindByName(name string) (*vnet.VNet, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findByName(name)
-}
-
-func (p *VNetStorage) findByName(name string) (*vnet.VNet, bool) {
-	for _, item := range p.VNets {
-		if item.Name == name {
-			return item, true
-		}
-	}
-	return nil, false
+	item, ok := p.load().byName[name]
+	return item, ok
 }
 
 // FindByID .
 func (p *VNetStorage) FindByID(id int) (*vnet.VNet, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.findByID(id)
-	if !ok {
-		_ = p.download()
-		return p.findByID(id)
-	}
+	var item *vnet.VNet
+	ok := p.misses.find(idKey(id), func() bool {
+		var found bool
+		item, found = p.load().byID[id]
+		return found
+	}, p.Download)
 	return item, ok
 }
 
-func (p *VNetStorage) findByID(id int) (*vnet.VNet, bool) {
-	for _, item := range p.VNets {
-		if item.ID == id {
-			return item, true
-		}
-	}
-	return nil, false
-}
-
 // FindByGateway .
 func (p *VNetStorage) FindByGateway(gateway string) (*vnet.VNet, bool) {
-	p.Lock()
-	defer p.Unlock()
-	return p.findByGateway(gateway)
-}
-
-func (p *VNetStorage) findByGateway(gateway string) (*vnet.VNet, bool) {
-	for _, item := range p.VNets {
+	_, ipNet, err := net.ParseCIDR(gateway)
+	if err != nil {
+		return nil, false
+	}
+	s := p.load()
+	if item, ok := s.byNetwork[ipNet.String()]; ok {
+		return item, true
+	}
+	for _, item := range s.items {
 		for _, gway := range item.Gateways {
 			if gway.Prefix == gateway {
 				return item, true
 			}
-			_, ipNet, err := net.ParseCIDR(gateway)
-			if err != nil {
-				return nil, false
-			}
 			if ipNet.Contains(net.ParseIP(strings.Split(gway.Prefix, "/")[0])) {
 				return item, true
 			}
@@ -118,7 +148,7 @@ func (p *VNetStorage) findByGateway(gateway string) (*vnet.VNet, bool) {
 
 // Download .
 func (p *VNetStorage) download() error {
-	items, err := Cred.VNet().Get()
+	items, err := netrisapi.Get(p.cred, "vnet", "list", p.cred.VNet().Get)
 	if err != nil {
 		return err
 	}
@@ -132,3 +162,18 @@ func (p *VNetStorage) Download() error {
 	defer p.Unlock()
 	return p.download()
 }
+
+func (p *VNetStorage) dump() interface{} {
+	return p.load().items
+}
+
+func (p *VNetStorage) restore(data []byte) error {
+	items := []*vnet.VNet{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.storeAll(items)
+	return nil
+}
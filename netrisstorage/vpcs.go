@@ -17,28 +17,69 @@ limitations under the License.
 package netrisstorage
 
 import (
+	"encoding/json"
 	"sync"
+	"sync/atomic"
 
+	"github.com/netrisai/netris-operator/netrisapi"
+	api "github.com/netrisai/netriswebapi/v2"
 	"github.com/netrisai/netriswebapi/v2/types/vpc"
 )
 
 // VPCStorage caches VPC objects retrieved from Netris API.
 type VPCStorage struct {
 	sync.Mutex
-	VPCs []*vpc.VPC
+	cred     *api.Clientset
+	snapshot atomic.Value
+	changes  *changeTracker
+	misses   *missRefresher
+}
+
+// vpcSnapshot is an immutable set of cached VPCs with lookup indexes.
+type vpcSnapshot struct {
+	items  []*vpc.VPC
+	byName map[string]*vpc.VPC
+	byID   map[int]*vpc.VPC
+}
+
+func newVPCSnapshot(items []*vpc.VPC) *vpcSnapshot {
+	s := &vpcSnapshot{
+		items:  items,
+		byName: make(map[string]*vpc.VPC, len(items)),
+		byID:   make(map[int]*vpc.VPC, len(items)),
+	}
+	for _, item := range items {
+		if _, ok := s.byName[item.Name]; !ok {
+			s.byName[item.Name] = item
+		}
+		if _, ok := s.byID[item.ID]; !ok {
+			s.byID[item.ID] = item
+		}
+	}
+	return s
 }
 
 // NewVPCStorage creates new VPC storage.
-func NewVPCStorage() *VPCStorage {
-	return &VPCStorage{}
+func NewVPCStorage(cred *api.Clientset) *VPCStorage {
+	changes := newChangeTracker(KindVPCs)
+	return &VPCStorage{
+		cred:    cred,
+		changes: changes,
+		misses:  newMissRefresher(KindVPCs, changes),
+	}
+}
+
+func (p *VPCStorage) load() *vpcSnapshot {
+	if s, ok := p.snapshot.Load().(*vpcSnapshot); ok {
+		return s
+	}
+	return newVPCSnapshot(nil)
 }
 
 // GetAll returns a copy of cached VPCs.
 func (p *VPCStorage) GetAll() []vpc.VPC {
-	p.Lock()
-	defer p.Unlock()
 	vpcs := []vpc.VPC{}
-	for _, item := range p.VPCs {
+	for _, item := range p.load().items {
 		vpcs = append(vpcs, *item)
 	}
 	return vpcs
@@ -46,52 +87,37 @@ func (p *VPCStorage) GetAll() []vpc.VPC {
 
 // FindByName returns VPC by name if present in cache, triggering refresh on miss.
 func (p *VPCStorage) FindByName(name string) (*vpc.VPC, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.findByName(name)
-	if !ok {
-		_ = p.download()
-		return p.findByName(name)
-	}
+	var item *vpc.VPC
+	ok := p.misses.find("name/"+name, func() bool {
+		var found bool
+		item, found = p.load().byName[name]
+		return found
+	}, p.Download)
 	return item, ok
 }
 
-func (p *VPCStorage) findByName(name string) (*vpc.VPC, bool) {
-	for _, item := range p.VPCs {
-		if item.Name == name {
-			return item, true
-		}
-	}
-	return nil, false
-}
-
 // FindByID returns VPC by ID, refreshing cache on miss.
 func (p *VPCStorage) FindByID(id int) (*vpc.VPC, bool) {
-	p.Lock()
-	defer p.Unlock()
-	item, ok := p.findByID(id)
-	if !ok {
-		_ = p.download()
-		return p.findByID(id)
-	}
+	var item *vpc.VPC
+	ok := p.misses.find(idKey(id), func() bool {
+		var found bool
+		item, found = p.load().byID[id]
+		return found
+	}, p.Download)
 	return item, ok
 }
 
-func (p *VPCStorage) findByID(id int) (*vpc.VPC, bool) {
-	for _, item := range p.VPCs {
-		if item.ID == id {
-			return item, true
-		}
-	}
-	return nil, false
-}
-
 func (p *VPCStorage) storeAll(items []*vpc.VPC) {
-	p.VPCs = items
+	p.snapshot.Store(newVPCSnapshot(items))
+	tracked := make([]trackedItem, 0, len(items))
+	for _, item := range items {
+		tracked = append(tracked, trackedItem{Key: idKey(item.ID), ID: item.ID, Object: item})
+	}
+	p.changes.track(tracked)
 }
 
 func (p *VPCStorage) download() error {
-	items, err := Cred.VPC().Get()
+	items, err := netrisapi.Get(p.cred, "vpc", "list", p.cred.VPC().Get)
 	if err != nil {
 		return err
 	}
@@ -105,3 +131,18 @@ func (p *VPCStorage) Download() error {
 	defer p.Unlock()
 	return p.download()
 }
+
+func (p *VPCStorage) dump() interface{} {
+	return p.load().items
+}
+
+func (p *VPCStorage) restore(data []byte) error {
+	items := []*vpc.VPC{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.storeAll(items)
+	return nil
+}